@@ -0,0 +1,369 @@
+package piraterf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	audioTrimmedSuffix  = "_trimmed"
+	audioCutSuffix      = "_cut"
+	audioStrippedSuffix = "_stripped"
+
+	defaultSilenceThresholdDB = -50.0 // dBFS, quieter than this is silence
+	minSilenceThresholdDB     = -90.0
+	maxSilenceThresholdDB     = -10.0
+	defaultSilenceMinDuration = 0.1 // seconds of silence before it's stripped
+	maxSilenceMinDuration     = 10.0
+)
+
+// audioEditOutput is where an edit goes: a new file next to the original
+// or over the original.
+type audioEditOutput struct {
+	// Name of the new file, empty for the original name with a suffix
+	OutputFileName string `json:"outputFileName"`
+	// Overwrite the original instead (the output name is ignored)
+	Replace bool `json:"replace"`
+}
+
+// audioTrim keeps the part of a file between Start and End, or drops it
+// with Cut. End 0 means the end of the file.
+type audioTrim struct {
+	Start float64 `json:"start"` // seconds
+	End   float64 `json:"end"`   // seconds
+	Cut   bool    `json:"cut"`
+}
+
+// audioSilenceStrip drops the silence at the start and the end of a file.
+type audioSilenceStrip struct {
+	Threshold   float64 `json:"threshold"`   // dBFS, 0 for the default
+	MinDuration float64 `json:"minDuration"` // seconds, 0 for the default
+}
+
+// trimAudioFile cuts a WAV down to (or cuts out) the given segment.
+func (s *PIrateRF) trimAudioFile(
+	inputPath string,
+	trim audioTrim,
+	output audioEditOutput,
+) (string, error) {
+	if err := validateAudioEditInput(inputPath); err != nil {
+		return "", err
+	}
+
+	duration, err := s.getAudioDuration(inputPath)
+	if err != nil {
+		return "", ctxerrors.Wrap(err, "failed to measure audio")
+	}
+
+	effects, err := trim.soxEffects(duration)
+	if err != nil {
+		return "", err
+	}
+
+	suffix := audioTrimmedSuffix
+	if trim.Cut {
+		suffix = audioCutSuffix
+	}
+
+	return s.editAudioFile(inputPath, output, suffix, effects)
+}
+
+// soxEffects validates the segment against the file duration and returns
+// the sox trim doing it. Positions starting with = are absolute.
+func (t audioTrim) soxEffects(duration float64) ([]string, error) {
+	end := t.End
+	if end == 0 {
+		end = duration
+	}
+
+	switch {
+	case t.Start < 0 || t.End < 0:
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "start and end can't be negative",
+		)
+	case t.Start >= duration:
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"start %gs is past the end of the %.1fs file",
+			t.Start, duration,
+		)
+	case end <= t.Start:
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "end must be after start",
+		)
+	case end > duration:
+		end = duration
+	}
+
+	start := formatSeconds(t.Start)
+	atEnd := end >= duration
+
+	if !t.Cut {
+		if t.Start == 0 && atEnd {
+			return nil, ctxerrors.Wrap(
+				commonerrors.ErrInvalidValue, "nothing to trim",
+			)
+		}
+
+		if atEnd {
+			return []string{"trim", start}, nil
+		}
+
+		return []string{"trim", start, "=" + formatSeconds(end)}, nil
+	}
+
+	// sox alternates between keeping and skipping at each position
+	switch {
+	case t.Start == 0 && atEnd:
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "can't cut out the whole file",
+		)
+	case t.Start == 0:
+		return []string{"trim", formatSeconds(end)}, nil
+	case atEnd:
+		return []string{"trim", "0", "=" + start}, nil
+	default:
+		return []string{"trim", "0", "=" + start, "=" + formatSeconds(end)}, nil
+	}
+}
+
+// stripAudioSilence drops the silence at both ends of a WAV.
+func (s *PIrateRF) stripAudioSilence(
+	inputPath string,
+	strip audioSilenceStrip,
+	output audioEditOutput,
+) (string, error) {
+	if err := validateAudioEditInput(inputPath); err != nil {
+		return "", err
+	}
+
+	effects, err := strip.soxEffects()
+	if err != nil {
+		return "", err
+	}
+
+	return s.editAudioFile(inputPath, output, audioStrippedSuffix, effects)
+}
+
+// soxEffects returns the sox silence run stripping the start, turning the
+// audio around to strip the end the same way and turning it back.
+func (st audioSilenceStrip) soxEffects() ([]string, error) {
+	threshold := st.Threshold
+	if threshold == 0 {
+		threshold = defaultSilenceThresholdDB
+	}
+
+	minDuration := st.MinDuration
+	if minDuration == 0 {
+		minDuration = defaultSilenceMinDuration
+	}
+
+	if threshold < minSilenceThresholdDB || threshold > maxSilenceThresholdDB {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"threshold must be between %g and %g dBFS, got %g",
+			minSilenceThresholdDB, maxSilenceThresholdDB, threshold,
+		)
+	}
+
+	if minDuration < 0 || minDuration > maxSilenceMinDuration {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"minDuration must be between 0 and %g seconds, got %g",
+			maxSilenceMinDuration, minDuration,
+		)
+	}
+
+	silence := []string{
+		"silence", "1",
+		formatSeconds(minDuration),
+		strconv.FormatFloat(threshold, 'f', 1, 64) + "d",
+	}
+
+	effects := append([]string{}, silence...)
+	effects = append(effects, "reverse")
+	effects = append(effects, silence...)
+
+	return append(effects, "reverse"), nil
+}
+
+// validateAudioEditInput makes sure the file to edit is a WAV that's there.
+func validateAudioEditInput(inputPath string) error {
+	if !strings.EqualFold(filepath.Ext(inputPath), constants.FileExtensionWAV) {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"only WAV files can be edited: %s",
+			filepath.Base(inputPath),
+		)
+	}
+
+	if _, err := os.Stat(inputPath); err != nil {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrNotFound,
+			"file does not exist: %s",
+			inputPath,
+		)
+	}
+
+	return nil
+}
+
+// audioEditInputPath resolves the file an edit request names, HTTP or
+// file system path. Only uploads and SFX can be edited, whatever resolves
+// to somewhere else gets refused.
+func (s *PIrateRF) audioEditInputPath(filePath string) (string, error) {
+	inputPath, ok, err := s.filesPathUnder(
+		filePath,
+		audioUploadsPath,
+		filepath.Join(audioFilesDir, audioSFXDir),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"only uploads and SFX can be edited: %s",
+			filePath,
+		)
+	}
+
+	return inputPath, nil
+}
+
+// filesPathUnder resolves an HTTP or file system path and tells whether it
+// ends up inside one of the given dirs of FilesDir.
+func (s *PIrateRF) filesPathUnder(
+	filePath string,
+	dirs ...string,
+) (string, bool, error) {
+	resolved, err := filepath.Abs(s.convertHTTPPathToFileSystem(filePath))
+	if err != nil {
+		return "", false, ctxerrors.Wrap(err, "failed to resolve file path")
+	}
+
+	for _, dir := range dirs {
+		root, err := filepath.Abs(filepath.Join(s.config.FilesDir, dir))
+		if err != nil {
+			return "", false, ctxerrors.Wrap(err, "failed to resolve files dir")
+		}
+
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != "." && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, true, nil
+		}
+	}
+
+	return resolved, false, nil
+}
+
+// audioEditOutputPath returns where an edit of inputPath goes. New files
+// go next to the original and never over another file.
+func audioEditOutputPath(
+	inputPath string,
+	output audioEditOutput,
+	suffix string,
+) (string, error) {
+	if output.Replace {
+		return inputPath, nil
+	}
+
+	name := output.OutputFileName
+	if name == "" {
+		name = strings.TrimSuffix(
+			filepath.Base(inputPath),
+			filepath.Ext(inputPath),
+		) + suffix
+	}
+
+	if filepath.Base(name) != name || strings.HasPrefix(name, ".") {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid output file name: %s",
+			name,
+		)
+	}
+
+	if !strings.EqualFold(filepath.Ext(name), constants.FileExtensionWAV) {
+		name += constants.FileExtensionWAV
+	}
+
+	outputPath := filepath.Join(filepath.Dir(inputPath), name)
+	if _, err := os.Stat(outputPath); err == nil {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"file already exists: %s",
+			name,
+		)
+	}
+
+	return outputPath, nil
+}
+
+// editAudioFile runs the sox effects on a WAV. The result is written next
+// to the output and moved in place when it's done so a failed edit never
+// leaves half a file, not even over the original. New files keep the tags
+// of the original and everything gets fresh peaks.
+func (s *PIrateRF) editAudioFile(
+	inputPath string,
+	output audioEditOutput,
+	suffix string,
+	effects []string,
+) (string, error) {
+	outputPath, err := audioEditOutputPath(inputPath, output, suffix)
+	if err != nil {
+		return "", err
+	}
+
+	tmpPath := filepath.Join(
+		filepath.Dir(outputPath),
+		".edit_"+uuid.New().String()+constants.FileExtensionWAV,
+	)
+
+	ctx, cancel := context.WithTimeout(s.serviceCtx, audioConversionTimeout)
+	defer cancel()
+
+	args := append([]string{inputPath, tmpPath}, effects...)
+
+	_, stderr, err := s.commander.Output(ctx, constants.ToolSox, args)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return "", ctxerrors.Wrapf(
+			err,
+			"sox edit failed, stderr: %s",
+			string(stderr),
+		)
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return "", ctxerrors.Wrap(err, "failed to save edited file")
+	}
+
+	if !output.Replace {
+		if meta, ok := loadAudioMetadata(inputPath); ok {
+			if err := writeAudioMetadata(outputPath, meta); err != nil {
+				logrus.WithError(err).
+					WithField("file", outputPath).
+					Warn("Failed to copy audio metadata")
+			}
+		}
+	}
+
+	cacheAudioPeaks(outputPath)
+
+	return outputPath, nil
+}
@@ -0,0 +1,280 @@
+package piraterf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioTrim_SoxEffects(t *testing.T) {
+	tests := []struct {
+		name     string
+		trim     audioTrim
+		expected []string
+	}{
+		{
+			name:     "keep from start",
+			trim:     audioTrim{Start: 1.5},
+			expected: []string{"trim", "1.500"},
+		},
+		{
+			name:     "keep segment",
+			trim:     audioTrim{Start: 1, End: 4},
+			expected: []string{"trim", "1.000", "=4.000"},
+		},
+		{
+			name:     "keep head",
+			trim:     audioTrim{End: 4},
+			expected: []string{"trim", "0.000", "=4.000"},
+		},
+		{
+			name:     "end past the file is the end",
+			trim:     audioTrim{Start: 2, End: 60},
+			expected: []string{"trim", "2.000"},
+		},
+		{
+			name:     "cut middle",
+			trim:     audioTrim{Start: 2, End: 3, Cut: true},
+			expected: []string{"trim", "0", "=2.000", "=3.000"},
+		},
+		{
+			name:     "cut head",
+			trim:     audioTrim{End: 3, Cut: true},
+			expected: []string{"trim", "3.000"},
+		},
+		{
+			name:     "cut tail",
+			trim:     audioTrim{Start: 8, Cut: true},
+			expected: []string{"trim", "0", "=8.000"},
+		},
+		{name: "negative", trim: audioTrim{Start: -1}},
+		{name: "start past the end", trim: audioTrim{Start: 10}},
+		{name: "end before start", trim: audioTrim{Start: 5, End: 2}},
+		{name: "nothing to trim", trim: audioTrim{}},
+		{name: "cut everything", trim: audioTrim{Cut: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effects, err := tt.trim.soxEffects(10)
+			if tt.expected == nil {
+				require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, effects)
+		})
+	}
+}
+
+func TestAudioSilenceStrip_SoxEffects(t *testing.T) {
+	effects, err := audioSilenceStrip{}.soxEffects()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"silence", "1", "0.100", "-50.0d", "reverse",
+		"silence", "1", "0.100", "-50.0d", "reverse",
+	}, effects)
+
+	effects, err = audioSilenceStrip{Threshold: -40, MinDuration: 0.5}.
+		soxEffects()
+	require.NoError(t, err)
+	assert.Equal(t, "-40.0d", effects[3])
+	assert.Equal(t, "0.500", effects[2])
+
+	_, err = audioSilenceStrip{Threshold: -5}.soxEffects()
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = audioSilenceStrip{MinDuration: 11}.soxEffects()
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestAudioEditOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "song.wav")
+	require.NoError(t, os.WriteFile(inputPath, []byte("wav"), 0o600))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "taken.wav"), []byte("wav"), 0o600,
+	))
+
+	path, err := audioEditOutputPath(inputPath, audioEditOutput{}, "_trimmed")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "song_trimmed.wav"), path)
+
+	path, err = audioEditOutputPath(
+		inputPath, audioEditOutput{OutputFileName: "short"}, "_trimmed",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "short.wav"), path)
+
+	path, err = audioEditOutputPath(
+		inputPath,
+		audioEditOutput{OutputFileName: "ignored", Replace: true},
+		"_trimmed",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, inputPath, path)
+
+	for _, name := range []string{"taken.wav", "../escape", ".hidden"} {
+		_, err = audioEditOutputPath(
+			inputPath, audioEditOutput{OutputFileName: name}, "_trimmed",
+		)
+		require.ErrorIs(t, err, commonerrors.ErrInvalidValue, name)
+	}
+}
+
+func TestAudioEditInputPath(t *testing.T) {
+	filesDir := t.TempDir()
+	service := &PIrateRF{config: Config{FilesDir: filesDir}}
+
+	allowed := map[string]string{
+		"/files/audio/uploads/song.wav": filepath.Join(
+			filesDir, audioUploadsPath, "song.wav",
+		),
+		"/files/audio/sfx/horn.wav": filepath.Join(
+			filesDir, audioFilesDir, audioSFXDir, "horn.wav",
+		),
+		filepath.Join(filesDir, audioUploadsPath, "song.wav"): filepath.Join(
+			filesDir, audioUploadsPath, "song.wav",
+		),
+	}
+
+	for filePath, expected := range allowed {
+		inputPath, err := service.audioEditInputPath(filePath)
+		require.NoError(t, err, filePath)
+		assert.Equal(t, expected, inputPath)
+	}
+
+	refused := []string{
+		"/files/audio/uploads/../../../etc/x.wav",
+		"/files/audio/uploads/../recordings/live.wav",
+		"/files/audio/uploads",
+		"/files/images/uploads/x.wav",
+		"/etc/x.wav",
+		filepath.Join(filesDir, audioUploadsPath, "..", "..", "x.wav"),
+		"../x.wav",
+	}
+
+	for _, filePath := range refused {
+		_, err := service.audioEditInputPath(filePath)
+		require.ErrorIs(t, err, commonerrors.ErrInvalidValue, filePath)
+	}
+}
+
+func TestTrimAudioFile(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "song.wav")
+	writeTestWAV(t, inputPath, 1, 1000, make([]int16, 3000))
+
+	// The duration comes from the sidecar, sox only edits
+	_, err := writeAudioPeaks(inputPath)
+	require.NoError(t, err)
+	require.NoError(t, writeAudioMetadata(
+		inputPath, audioMetadata{Title: "Hack the Planet"},
+	))
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(inputPath),
+		commander.Regex(`/\.edit_[0-9a-f-]+\.wav$`),
+		commander.Exact("trim"),
+		commander.Exact("1.000"),
+		commander.Exact("=2.500"),
+	)
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	outputPath, err := service.trimAudioFile(
+		inputPath, audioTrim{Start: 1, End: 2.5}, audioEditOutput{},
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+
+	assert.Equal(t, filepath.Join(dir, "song_trimmed.wav"), outputPath)
+	assert.FileExists(t, outputPath)
+	assert.FileExists(t, inputPath)
+
+	meta, ok := loadAudioMetadata(outputPath)
+	require.True(t, ok)
+	assert.Equal(t, "Hack the Planet", meta.Title)
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".edit_*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+
+	// Out of range never gets to sox
+	_, err = service.trimAudioFile(
+		inputPath, audioTrim{Start: 5}, audioEditOutput{},
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = service.trimAudioFile(
+		filepath.Join(dir, "missing.wav"), audioTrim{Start: 1}, audioEditOutput{},
+	)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	_, err = service.trimAudioFile(
+		filepath.Join(dir, "song.mp3"), audioTrim{Start: 1}, audioEditOutput{},
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestStripAudioSilence_Replace(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "song.wav")
+	writeTestWAV(t, inputPath, 1, 1000, make([]int16, 3000))
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(inputPath),
+		commander.Regex(`/\.edit_[0-9a-f-]+\.wav$`),
+		commander.Exact("silence"), commander.Any(), commander.Any(),
+		commander.Any(), commander.Exact("reverse"),
+		commander.Exact("silence"), commander.Any(), commander.Any(),
+		commander.Any(), commander.Exact("reverse"),
+	)
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(inputPath),
+		commander.Regex(`/\.edit_[0-9a-f-]+\.wav$`),
+		commander.Exact("silence"), commander.Any(), commander.Any(),
+		commander.Any(), commander.Exact("reverse"),
+		commander.Exact("silence"), commander.Any(), commander.Any(),
+		commander.Any(), commander.Exact("reverse"),
+	).ReturnError(errors.New("sox broke"))
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	outputPath, err := service.stripAudioSilence(
+		inputPath, audioSilenceStrip{}, audioEditOutput{Replace: true},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, inputPath, outputPath)
+
+	data, err := os.ReadFile(inputPath)
+	require.NoError(t, err)
+	assert.Equal(t, "fake wav", string(data))
+
+	// A failed edit leaves the original as it was
+	_, err = service.stripAudioSilence(
+		inputPath, audioSilenceStrip{}, audioEditOutput{Replace: true},
+	)
+	require.Error(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+
+	data, err = os.ReadFile(inputPath)
+	require.NoError(t, err)
+	assert.Equal(t, "fake wav", string(data))
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".edit_*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
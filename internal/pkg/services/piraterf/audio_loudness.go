@@ -0,0 +1,357 @@
+package piraterf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+)
+
+const (
+	// Upload form fields controlling loudness normalisation.
+	formFieldNormalize      = "normalize"
+	formFieldLoudnessTarget = "loudnessTarget"
+	formFieldTruePeak       = "truePeak"
+	formFieldLoudnessRange  = "loudnessRange"
+	formFieldHighpass       = "highpass"
+
+	// Defaults suit FM - loud enough without squashing everything.
+	defaultLoudnessTarget = -16.0 // LUFS
+	defaultTruePeak       = -1.5  // dBTP
+	defaultLoudnessRange  = 11.0  // LU
+
+	// Ranges accepted by the ffmpeg loudnorm filter.
+	minLoudnessTarget = -70.0
+	maxLoudnessTarget = -5.0
+	minTruePeak       = -9.0
+	maxTruePeak       = 0.0
+	minLoudnessRange  = 1.0
+	maxLoudnessRange  = 50.0
+
+	// maxHighpass is the highest high-pass cutoff in Hz. Anything above
+	// it eats the voice, not the rumble.
+	maxHighpass = 1000.0
+
+	decibelsPerAmplitudeDecade = 20
+)
+
+// loudnessOptions are the EBU R128 normalisation targets of an upload.
+type loudnessOptions struct {
+	Target        float64 `json:"target"`        // integrated loudness, LUFS
+	TruePeak      float64 `json:"truePeak"`      // dBTP, also the limiter
+	LoudnessRange float64 `json:"loudnessRange"` // LU
+	Highpass      float64 `json:"highpass"`      // Hz, 0 is off
+}
+
+// loudnessMeasurement is what loudnorm measured on one side of the filter.
+type loudnessMeasurement struct {
+	Integrated    float64 `json:"integrated"`    // LUFS
+	TruePeak      float64 `json:"truePeak"`      // dBTP
+	LoudnessRange float64 `json:"loudnessRange"` // LU
+	Threshold     float64 `json:"threshold"`     // LUFS
+}
+
+// loudnessReport is reported in the upload response as "loudness".
+type loudnessReport struct {
+	Input   loudnessMeasurement `json:"input"`
+	Output  loudnessMeasurement `json:"output"`
+	Targets loudnessOptions     `json:"targets"`
+}
+
+// loudnormStats is the JSON loudnorm prints with print_format=json.
+// Everything comes as strings.
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	OutputI      string `json:"output_i"`
+	OutputTP     string `json:"output_tp"`
+	OutputLRA    string `json:"output_lra"`
+	OutputThresh string `json:"output_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// parseLoudnessOptions reads the normalisation form fields of an upload.
+// It returns nil when normalisation wasn't asked for.
+func parseLoudnessOptions(request *http.Request) (*loudnessOptions, error) {
+	normalize := request.FormValue(formFieldNormalize)
+	if normalize == "" {
+		return nil, nil //nolint:nilnil // nil options mean no normalisation
+	}
+
+	enabled, err := strconv.ParseBool(normalize)
+	if err != nil {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid %s: %s", formFieldNormalize, normalize,
+		)
+	}
+
+	if !enabled {
+		return nil, nil //nolint:nilnil // nil options mean no normalisation
+	}
+
+	opts := &loudnessOptions{
+		Target:        defaultLoudnessTarget,
+		TruePeak:      defaultTruePeak,
+		LoudnessRange: defaultLoudnessRange,
+	}
+
+	fields := []struct {
+		name     string
+		value    *float64
+		min, max float64
+	}{
+		{
+			formFieldLoudnessTarget, &opts.Target,
+			minLoudnessTarget, maxLoudnessTarget,
+		},
+		{formFieldTruePeak, &opts.TruePeak, minTruePeak, maxTruePeak},
+		{
+			formFieldLoudnessRange, &opts.LoudnessRange,
+			minLoudnessRange, maxLoudnessRange,
+		},
+		{formFieldHighpass, &opts.Highpass, 0, maxHighpass},
+	}
+
+	for _, field := range fields {
+		raw := request.FormValue(field.name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < field.min || value > field.max {
+			return nil, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"%s must be between %g and %g, got %s",
+				field.name, field.min, field.max, raw,
+			)
+		}
+
+		*field.value = value
+	}
+
+	return opts, nil
+}
+
+// normalizeAudioFileWithFFmpeg converts an audio file to the same format as
+// convertAudioFileWithFFmpeg while bringing it to the loudness targets. The
+// first pass measures the file and the second applies linear loudnorm with
+// those measurements, followed by a true peak limiter.
+func (s *PIrateRF) normalizeAudioFileWithFFmpeg(
+	inputPath string,
+	opts *loudnessOptions,
+	stereo bool,
+) (string, *loudnessReport, error) {
+	if err := s.ensureFilesDirsExist(); err != nil {
+		return "", nil, err
+	}
+
+	baseFilename := strings.TrimSuffix(
+		filepath.Base(inputPath),
+		filepath.Ext(inputPath),
+	)
+	outputPath := filepath.Join(
+		s.config.FilesDir,
+		audioUploadsPath,
+		baseFilename+constants.FileExtensionWAV,
+	)
+
+	ctx, cancel := context.WithTimeout(s.serviceCtx, audioConversionTimeout)
+	defer cancel()
+
+	// Pass 1: measure, output goes nowhere
+	measured, err := s.runLoudnorm(ctx, []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-af", loudnessFilters(opts, nil, stereo),
+		"-f", "null", "-",
+	})
+	if err != nil {
+		return "", nil, ctxerrors.Wrap(err, "loudness measurement failed")
+	}
+
+	input, err := measured.input()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Pass 2: normalise into 16-bit 48kHz WAV, the filters pick the channels
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-af", loudnessFilters(opts, measured, stereo),
+		"-ar", audioSampleRate,
+	}
+
+	if !stereo {
+		args = append(args, "-ac", audioChannels)
+	}
+
+	normalized, err := s.runLoudnorm(ctx, append(args,
+		"-c:a", "pcm_s16le",
+		"-y",
+		outputPath,
+	))
+	if err != nil {
+		return "", nil, ctxerrors.Wrap(err, "loudness normalisation failed")
+	}
+
+	output, err := normalized.output()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, err := os.Stat(outputPath); err != nil {
+		return "", nil, ctxerrors.Wrapf(err, "normalised file not found")
+	}
+
+	return outputPath, &loudnessReport{
+		Input:   input,
+		Output:  output,
+		Targets: *opts,
+	}, nil
+}
+
+// runLoudnorm runs ffmpeg and returns the stats loudnorm printed.
+func (s *PIrateRF) runLoudnorm(
+	ctx context.Context,
+	args []string,
+) (*loudnormStats, error) {
+	_, stderr, err := s.commander.Output(ctx, "ffmpeg", args)
+	if err != nil {
+		return nil, ctxerrors.Wrapf(
+			err,
+			"ffmpeg failed, stderr: %s",
+			string(stderr),
+		)
+	}
+
+	return parseLoudnormStats(stderr)
+}
+
+// loudnessFilters builds the ffmpeg filter chain. The downmix comes first
+// so what gets measured is what goes on air. Without measured it's the
+// measuring pass.
+func loudnessFilters(
+	opts *loudnessOptions,
+	measured *loudnormStats,
+	stereo bool,
+) string {
+	filters := []string{
+		"aformat=channel_layouts=" + uploadChannelLayouts(stereo),
+	}
+
+	if opts.Highpass > 0 {
+		filters = append(filters, fmt.Sprintf("highpass=f=%g", opts.Highpass))
+	}
+
+	loudnorm := fmt.Sprintf(
+		"loudnorm=I=%g:TP=%g:LRA=%g",
+		opts.Target, opts.TruePeak, opts.LoudnessRange,
+	)
+
+	if measured != nil {
+		loudnorm += fmt.Sprintf(
+			":measured_I=%s:measured_TP=%s:measured_LRA=%s"+
+				":measured_thresh=%s:offset=%s:linear=true",
+			measured.InputI, measured.InputTP, measured.InputLRA,
+			measured.InputThresh, measured.TargetOffset,
+		)
+	}
+
+	filters = append(filters, loudnorm+":print_format=json")
+
+	if measured != nil {
+		// loudnorm falls back to dynamic mode when linear can't reach the
+		// target without clipping, the limiter catches what's left
+		filters = append(filters, fmt.Sprintf(
+			"alimiter=limit=%.4f:level=disabled",
+			math.Pow(10, opts.TruePeak/decibelsPerAmplitudeDecade),
+		))
+	}
+
+	return strings.Join(filters, ",")
+}
+
+// parseLoudnormStats picks the loudnorm JSON out of ffmpeg's stderr. It's
+// the last thing ffmpeg prints.
+func parseLoudnormStats(stderr []byte) (*loudnormStats, error) {
+	start := bytes.LastIndexByte(stderr, '{')
+	end := bytes.LastIndexByte(stderr, '}')
+
+	if start < 0 || end < start {
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrNotFound,
+			"no loudnorm stats in ffmpeg output",
+		)
+	}
+
+	var stats loudnormStats
+	if err := json.Unmarshal(stderr[start:end+1], &stats); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to parse loudnorm stats")
+	}
+
+	return &stats, nil
+}
+
+func (st *loudnormStats) input() (loudnessMeasurement, error) {
+	return parseLoudnessMeasurement(
+		st.InputI, st.InputTP, st.InputLRA, st.InputThresh,
+	)
+}
+
+func (st *loudnormStats) output() (loudnessMeasurement, error) {
+	return parseLoudnessMeasurement(
+		st.OutputI, st.OutputTP, st.OutputLRA, st.OutputThresh,
+	)
+}
+
+func parseLoudnessMeasurement(
+	integrated, truePeak, loudnessRange, threshold string,
+) (loudnessMeasurement, error) {
+	var measurement loudnessMeasurement
+
+	fields := []struct {
+		raw   string
+		value *float64
+	}{
+		{integrated, &measurement.Integrated},
+		{truePeak, &measurement.TruePeak},
+		{loudnessRange, &measurement.LoudnessRange},
+		{threshold, &measurement.Threshold},
+	}
+
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(field.raw, 64)
+		if err != nil {
+			return loudnessMeasurement{}, ctxerrors.Wrapf(
+				err, "invalid loudnorm value '%s'", field.raw,
+			)
+		}
+
+		// Silence measures -inf which there's nothing to normalise from
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return loudnessMeasurement{}, ctxerrors.Wrap(
+				commonerrors.ErrInvalidValue,
+				"audio is silent, nothing to normalise",
+			)
+		}
+
+		*field.value = value
+	}
+
+	return measurement, nil
+}
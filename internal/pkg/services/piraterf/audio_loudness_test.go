@@ -0,0 +1,252 @@
+package piraterf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loudnormOutput is what ffmpeg prints to stderr with a loudnorm filter.
+const loudnormOutput = `Input #0, mp3, from 'test_2s.mp3':
+[Parsed_loudnorm_1 @ 0x5581]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-9.32",
+	"input_lra" : "3.20",
+	"input_thresh" : "-37.88",
+	"output_i" : "-16.02",
+	"output_tp" : "-1.50",
+	"output_lra" : "3.10",
+	"output_thresh" : "-26.29",
+	"normalization_type" : "linear",
+	"target_offset" : "0.02"
+}
+`
+
+func newUploadFormRequest(values url.Values) *http.Request {
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/upload",
+		strings.NewReader(values.Encode()),
+	)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return request
+}
+
+func TestParseLoudnessOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      url.Values
+		expected    *loudnessOptions
+		expectError bool
+	}{
+		{name: "not asked for", values: url.Values{}},
+		{
+			name:   "turned off",
+			values: url.Values{formFieldNormalize: {"false"}},
+		},
+		{
+			name:   "defaults",
+			values: url.Values{formFieldNormalize: {"true"}},
+			expected: &loudnessOptions{
+				Target:        defaultLoudnessTarget,
+				TruePeak:      defaultTruePeak,
+				LoudnessRange: defaultLoudnessRange,
+			},
+		},
+		{
+			name: "custom targets",
+			values: url.Values{
+				formFieldNormalize:      {"1"},
+				formFieldLoudnessTarget: {"-23"},
+				formFieldTruePeak:       {"-2"},
+				formFieldLoudnessRange:  {"7"},
+				formFieldHighpass:       {"80"},
+			},
+			expected: &loudnessOptions{
+				Target:        -23,
+				TruePeak:      -2,
+				LoudnessRange: 7,
+				Highpass:      80,
+			},
+		},
+		{
+			name:        "bad switch",
+			values:      url.Values{formFieldNormalize: {"maybe"}},
+			expectError: true,
+		},
+		{
+			name: "target out of range",
+			values: url.Values{
+				formFieldNormalize:      {"true"},
+				formFieldLoudnessTarget: {"3"},
+			},
+			expectError: true,
+		},
+		{
+			name: "high-pass not a number",
+			values: url.Values{
+				formFieldNormalize: {"true"},
+				formFieldHighpass:  {"lots"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseLoudnessOptions(newUploadFormRequest(tt.values))
+
+			if tt.expectError {
+				require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, opts)
+		})
+	}
+}
+
+func TestLoudnessFilters(t *testing.T) {
+	opts := &loudnessOptions{
+		Target:        -16,
+		TruePeak:      -1.5,
+		LoudnessRange: 11,
+		Highpass:      80,
+	}
+
+	assert.Equal(t,
+		"aformat=channel_layouts=mono,highpass=f=80,"+
+			"loudnorm=I=-16:TP=-1.5:LRA=11:print_format=json",
+		loudnessFilters(opts, nil, false),
+	)
+
+	measured, err := parseLoudnormStats([]byte(loudnormOutput))
+	require.NoError(t, err)
+
+	opts.Highpass = 0
+	assert.Equal(t,
+		"aformat=channel_layouts=mono,"+
+			"loudnorm=I=-16:TP=-1.5:LRA=11"+
+			":measured_I=-27.61:measured_TP=-9.32:measured_LRA=3.20"+
+			":measured_thresh=-37.88:offset=0.02:linear=true"+
+			":print_format=json,"+
+			"alimiter=limit=0.8414:level=disabled",
+		loudnessFilters(opts, measured, false),
+	)
+
+	// Kept in stereo it's measured and normalised in stereo
+	assert.Equal(t,
+		"aformat=channel_layouts=mono|stereo,"+
+			"loudnorm=I=-16:TP=-1.5:LRA=11:print_format=json",
+		loudnessFilters(opts, nil, true),
+	)
+}
+
+func TestParseLoudnormStats(t *testing.T) {
+	stats, err := parseLoudnormStats([]byte(loudnormOutput))
+	require.NoError(t, err)
+
+	input, err := stats.input()
+	require.NoError(t, err)
+	assert.Equal(t, loudnessMeasurement{
+		Integrated:    -27.61,
+		TruePeak:      -9.32,
+		LoudnessRange: 3.2,
+		Threshold:     -37.88,
+	}, input)
+
+	output, err := stats.output()
+	require.NoError(t, err)
+	assert.InDelta(t, -16.02, output.Integrated, 0)
+
+	_, err = parseLoudnormStats([]byte("no stats here"))
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	silent, err := parseLoudnormStats([]byte(
+		`{"input_i":"-inf","input_tp":"-inf","input_lra":"0.00",` +
+			`"input_thresh":"-70.00"}`,
+	))
+	require.NoError(t, err)
+
+	_, err = silent.input()
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestAudioConversionPostprocessor_Normalize(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	tempDir := t.TempDir()
+
+	inputPath := filepath.Join(tempDir, "loud.mp3")
+	require.NoError(t, os.WriteFile(inputPath, []byte("mp3"), 0o600))
+
+	mockCmd := &fileCreatingMockCommander{
+		MockCommander: *commander.NewMock(),
+	}
+
+	// Measuring pass, then the normalising one
+	mockCmd.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-hide_banner"), commander.Exact("-nostats"),
+		commander.Exact("-i"), commander.Exact(inputPath),
+		commander.Exact("-af"), commander.Regex(`^aformat.*loudnorm`),
+		commander.Exact("-f"), commander.Exact("null"), commander.Exact("-"),
+	).ReturnOutput([]byte(loudnormOutput))
+	mockCmd.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-hide_banner"), commander.Exact("-nostats"),
+		commander.Exact("-i"), commander.Exact(inputPath),
+		commander.Exact("-af"), commander.Regex(`measured_I=-27.61.*alimiter`),
+		commander.Exact("-ar"), commander.Exact(audioSampleRate),
+		commander.Exact("-ac"), commander.Exact(audioChannels),
+		commander.Exact("-c:a"), commander.Exact("pcm_s16le"),
+		commander.Exact("-y"), commander.Any(),
+	).ReturnOutput([]byte(loudnormOutput))
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: tempDir},
+		commander:  mockCmd,
+		rpitx:      gorpitx.GetInstance(),
+	}
+
+	opts := &loudnessOptions{
+		Target:        defaultLoudnessTarget,
+		TruePeak:      defaultTruePeak,
+		LoudnessRange: defaultLoudnessRange,
+	}
+
+	result, err := service.audioConversionPostprocessor(
+		map[string]any{"path": inputPath},
+		opts,
+		false,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mockCmd.VerifyExpectations())
+
+	expectedPath := filepath.Join(tempDir, audioUploadsPath, "loud.wav")
+	assert.Equal(t, expectedPath, result["path"])
+	assert.Equal(t, true, result["converted"])
+	assert.FileExists(t, expectedPath)
+	assert.NoFileExists(t, inputPath)
+
+	report, ok := result["loudness"].(*loudnessReport)
+	require.True(t, ok)
+	assert.InDelta(t, -27.61, report.Input.Integrated, 0)
+	assert.InDelta(t, -16.02, report.Output.Integrated, 0)
+	assert.Equal(t, *opts, report.Targets)
+}
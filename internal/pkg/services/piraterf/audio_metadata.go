@@ -0,0 +1,247 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+// audioMetadataSuffix replaces the .wav of an audio file to name the
+// sidecar holding its tags.
+const audioMetadataSuffix = ".meta.json"
+
+// audioMetadata is what the tags of an upload said before it became a WAV.
+type audioMetadata struct {
+	Title  string `json:"title,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+}
+
+// ffprobeTags is the part of ffprobe's JSON output holding tags. Most
+// formats keep them on the container, Ogg keeps them on the stream.
+type ffprobeTags struct {
+	Format struct {
+		Tags map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Tags map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+func (m audioMetadata) isZero() bool {
+	return m == audioMetadata{}
+}
+
+// radioText is the RDS RT for the track: "Artist - Title", or whichever
+// of them there is.
+func (m audioMetadata) radioText() string {
+	text := m.Title
+	if m.Artist != "" && m.Title != "" {
+		text = m.Artist + " - " + m.Title
+	} else if m.Artist != "" {
+		text = m.Artist
+	}
+
+	return truncateRDSText(text, rdsRTMaxLength)
+}
+
+// programService is the RDS PS for the track: the artist, or the title
+// when there's no artist.
+func (m audioMetadata) programService() string {
+	text := m.Artist
+	if text == "" {
+		text = m.Title
+	}
+
+	return truncateRDSText(text, rdsPSMaxLength)
+}
+
+// truncateRDSText makes text fit an RDS field: one line, at most maxLength
+// bytes and no half characters.
+func truncateRDSText(text string, maxLength int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	for len(text) > maxLength {
+		runes := []rune(text)
+		text = string(runes[:len(runes)-1])
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// extractAudioMetadata reads the title, artist and album tags of an audio
+// file with ffprobe.
+func (s *PIrateRF) extractAudioMetadata(
+	inputPath string,
+) (audioMetadata, error) {
+	ctx, cancel := context.WithTimeout(s.serviceCtx, audioConversionTimeout)
+	defer cancel()
+
+	stdout, stderr, err := s.commander.Output(
+		ctx,
+		constants.ToolFFProbe,
+		[]string{
+			"-v", "quiet",
+			"-print_format", "json",
+			"-show_format",
+			"-show_streams",
+			inputPath,
+		},
+	)
+	if err != nil {
+		return audioMetadata{}, ctxerrors.Wrapf(
+			err,
+			"ffprobe failed, stderr: %s",
+			string(stderr),
+		)
+	}
+
+	var probe ffprobeTags
+	if err := json.Unmarshal(stdout, &probe); err != nil {
+		return audioMetadata{}, ctxerrors.Wrap(
+			err, "failed to parse ffprobe output",
+		)
+	}
+
+	tagSets := []map[string]string{probe.Format.Tags}
+	for _, stream := range probe.Streams {
+		tagSets = append(tagSets, stream.Tags)
+	}
+
+	var meta audioMetadata
+
+	for _, tags := range tagSets {
+		for key, value := range tags {
+			value = strings.TrimSpace(value)
+
+			switch strings.ToLower(key) {
+			case "title":
+				meta.Title = firstNonEmpty(meta.Title, value)
+			case "artist":
+				meta.Artist = firstNonEmpty(meta.Artist, value)
+			case "album":
+				meta.Album = firstNonEmpty(meta.Album, value)
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// audioMetadataPath returns the sidecar path of an audio file.
+func audioMetadataPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) +
+		audioMetadataSuffix
+}
+
+// writeAudioMetadata saves the sidecar next to the audio file.
+func writeAudioMetadata(audioPath string, meta audioMetadata) error {
+	return writeSidecarJSON(audioMetadataPath(audioPath), meta)
+}
+
+// writeSidecarJSON saves a sidecar through a temp file so readers never
+// see half of one.
+func writeSidecarJSON(sidecarPath string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal sidecar")
+	}
+
+	tmpPath := sidecarPath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, data, filePerms); err != nil {
+		return ctxerrors.Wrap(err, "failed to write sidecar")
+	}
+
+	if err := os.Rename(tmpPath, sidecarPath); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return ctxerrors.Wrap(err, "failed to save sidecar")
+	}
+
+	return nil
+}
+
+// loadAudioMetadata reads the sidecar of an audio file. Files without one
+// have no metadata.
+func loadAudioMetadata(audioPath string) (audioMetadata, bool) {
+	data, err := os.ReadFile(audioMetadataPath(audioPath))
+	if err != nil {
+		return audioMetadata{}, false
+	}
+
+	var meta audioMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		logrus.WithError(err).WithField("audio", audioPath).
+			Warn("ignoring unreadable audio metadata")
+
+		return audioMetadata{}, false
+	}
+
+	return meta, !meta.isZero()
+}
+
+// audioSidecarPaths returns the sidecar files that belong to an audio file.
+// Only WAVs have sidecars.
+func audioSidecarPaths(audioPath string) []string {
+	if !strings.EqualFold(
+		filepath.Ext(audioPath), constants.FileExtensionWAV,
+	) {
+		return nil
+	}
+
+	return []string{audioMetadataPath(audioPath), audioPeaksPath(audioPath)}
+}
+
+// renameAudioSidecars takes the sidecars of a renamed audio file along.
+func renameAudioSidecars(oldPath, newPath string) error {
+	oldSidecars := audioSidecarPaths(oldPath)
+	newSidecars := audioSidecarPaths(newPath)
+
+	if len(newSidecars) != len(oldSidecars) {
+		// Renamed away from .wav, the sidecars don't belong to it anymore
+		return removeAudioSidecars(oldPath)
+	}
+
+	var errs []error
+
+	for i, oldSidecar := range oldSidecars {
+		err := os.Rename(oldSidecar, newSidecars[i])
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, ctxerrors.Wrap(err, "failed to rename sidecar"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// removeAudioSidecars removes the sidecars of a deleted audio file.
+func removeAudioSidecars(audioPath string) error {
+	var errs []error
+
+	for _, sidecar := range audioSidecarPaths(audioPath) {
+		err := os.Remove(sidecar)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, ctxerrors.Wrap(err, "failed to remove sidecar"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
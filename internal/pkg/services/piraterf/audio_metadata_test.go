@@ -0,0 +1,174 @@
+package piraterf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioMetadata_RDSTexts(t *testing.T) {
+	tests := []struct {
+		name       string
+		meta       audioMetadata
+		expectedRT string
+		expectedPS string
+	}{
+		{
+			name:       "artist and title",
+			meta:       audioMetadata{Title: "Hack the Planet", Artist: "Zero Cool"},
+			expectedRT: "Zero Cool - Hack the Planet",
+			expectedPS: "Zero Coo",
+		},
+		{
+			name:       "title only",
+			meta:       audioMetadata{Title: "Jingle"},
+			expectedRT: "Jingle",
+			expectedPS: "Jingle",
+		},
+		{
+			name:       "artist only",
+			meta:       audioMetadata{Artist: "Acid Burn", Album: "Hackers"},
+			expectedRT: "Acid Burn",
+			expectedPS: "Acid Bur",
+		},
+		{
+			name: "line breaks and overlong",
+			meta: audioMetadata{
+				Title: "A very\nlong title that goes on and on and on and " +
+					"on and on and on and on",
+				Artist: "Ünïcödé",
+			},
+			expectedRT: "Ünïcödé - A very long title that goes on and on " +
+				"and on and o",
+			expectedPS: "Ünïcö",
+		},
+		{name: "album only", meta: audioMetadata{Album: "Hackers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := tt.meta.radioText()
+			assert.Equal(t, tt.expectedRT, rt)
+			assert.LessOrEqual(t, len(rt), rdsRTMaxLength)
+			assert.Empty(t, rdsRTText().validate(rt))
+
+			assert.Equal(t, tt.expectedPS, tt.meta.programService())
+			assert.LessOrEqual(t, len(tt.meta.programService()), rdsPSMaxLength)
+		})
+	}
+}
+
+func TestExtractAudioMetadata(t *testing.T) {
+	mock := commander.NewMock()
+	mock.Expect(constants.ToolFFProbe,
+		"-v", "quiet", "-print_format", "json",
+		"-show_format", "-show_streams", "song.ogg",
+	).ReturnOutput([]byte(`{
+		"format": {"tags": {"ALBUM": "Hackers"}},
+		"streams": [
+			{"tags": {"TITLE": " Hack the Planet ", "ARTIST": "Zero Cool"}}
+		]
+	}`))
+	mock.Expect(constants.ToolFFProbe,
+		"-v", "quiet", "-print_format", "json",
+		"-show_format", "-show_streams", "broken.mp3",
+	).ReturnOutput([]byte("nope"))
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	meta, err := service.extractAudioMetadata("song.ogg")
+	require.NoError(t, err)
+	assert.Equal(t, audioMetadata{
+		Title: "Hack the Planet", Artist: "Zero Cool", Album: "Hackers",
+	}, meta)
+
+	_, err = service.extractAudioMetadata("broken.mp3")
+	require.Error(t, err)
+
+	_, err = service.extractAudioMetadata("missing.mp3")
+	require.Error(t, err)
+}
+
+func TestAudioMetadataSidecar(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "song.wav")
+	meta := audioMetadata{Title: "Hack the Planet", Artist: "Zero Cool"}
+
+	_, ok := loadAudioMetadata(audioPath)
+	assert.False(t, ok)
+
+	require.NoError(t, writeAudioMetadata(audioPath, meta))
+	assert.FileExists(t, filepath.Join(dir, "song"+audioMetadataSuffix))
+
+	loaded, ok := loadAudioMetadata(audioPath)
+	require.True(t, ok)
+	assert.Equal(t, meta, loaded)
+
+	// Renaming the WAV takes the sidecar along
+	renamedPath := filepath.Join(dir, "renamed.wav")
+	require.NoError(t, renameAudioSidecars(audioPath, renamedPath))
+
+	_, ok = loadAudioMetadata(audioPath)
+	assert.False(t, ok)
+
+	loaded, ok = loadAudioMetadata(renamedPath)
+	require.True(t, ok)
+	assert.Equal(t, meta, loaded)
+
+	// Other files have no sidecars to touch
+	assert.Nil(t, audioSidecarPaths(filepath.Join(dir, "renamed.Y")))
+
+	require.NoError(t, removeAudioSidecars(renamedPath))
+	assert.NoFileExists(t, audioMetadataPath(renamedPath))
+	require.NoError(t, removeAudioSidecars(renamedPath))
+}
+
+func TestAudioConversionPostprocessor_Metadata(t *testing.T) {
+	tempDir := t.TempDir()
+
+	inputPath := filepath.Join(tempDir, "song.mp3")
+	require.NoError(t, os.WriteFile(inputPath, []byte("mp3"), 0o600))
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolFFProbe,
+		commander.Exact("-v"), commander.Exact("quiet"),
+		commander.Exact("-print_format"), commander.Exact("json"),
+		commander.Exact("-show_format"), commander.Exact("-show_streams"),
+		commander.Exact(inputPath),
+	).ReturnOutput([]byte(
+		`{"format": {"tags": {"title": "Hack the Planet"}}}`,
+	))
+	mock.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-i"), commander.Exact(inputPath),
+		commander.Exact("-ar"), commander.Any(),
+		commander.Exact("-ac"), commander.Any(),
+		commander.Exact("-c:a"), commander.Any(),
+		commander.Exact("-y"), commander.Any(),
+	)
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: tempDir},
+		commander:  mock,
+		rpitx:      gorpitx.GetInstance(),
+	}
+
+	result, err := service.audioConversionPostprocessor(
+		map[string]any{"path": inputPath}, nil, false,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+
+	assert.Equal(t, audioMetadata{Title: "Hack the Planet"}, result["metadata"])
+
+	meta, ok := loadAudioMetadata(result["path"].(string))
+	require.True(t, ok)
+	assert.Equal(t, "Hack the Planet", meta.Title)
+}
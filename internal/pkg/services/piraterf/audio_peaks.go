@@ -0,0 +1,403 @@
+package piraterf
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// audioPeaksSuffix replaces the .wav of an audio file to name the
+	// sidecar holding its waveform.
+	audioPeaksSuffix = ".peaks.json"
+
+	waveformBuckets = 1000  // min/max pairs drawn per waveform
+	silenceFloorDB  = -96.0 // quietest level reported, 16-bit silence
+
+	wavHeaderSize      = 12 // "RIFF", size, "WAVE"
+	wavChunkHeaderSize = 8  // chunk ID, size
+	wavFmtMinSize      = 16 // PCM fmt chunk without extension
+	wavFormatPCM       = 1
+	wavFormatExtended  = 0xFFFE
+	wavBitsPerSample   = 16
+	wavBytesPerSample  = wavBitsPerSample / 8
+	wavReadFrames      = 4096 // frames read from disk at once
+	int16FullScale     = 32768.0
+	peaksLevelDecimals = 1000 // min/max are kept to 3 decimals
+	peaksDBDecimals    = 100  // levels are kept to 2 decimals
+)
+
+// audioPeaks is the waveform of an audio file small enough for the UI to
+// draw without fetching the WAV: the lowest and highest sample of every
+// bucket (-1 to 1) plus the overall levels in dBFS.
+type audioPeaks struct {
+	Duration   float64   `json:"duration"` // seconds
+	SampleRate int       `json:"sampleRate"`
+	Channels   int       `json:"channels"`
+	RMS        float64   `json:"rms"`  // dBFS
+	Peak       float64   `json:"peak"` // dBFS
+	Min        []float64 `json:"min"`
+	Max        []float64 `json:"max"`
+}
+
+// wavFormat is what the fmt chunk of a WAV says about its samples.
+type wavFormat struct {
+	channels   int
+	sampleRate int
+	blockAlign int
+}
+
+// computeAudioPeaks reads a 16-bit PCM WAV and works out its waveform and
+// levels. Every upload ends up as one so that's all this reads.
+func computeAudioPeaks(audioPath string) (audioPeaks, error) {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return audioPeaks{}, ctxerrors.Wrap(err, "failed to open audio file")
+	}
+
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+
+	format, dataSize, err := readWAVHeader(reader)
+	if err != nil {
+		return audioPeaks{}, err
+	}
+
+	frames := dataSize / int64(format.blockAlign)
+	bucketFrames := max((frames+waveformBuckets-1)/waveformBuckets, 1)
+
+	peaks := audioPeaks{
+		SampleRate: format.sampleRate,
+		Channels:   format.channels,
+		Min:        make([]float64, 0, waveformBuckets),
+		Max:        make([]float64, 0, waveformBuckets),
+	}
+
+	var (
+		sumSquares float64
+		peak       float64
+		bucketMin  = 1.0
+		bucketMax  = -1.0
+		inBucket   int64
+	)
+
+	buf := make([]byte, wavReadFrames*format.blockAlign)
+
+	read := int64(0)
+
+	for read < frames {
+		chunk := buf[:min(frames-read, wavReadFrames)*int64(format.blockAlign)]
+
+		// A file cut short still has a waveform, up to where it ends
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) &&
+			!errors.Is(err, io.EOF) {
+			return audioPeaks{}, ctxerrors.Wrap(err, "failed to read samples")
+		}
+
+		chunkFrames := int64(n / format.blockAlign)
+
+		for frame := range int(chunkFrames) {
+			for channel := range format.channels {
+				offset := frame*format.blockAlign + channel*wavBytesPerSample
+				sample := float64(int16(
+					binary.LittleEndian.Uint16(chunk[offset:]),
+				)) / int16FullScale
+
+				sumSquares += sample * sample
+				peak = max(peak, math.Abs(sample))
+				bucketMin = min(bucketMin, sample)
+				bucketMax = max(bucketMax, sample)
+			}
+
+			inBucket++
+			if inBucket == bucketFrames {
+				peaks.appendBucket(bucketMin, bucketMax)
+				bucketMin, bucketMax, inBucket = 1, -1, 0
+			}
+		}
+
+		read += chunkFrames
+
+		if err != nil {
+			break
+		}
+	}
+
+	if inBucket > 0 {
+		peaks.appendBucket(bucketMin, bucketMax)
+	}
+
+	peaks.Duration = float64(read) / float64(format.sampleRate)
+
+	if read > 0 {
+		peaks.RMS = levelDB(
+			math.Sqrt(sumSquares / float64(read*int64(format.channels))),
+		)
+	} else {
+		peaks.RMS = silenceFloorDB
+	}
+
+	peaks.Peak = levelDB(peak)
+
+	return peaks, nil
+}
+
+func (p *audioPeaks) appendBucket(bucketMin, bucketMax float64) {
+	p.Min = append(p.Min, roundTo(bucketMin, peaksLevelDecimals))
+	p.Max = append(p.Max, roundTo(bucketMax, peaksLevelDecimals))
+}
+
+// readWAVHeader walks the chunks up to the sample data and returns the
+// format and how many bytes of samples follow.
+func readWAVHeader(reader io.Reader) (wavFormat, int64, error) {
+	header := make([]byte, wavHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return wavFormat{}, 0, ctxerrors.Wrap(err, "failed to read WAV header")
+	}
+
+	if string(header[:4]) != "RIFF" || string(header[8:]) != "WAVE" {
+		return wavFormat{}, 0, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "not a WAV file",
+		)
+	}
+
+	var format *wavFormat
+
+	chunkHeader := make([]byte, wavChunkHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, chunkHeader); err != nil {
+			return wavFormat{}, 0, ctxerrors.Wrap(err, "no WAV data chunk")
+		}
+
+		id := string(chunkHeader[:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:]))
+
+		switch id {
+		case "fmt ":
+			parsed, err := readWAVFormat(reader, size)
+			if err != nil {
+				return wavFormat{}, 0, err
+			}
+
+			format = &parsed
+		case "data":
+			if format == nil {
+				return wavFormat{}, 0, ctxerrors.Wrap(
+					commonerrors.ErrInvalidValue, "WAV data before fmt chunk",
+				)
+			}
+
+			return *format, size, nil
+		default:
+			// Chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, reader, size+size%2); err != nil {
+				return wavFormat{}, 0, ctxerrors.Wrapf(
+					err, "failed to skip WAV %q chunk", id,
+				)
+			}
+		}
+	}
+}
+
+// readWAVFormat reads a fmt chunk and makes sure it's 16-bit PCM.
+func readWAVFormat(reader io.Reader, size int64) (wavFormat, error) {
+	if size < wavFmtMinSize {
+		return wavFormat{}, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "WAV fmt chunk too short",
+		)
+	}
+
+	data := make([]byte, size+size%2)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return wavFormat{}, ctxerrors.Wrap(err, "failed to read WAV fmt chunk")
+	}
+
+	audioFormat := binary.LittleEndian.Uint16(data[0:])
+	format := wavFormat{
+		channels:   int(binary.LittleEndian.Uint16(data[2:])),
+		sampleRate: int(binary.LittleEndian.Uint32(data[4:])),
+		blockAlign: int(binary.LittleEndian.Uint16(data[12:])),
+	}
+	bitsPerSample := binary.LittleEndian.Uint16(data[14:])
+
+	if audioFormat != wavFormatPCM && audioFormat != wavFormatExtended ||
+		bitsPerSample != wavBitsPerSample {
+		return wavFormat{}, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"only 16-bit PCM WAVs are supported, got format %d with %d bits",
+			audioFormat, bitsPerSample,
+		)
+	}
+
+	if format.channels < 1 || format.sampleRate < 1 ||
+		format.blockAlign != format.channels*wavBytesPerSample {
+		return wavFormat{}, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "broken WAV fmt chunk",
+		)
+	}
+
+	return format, nil
+}
+
+// levelDB converts a linear level to dBFS, silence stops at the floor.
+func levelDB(level float64) float64 {
+	if level <= 0 {
+		return silenceFloorDB
+	}
+
+	//nolint:mnd
+	return roundTo(max(20*math.Log10(level), silenceFloorDB), peaksDBDecimals)
+}
+
+func roundTo(value, decimals float64) float64 {
+	return math.Round(value*decimals) / decimals
+}
+
+// audioPeaksPath returns the waveform sidecar path of an audio file.
+func audioPeaksPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) +
+		audioPeaksSuffix
+}
+
+// writeAudioPeaks works out the waveform of an audio file and saves it in
+// the sidecar next to it.
+func writeAudioPeaks(audioPath string) (audioPeaks, error) {
+	peaks, err := computeAudioPeaks(audioPath)
+	if err != nil {
+		return audioPeaks{}, err
+	}
+
+	if err := writeSidecarJSON(audioPeaksPath(audioPath), peaks); err != nil {
+		return audioPeaks{}, err
+	}
+
+	return peaks, nil
+}
+
+// cacheAudioPeaks writes the waveform sidecar of a file that just landed in
+// the audio directories. Not having one only costs a sox call later, so
+// failing is just logged.
+func cacheAudioPeaks(audioPath string) (audioPeaks, bool) {
+	peaks, err := writeAudioPeaks(audioPath)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("file", audioPath).
+			Warn("Failed to compute audio peaks")
+
+		return audioPeaks{}, false
+	}
+
+	return peaks, true
+}
+
+// loadAudioPeaks reads the waveform sidecar of an audio file. A sidecar
+// older than the file describes something else and doesn't count.
+func loadAudioPeaks(audioPath string) (audioPeaks, bool) {
+	sidecarPath := audioPeaksPath(audioPath)
+
+	audioInfo, err := os.Stat(audioPath)
+	if err != nil {
+		return audioPeaks{}, false
+	}
+
+	sidecarInfo, err := os.Stat(sidecarPath)
+	if err != nil || sidecarInfo.ModTime().Before(audioInfo.ModTime()) {
+		return audioPeaks{}, false
+	}
+
+	data, err := os.ReadFile(sidecarPath)
+	if err != nil {
+		return audioPeaks{}, false
+	}
+
+	var peaks audioPeaks
+	if err := json.Unmarshal(data, &peaks); err != nil {
+		logrus.WithError(err).WithField("audio", audioPath).
+			Warn("ignoring unreadable audio peaks")
+
+		return audioPeaks{}, false
+	}
+
+	return peaks, true
+}
+
+// getAudioDuration returns how long an audio file lasts, from its waveform
+// sidecar when there's an up to date one and from sox otherwise.
+func (s *PIrateRF) getAudioDuration(audioPath string) (float64, error) {
+	if peaks, ok := loadAudioPeaks(audioPath); ok {
+		return peaks.Duration, nil
+	}
+
+	return s.getAudioDurationWithSox(audioPath)
+}
+
+// backfillAudioPeaks writes the missing or outdated waveform sidecars of
+// the uploads and SFX, for files that got there before the sidecars
+// existed or without going through an upload.
+func (s *PIrateRF) backfillAudioPeaks(ctx context.Context) {
+	dirs := []string{
+		filepath.Join(s.config.FilesDir, audioUploadsPath),
+		filepath.Join(s.config.FilesDir, audioFilesDir, audioSFXDir),
+	}
+
+	written := 0
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(
+			path string,
+			entry fs.DirEntry,
+			err error,
+		) error {
+			if err != nil {
+				return err
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if entry.IsDir() || !strings.EqualFold(
+				filepath.Ext(path), constants.FileExtensionWAV,
+			) {
+				return nil
+			}
+
+			if _, ok := loadAudioPeaks(path); ok {
+				return nil
+			}
+
+			if _, ok := cacheAudioPeaks(path); ok {
+				written++
+			}
+
+			return nil
+		})
+		if err != nil {
+			logrus.WithError(err).
+				WithField("dir", dir).
+				Warn("Audio peaks backfill stopped")
+
+			return
+		}
+	}
+
+	if written > 0 {
+		logrus.WithField("files", written).Info("Audio peaks backfilled")
+	}
+}
@@ -0,0 +1,255 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestWAV writes a 16-bit PCM WAV of interleaved samples with a LIST
+// chunk in front of the data, the way ffmpeg tags its output.
+func writeTestWAV(
+	t *testing.T,
+	path string,
+	channels, sampleRate int,
+	samples []int16,
+) {
+	t.Helper()
+
+	list := []byte("INFOISFT\x05\x00\x00\x00test\x00")
+	dataSize := len(samples) * 2
+
+	var buf []byte
+
+	buf = append(buf, "RIFF"...)
+	buf = binary.LittleEndian.AppendUint32(
+		buf, uint32(4+8+16+8+len(list)+1+8+dataSize),
+	)
+	buf = append(buf, "WAVEfmt "...)
+	buf = binary.LittleEndian.AppendUint32(buf, 16)
+	buf = binary.LittleEndian.AppendUint16(buf, wavFormatPCM)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(channels))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(sampleRate))
+	buf = binary.LittleEndian.AppendUint32(
+		buf, uint32(sampleRate*channels*2),
+	)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(channels*2))
+	buf = binary.LittleEndian.AppendUint16(buf, wavBitsPerSample)
+	buf = append(buf, "LIST"...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(list)))
+	buf = append(buf, list...)
+	buf = append(buf, 0) // odd sized chunks get padded
+	buf = append(buf, "data"...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(dataSize))
+
+	for _, sample := range samples {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(sample))
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, buf, 0o600))
+}
+
+func TestComputeAudioPeaks_Fixture(t *testing.T) {
+	peaks, err := computeAudioPeaks(".fixtures/test_2s.wav")
+	require.NoError(t, err)
+
+	assert.InDelta(t, 2.0, peaks.Duration, 0.001)
+	assert.Equal(t, 44100, peaks.SampleRate)
+	assert.Equal(t, 1, peaks.Channels)
+	assert.Len(t, peaks.Max, len(peaks.Min))
+	assert.NotEmpty(t, peaks.Min)
+	assert.LessOrEqual(t, len(peaks.Min), waveformBuckets)
+	assert.LessOrEqual(t, peaks.Peak, 0.0)
+	assert.Less(t, peaks.RMS, peaks.Peak)
+
+	for i := range peaks.Min {
+		assert.LessOrEqual(t, peaks.Min[i], peaks.Max[i])
+	}
+}
+
+func TestComputeAudioPeaks_Stereo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stereo.wav")
+
+	// Left at half scale, right at minus half scale
+	samples := make([]int16, 0, 2*3000)
+	for range 3000 {
+		samples = append(samples, 16384, -16384)
+	}
+
+	writeTestWAV(t, path, 2, 1000, samples)
+
+	peaks, err := computeAudioPeaks(path)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 3.0, peaks.Duration, 0.001)
+	assert.Equal(t, 2, peaks.Channels)
+	assert.Len(t, peaks.Min, waveformBuckets)
+	assert.InDelta(t, -6.02, peaks.Peak, 0.01)
+	assert.InDelta(t, -6.02, peaks.RMS, 0.01)
+
+	for i := range peaks.Min {
+		assert.InDelta(t, -0.5, peaks.Min[i], 0.001)
+		assert.InDelta(t, 0.5, peaks.Max[i], 0.001)
+	}
+}
+
+func TestComputeAudioPeaks_Edges(t *testing.T) {
+	dir := t.TempDir()
+
+	// Nothing in it is silence
+	emptyPath := filepath.Join(dir, "empty.wav")
+	writeTestWAV(t, emptyPath, 1, 48000, nil)
+
+	peaks, err := computeAudioPeaks(emptyPath)
+	require.NoError(t, err)
+	assert.Zero(t, peaks.Duration)
+	assert.Empty(t, peaks.Min)
+	assert.InDelta(t, silenceFloorDB, peaks.RMS, 0)
+	assert.InDelta(t, silenceFloorDB, peaks.Peak, 0)
+
+	// A file cut short is as long as what's there
+	cutPath := filepath.Join(dir, "cut.wav")
+	writeTestWAV(t, cutPath, 1, 1000, make([]int16, 2000))
+
+	data, err := os.ReadFile(cutPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cutPath, data[:len(data)-1001], 0o600))
+
+	peaks, err = computeAudioPeaks(cutPath)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.499, peaks.Duration, 0.001)
+
+	// Not a WAV
+	textPath := filepath.Join(dir, "text.wav")
+	require.NoError(t, os.WriteFile(textPath, []byte("not a wav at all"), 0o600))
+
+	_, err = computeAudioPeaks(textPath)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	// 8-bit isn't what uploads turn into
+	eightBitPath := filepath.Join(dir, "8bit.wav")
+	writeTestWAV(t, eightBitPath, 1, 8000, make([]int16, 10))
+
+	data, err = os.ReadFile(eightBitPath)
+	require.NoError(t, err)
+	binary.LittleEndian.PutUint16(data[34:], 8)
+	require.NoError(t, os.WriteFile(eightBitPath, data, 0o600))
+
+	_, err = computeAudioPeaks(eightBitPath)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = computeAudioPeaks(filepath.Join(dir, "missing.wav"))
+	require.Error(t, err)
+}
+
+func TestAudioPeaksSidecar(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "song.wav")
+	writeTestWAV(t, audioPath, 1, 1000, make([]int16, 1500))
+
+	_, ok := loadAudioPeaks(audioPath)
+	assert.False(t, ok)
+
+	written, err := writeAudioPeaks(audioPath)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "song"+audioPeaksSuffix))
+
+	loaded, ok := loadAudioPeaks(audioPath)
+	require.True(t, ok)
+	assert.Equal(t, written, loaded)
+
+	// The sidecars go wherever the WAV goes
+	renamedPath := filepath.Join(dir, "renamed.wav")
+	require.NoError(t, os.Rename(audioPath, renamedPath))
+	require.NoError(t, renameAudioSidecars(audioPath, renamedPath))
+
+	_, ok = loadAudioPeaks(renamedPath)
+	assert.True(t, ok)
+
+	// A WAV changed after its sidecar was written doesn't trust it
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(renamedPath, later, later))
+
+	_, ok = loadAudioPeaks(renamedPath)
+	assert.False(t, ok)
+
+	require.NoError(t, removeAudioSidecars(renamedPath))
+	assert.NoFileExists(t, audioPeaksPath(renamedPath))
+}
+
+func TestGetAudioDuration(t *testing.T) {
+	dir := t.TempDir()
+
+	cachedPath := filepath.Join(dir, "cached.wav")
+	writeTestWAV(t, cachedPath, 1, 1000, make([]int16, 2500))
+
+	_, err := writeAudioPeaks(cachedPath)
+	require.NoError(t, err)
+
+	uncachedPath := filepath.Join(dir, "uncached.wav")
+	writeTestWAV(t, uncachedPath, 1, 1000, make([]int16, 10))
+
+	// Only the file without a sidecar goes to sox
+	mock := commander.NewMock()
+	mock.Expect(constants.ToolSox, "--info", "-D", uncachedPath).
+		ReturnOutput([]byte("7.250000"))
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	duration, err := service.getAudioDuration(cachedPath)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, duration, 0.001)
+
+	duration, err = service.getAudioDuration(uncachedPath)
+	require.NoError(t, err)
+	assert.InDelta(t, 7.25, duration, 0.001)
+	require.NoError(t, mock.VerifyExpectations())
+}
+
+func TestBackfillAudioPeaks(t *testing.T) {
+	filesDir := t.TempDir()
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+
+	uploadPath := filepath.Join(audioDir, uploadsSubdir, "upload.wav")
+	sfxPath := filepath.Join(audioDir, audioSFXDir, "jingles", "sfx.wav")
+	brokenPath := filepath.Join(audioDir, uploadsSubdir, "broken.wav")
+	otherPath := filepath.Join(audioDir, uploadsSubdir, "notes.txt")
+
+	writeTestWAV(t, uploadPath, 1, 1000, make([]int16, 1000))
+	writeTestWAV(t, sfxPath, 1, 1000, make([]int16, 500))
+	require.NoError(t, os.WriteFile(brokenPath, []byte("nope"), 0o600))
+	require.NoError(t, os.WriteFile(otherPath, []byte("notes"), 0o600))
+
+	service := &PIrateRF{config: Config{FilesDir: filesDir}}
+	service.backfillAudioPeaks(context.Background())
+
+	peaks, ok := loadAudioPeaks(uploadPath)
+	require.True(t, ok)
+	assert.InDelta(t, 1.0, peaks.Duration, 0.001)
+
+	peaks, ok = loadAudioPeaks(sfxPath)
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, peaks.Duration, 0.001)
+
+	assert.NoFileExists(t, audioPeaksPath(brokenPath))
+	assert.NoFileExists(t, audioPeaksPath(otherPath))
+
+	// Nothing happens once it's cancelled
+	require.NoError(t, removeAudioSidecars(uploadPath))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.backfillAudioPeaks(ctx)
+
+	assert.NoFileExists(t, audioPeaksPath(uploadPath))
+}
@@ -1,16 +1,20 @@
 package piraterf
 
 import (
+	"bufio"
 	"context"
 	"maps"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
 	"github.com/psyb0t/ctxerrors"
 	"github.com/sirupsen/logrus"
 )
@@ -20,12 +24,50 @@ const (
 	audioConversionTimeout = 30 * time.Second
 	audioPlaylistTimeout   = 120 * time.Second
 	audioArgsReservedCount = 6 // Reserved space for audio command arguments
+
+	// formFieldStereo is the upload form field keeping stereo uploads
+	// stereo. Without it the server default goes.
+	formFieldStereo = "stereo"
 )
 
+// parseStereoOption reads whether an upload keeps its stereo.
+func parseStereoOption(request *http.Request, fallback bool) (bool, error) {
+	raw := request.FormValue(formFieldStereo)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	stereo, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid %s: %s", formFieldStereo, raw,
+		)
+	}
+
+	return stereo, nil
+}
+
+// uploadChannelLayouts are the channel layouts a converted upload can end
+// up with. Kept in stereo, mono stays mono and anything with more channels
+// gets mixed down to stereo.
+func uploadChannelLayouts(stereo bool) string {
+	if stereo {
+		return "mono|stereo"
+	}
+
+	return "mono"
+}
+
 // audioConversionPostprocessor converts uploaded audio files to optimal format
-// using ffmpeg.
+// using ffmpeg. With loudness options it also normalises them and reports the
+// measured loudness. Tags are saved in a sidecar next to the WAV since the
+// conversion loses them, the waveform and duration in another one. Stereo
+// uploads stay stereo when asked to, everything else ends up mono.
 func (s *PIrateRF) audioConversionPostprocessor(
 	response map[string]any,
+	loudness *loudnessOptions,
+	stereo bool,
 ) (map[string]any, error) {
 	// Get the file path from the response
 	filePath, ok := response["path"].(string)
@@ -54,8 +96,36 @@ func (s *PIrateRF) audioConversionPostprocessor(
 		return response, nil
 	}
 
+	var (
+		convertedPath string
+		converted     bool
+		report        *loudnessReport
+		err           error
+	)
+
+	// Read the tags while there's still an original to read them from
+	metadata, metadataErr := s.extractAudioMetadata(filePath)
+	if metadataErr != nil {
+		logrus.WithError(metadataErr).
+			WithField("file", filePath).
+			Warn("Failed to read audio tags")
+	}
+
 	// Convert the audio file
-	convertedPath, converted, err := s.convertAudioFileWithFFmpeg(filePath)
+	if loudness != nil {
+		convertedPath, report, err = s.normalizeAudioFileWithFFmpeg(
+			filePath,
+			loudness,
+			stereo,
+		)
+		converted = err == nil
+	} else {
+		convertedPath, converted, err = s.convertAudioFileWithFFmpeg(
+			filePath,
+			stereo,
+		)
+	}
+
 	if err != nil {
 		logrus.WithError(err).
 			WithField("file", filePath).
@@ -85,14 +155,34 @@ func (s *PIrateRF) audioConversionPostprocessor(
 	newResponse["saved_filename"] = filepath.Base(convertedPath)
 	newResponse["converted"] = true
 
+	if report != nil {
+		newResponse["loudness"] = report
+	}
+
+	if !metadata.isZero() {
+		if err := writeAudioMetadata(convertedPath, metadata); err != nil {
+			logrus.WithError(err).
+				WithField("file", convertedPath).
+				Error("Failed to save audio metadata")
+		} else {
+			newResponse["metadata"] = metadata
+		}
+	}
+
+	if peaks, ok := cacheAudioPeaks(convertedPath); ok {
+		newResponse["duration"] = peaks.Duration
+		newResponse["channels"] = peaks.Channels
+	}
+
 	// Update file size
 	if stat, err := os.Stat(convertedPath); err == nil {
 		newResponse["size"] = stat.Size()
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"original":  filePath,
-		"converted": convertedPath,
+		"original":   filePath,
+		"converted":  convertedPath,
+		"normalized": report != nil,
 	}).Info("Audio file converted")
 
 	return newResponse, nil
@@ -102,6 +192,7 @@ func (s *PIrateRF) audioConversionPostprocessor(
 // ffmpeg. Returns: convertedPath, wasConverted, error.
 func (s *PIrateRF) convertAudioFileWithFFmpeg(
 	inputPath string,
+	stereo bool,
 ) (string, bool, error) {
 	// Ensure files directory structure exists
 	if err := s.ensureFilesDirsExist(); err != nil {
@@ -120,7 +211,8 @@ func (s *PIrateRF) convertAudioFileWithFFmpeg(
 		baseFilename+constants.FileExtensionWAV,
 	)
 
-	// Use ffmpeg to convert to optimal format: 16-bit 48kHz mono WAV
+	// Use ffmpeg to convert to optimal format: 16-bit 48kHz mono WAV, or
+	// mono/stereo as the source is when keeping stereo
 
 	ctx, cancel := context.WithTimeout(
 		s.serviceCtx,
@@ -129,14 +221,25 @@ func (s *PIrateRF) convertAudioFileWithFFmpeg(
 	defer cancel()
 
 	// ffmpeg -i input.webm -ar 48000 -ac 1 -c:a pcm_s16le output.wav
-	process, err := s.commander.Start(ctx, "ffmpeg", []string{
+	args := []string{
 		"-i", inputPath,
 		"-ar", audioSampleRate, // 48kHz sample rate
-		"-ac", audioChannels, // mono (1 channel)
+	}
+
+	if stereo {
+		args = append(args, "-af", "aformat=channel_layouts="+
+			uploadChannelLayouts(stereo))
+	} else {
+		args = append(args, "-ac", audioChannels) // mono (1 channel)
+	}
+
+	args = append(args,
 		"-c:a", "pcm_s16le", // 16-bit signed little-endian PCM
 		"-y", // overwrite output file
 		outputPath,
-	})
+	)
+
+	process, err := s.commander.Start(ctx, "ffmpeg", args)
 	if err != nil {
 		return "", false, ctxerrors.Wrapf(err, "start ffmpeg conversion")
 	}
@@ -155,21 +258,77 @@ func (s *PIrateRF) convertAudioFileWithFFmpeg(
 }
 
 // createPlaylistFromFiles concatenates multiple audio files into a single
-// playlist file using sox. Returns the path to the created playlist file.
-// If outputDir is empty, uses the default uploads directory for permanent
-// playlists. If outputDir is specified, uses that directory for temporary
-// playlists.
+// playlist file using sox, or ffmpeg when there are transitions or the
+// files don't all have the same channels, which sox can't join. The
+// playlist is stereo when any of the files is. Returns the path to the
+// created playlist file. If outputDir is empty, uses the
+// default uploads directory for permanent playlists. If outputDir is
+// specified, uses that directory for temporary playlists.
 func (s *PIrateRF) createPlaylistFromFiles(
 	playlistName string,
 	filePaths []string,
+	transitions playlistTransitions,
 	outputDir ...string,
 ) (string, error) {
+	if err := transitions.validate(); err != nil {
+		return "", err
+	}
+
 	outputPath := s.getPlaylistOutputPath(playlistName, outputDir...)
 	if outputPath == "" {
 		return "", ctxerrors.New("failed to determine output path")
 	}
 
-	return s.executePlaylistCreation(filePaths, outputPath)
+	channels, mixed := playlistChannels(parsePlaylistInputs(filePaths))
+
+	if !transitions.isZero() || mixed {
+		return s.createTransitionPlaylist(
+			filePaths, outputPath, transitions, channels,
+		)
+	}
+
+	return s.executePlaylistCreation(filePaths, outputPath, channels)
+}
+
+// playlistChannels is the channel count of a playlist of the inputs and
+// whether they don't all have the same.
+func playlistChannels(inputs []playlistInput) (string, bool) {
+	channels := audioChannels
+	first := ""
+	mixed := false
+
+	for _, input := range inputs {
+		inputChannels := wavChannels(input.path)
+		if first == "" {
+			first = inputChannels
+		}
+
+		mixed = mixed || inputChannels != first
+
+		if inputChannels == audioChannelsStereo {
+			channels = audioChannelsStereo
+		}
+	}
+
+	return channels, mixed
+}
+
+// wavChannels is audioChannels or audioChannelsStereo for a WAV, mono
+// when it can't tell.
+func wavChannels(audioPath string) string {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return audioChannels
+	}
+
+	defer func() { _ = file.Close() }()
+
+	format, _, err := readWAVHeader(bufio.NewReader(file))
+	if err != nil || format.channels < 2 {
+		return audioChannels
+	}
+
+	return audioChannelsStereo
 }
 
 func (s *PIrateRF) getPlaylistOutputPath(
@@ -202,11 +361,12 @@ func (s *PIrateRF) ensureWavExtension(playlistName string) string {
 func (s *PIrateRF) executePlaylistCreation(
 	filePaths []string,
 	outputPath string,
+	channels string,
 ) (string, error) {
 	ctx, cancel := context.WithTimeout(s.serviceCtx, audioPlaylistTimeout)
 	defer cancel()
 
-	soxArgs := s.buildSoxArgs(filePaths, outputPath)
+	soxArgs := s.buildSoxArgs(filePaths, outputPath, channels)
 
 	process, err := s.commander.Start(ctx, "sox", soxArgs)
 	if err != nil {
@@ -227,12 +387,13 @@ func (s *PIrateRF) executePlaylistCreation(
 func (s *PIrateRF) buildSoxArgs(
 	filePaths []string,
 	outputPath string,
+	channels string,
 ) []string {
 	soxArgs := make([]string, 0, len(filePaths)+audioArgsReservedCount)
 	soxArgs = append(soxArgs, filePaths...)
 	soxArgs = append(soxArgs, "-r", audioSampleRate)
 	soxArgs = append(soxArgs, "-b", audioBitDepth)
-	soxArgs = append(soxArgs, "-c", audioChannels)
+	soxArgs = append(soxArgs, "-c", channels)
 	soxArgs = append(soxArgs, outputPath)
 
 	return soxArgs
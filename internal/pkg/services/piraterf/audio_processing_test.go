@@ -239,7 +239,9 @@ func TestAudioConversionPostprocessor(t *testing.T) {
 				}
 			}
 
-			result, err := service.audioConversionPostprocessor(tt.inputResponse)
+			result, err := service.audioConversionPostprocessor(
+				tt.inputResponse, nil, false,
+			)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -330,7 +332,7 @@ func TestConvertAudioFileWithFFmpeg(t *testing.T) {
 			}
 
 			convertedPath, wasConverted, err := service.
-				convertAudioFileWithFFmpeg(tt.inputFile)
+				convertAudioFileWithFFmpeg(tt.inputFile, false)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -519,12 +521,14 @@ func TestCreatePlaylistFromFiles(t *testing.T) {
 				outputPath, err = service.createPlaylistFromFiles(
 					tt.playlistName,
 					tt.filePaths,
+					playlistTransitions{},
 					tt.outputDir[0],
 				)
 			} else {
 				outputPath, err = service.createPlaylistFromFiles(
 					tt.playlistName,
 					tt.filePaths,
+					playlistTransitions{},
 				)
 			}
 
@@ -545,3 +549,77 @@ func TestCreatePlaylistFromFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStereoOption(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      url.Values
+		fallback    bool
+		expected    bool
+		expectError bool
+	}{
+		{name: "server default", fallback: true, expected: true},
+		{
+			name:     "upload keeps stereo",
+			values:   url.Values{formFieldStereo: {"true"}},
+			expected: true,
+		},
+		{
+			name:     "upload goes mono",
+			values:   url.Values{formFieldStereo: {"false"}},
+			fallback: true,
+			expected: false,
+		},
+		{
+			name:        "invalid",
+			values:      url.Values{formFieldStereo: {"maybe"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stereo, err := parseStereoOption(
+				newUploadFormRequest(tt.values), tt.fallback,
+			)
+			if tt.expectError {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stereo)
+		})
+	}
+}
+
+func TestConvertAudioFileWithFFmpeg_Stereo(t *testing.T) {
+	tempDir := t.TempDir()
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-i"), commander.Exact(".fixtures/test_2s.mp3"),
+		commander.Exact("-ar"), commander.Exact(audioSampleRate),
+		// Stereo stays stereo, mono stays mono
+		commander.Exact("-af"),
+		commander.Exact("aformat=channel_layouts=mono|stereo"),
+		commander.Exact("-c:a"), commander.Exact("pcm_s16le"),
+		commander.Exact("-y"),
+		commander.Exact(filepath.Join(tempDir, audioUploadsPath, "test_2s.wav")),
+	)
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: tempDir},
+		commander:  mock,
+		rpitx:      gorpitx.GetInstance(),
+	}
+
+	_, converted, err := service.convertAudioFileWithFFmpeg(
+		".fixtures/test_2s.mp3", true,
+	)
+	require.NoError(t, err)
+	assert.True(t, converted)
+	require.NoError(t, mock.VerifyExpectations())
+}
@@ -0,0 +1,296 @@
+package piraterf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+)
+
+const (
+	maxCrossfadeSeconds = 10.0 // longest crossfade between two tracks
+	maxGapSeconds       = 30.0 // longest silence between two tracks
+	maxFadeSeconds      = 30.0 // longest fade in or out
+)
+
+// playlistTransitions smooth the joins of a playlist: the tracks either
+// crossfade into each other or get some silence between them and the whole
+// thing can fade in and out. All values are in seconds, zero means off.
+type playlistTransitions struct {
+	Crossfade float64 `json:"crossfade"`
+	Gap       float64 `json:"gap"`
+	FadeIn    float64 `json:"fadeIn"`
+	FadeOut   float64 `json:"fadeOut"`
+}
+
+// playlistInput is one track going into a playlist with the volume factor
+// sox would apply to it (empty for none).
+type playlistInput struct {
+	path   string
+	volume string
+}
+
+func (t playlistTransitions) isZero() bool {
+	return t == playlistTransitions{}
+}
+
+func (t playlistTransitions) validate() error {
+	limits := []struct {
+		name  string
+		value float64
+		max   float64
+	}{
+		{name: "crossfade", value: t.Crossfade, max: maxCrossfadeSeconds},
+		{name: "gap", value: t.Gap, max: maxGapSeconds},
+		{name: "fadeIn", value: t.FadeIn, max: maxFadeSeconds},
+		{name: "fadeOut", value: t.FadeOut, max: maxFadeSeconds},
+	}
+
+	for _, limit := range limits {
+		if limit.value < 0 || limit.value > limit.max {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"%s must be between 0 and %g seconds, got %g",
+				limit.name, limit.max, limit.value,
+			)
+		}
+	}
+
+	if t.Crossfade > 0 && t.Gap > 0 {
+		return ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue,
+			"tracks can either crossfade or have a gap between them, not both",
+		)
+	}
+
+	return nil
+}
+
+// joinsOnly drops the fades, for a playlist that goes inside another one
+// which does the fading.
+func (t playlistTransitions) joinsOnly() playlistTransitions {
+	return playlistTransitions{Crossfade: t.Crossfade, Gap: t.Gap}
+}
+
+// joinedDuration is how long tracks of the given durations last once
+// joined: every join adds the gap and loses the crossfade.
+func (t playlistTransitions) joinedDuration(durations []float64) float64 {
+	total := 0.0
+	for _, duration := range durations {
+		total += duration
+	}
+
+	if len(durations) > 1 {
+		total += float64(len(durations)-1) * (t.Gap - t.Crossfade)
+	}
+
+	return total
+}
+
+// createTransitionPlaylist joins the files with ffmpeg since sox can't
+// crossfade a list of files or join mono with stereo. The tracks get
+// measured first to place the fade out and make sure each one outlasts the
+// crossfade.
+func (s *PIrateRF) createTransitionPlaylist(
+	filePaths []string,
+	outputPath string,
+	transitions playlistTransitions,
+	channels string,
+) (string, error) {
+	inputs := parsePlaylistInputs(filePaths)
+
+	durations := make([]float64, len(inputs))
+	for i, input := range inputs {
+		duration, err := s.getAudioDuration(input.path)
+		if err != nil {
+			return "", ctxerrors.Wrapf(err, "failed to measure %s", input.path)
+		}
+
+		if len(inputs) > 1 && duration <= transitions.Crossfade {
+			return "", ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"%s is %.1fs long, too short for a %gs crossfade",
+				input.path, duration, transitions.Crossfade,
+			)
+		}
+
+		durations[i] = duration
+	}
+
+	total := transitions.joinedDuration(durations)
+	if transitions.FadeIn+transitions.FadeOut > total {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"fades of %gs and %gs don't fit in %.1fs of audio",
+			transitions.FadeIn, transitions.FadeOut, total,
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(s.serviceCtx, audioPlaylistTimeout)
+	defer cancel()
+
+	args := buildTransitionFFmpegArgs(
+		inputs, total, transitions, channels, outputPath,
+	)
+
+	_, stderr, err := s.commander.Output(ctx, "ffmpeg", args)
+	if err != nil {
+		return "", ctxerrors.Wrapf(
+			err,
+			"ffmpeg playlist creation failed, stderr: %s",
+			string(stderr),
+		)
+	}
+
+	if _, err := os.Stat(outputPath); err != nil {
+		return "", ctxerrors.Wrapf(err, "playlist file not found")
+	}
+
+	return outputPath, nil
+}
+
+// parsePlaylistInputs splits sox style playlist inputs, where -v sets the
+// volume of the file right after it.
+func parsePlaylistInputs(filePaths []string) []playlistInput {
+	inputs := make([]playlistInput, 0, len(filePaths))
+	volume := ""
+
+	for i := 0; i < len(filePaths); i++ {
+		if filePaths[i] == "-v" && i+1 < len(filePaths) {
+			volume = filePaths[i+1]
+			i++
+
+			continue
+		}
+
+		inputs = append(inputs, playlistInput{path: filePaths[i], volume: volume})
+		volume = ""
+	}
+
+	return inputs
+}
+
+// buildTransitionFFmpegArgs builds the ffmpeg run joining the inputs into
+// a playlist of total seconds with the given channels.
+func buildTransitionFFmpegArgs(
+	inputs []playlistInput,
+	total float64,
+	transitions playlistTransitions,
+	channels string,
+	outputPath string,
+) []string {
+	args := []string{"-hide_banner", "-nostats"}
+	for _, input := range inputs {
+		args = append(args, "-i", input.path)
+	}
+
+	return append(args,
+		"-filter_complex", transitionFilterGraph(
+			inputs, total, transitions, channelLayout(channels),
+		),
+		"-map", "[out]",
+		"-ar", audioSampleRate,
+		"-ac", channels,
+		"-c:a", "pcm_s16le",
+		"-y",
+		outputPath,
+	)
+}
+
+// transitionFilterGraph brings every input to the output format and
+// channel layout (with its volume and the gap after it), joins them by
+// crossfading or concatenating and fades the result.
+func transitionFilterGraph(
+	inputs []playlistInput,
+	total float64,
+	transitions playlistTransitions,
+	layout string,
+) string {
+	chains := make([]string, 0, len(inputs)+1)
+
+	for i, input := range inputs {
+		filters := []string{}
+		if input.volume != "" {
+			filters = append(filters, "volume="+input.volume)
+		}
+
+		filters = append(filters, fmt.Sprintf(
+			"aformat=sample_rates=%s:channel_layouts=%s",
+			audioSampleRate, layout,
+		))
+
+		if transitions.Gap > 0 && i < len(inputs)-1 {
+			filters = append(filters, "apad=pad_dur="+
+				formatSeconds(transitions.Gap))
+		}
+
+		chains = append(chains, fmt.Sprintf(
+			"[%d:a]%s[a%d]", i, strings.Join(filters, ","), i,
+		))
+	}
+
+	joined := "[a0]"
+
+	switch {
+	case len(inputs) == 1:
+	case transitions.Crossfade > 0:
+		for i := 1; i < len(inputs); i++ {
+			label := fmt.Sprintf("[x%d]", i)
+			chains = append(chains, fmt.Sprintf(
+				"%s[a%d]acrossfade=d=%s:c1=tri:c2=tri%s",
+				joined, i, formatSeconds(transitions.Crossfade), label,
+			))
+			joined = label
+		}
+	default:
+		labels := ""
+		for i := range inputs {
+			labels += fmt.Sprintf("[a%d]", i)
+		}
+
+		chains = append(chains, fmt.Sprintf(
+			"%sconcat=n=%d:v=0:a=1[joined]", labels, len(inputs),
+		))
+		joined = "[joined]"
+	}
+
+	fades := []string{}
+	if transitions.FadeIn > 0 {
+		fades = append(fades, "afade=t=in:st=0:d="+
+			formatSeconds(transitions.FadeIn))
+	}
+
+	if transitions.FadeOut > 0 {
+		fades = append(fades, fmt.Sprintf(
+			"afade=t=out:st=%s:d=%s",
+			formatSeconds(total-transitions.FadeOut),
+			formatSeconds(transitions.FadeOut),
+		))
+	}
+
+	if len(fades) == 0 {
+		fades = append(fades, "anull")
+	}
+
+	chains = append(chains, joined+strings.Join(fades, ",")+"[out]")
+
+	return strings.Join(chains, ";")
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64) //nolint:mnd
+}
+
+// channelLayout is the ffmpeg channel layout of audioChannels or
+// audioChannelsStereo.
+func channelLayout(channels string) string {
+	if channels == audioChannelsStereo {
+		return "stereo"
+	}
+
+	return "mono"
+}
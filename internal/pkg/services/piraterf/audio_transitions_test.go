@@ -0,0 +1,306 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylistTransitions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		transitions playlistTransitions
+		expectError bool
+	}{
+		{name: "none", transitions: playlistTransitions{}},
+		{
+			name: "crossfade with fades",
+			transitions: playlistTransitions{
+				Crossfade: 2, FadeIn: 1, FadeOut: 3,
+			},
+		},
+		{name: "gap", transitions: playlistTransitions{Gap: 1.5}},
+		{
+			name:        "crossfade and gap",
+			transitions: playlistTransitions{Crossfade: 1, Gap: 1},
+			expectError: true,
+		},
+		{
+			name:        "negative fade",
+			transitions: playlistTransitions{FadeIn: -1},
+			expectError: true,
+		},
+		{
+			name:        "crossfade too long",
+			transitions: playlistTransitions{Crossfade: 11},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.transitions.validate()
+			if tt.expectError {
+				require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlaylistTransitions_JoinedDuration(t *testing.T) {
+	durations := []float64{10, 20, 30}
+
+	assert.InDelta(t, 60, playlistTransitions{}.joinedDuration(durations), 0)
+	assert.InDelta(t, 56,
+		playlistTransitions{Crossfade: 2}.joinedDuration(durations), 0)
+	assert.InDelta(t, 63,
+		playlistTransitions{Gap: 1.5}.joinedDuration(durations), 0)
+	assert.InDelta(t, 10,
+		playlistTransitions{Crossfade: 2}.joinedDuration(durations[:1]), 0)
+}
+
+func TestParsePlaylistInputs(t *testing.T) {
+	assert.Equal(t, []playlistInput{
+		{path: "a.wav"},
+		{path: "b.wav", volume: "0.5012"},
+		{path: "c.wav"},
+	}, parsePlaylistInputs([]string{"a.wav", "-v", "0.5012", "b.wav", "c.wav"}))
+}
+
+func TestTransitionFilterGraph(t *testing.T) {
+	inputs := []playlistInput{
+		{path: "a.wav"},
+		{path: "b.wav", volume: "0.5012"},
+		{path: "c.wav"},
+	}
+	format := "aformat=sample_rates=48000:channel_layouts=mono"
+
+	tests := []struct {
+		name        string
+		inputs      []playlistInput
+		transitions playlistTransitions
+		expected    string
+	}{
+		{
+			name:        "crossfade with fades",
+			inputs:      inputs,
+			transitions: playlistTransitions{Crossfade: 2, FadeIn: 1, FadeOut: 3},
+			expected: "[0:a]" + format + "[a0];" +
+				"[1:a]volume=0.5012," + format + "[a1];" +
+				"[2:a]" + format + "[a2];" +
+				"[a0][a1]acrossfade=d=2.000:c1=tri:c2=tri[x1];" +
+				"[x1][a2]acrossfade=d=2.000:c1=tri:c2=tri[x2];" +
+				"[x2]afade=t=in:st=0:d=1.000," +
+				"afade=t=out:st=53.000:d=3.000[out]",
+		},
+		{
+			name:        "gap",
+			inputs:      inputs[:2],
+			transitions: playlistTransitions{Gap: 1.5},
+			expected: "[0:a]" + format + ",apad=pad_dur=1.500[a0];" +
+				"[1:a]volume=0.5012," + format + "[a1];" +
+				"[a0][a1]concat=n=2:v=0:a=1[joined];" +
+				"[joined]anull[out]",
+		},
+		{
+			name:        "single track fade out",
+			inputs:      inputs[:1],
+			transitions: playlistTransitions{FadeOut: 2},
+			expected: "[0:a]" + format + "[a0];" +
+				"[a0]afade=t=out:st=54.000:d=2.000[out]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected,
+				transitionFilterGraph(tt.inputs, 56, tt.transitions, "mono"))
+		})
+	}
+}
+
+func TestCreatePlaylistFromFiles_Transitions(t *testing.T) {
+	tempDir := t.TempDir()
+	transitions := playlistTransitions{Crossfade: 2, FadeOut: 3}
+	aPath := filepath.Join(tempDir, "a.wav")
+	bPath := filepath.Join(tempDir, "b.wav")
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact("--info"), commander.Exact("-D"), commander.Exact(aPath),
+	).ReturnOutput([]byte("10.0"))
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact("--info"), commander.Exact("-D"), commander.Exact(bPath),
+	).ReturnOutput([]byte("20.0"))
+	mock.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-hide_banner"), commander.Exact("-nostats"),
+		commander.Exact("-i"), commander.Exact(aPath),
+		commander.Exact("-i"), commander.Exact(bPath),
+		commander.Exact("-filter_complex"),
+		commander.Regex(`acrossfade=d=2\.000.*afade=t=out:st=25\.000:d=3\.000`),
+		commander.Exact("-map"), commander.Exact("[out]"),
+		commander.Exact("-ar"), commander.Exact(audioSampleRate),
+		commander.Exact("-ac"), commander.Exact(audioChannels),
+		commander.Exact("-c:a"), commander.Exact("pcm_s16le"),
+		commander.Exact("-y"),
+		commander.Exact(filepath.Join(tempDir, "show.wav")),
+	)
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	outputPath, err := service.createPlaylistFromFiles(
+		"show", []string{aPath, bPath}, transitions, tempDir,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+	assert.FileExists(t, outputPath)
+
+	// Tracks shorter than the crossfade can't be joined
+	mock = &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.Expect(constants.ToolSox, "--info", "-D", aPath).
+		ReturnOutput([]byte("1.5"))
+	service.commander = mock
+
+	_, err = service.createPlaylistFromFiles(
+		"short", []string{aPath, bPath}, transitions, tempDir,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	// Nor can fades longer than the whole thing
+	mock = &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.Expect(constants.ToolSox, "--info", "-D", aPath).
+		ReturnOutput([]byte("4.0"))
+	service.commander = mock
+
+	_, err = service.createPlaylistFromFiles(
+		"short", []string{aPath},
+		playlistTransitions{FadeIn: 3, FadeOut: 3}, tempDir,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	// Bad transitions never get to run anything
+	_, err = service.createPlaylistFromFiles(
+		"bad", []string{aPath}, playlistTransitions{Gap: -1}, tempDir,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestCreatePlaylistFromFiles_Channels(t *testing.T) {
+	tempDir := t.TempDir()
+	monoPath := filepath.Join(tempDir, "mono.wav")
+	stereoPath := filepath.Join(tempDir, "stereo.wav")
+
+	writeTestWAV(t, monoPath, 1, 48000, []int16{1, 2})
+	writeTestWAV(t, stereoPath, 2, 48000, []int16{1, 2})
+
+	// Stereo files stay stereo
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.Expect(constants.ToolSox,
+		stereoPath, stereoPath,
+		"-r", audioSampleRate,
+		"-b", audioBitDepth,
+		"-c", audioChannelsStereo,
+		filepath.Join(tempDir, "stereo_show.wav"),
+	)
+
+	service := &PIrateRF{serviceCtx: context.Background(), commander: mock}
+
+	_, err := service.createPlaylistFromFiles(
+		"stereo_show", []string{stereoPath, stereoPath},
+		playlistTransitions{}, tempDir,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+
+	// Sox can't join mono with stereo, ffmpeg brings them all to stereo
+	mock = &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.Expect(constants.ToolSox, "--info", "-D", monoPath).
+		ReturnOutput([]byte("10.0"))
+	mock.Expect(constants.ToolSox, "--info", "-D", stereoPath).
+		ReturnOutput([]byte("20.0"))
+	mock.ExpectWithMatchers("ffmpeg",
+		commander.Exact("-hide_banner"), commander.Exact("-nostats"),
+		commander.Exact("-i"), commander.Exact(monoPath),
+		commander.Exact("-i"), commander.Exact(stereoPath),
+		commander.Exact("-filter_complex"),
+		commander.Exact(
+			"[0:a]aformat=sample_rates=48000:channel_layouts=stereo[a0];"+
+				"[1:a]aformat=sample_rates=48000:channel_layouts=stereo[a1];"+
+				"[a0][a1]concat=n=2:v=0:a=1[joined];"+
+				"[joined]anull[out]",
+		),
+		commander.Exact("-map"), commander.Exact("[out]"),
+		commander.Exact("-ar"), commander.Exact(audioSampleRate),
+		commander.Exact("-ac"), commander.Exact(audioChannelsStereo),
+		commander.Exact("-c:a"), commander.Exact("pcm_s16le"),
+		commander.Exact("-y"),
+		commander.Exact(filepath.Join(tempDir, "mixed_show.wav")),
+	)
+	service.commander = mock
+
+	_, err = service.createPlaylistFromFiles(
+		"mixed_show", []string{monoPath, stereoPath},
+		playlistTransitions{}, tempDir,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+}
+
+func TestProcessAudioModifications_Transitions(t *testing.T) {
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		commander:  &testMockCommander{tempDir: t.TempDir()},
+	}
+
+	intro := introFile
+	msg := rpitxExecutionStartMessage{
+		Args: json.RawMessage(
+			`{"freq":107.9,"audio":"` + mainAudioFile + `"}`,
+		),
+		Intro:               &intro,
+		playlistTransitions: playlistTransitions{Crossfade: 1, FadeIn: 1},
+	}
+
+	_, tempPaths, finalArgs, err := service.processAudioModifications(
+		msg, 0, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		for _, path := range tempPaths {
+			_ = os.Remove(path)
+		}
+	})
+
+	require.Len(t, tempPaths, 1)
+
+	var args map[string]any
+	require.NoError(t, json.Unmarshal(finalArgs, &args))
+	assert.Equal(t, tempPaths[0], args["audio"])
+	assert.FileExists(t, tempPaths[0])
+}
+
+func TestRPITXExecutionStartMessage_Transitions(t *testing.T) {
+	var msg rpitxExecutionStartMessage
+	require.NoError(t, json.Unmarshal([]byte(
+		`{"moduleName":"pifmrds","crossfade":2.5,"fadeIn":1,"fadeOut":4}`,
+	), &msg))
+
+	assert.Equal(t,
+		playlistTransitions{Crossfade: 2.5, FadeIn: 1, FadeOut: 4},
+		msg.playlistTransitions,
+	)
+}
@@ -0,0 +1,189 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bandPlanFilename = "bandplan.json"
+
+	// pifmrds takes its frequency in MHz, every other module wants Hz.
+	hzPerMHz = 1e6
+)
+
+// bandPlanRange is an inclusive frequency range in Hz and the modules
+// allowed to transmit in it. No modules means any module.
+type bandPlanRange struct {
+	Name         string               `json:"name"`
+	MinFrequency float64              `json:"minFrequency"`
+	MaxFrequency float64              `json:"maxFrequency"`
+	Modules      []gorpitx.ModuleName `json:"modules,omitempty"`
+}
+
+// bandPlan is the allowlist every transmission gets checked against before
+// it reaches gorpitx.
+type bandPlan struct {
+	Ranges []bandPlanRange `json:"ranges"`
+
+	// enforced is false when there's no band plan file, in which case
+	// everything is allowed like it always was.
+	enforced bool
+}
+
+// bandPlanViolation says why a transmission was refused.
+type bandPlanViolation struct {
+	moduleName gorpitx.ModuleName
+	frequency  float64 // Hz, 0 when the args don't have one
+	reason     string
+}
+
+// loadBandPlan reads the band plan from filePath. A missing file means no
+// enforcement but a broken one is an error - we don't want a typo to
+// quietly turn the safety net off.
+func loadBandPlan(filePath string) (*bandPlan, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logrus.WithField("path", filePath).
+				Info("no band plan file, frequency enforcement disabled")
+
+			return &bandPlan{}, nil
+		}
+
+		return nil, ctxerrors.Wrap(err, "failed to read band plan file")
+	}
+
+	plan := &bandPlan{enforced: true}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to unmarshal band plan")
+	}
+
+	for i, r := range plan.Ranges {
+		if r.MinFrequency <= 0 || r.MaxFrequency < r.MinFrequency {
+			return nil, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"band plan range %d (%s): invalid frequency range %.0f-%.0f Hz",
+				i, r.Name, r.MinFrequency, r.MaxFrequency,
+			)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":   filePath,
+		"ranges": len(plan.Ranges),
+	}).Info("band plan loaded")
+
+	return plan, nil
+}
+
+// check returns nil when the module may transmit with the given args,
+// otherwise the violation to report back.
+func (p *bandPlan) check(
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+) *bandPlanViolation {
+	if p == nil || !p.enforced {
+		return nil
+	}
+
+	frequency, ok := executionFrequency(moduleName, args)
+	if !ok {
+		return &bandPlanViolation{
+			moduleName: moduleName,
+			reason:     "no valid frequency in args",
+		}
+	}
+
+	for _, r := range p.Ranges {
+		if frequency < r.MinFrequency || frequency > r.MaxFrequency {
+			continue
+		}
+
+		if len(r.Modules) == 0 || slices.Contains(r.Modules, moduleName) {
+			return nil
+		}
+	}
+
+	return &bandPlanViolation{
+		moduleName: moduleName,
+		frequency:  frequency,
+		reason:     "frequency is not in the band plan for this module",
+	}
+}
+
+// executionFrequency pulls the transmit frequency out of module args and
+// normalises it to Hz. It reports false when there's no usable frequency.
+func executionFrequency(
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+) (float64, bool) {
+	var fields struct {
+		Freq      float64 `json:"freq"`
+		Frequency float64 `json:"frequency"`
+	}
+
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return 0, false
+	}
+
+	var frequency float64
+
+	switch moduleName {
+	case gorpitx.ModuleNamePIFMRDS:
+		frequency = fields.Freq * hzPerMHz
+	case gorpitx.ModuleNameSENDIQ:
+		frequency = fields.Freq
+	default:
+		frequency = fields.Frequency
+	}
+
+	return frequency, frequency > 0
+}
+
+func (v *bandPlanViolation) message() string {
+	if v.frequency == 0 {
+		return fmt.Sprintf("%s: %s", v.moduleName, v.reason)
+	}
+
+	return fmt.Sprintf(
+		"%s at %.0f Hz: %s", v.moduleName, v.frequency, v.reason,
+	)
+}
+
+// sendBandPlanRejection tells the requesting client its transmission was
+// refused. Only the requester gets it so whatever is on air for everybody
+// else isn't disturbed.
+func (s *PIrateRF) sendBandPlanRejection(
+	client *wshub.Client,
+	violation *bandPlanViolation,
+	logger *logrus.Entry,
+) {
+	logger.WithFields(logrus.Fields{
+		"module":    violation.moduleName,
+		"frequency": violation.frequency,
+		"reason":    violation.reason,
+	}).Warn("transmission rejected by band plan")
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeRPITXExecutionRejected,
+		rpitxExecutionRejectedMessageData{
+			Error:      "band plan violation",
+			Message:    violation.message(),
+			ModuleName: violation.moduleName,
+			Frequency:  violation.frequency,
+			Timestamp:  time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,271 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBandPlan() *bandPlan {
+	return &bandPlan{
+		enforced: true,
+		Ranges: []bandPlanRange{
+			{
+				Name:         "FM broadcast",
+				MinFrequency: 87.5e6,
+				MaxFrequency: 108e6,
+				Modules:      []gorpitx.ModuleName{gorpitx.ModuleNamePIFMRDS},
+			},
+			{
+				Name:         "2m",
+				MinFrequency: 144e6,
+				MaxFrequency: 146e6,
+			},
+		},
+	}
+}
+
+func TestLoadBandPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		write        bool
+		wantErr      bool
+		wantEnforced bool
+		wantRanges   int
+	}{
+		{
+			name:         "missing file disables enforcement",
+			wantEnforced: false,
+		},
+		{
+			name:  "valid plan",
+			write: true,
+			content: `{"ranges":[{"name":"2m","minFrequency":144000000,` +
+				`"maxFrequency":146000000,"modules":["morse","pocsag"]}]}`,
+			wantEnforced: true,
+			wantRanges:   1,
+		},
+		{
+			name:         "empty plan allows nothing",
+			write:        true,
+			content:      `{"ranges":[]}`,
+			wantEnforced: true,
+		},
+		{
+			name:    "invalid json",
+			write:   true,
+			content: `{"ranges":`,
+			wantErr: true,
+		},
+		{
+			name:  "inverted range",
+			write: true,
+			content: `{"ranges":[{"minFrequency":146000000,` +
+				`"maxFrequency":144000000}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing minimum",
+			write:   true,
+			content: `{"ranges":[{"maxFrequency":144000000}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := path.Join(t.TempDir(), bandPlanFilename)
+
+			if tt.write {
+				require.NoError(t, os.WriteFile(
+					filePath, []byte(tt.content), filePerms,
+				))
+			}
+
+			plan, err := loadBandPlan(filePath)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEnforced, plan.enforced)
+			assert.Len(t, plan.Ranges, tt.wantRanges)
+		})
+	}
+}
+
+func TestExecutionFrequency(t *testing.T) {
+	tests := []struct {
+		name       string
+		moduleName gorpitx.ModuleName
+		args       string
+		expected   float64
+		ok         bool
+	}{
+		{
+			name:       "pifmrds freq in MHz",
+			moduleName: gorpitx.ModuleNamePIFMRDS,
+			args:       `{"freq":107.9,"audio":"a.wav"}`,
+			expected:   107.9e6,
+			ok:         true,
+		},
+		{
+			name:       "sendiq freq in Hz",
+			moduleName: gorpitx.ModuleNameSENDIQ,
+			args:       `{"freq":434000000}`,
+			expected:   434e6,
+			ok:         true,
+		},
+		{
+			name:       "frequency in Hz",
+			moduleName: gorpitx.ModuleNameMORSE,
+			args:       `{"frequency":144500000}`,
+			expected:   144.5e6,
+			ok:         true,
+		},
+		{
+			name:       "pifmrds ignores frequency",
+			moduleName: gorpitx.ModuleNamePIFMRDS,
+			args:       `{"frequency":107900000}`,
+		},
+		{
+			name:       "missing frequency",
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{}`,
+		},
+		{
+			name:       "negative frequency",
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{"frequency":-1}`,
+		},
+		{
+			name:       "invalid args",
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{"frequency":"high"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frequency, ok := executionFrequency(
+				tt.moduleName, json.RawMessage(tt.args),
+			)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected, frequency, 1)
+		})
+	}
+}
+
+func TestBandPlan_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		plan       *bandPlan
+		moduleName gorpitx.ModuleName
+		args       string
+		allowed    bool
+	}{
+		{
+			name:       "nil plan allows everything",
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{"frequency":1000}`,
+			allowed:    true,
+		},
+		{
+			name:       "unenforced plan allows everything",
+			plan:       &bandPlan{},
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{"frequency":1000}`,
+			allowed:    true,
+		},
+		{
+			name:       "pifmrds inside its range",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNamePIFMRDS,
+			args:       `{"freq":100.0}`,
+			allowed:    true,
+		},
+		{
+			name:       "range edges are inclusive",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNamePIFMRDS,
+			args:       `{"freq":108}`,
+			allowed:    true,
+		},
+		{
+			name:       "module not allowed in range",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNameTUNE,
+			args:       `{"frequency":100000000}`,
+		},
+		{
+			name:       "range without modules allows any module",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNameSENDIQ,
+			args:       `{"freq":145000000}`,
+			allowed:    true,
+		},
+		{
+			name:       "outside every range",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNameMORSE,
+			args:       `{"frequency":433000000}`,
+		},
+		{
+			name:       "no frequency",
+			plan:       testBandPlan(),
+			moduleName: gorpitx.ModuleNameMORSE,
+			args:       `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := tt.plan.check(tt.moduleName, json.RawMessage(tt.args))
+			if tt.allowed {
+				assert.Nil(t, violation)
+
+				return
+			}
+
+			require.NotNil(t, violation)
+			assert.Equal(t, tt.moduleName, violation.moduleName)
+			assert.NotEmpty(t, violation.message())
+		})
+	}
+}
+
+func TestHandleRPITXExecutionStart_BandPlanRejection(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	service := &PIrateRF{
+		rpitx:    gorpitx.GetInstance(),
+		bandPlan: testBandPlan(),
+	}
+	service.executionManager = newExecutionManager(service.rpitx, hub)
+
+	event := dabluveees.NewEvent(eventTypeRPITXExecutionStart, map[string]any{
+		"moduleName": gorpitx.ModuleNameMORSE,
+		"args": map[string]any{
+			"frequency": 433000000,
+			"message":   "CQ",
+		},
+	})
+
+	err := service.handleRPITXExecutionStart(hub, wshub.NewClient(), event)
+	require.NoError(t, err)
+
+	// Rejected requests never reach the queue
+	assert.Empty(t, service.executionManager.getQueueSnapshot())
+	assert.Equal(t, "idle", service.executionManager.getStatus().State)
+}
@@ -1,20 +1,26 @@
 package piraterf
 
 import (
+	"time"
+
 	"github.com/psyb0t/ctxerrors"
 	"github.com/psyb0t/gonfiguration"
 )
 
 const (
-	envVarNameHTMLDir          = "PIRATERF_HTMLDIR"
-	envVarNameStaticDir        = "PIRATERF_STATICDIR"
-	envVarNamePiraterfFilesDir = "PIRATERF_FILESDIR"
-	envVarNameUploadDir        = "PIRATERF_UPLOADDIR"
-
-	defaultHTMLDir   = "./html"
-	defaultStaticDir = "./static"
-	defaultFilesDir  = "./files"
-	defaultUploadDir = "./uploads"
+	envVarNameHTMLDir             = "PIRATERF_HTMLDIR"
+	envVarNameStaticDir           = "PIRATERF_STATICDIR"
+	envVarNamePiraterfFilesDir    = "PIRATERF_FILESDIR"
+	envVarNameUploadDir           = "PIRATERF_UPLOADDIR"
+	envVarNameHistoryDir          = "PIRATERF_HISTORYDIR"
+	envVarNameOutputFlushInterval = "PIRATERF_OUTPUTFLUSHINTERVAL"
+	envVarNamePreserveStereo      = "PIRATERF_PRESERVESTEREO"
+
+	defaultHTMLDir    = "./html"
+	defaultStaticDir  = "./static"
+	defaultFilesDir   = "./files"
+	defaultUploadDir  = "./uploads"
+	defaultHistoryDir = "./history"
 )
 
 type Config struct {
@@ -22,16 +28,28 @@ type Config struct {
 	StaticDir string `env:"PIRATERF_STATICDIR"`
 	FilesDir  string `env:"PIRATERF_FILESDIR"`
 	UploadDir string `env:"PIRATERF_UPLOADDIR"`
+	// HistoryDir holds the execution history, away from FilesDir since
+	// that's served over HTTP
+	HistoryDir string `env:"PIRATERF_HISTORYDIR"`
+	// OutputFlushInterval is how long execution output gets batched before
+	// it's broadcast
+	OutputFlushInterval time.Duration `env:"PIRATERF_OUTPUTFLUSHINTERVAL"`
+	// PreserveStereo keeps stereo uploads stereo when the upload doesn't
+	// say, so pifmrds can broadcast them in FM Stereo
+	PreserveStereo bool `env:"PIRATERF_PRESERVESTEREO"`
 }
 
 func parseConfig() (Config, error) {
 	cfg := Config{}
 
 	gonfiguration.SetDefaults(map[string]any{
-		envVarNamePiraterfFilesDir: defaultFilesDir,
-		envVarNameHTMLDir:          defaultHTMLDir,
-		envVarNameStaticDir:        defaultStaticDir,
-		envVarNameUploadDir:        defaultUploadDir,
+		envVarNamePiraterfFilesDir:    defaultFilesDir,
+		envVarNameHTMLDir:             defaultHTMLDir,
+		envVarNameStaticDir:           defaultStaticDir,
+		envVarNameUploadDir:           defaultUploadDir,
+		envVarNameHistoryDir:          defaultHistoryDir,
+		envVarNameOutputFlushInterval: defaultOutputFlushInterval,
+		envVarNamePreserveStereo:      false,
 	})
 
 	if err := gonfiguration.Parse(&cfg); err != nil {
@@ -2,6 +2,7 @@ package piraterf
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,15 +24,21 @@ func TestParseConfig(t *testing.T) {
 				assert.Equal(t, defaultStaticDir, cfg.StaticDir)
 				assert.Equal(t, defaultFilesDir, cfg.FilesDir)
 				assert.Equal(t, defaultUploadDir, cfg.UploadDir)
+				assert.Equal(
+					t, defaultOutputFlushInterval, cfg.OutputFlushInterval,
+				)
+				assert.False(t, cfg.PreserveStereo)
 			},
 		},
 		{
 			name: "custom config from environment variables",
 			envVars: map[string]string{
-				envVarNameHTMLDir:          "/custom/html",
-				envVarNameStaticDir:        "/custom/static",
-				envVarNamePiraterfFilesDir: "/custom/files",
-				envVarNameUploadDir:        "/custom/uploads",
+				envVarNameHTMLDir:             "/custom/html",
+				envVarNameStaticDir:           "/custom/static",
+				envVarNamePiraterfFilesDir:    "/custom/files",
+				envVarNameUploadDir:           "/custom/uploads",
+				envVarNameOutputFlushInterval: "1s",
+				envVarNamePreserveStereo:      "true",
 			},
 			expectError: false,
 			validate: func(t *testing.T, cfg Config) {
@@ -40,6 +47,8 @@ func TestParseConfig(t *testing.T) {
 				assert.Equal(t, "/custom/static", cfg.StaticDir)
 				assert.Equal(t, "/custom/files", cfg.FilesDir)
 				assert.Equal(t, "/custom/uploads", cfg.UploadDir)
+				assert.Equal(t, time.Second, cfg.OutputFlushInterval)
+				assert.True(t, cfg.PreserveStereo)
 			},
 		},
 		{
@@ -0,0 +1,254 @@
+package piraterf
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+)
+
+const (
+	cronFieldCount = 5 // minute hour day-of-month month day-of-week
+
+	// cronSearchLimit bounds how far ahead next() looks for a match so that
+	// impossible expressions (e.g. "0 0 31 2 *") don't loop forever.
+	cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+	cronStepSeparator  = "/"
+	cronRangeSeparator = "-"
+	cronListSeparator  = ","
+	cronWildcard       = "*"
+)
+
+// cronMacros maps the common shorthand expressions to their 5 field form.
+func cronMacros() map[string]string {
+	return map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+}
+
+// cronField is a bitset of allowed values for a single cron field.
+type cronField uint64
+
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+type cronFieldBounds struct {
+	name               string
+	minValue, maxValue int
+}
+
+// cronExpression is a parsed standard 5 field cron expression.
+type cronExpression struct {
+	minutes     cronField
+	hours       cronField
+	daysOfMonth cronField
+	months      cronField
+	daysOfWeek  cronField
+	// When both day fields are restricted a time matches if either matches,
+	// same as classic cron.
+	domRestricted bool
+	dowRestricted bool
+}
+
+// parseCronExpression parses "minute hour day-of-month month day-of-week"
+// expressions supporting *, lists (1,2), ranges (1-5), steps (*/15, 1-30/5)
+// and the @hourly/@daily/@weekly/@monthly/@yearly macros. Day of week 7 is
+// treated as Sunday.
+func parseCronExpression(expr string) (*cronExpression, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros()[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != cronFieldCount {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"cron expression must have %d fields, got %d",
+			cronFieldCount, len(parts),
+		)
+	}
+
+	bounds := []cronFieldBounds{
+		{name: "minute", minValue: 0, maxValue: 59},
+		{name: "hour", minValue: 0, maxValue: 23},
+		{name: "day of month", minValue: 1, maxValue: 31},
+		{name: "month", minValue: 1, maxValue: 12},
+		{name: "day of week", minValue: 0, maxValue: 7},
+	}
+
+	fields := make([]cronField, cronFieldCount)
+
+	for i, part := range parts {
+		field, err := parseCronField(part, bounds[i])
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = field
+	}
+
+	const (
+		sunday        = 0
+		sundayAlias   = 7
+		dowFieldIdx   = 4
+		domFieldIdx   = 2
+		monthFieldIdx = 3
+	)
+
+	// Fold day of week 7 into 0 so both mean Sunday
+	if fields[dowFieldIdx].has(sundayAlias) {
+		fields[dowFieldIdx] |= 1 << sunday
+	}
+
+	return &cronExpression{
+		minutes:       fields[0],
+		hours:         fields[1],
+		daysOfMonth:   fields[domFieldIdx],
+		months:        fields[monthFieldIdx],
+		daysOfWeek:    fields[dowFieldIdx],
+		domRestricted: parts[domFieldIdx] != cronWildcard,
+		dowRestricted: parts[dowFieldIdx] != cronWildcard,
+	}, nil
+}
+
+func parseCronField(field string, bounds cronFieldBounds) (cronField, error) {
+	var result cronField
+
+	for item := range strings.SplitSeq(field, cronListSeparator) {
+		start, end, step, err := parseCronItem(item, bounds)
+		if err != nil {
+			return 0, err
+		}
+
+		for value := start; value <= end; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
+
+func parseCronItem(
+	item string,
+	bounds cronFieldBounds,
+) (int, int, int, error) {
+	invalid := func(reason string) error {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid %s field %q: %s",
+			bounds.name, item, reason,
+		)
+	}
+
+	rangePart, stepPart, hasStep := strings.Cut(item, cronStepSeparator)
+
+	step := 1
+
+	if hasStep {
+		parsedStep, err := strconv.Atoi(stepPart)
+		if err != nil || parsedStep <= 0 {
+			return 0, 0, 0, invalid("step must be a positive number")
+		}
+
+		step = parsedStep
+	}
+
+	start, end := bounds.minValue, bounds.maxValue
+
+	switch {
+	case rangePart == cronWildcard:
+	case strings.Contains(rangePart, cronRangeSeparator):
+		startStr, endStr, _ := strings.Cut(rangePart, cronRangeSeparator)
+
+		var errStart, errEnd error
+
+		start, errStart = strconv.Atoi(startStr)
+		end, errEnd = strconv.Atoi(endStr)
+
+		if errStart != nil || errEnd != nil {
+			return 0, 0, 0, invalid("range bounds must be numbers")
+		}
+	default:
+		value, err := strconv.Atoi(rangePart)
+		if err != nil {
+			return 0, 0, 0, invalid("value must be a number")
+		}
+
+		start = value
+		// A single value with a step ("5/15") runs from the value to max
+		if !hasStep {
+			end = value
+		}
+	}
+
+	if start < bounds.minValue || end > bounds.maxValue || start > end {
+		return 0, 0, 0, invalid(
+			"values must be between " + strconv.Itoa(bounds.minValue) +
+				" and " + strconv.Itoa(bounds.maxValue),
+		)
+	}
+
+	return start, end, step, nil
+}
+
+// next returns the first matching time strictly after the given time.
+// Returns false if nothing matches within cronSearchLimit.
+func (c *cronExpression) next(after time.Time) (time.Time, bool) {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		if !c.months.has(int(month)) {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !c.hours.has(t.Hour()) {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !c.minutes.has(t.Minute()) {
+			t = t.Add(time.Minute)
+
+			continue
+		}
+
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+func (c *cronExpression) dayMatches(t time.Time) bool {
+	domMatch := c.daysOfMonth.has(t.Day())
+	dowMatch := c.daysOfWeek.has(int(t.Weekday()))
+
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
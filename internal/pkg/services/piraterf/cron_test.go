@@ -0,0 +1,143 @@
+package piraterf
+
+import (
+	"testing"
+	"time"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronExpression_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "empty", expr: ""},
+		{name: "too few fields", expr: "* * * *"},
+		{name: "too many fields", expr: "* * * * * *"},
+		{name: "minute out of range", expr: "60 * * * *"},
+		{name: "hour out of range", expr: "* 24 * * *"},
+		{name: "day of month zero", expr: "* * 0 * *"},
+		{name: "month out of range", expr: "* * * 13 *"},
+		{name: "day of week out of range", expr: "* * * * 8"},
+		{name: "not a number", expr: "abc * * * *"},
+		{name: "reversed range", expr: "30-10 * * * *"},
+		{name: "zero step", expr: "*/0 * * * *"},
+		{name: "bad step", expr: "*/x * * * *"},
+		{name: "unknown macro", expr: "@often"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCronExpression(tt.expr)
+			require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+		})
+	}
+}
+
+func TestCronExpression_Next(t *testing.T) {
+	// Wednesday
+	base := time.Date(2025, time.January, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "every minute",
+			expr:     "* * * * *",
+			after:    base,
+			expected: time.Date(2025, time.January, 15, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 15 minutes",
+			expr:     "*/15 * * * *",
+			after:    base,
+			expected: time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at fixed time later today",
+			expr:     "0 18 * * *",
+			after:    base,
+			expected: time.Date(2025, time.January, 15, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at fixed time tomorrow",
+			expr:     "0 9 * * *",
+			after:    base,
+			expected: time.Date(2025, time.January, 16, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "hourly macro",
+			expr:     "@hourly",
+			after:    base,
+			expected: time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays list",
+			expr:     "0 8 * * 1,5",
+			after:    base,
+			expected: time.Date(2025, time.January, 17, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			expr:     "0 0 * * 7",
+			after:    base,
+			expected: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "range with step",
+			expr:     "10-40/10 * * * *",
+			after:    base,
+			expected: time.Date(2025, time.January, 15, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly rolls over year",
+			expr:     "0 0 1 1 *",
+			after:    base,
+			expected: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "day of month or day of week",
+			expr:  "0 12 20 * 5",
+			after: base,
+			// Friday the 17th comes before the 20th
+			expected: time.Date(2025, time.January, 17, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 2 *",
+			after:    base,
+			expected: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "exact match is skipped",
+			expr:     "30 10 * * *",
+			after:    time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC),
+			expected: time.Date(2025, time.January, 16, 10, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := parseCronExpression(tt.expr)
+			require.NoError(t, err)
+
+			next, ok := expr.next(tt.after)
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, next)
+		})
+	}
+}
+
+func TestCronExpression_NextNeverMatches(t *testing.T) {
+	expr, err := parseCronExpression("0 0 31 2 *")
+	require.NoError(t, err)
+
+	_, ok := expr.next(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
@@ -0,0 +1,172 @@
+package piraterf
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultDeadManGrace is how long the initiating client may stay quiet
+	// before the dead-man switch stops the execution. The frontend sends a
+	// heartbeat every 5 seconds so this allows a few to go missing.
+	defaultDeadManGrace = 30 * time.Second
+	// minDeadManGrace keeps a tiny grace from tripping between heartbeats.
+	minDeadManGrace = 10 * time.Second
+	// maxDeadManCheckInterval bounds how often the switch looks at the
+	// client.
+	maxDeadManCheckInterval = time.Second
+	deadManChecksPerGrace   = 4
+)
+
+// deadManSwitch stops an execution once the client that started it has
+// gone quiet - no heartbeat for longer than the grace period. Disconnects
+// alone don't trip it so a phone hopping between access points can come
+// back within the grace period without losing its transmission.
+type deadManSwitch struct {
+	clientID uuid.UUID
+	grace    time.Duration
+	lastSeen atomic.Int64 // unix nanos of the last heartbeat
+	cancel   context.CancelFunc
+}
+
+// deadManGrace turns the grace requested in a start message into the one
+// used, in seconds. 0 means the default.
+func deadManGrace(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultDeadManGrace
+	}
+
+	return max(time.Duration(seconds)*time.Second, minDeadManGrace)
+}
+
+func (d *deadManSwitch) seen(at time.Time) {
+	d.lastSeen.Store(at.UnixNano())
+}
+
+func (d *deadManSwitch) quietFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, d.lastSeen.Load()))
+}
+
+// armDeadMan starts watching clientID for the current execution. Clients
+// that aren't connected to the hub (like the scheduler) never send a
+// heartbeat so there's nothing to watch for them.
+func (em *executionManager) armDeadMan(
+	clientID uuid.UUID,
+	grace time.Duration,
+) {
+	logger := logrus.WithFields(logrus.Fields{
+		"clientID": clientID,
+		"grace":    grace,
+	})
+
+	if em.hub.GetClient(clientID) == nil {
+		logger.Debug("initiating client is not on the hub, dead-man disabled")
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &deadManSwitch{
+		clientID: clientID,
+		grace:    grace,
+		cancel:   cancel,
+	}
+	d.seen(time.Now())
+
+	em.mu.Lock()
+	previous := em.deadMan
+	em.deadMan = d
+	em.mu.Unlock()
+
+	if previous != nil {
+		previous.cancel()
+	}
+
+	logger.Info("dead-man switch armed")
+
+	go em.watchDeadMan(ctx, d)
+}
+
+// disarmDeadMan stops watching the client of the current execution.
+func (em *executionManager) disarmDeadMan() {
+	em.mu.Lock()
+	d := em.deadMan
+	em.deadMan = nil
+	em.mu.Unlock()
+
+	if d != nil {
+		d.cancel()
+	}
+}
+
+// heartbeat records that clientID is still around.
+func (em *executionManager) heartbeat(clientID uuid.UUID) {
+	em.mu.RLock()
+	d := em.deadMan
+	em.mu.RUnlock()
+
+	if d == nil || d.clientID != clientID {
+		return
+	}
+
+	d.seen(time.Now())
+}
+
+func (em *executionManager) watchDeadMan(
+	ctx context.Context,
+	d *deadManSwitch,
+) {
+	logger := logrus.WithField("clientID", d.clientID)
+
+	ticker := time.NewTicker(
+		min(d.grace/deadManChecksPerGrace, maxDeadManCheckInterval),
+	)
+	defer ticker.Stop()
+
+	connected := true
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			isConnected := em.isClientConnected(d.clientID)
+			if isConnected != connected {
+				connected = isConnected
+				logger.WithField("connected", connected).
+					Warn("initiating client connection changed")
+			}
+
+			if d.quietFor(now) <= d.grace {
+				continue
+			}
+
+			// Disarmed while we were looking - the execution is gone
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.WithField("quietFor", d.quietFor(now)).
+				Warn("dead-man switch tripped, stopping execution")
+
+			if err := em.stopExecutionBy(
+				uuid.Nil, terminationReasonDeadMan,
+			); err != nil {
+				logger.WithError(err).Error("dead-man stop failed")
+			}
+
+			return
+		}
+	}
+}
+
+func (em *executionManager) isClientConnected(clientID uuid.UUID) bool {
+	client := em.hub.GetClient(clientID)
+
+	return client != nil && client.ConnectionCount() > 0
+}
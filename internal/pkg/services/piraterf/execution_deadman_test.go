@@ -0,0 +1,103 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadManGrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		seconds  int
+		expected time.Duration
+	}{
+		{name: "default", expected: defaultDeadManGrace},
+		{name: "negative", seconds: -5, expected: defaultDeadManGrace},
+		{name: "too short", seconds: 1, expected: minDeadManGrace},
+		{name: "requested", seconds: 60, expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, deadManGrace(tt.seconds))
+		})
+	}
+}
+
+func TestExecutionManager_DeadManHeartbeat(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	client := wshub.NewClient()
+	hub.AddClient(client)
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	// Nobody to watch when the client isn't on the hub
+	em.armDeadMan(uuid.New(), time.Minute)
+	assert.Nil(t, em.deadMan)
+
+	em.armDeadMan(client.ID(), time.Minute)
+	require.NotNil(t, em.deadMan)
+
+	d := em.deadMan
+	d.lastSeen.Store(0)
+
+	// Heartbeats from other clients don't count
+	em.heartbeat(uuid.New())
+	assert.Zero(t, d.lastSeen.Load())
+
+	em.heartbeat(client.ID())
+	assert.Less(t, d.quietFor(time.Now()), time.Minute)
+
+	em.disarmDeadMan()
+	assert.Nil(t, em.deadMan)
+
+	// Heartbeats after disarming are ignored
+	em.heartbeat(client.ID())
+}
+
+func TestExecutionManager_DeadManStopsQuietClient(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	client := wshub.NewClient()
+	hub.AddClient(client)
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	em.history = newHistoryStore(t.TempDir())
+
+	// In dev mode gorpitx runs a mock loop that never ends on its own
+	require.NoError(t, em.startExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		client,
+		nil,
+		withDeadMan(200*time.Millisecond),
+	))
+
+	require.Eventually(t, func() bool {
+		return !em.running.Load()
+	}, 5*time.Second, 20*time.Millisecond)
+
+	page, err := em.history.list(historyQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+	assert.Equal(
+		t, terminationReasonDeadMan, page.Records[0].TerminationReason,
+	)
+	assert.Nil(t, em.deadMan)
+}
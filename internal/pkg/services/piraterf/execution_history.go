@@ -0,0 +1,76 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+// startRecording begins collecting the history record for the execution
+// that's about to go on air.
+func (em *executionManager) startRecording(
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+	clientID uuid.UUID,
+) {
+	if em.history == nil {
+		return
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.recorder = newExecutionRecorder(
+		moduleName, args, clientID, time.Now().Unix(),
+	)
+}
+
+func (em *executionManager) recordOutput(outputType, line string) {
+	em.mu.RLock()
+	recorder := em.recorder
+	em.mu.RUnlock()
+
+	if recorder == nil {
+		return
+	}
+
+	recorder.addOutput(outputType, line)
+}
+
+// finishRecording closes the current record with the execution outcome
+// and appends it to the history file.
+func (em *executionManager) finishRecording(termination executionTermination) {
+	em.mu.Lock()
+	recorder := em.recorder
+	em.recorder = nil
+	em.mu.Unlock()
+
+	if recorder == nil {
+		return
+	}
+
+	stoppingClientID := ""
+	if termination.stopRequested() {
+		if val, ok := em.stoppingClient.Load().(uuid.UUID); ok {
+			stoppingClientID = stoppingClientIDString(val)
+		}
+	}
+
+	errMsg := ""
+	if termination.err != nil {
+		errMsg = termination.err.Error()
+	}
+
+	record := recorder.finish(
+		stoppingClientID, time.Now().Unix(), termination, errMsg,
+	)
+
+	if err := em.history.append(record); err != nil {
+		logrus.WithError(err).
+			WithField("recordID", record.ID).
+			Error("failed to save execution history")
+	}
+}
@@ -0,0 +1,74 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionManager_RecordsHistory(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	em.history = newHistoryStore(t.TempDir())
+
+	initiator := wshub.NewClient()
+	stopper := wshub.NewClient()
+
+	args := json.RawMessage(`{"frequency":144500000}`)
+	em.startRecording(gorpitx.ModuleNameTUNE, args, initiator.ID())
+	em.sendOutputLines(newOutputLine("stdout", "hello"))
+	em.sendOutputLines(newOutputLine("stderr", "oops"))
+
+	em.stoppingClient.Store(stopper.ID())
+	em.stopRequested.Store(true)
+	em.finishRecording(em.classifyTermination(
+		ctxerrors.Wrap(commonerrors.ErrKilled, "killed"), 0, false, time.Second,
+	))
+
+	page, err := em.history.list(historyQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+
+	record, err := em.history.get(page.Records[0].ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, gorpitx.ModuleNameTUNE, record.ModuleName)
+	assert.JSONEq(t, string(args), string(record.Args))
+	assert.Equal(t, initiator.ID().String(), record.InitiatingClientID)
+	assert.Equal(t, stopper.ID().String(), record.StoppingClientID)
+	assert.Equal(t, terminationReasonUserStop, record.TerminationReason)
+	assert.Nil(t, record.ExitCode)
+	assert.Equal(t, []string{"hello"}, record.Stdout)
+	assert.Equal(t, []string{"oops"}, record.Stderr)
+	assert.NotZero(t, record.StartedAt)
+	assert.GreaterOrEqual(t, record.EndedAt, record.StartedAt)
+
+	// Output after the record is closed goes nowhere
+	require.NotPanics(t, func() {
+		em.sendOutputLines(newOutputLine("stdout", "late"))
+	})
+}
+
+func TestExecutionManager_HistoryDisabled(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	require.NotPanics(t, func() {
+		em.startRecording(gorpitx.ModuleNameTUNE, nil, wshub.NewClient().ID())
+		em.sendOutputLines(newOutputLine("stdout", "line"))
+		em.finishRecording(executionTermination{
+			reason: terminationReasonProcessExit,
+		})
+	})
+}
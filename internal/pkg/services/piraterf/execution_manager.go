@@ -3,8 +3,6 @@ package piraterf
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,7 +10,6 @@ import (
 	"github.com/google/uuid"
 	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
 	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
-	commonerrors "github.com/psyb0t/common-go/errors"
 	"github.com/psyb0t/gorpitx"
 	"github.com/sirupsen/logrus"
 )
@@ -26,7 +23,10 @@ const (
 )
 
 const (
-	stopTimeout             = 3 * time.Second
+	stopTimeout = 3 * time.Second
+	// shutdownTimeout bounds how long a service shutdown waits for the
+	// active transmission to go off air and clean up after itself.
+	shutdownTimeout         = 5 * time.Second
 	stdoutChannelBufferSize = 50
 	stderrChannelBufferSize = 10
 )
@@ -35,14 +35,32 @@ type executionManager struct {
 	rpitx            *gorpitx.RPITX
 	hub              wshub.Hub
 	state            atomic.Int32
-	initiatingClient atomic.Value // stores uuid.UUID
-	stopRequested    atomic.Bool  // tracks if stop was requested
-	outputChannels   struct {
-		stdout chan string
-		stderr chan string
-	}
-	streamingCancel context.CancelFunc
-	mu              sync.RWMutex
+	initiatingClient atomic.Value    // stores uuid.UUID
+	stopRequested    atomic.Bool     // tracks if stop was requested
+	output           *outputPipeline // current execution, guarded by mu
+	mu               sync.RWMutex
+	running          atomic.Bool // true while an executeModule goroutine runs
+	queue            []*queuedExecution
+	queueMu          sync.Mutex
+	history          *historyStore      // optional, nil disables history
+	recorder         *executionRecorder // current execution, guarded by mu
+	stoppingClient   atomic.Value       // stores uuid.UUID
+	active           *activeExecution   // what's on air, guarded by mu
+	recentOutput     *outputRingBuffer
+	executionDone    chan struct{}    // current execution, guarded by mu
+	activeWrapUp     *executionWrapUp // current execution, guarded by mu
+	shuttingDown     atomic.Bool
+	stopReason       atomic.Value      // stores terminationReason
+	deadMan          *deadManSwitch    // current execution, guarded by mu
+	progress         *progressReporter // current execution, guarded by mu
+	rdsTracks        *rdsTrackUpdater  // current execution, guarded by mu
+	// outputFlushInterval is how often output lines get broadcast, 0 means
+	// defaultOutputFlushInterval
+	outputFlushInterval time.Duration
+	// scheduledClient starts scheduled executions. Nobody is behind it so
+	// its errors go to scheduleErrorFn instead (optional)
+	scheduledClient *wshub.Client
+	scheduleErrorFn func(errorType, message string)
 }
 
 func newExecutionManager(
@@ -50,8 +68,9 @@ func newExecutionManager(
 	hub wshub.Hub,
 ) *executionManager {
 	return &executionManager{
-		rpitx: rpitx,
-		hub:   hub,
+		rpitx:        rpitx,
+		hub:          hub,
+		recentOutput: newOutputRingBuffer(statusOutputLines),
 	}
 }
 
@@ -62,45 +81,28 @@ func (em *executionManager) startExecution(
 	timeout int,
 	client *wshub.Client,
 	callback func() error,
+	opts ...executionOption,
 ) error {
-	// Atomic state transition - only allow if idle
-	if !em.state.CompareAndSwap(
-		int32(executionStateIdle),
-		int32(executionStateExecuting),
-	) {
-		currentState := executionState(em.state.Load())
-		switch currentState {
-		case executionStateIdle:
-			// This shouldn't happen due to CompareAndSwap, but handle it
-			return nil
-		case executionStateExecuting:
-			em.sendErrorEvent(
-				"already executing",
-				"execution already in progress",
-			)
-		case executionStateStopping:
-			em.sendErrorEvent(
-				"currently stopping",
-				"execution currently stopping",
-			)
-		}
-
-		return nil // Don't return error - just broadcast
+	job := newQueuedExecution(ctx, moduleName, args, timeout, client, callback)
+	for _, opt := range opts {
+		opt(job)
 	}
 
-	// Validate timeout
-	validTimeout := em.validateTimeout(timeout)
-
-	// Store initiating client
-	em.initiatingClient.Store(client.ID())
-
-	// Start execution in goroutine
-	go em.executeModule(ctx, moduleName, args, validTimeout, client, callback)
+	// Every start request goes through the queue - it starts right away when
+	// nothing is on air and waits for its turn otherwise
+	return em.enqueueExecution(job)
+}
 
-	return nil
+func (em *executionManager) stopExecution(client *wshub.Client) error {
+	return em.stopExecutionBy(client.ID(), terminationReasonUserStop)
 }
 
-func (em *executionManager) stopExecution(_ *wshub.Client) error {
+// stopExecutionBy stops the active execution and remembers who asked and
+// why so the stopped event and history can tell.
+func (em *executionManager) stopExecutionBy(
+	stoppingClientID uuid.UUID,
+	reason terminationReason,
+) error {
 	currentState := executionState(em.state.Load())
 
 	// Idempotent - return success for already stopped or stopping
@@ -111,6 +113,8 @@ func (em *executionManager) stopExecution(_ *wshub.Client) error {
 
 	// Set stopping state and mark that stop was requested
 	em.setState(executionStateStopping)
+	em.stoppingClient.Store(stoppingClientID)
+	em.stopReason.Store(reason)
 	em.stopRequested.Store(true)
 
 	// Stop RPITX execution - wait for it to complete
@@ -135,37 +139,96 @@ func (em *executionManager) stopExecution(_ *wshub.Client) error {
 }
 
 func (em *executionManager) executeModule(
-	ctx context.Context,
-	moduleName gorpitx.ModuleName,
-	args json.RawMessage,
+	job *queuedExecution,
 	timeout time.Duration,
-	client *wshub.Client,
-	callback func() error,
+	wrapUp *executionWrapUp,
 ) {
-	defer em.cleanupAfterExecution(client, callback)
+	client := job.client
+
+	defer em.cleanupAfterExecution(client, wrapUp)
 
-	em.logExecutionStart(moduleName, timeout, client)
-	em.sendStartedEvent(moduleName, args, client.ID())
-	em.setupOutputChannels(ctx)
+	if job.launch != nil {
+		if err := job.launch(); err != nil {
+			em.sendClientError(client, "launch failed", err)
+
+			return
+		}
+	}
+
+	// A stop that came in before rpitx got started - during the launch,
+	// or a shutdown right as the job left the queue - found nothing to
+	// kill, so it never goes on air
+	if em.stopRequested.Load() {
+		termination := em.classifyTermination(nil, timeout, job.playOnce, 0)
+		termination.exitCode = nil
+
+		em.handleExecutionResult(termination, client, wrapUp)
+
+		return
+	}
+
+	startedAt := time.Now()
+
+	em.logExecutionStart(job.moduleName, timeout, client)
+	em.setActiveExecution(&activeExecution{
+		moduleName:         job.moduleName,
+		args:               job.args,
+		initiatingClientID: client.ID(),
+		startedAt:          startedAt,
+	})
+	em.sendStartedEvent(job.moduleName, job.args, client.ID())
+
+	if job.deadManGrace > 0 {
+		em.armDeadMan(client.ID(), job.deadManGrace)
+	}
 
-	err := em.runExecution(ctx, moduleName, args, timeout)
-	em.handleExecutionResult(err, client)
+	if job.progress != nil {
+		em.startProgress(job.progress, startedAt)
+	}
+
+	if job.rdsTracks != nil {
+		em.startRDSTracks(job.rdsTracks, startedAt)
+	}
+
+	em.startRecording(job.moduleName, job.args, client.ID())
+	output := em.startOutputPipeline()
+
+	err := em.runExecution(
+		job.ctx, job.moduleName, job.args, timeout, output,
+	)
+	em.stopProgress()
+	em.stopRDSTracks()
+
+	termination := em.classifyTermination(
+		err, timeout, job.playOnce, time.Since(startedAt),
+	)
+
+	em.handleExecutionResult(termination, client, wrapUp)
+	em.finishRecording(termination)
 }
 
 func (em *executionManager) cleanupAfterExecution(
 	client *wshub.Client,
-	callback func() error,
+	wrapUp *executionWrapUp,
 ) {
 	logrus.WithField("clientID", client.ID()).
 		Debug("executeModule finished, setting state to idle")
 
+	em.disarmDeadMan()
+	em.stopProgress()
+	em.stopRDSTracks()
 	em.setState(executionStateIdle)
+	em.setActiveExecution(nil)
 	em.stopRequested.Store(false)
+	em.stoppingClient.Store(uuid.Nil)
 
-	if callback != nil {
-		if err := callback(); err != nil {
-			logrus.WithError(err).Error("callback failed")
-		}
+	wrapUp.runCallback(false)
+
+	em.running.Store(false)
+
+	// Kick off the next queued job now that the transmitter is free
+	if em.startNextQueued() {
+		em.sendQueueUpdatedEvent()
 	}
 }
 
@@ -186,15 +249,13 @@ func (em *executionManager) runExecution(
 	moduleName gorpitx.ModuleName,
 	args json.RawMessage,
 	timeout time.Duration,
+	output *outputPipeline,
 ) error {
 	execDone := make(chan error, 1)
 
-	em.mu.RLock()
-	stdoutCh := em.outputChannels.stdout
-	stderrCh := em.outputChannels.stderr
-	em.mu.RUnlock()
-
-	em.rpitx.StreamOutputsAsync(stdoutCh, stderrCh)
+	// Commander takes the channels over from here and closes them once the
+	// process is gone
+	em.rpitx.StreamOutputsAsync(output.stdout, output.stderr)
 
 	go func() {
 		logrus.Debug("calling rpitx.Exec")
@@ -209,160 +270,37 @@ func (em *executionManager) runExecution(
 }
 
 func (em *executionManager) handleExecutionResult(
-	err error,
+	termination executionTermination,
 	client *wshub.Client,
+	wrapUp *executionWrapUp,
 ) {
 	logrus.WithFields(logrus.Fields{
-		"error":    err,
+		"reason":   termination.reason,
+		"error":    termination.err,
 		"clientID": client.ID(),
 	}).Debug("execution completed, processing cleanup")
 
 	em.stopStreaming()
 
-	if err != nil {
-		if em.isExpectedTermination(err) {
-			logrus.WithError(err).Debug("execution completed with expected termination")
-			em.sendStoppedEvent(client.ID())
-
-			return
-		}
-
-		logrus.WithError(err).Debug("sending error event")
-		em.sendErrorEvent("execution failed", err.Error())
-
-		return
-	}
-
-	logrus.WithField("clientID", client.ID()).Debug("sending stopped event")
-	em.sendStoppedEvent(client.ID())
-}
-
-func (em *executionManager) setupOutputChannels(ctx context.Context) {
-	em.mu.Lock()
-	defer em.mu.Unlock()
-
-	// Create buffered channels
-	em.outputChannels.stdout = make(chan string, stdoutChannelBufferSize)
-	em.outputChannels.stderr = make(chan string, stderrChannelBufferSize)
-
-	// Create cancellable context for streaming
-	streamingCtx, streamingCancel := context.WithCancel(ctx)
-	em.streamingCancel = streamingCancel
-
-	// Start output broadcasting goroutine
-	go em.streamOutput(streamingCtx)
-}
-
-func (em *executionManager) streamOutput(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if !em.processOutputChannels(ctx) {
-				return
-			}
-		}
-
-		time.Sleep(time.Millisecond)
-	}
-}
-
-func (em *executionManager) processOutputChannels(ctx context.Context) bool {
-	// Get channel references safely
-	em.mu.RLock()
-	stdoutCh := em.outputChannels.stdout
-	stderrCh := em.outputChannels.stderr
-	em.mu.RUnlock()
-
-	if stdoutCh == nil && stderrCh == nil {
-		return false
+	if termination.reason == terminationReasonError {
+		logrus.WithError(termination.err).Debug("sending error event")
+		em.sendErrorEvent("execution failed", termination.err.Error())
 	}
 
-	// Now read from channels safely
-	select {
-	case <-ctx.Done():
-		return false
-	case line, ok := <-stdoutCh:
-		if !ok {
-			return true
+	stoppingClientID := client.ID()
+	if termination.stopRequested() {
+		if val, ok := em.stoppingClient.Load().(uuid.UUID); ok {
+			stoppingClientID = val
 		}
-
-		em.sendOutputEvent("stdout", line)
-	case line, ok := <-stderrCh:
-		if !ok {
-			return true
-		}
-
-		em.sendOutputEvent("stderr", line)
-	default:
-		// Avoid busy loop
-		return true
 	}
 
-	return true
-}
-
-func (em *executionManager) stopStreaming() {
-	em.mu.Lock()
-	defer em.mu.Unlock()
-
-	// Cancel streaming context to stop the streaming goroutine
-	if em.streamingCancel != nil {
-		em.streamingCancel()
-	}
-
-	// Close channels safely - they might already be closed by commander process
-	if em.outputChannels.stdout != nil {
-		func() {
-			defer func() {
-				_ = recover() // Channel already closed, ignore
-			}()
-
-			close(em.outputChannels.stdout)
-		}()
-
-		em.outputChannels.stdout = nil
-	}
-
-	if em.outputChannels.stderr != nil {
-		func() {
-			defer func() {
-				_ = recover() // Channel already closed, ignore
-			}()
-
-			close(em.outputChannels.stderr)
-		}()
-
-		em.outputChannels.stderr = nil
-	}
-}
-
-func (em *executionManager) isExpectedTermination(err error) bool {
-	// If stop was requested, then termination/kill is expected
-	if em.stopRequested.Load() {
-		return errors.Is(err, commonerrors.ErrTerminated) ||
-			errors.Is(err, commonerrors.ErrKilled)
-	}
-
-	// Natural timeout is also expected (not an error)
-	if errors.Is(err, commonerrors.ErrTimeout) {
-		return true
-	}
-
-	// Process cleanup errors after timeout are also expected
-	if err != nil && (strings.Contains(
-		err.Error(),
-		"process wait failed: exit status",
-	) ||
-		strings.Contains(
-			err.Error(),
-			"process failed after timeout stop",
-		)) {
-		return true
+	// Shutdown already told everybody if it gave up waiting on us
+	if !wrapUp.claim(false) {
+		return
 	}
 
-	return false
+	logrus.WithField("clientID", client.ID()).Debug("sending stopped event")
+	em.sendStoppedEvent(stoppingClientID, termination)
 }
 
 func (em *executionManager) validateTimeout(timeout int) time.Duration {
@@ -397,7 +335,10 @@ func (em *executionManager) sendStartedEvent(
 	))
 }
 
-func (em *executionManager) sendStoppedEvent(stoppingClientID uuid.UUID) {
+func (em *executionManager) sendStoppedEvent(
+	stoppingClientID uuid.UUID,
+	termination executionTermination,
+) {
 	initiatingClientID := uuid.UUID{}
 
 	if val := em.initiatingClient.Load(); val != nil {
@@ -410,12 +351,25 @@ func (em *executionManager) sendStoppedEvent(stoppingClientID uuid.UUID) {
 		eventTypeRPITXExecutionStopped,
 		rpitxExecutionStoppedMessageData{
 			InitiatingClientID: initiatingClientID.String(),
-			StoppingClientID:   stoppingClientID.String(),
+			StoppingClientID:   stoppingClientIDString(stoppingClientID),
+			Reason:             termination.reason,
+			ExitCode:           termination.exitCode,
+			Duration:           termination.duration.Seconds(),
 			Timestamp:          time.Now().Unix(),
 		},
 	))
 }
 
+// stoppingClientIDString reports a stop nobody asked for - shutdown, the
+// dead-man switch - with an empty ID rather than the nil UUID.
+func stoppingClientIDString(stoppingClientID uuid.UUID) string {
+	if stoppingClientID == uuid.Nil {
+		return ""
+	}
+
+	return stoppingClientID.String()
+}
+
 // SendError broadcasts an error event to all connected clients.
 func (em *executionManager) SendError(errorType, message string) {
 	em.sendErrorEvent(errorType, message)
@@ -438,13 +392,40 @@ func (em *executionManager) sendErrorEvent(errorType, message string) {
 	))
 }
 
-func (em *executionManager) sendOutputEvent(outputType, line string) {
-	em.hub.BroadcastToAll(dabluveees.NewEvent(
-		eventTypeRPITXExecutionOutputLine,
-		rpitxExecutionOutputLineMessageData{
-			Type:      outputType,
-			Line:      line,
+// sendClientError tells the client that asked for an execution why it
+// isn't going on air, nobody else needs to know. Scheduled executions have
+// nobody behind them so everybody gets a schedule.error instead.
+func (em *executionManager) sendClientError(
+	client *wshub.Client,
+	errorType string,
+	err error,
+) {
+	logrus.WithError(err).
+		WithFields(logrus.Fields{
+			"errorType": errorType,
+			"clientID":  client.ID(),
+		}).
+		Error("RPITX execution error occurred")
+
+	if client == em.scheduledClient && em.scheduleErrorFn != nil {
+		em.scheduleErrorFn(errorType, errorMessage(err))
+
+		return
+	}
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeRPITXExecutionError,
+		rpitxExecutionErrorMessageData{
+			Error:     errorType,
+			Message:   errorMessage(err),
 			Timestamp: time.Now().Unix(),
 		},
 	))
 }
+
+func (em *executionManager) sendStatusEvent(client *wshub.Client) {
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeRPITXExecutionStatus,
+		em.getStatus(),
+	))
+}
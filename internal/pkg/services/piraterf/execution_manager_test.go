@@ -8,238 +8,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
-	commonerrors "github.com/psyb0t/common-go/errors"
 	"github.com/psyb0t/goenv"
 	"github.com/psyb0t/gorpitx"
-	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestExecutionManager_StopStreaming_DoubleClose(t *testing.T) {
-	// Suppress debug logs in tests
-	logrus.SetLevel(logrus.WarnLevel)
-
-	// Set ENV=dev to avoid root check
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	// Create a mock hub
-	hub := wshub.NewHub("test")
-
-	// Create ExecutionManager with mock RPITX
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Setup output channels to simulate the scenario
-	ctx := context.Background()
-	em.setupOutputChannels(ctx)
-
-	// Simulate channels being closed by commander process
-	// (this would normally happen when the process finishes)
-	close(em.outputChannels.stdout)
-	close(em.outputChannels.stderr)
-
-	// Now call stopStreaming - this should NOT panic even though
-	// channels are already closed
-	require.NotPanics(t, func() {
-		em.stopStreaming()
-	}, "stopStreaming should not panic when channels are already closed")
-
-	// Verify channels are set to nil after cleanup
-	em.mu.RLock()
-	assert.Nil(
-		t,
-		em.outputChannels.stdout,
-		"stdout channel should be nil after cleanup",
-	)
-	assert.Nil(
-		t,
-		em.outputChannels.stderr,
-		"stderr channel should be nil after cleanup",
-	)
-	em.mu.RUnlock()
-}
-
-func TestExecutionManager_StopStreaming_NormalClose(t *testing.T) {
-	// Set ENV=dev to avoid root check
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	// Create a mock hub
-	hub := wshub.NewHub("test")
-
-	// Create ExecutionManager with mock RPITX
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Setup output channels
-	ctx := context.Background()
-	em.setupOutputChannels(ctx)
-
-	// Call stopStreaming - this should work normally
-	require.NotPanics(t, func() {
-		em.stopStreaming()
-	}, "stopStreaming should not panic during normal operation")
-
-	// Verify channels are set to nil after cleanup
-	em.mu.RLock()
-	assert.Nil(
-		t,
-		em.outputChannels.stdout,
-		"stdout channel should be nil after cleanup",
-	)
-	assert.Nil(
-		t,
-		em.outputChannels.stderr,
-		"stderr channel should be nil after cleanup",
-	)
-	em.mu.RUnlock()
-}
-
-func TestExecutionManager_StopStreaming_MultipleCallsSafe(t *testing.T) {
-	// Set ENV=dev to avoid root check
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	// Create a mock hub
-	hub := wshub.NewHub("test")
-
-	// Create ExecutionManager with mock RPITX
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Setup output channels
-	ctx := context.Background()
-	em.setupOutputChannels(ctx)
-
-	// Call stopStreaming multiple times - should be safe
-	require.NotPanics(t, func() {
-		em.stopStreaming()
-		em.stopStreaming() // Second call should be safe
-		em.stopStreaming() // Third call should be safe
-	}, "multiple calls to stopStreaming should be safe")
-}
-
-func TestExecutionManager_StreamOutput_ContextCancellation(t *testing.T) {
-	// Set ENV=dev to avoid root check
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	// Create a mock hub
-	hub := wshub.NewHub("test")
-
-	// Create ExecutionManager with mock RPITX
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Create a cancellable context
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Setup channels manually
-	em.mu.Lock()
-	em.outputChannels.stdout = make(chan string, 1)
-	em.outputChannels.stderr = make(chan string, 1)
-	em.mu.Unlock()
-
-	// Start streaming in goroutine
-	done := make(chan struct{})
-
-	go func() {
-		defer close(done)
-
-		em.streamOutput(ctx)
-	}()
-
-	// Cancel context to stop streaming
-	cancel()
-
-	// Wait for streaming to stop with timeout
-	select {
-	case <-done:
-		// Success - streaming stopped gracefully
-	case <-time.After(1 * time.Second):
-		t.Fatal("streamOutput did not stop after context cancellation")
-	}
-
-	// Clean up channels
-	em.mu.Lock()
-
-	if em.outputChannels.stdout != nil {
-		close(em.outputChannels.stdout)
-	}
-
-	if em.outputChannels.stderr != nil {
-		close(em.outputChannels.stderr)
-	}
-
-	em.mu.Unlock()
-}
-
-func TestExecutionManager_IsExpectedTermination(t *testing.T) {
-	// Set ENV=dev to avoid root check
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	hub := wshub.NewHub("test")
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	tests := []struct {
-		name          string
-		err           error
-		stopRequested bool
-		expected      bool
-	}{
-		{
-			name:          "timeout error is always expected",
-			err:           commonerrors.ErrTimeout,
-			stopRequested: false,
-			expected:      true,
-		},
-		{
-			name:          "terminated with stop requested is expected",
-			err:           commonerrors.ErrTerminated,
-			stopRequested: true,
-			expected:      true,
-		},
-		{
-			name:          "killed with stop requested is expected",
-			err:           commonerrors.ErrKilled,
-			stopRequested: true,
-			expected:      true,
-		},
-		{
-			name:          "terminated without stop requested is unexpected",
-			err:           commonerrors.ErrTerminated,
-			stopRequested: false,
-			expected:      false,
-		},
-		{
-			name:          "killed without stop requested is unexpected",
-			err:           commonerrors.ErrKilled,
-			stopRequested: false,
-			expected:      false,
-		},
-		{
-			name:          "other errors are unexpected",
-			err:           assert.AnError,
-			stopRequested: false,
-			expected:      false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			em.stopRequested.Store(tt.stopRequested)
-			result := em.isExpectedTermination(tt.err)
-			assert.Equal(
-				t,
-				tt.expected,
-				result,
-				"isExpectedTermination should return %v for %s",
-				tt.expected,
-				tt.name,
-			)
-		})
-	}
-}
-
 func TestExecutionManager_StartExecution(t *testing.T) {
 	// Set ENV=dev to avoid root check
 	t.Setenv(goenv.EnvVarName, goenv.Dev)
@@ -509,7 +283,9 @@ func TestSendStoppedEvent(t *testing.T) {
 	clientID := uuid.New()
 
 	require.NotPanics(t, func() {
-		em.sendStoppedEvent(clientID)
+		em.sendStoppedEvent(clientID, executionTermination{
+			reason: terminationReasonUserStop,
+		})
 	})
 }
 
@@ -528,52 +304,6 @@ func TestSendError(t *testing.T) {
 	})
 }
 
-func TestSendOutputEvent(t *testing.T) {
-	t.Setenv(goenv.EnvVarName, goenv.Dev)
-
-	hub := wshub.NewHub("test")
-	defer hub.Close()
-
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Test that sendOutputEvent doesn't panic
-	assert.NotPanics(t, func() {
-		em.sendOutputEvent("stdout", "test output line")
-	})
-}
-
-func TestExecutionManagerStreamOutput(t *testing.T) {
-	hub := wshub.NewHub("test")
-	defer hub.Close()
-
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	// Test streamOutput with context cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Start streamOutput in goroutine
-	done := make(chan bool)
-
-	go func() {
-		em.streamOutput(ctx)
-
-		done <- true
-	}()
-
-	// Cancel context to stop streaming
-	cancel()
-
-	// Wait for function to return
-	select {
-	case <-done:
-		// Success - function returned when context was cancelled
-	case <-time.After(1 * time.Second):
-		t.Fatal("streamOutput did not return when context was cancelled")
-	}
-}
-
 func TestExecutionManagerSendStoppedEvent(t *testing.T) {
 	hub := wshub.NewHub("test")
 	defer hub.Close()
@@ -585,7 +315,9 @@ func TestExecutionManagerSendStoppedEvent(t *testing.T) {
 
 	// Test with no initiating client
 	require.NotPanics(t, func() {
-		em.sendStoppedEvent(stoppingClientID)
+		em.sendStoppedEvent(stoppingClientID, executionTermination{
+			reason: terminationReasonTimeout,
+		})
 	})
 
 	// Test with initiating client set
@@ -593,64 +325,8 @@ func TestExecutionManagerSendStoppedEvent(t *testing.T) {
 	em.initiatingClient.Store(initiatingClientID)
 
 	require.NotPanics(t, func() {
-		em.sendStoppedEvent(stoppingClientID)
-	})
-}
-
-func TestProcessOutputChannels(t *testing.T) {
-	hub := wshub.NewHub("test")
-	defer hub.Close()
-
-	rpitx := gorpitx.GetInstance()
-	em := newExecutionManager(rpitx, hub)
-
-	ctx := t.Context()
-
-	tests := []struct {
-		name          string
-		setupChannels func() (chan string, chan string)
-		expectReturn  bool
-	}{
-		{
-			name: "nil channels should return false",
-			setupChannels: func() (chan string, chan string) {
-				return nil, nil
-			},
-			expectReturn: false,
-		},
-		{
-			name: "context cancelled should return false",
-			setupChannels: func() (chan string, chan string) {
-				stdoutCh := make(chan string, 1)
-				stderrCh := make(chan string, 1)
-
-				return stdoutCh, stderrCh
-			},
-			expectReturn: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			stdoutCh, stderrCh := tt.setupChannels()
-
-			// Set up channels in execution manager
-			em.mu.Lock()
-			em.outputChannels.stdout = stdoutCh
-			em.outputChannels.stderr = stderrCh
-			em.mu.Unlock()
-
-			if tt.name == "context cancelled should return false" {
-				// Cancel context for this test
-				testCtx, testCancel := context.WithCancel(ctx)
-				testCancel() // Cancel immediately
-
-				result := em.processOutputChannels(testCtx)
-				assert.Equal(t, tt.expectReturn, result)
-			} else {
-				result := em.processOutputChannels(ctx)
-				assert.Equal(t, tt.expectReturn, result)
-			}
+		em.sendStoppedEvent(stoppingClientID, executionTermination{
+			reason: terminationReasonTimeout,
 		})
-	}
+	})
 }
@@ -0,0 +1,209 @@
+package piraterf
+
+import (
+	"sync"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultOutputFlushInterval is how long output lines get collected
+	// before they go out in one rpitx.execution.output-line event.
+	defaultOutputFlushInterval = 250 * time.Millisecond
+	// outputMaxBatchLines flushes early so a chatty process doesn't build
+	// huge events.
+	outputMaxBatchLines = 100
+	// outputDrainQuiet is how long we keep waiting, once the process is
+	// gone, for commander to hand over more lines or close the channels. It
+	// never gets them when the execution failed before the process started.
+	outputDrainQuiet = 20 * time.Millisecond
+)
+
+// outputPipeline carries a process's output from commander to the hub.
+// Commander owns the channels once they're handed to it - it's the only
+// sender and it closes them when the process is gone - so we only ever read
+// from them and never close them.
+type outputPipeline struct {
+	stdout   chan string
+	stderr   chan string
+	finishCh chan struct{} // closed when the execution is over
+	doneCh   chan struct{} // closed when run returns
+	once     sync.Once
+}
+
+type rpitxExecutionOutputBatchMessageData struct {
+	Lines     []rpitxExecutionOutputLineMessageData `json:"lines"`
+	Timestamp int64                                 `json:"timestamp"`
+}
+
+func newOutputPipeline() *outputPipeline {
+	return &outputPipeline{
+		stdout:   make(chan string, stdoutChannelBufferSize),
+		stderr:   make(chan string, stderrChannelBufferSize),
+		finishCh: make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// finish tells the pipeline the execution is over and waits for it to send
+// whatever it still has. Safe to call more than once.
+func (p *outputPipeline) finish() {
+	p.once.Do(func() {
+		close(p.finishCh)
+	})
+
+	<-p.doneCh
+}
+
+// run batches lines and hands them to send every flushInterval. It blocks
+// on the channels the whole time - nothing runs while the process is quiet.
+// It returns once both channels are closed, or once they've been quiet for
+// outputDrainQuiet after finish was called.
+func (p *outputPipeline) run(
+	flushInterval time.Duration,
+	send func(lines []rpitxExecutionOutputLineMessageData),
+) {
+	defer close(p.doneCh)
+
+	var (
+		batch  []rpitxExecutionOutputLineMessageData
+		flushC <-chan time.Time // nil until there's something to flush
+		timer  *time.Timer
+		drain  *time.Timer // nil until finish is called
+		drainC <-chan time.Time
+	)
+
+	stdout, stderr, finishCh := p.stdout, p.stderr, p.finishCh
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+
+		flushC = nil
+
+		if len(batch) == 0 {
+			return
+		}
+
+		send(batch)
+		batch = nil
+	}
+
+	add := func(outputType, line string) {
+		batch = append(batch, newOutputLine(outputType, line))
+
+		if drain != nil {
+			drain.Reset(outputDrainQuiet)
+		}
+
+		if len(batch) >= outputMaxBatchLines {
+			flush()
+
+			return
+		}
+
+		if flushC == nil {
+			timer = time.NewTimer(flushInterval)
+			flushC = timer.C
+		}
+	}
+
+	defer flush()
+
+	for stdout != nil || stderr != nil {
+		select {
+		case line, ok := <-stdout:
+			if !ok {
+				stdout = nil
+
+				continue
+			}
+
+			add("stdout", line)
+		case line, ok := <-stderr:
+			if !ok {
+				stderr = nil
+
+				continue
+			}
+
+			add("stderr", line)
+		case <-flushC:
+			flush()
+		case <-finishCh:
+			finishCh = nil
+			drain = time.NewTimer(outputDrainQuiet)
+			drainC = drain.C
+		case <-drainC:
+			logrus.Debug("output channels went quiet without being closed")
+
+			return
+		}
+	}
+}
+
+func newOutputLine(
+	outputType, line string,
+) rpitxExecutionOutputLineMessageData {
+	return rpitxExecutionOutputLineMessageData{
+		Type:      outputType,
+		Line:      line,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// startOutputPipeline sets up the output pipeline of a new execution.
+func (em *executionManager) startOutputPipeline() *outputPipeline {
+	pipeline := newOutputPipeline()
+
+	flushInterval := em.outputFlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultOutputFlushInterval
+	}
+
+	em.mu.Lock()
+	em.output = pipeline
+	em.mu.Unlock()
+
+	go pipeline.run(flushInterval, func(
+		lines []rpitxExecutionOutputLineMessageData,
+	) {
+		em.sendOutputLines(lines...)
+	})
+
+	return pipeline
+}
+
+// stopStreaming finishes the output pipeline of the execution that just
+// ended so every line it printed has gone out before the stopped event.
+func (em *executionManager) stopStreaming() {
+	em.mu.Lock()
+	pipeline := em.output
+	em.output = nil
+	em.mu.Unlock()
+
+	if pipeline != nil {
+		pipeline.finish()
+	}
+}
+
+// sendOutputLines records output lines and broadcasts them in one event.
+func (em *executionManager) sendOutputLines(
+	lines ...rpitxExecutionOutputLineMessageData,
+) {
+	for _, line := range lines {
+		em.recordOutput(line.Type, line.Line)
+		em.recentOutput.add(line)
+	}
+
+	em.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeRPITXExecutionOutputLine,
+		rpitxExecutionOutputBatchMessageData{
+			Lines:     lines,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,204 @@
+package piraterf
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder collects what an outputPipeline sends.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]rpitxExecutionOutputLineMessageData
+}
+
+func (r *batchRecorder) send(lines []rpitxExecutionOutputLineMessageData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, lines)
+}
+
+func (r *batchRecorder) get() [][]rpitxExecutionOutputLineMessageData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.batches
+}
+
+func runPipeline(
+	p *outputPipeline,
+	flushInterval time.Duration,
+	r *batchRecorder,
+) {
+	go p.run(flushInterval, r.send)
+}
+
+func waitPipelineDone(t *testing.T, p *outputPipeline) {
+	t.Helper()
+
+	select {
+	case <-p.doneCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("output pipeline did not return")
+	}
+}
+
+func TestOutputPipeline_BatchesLines(t *testing.T) {
+	p := newOutputPipeline()
+	r := &batchRecorder{}
+	runPipeline(p, 50*time.Millisecond, r)
+
+	p.stdout <- "one"
+	p.stderr <- "two"
+	p.stdout <- "three"
+
+	require.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	batch := r.get()[0]
+	require.Len(t, batch, 3)
+
+	types := map[string][]string{}
+	for _, line := range batch {
+		types[line.Type] = append(types[line.Type], line.Line)
+	}
+
+	assert.Equal(t, []string{"one", "three"}, types["stdout"])
+	assert.Equal(t, []string{"two"}, types["stderr"])
+
+	// Nothing more goes out while the process is quiet
+	time.Sleep(120 * time.Millisecond)
+	assert.Len(t, r.get(), 1)
+
+	close(p.stdout)
+	close(p.stderr)
+	waitPipelineDone(t, p)
+}
+
+func TestOutputPipeline_FlushesFullBatchEarly(t *testing.T) {
+	p := newOutputPipeline()
+	r := &batchRecorder{}
+	runPipeline(p, time.Hour, r)
+
+	go func() {
+		for range outputMaxBatchLines {
+			p.stdout <- "line"
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Len(t, r.get()[0], outputMaxBatchLines)
+
+	close(p.stdout)
+	close(p.stderr)
+	waitPipelineDone(t, p)
+}
+
+func TestOutputPipeline_ReturnsWhenCommanderClosesChannels(t *testing.T) {
+	p := newOutputPipeline()
+	r := &batchRecorder{}
+	runPipeline(p, time.Hour, r)
+
+	p.stdout <- "last words"
+	close(p.stdout)
+	close(p.stderr)
+
+	// Returns on its own without finish and flushes what's left
+	waitPipelineDone(t, p)
+
+	batches := r.get()
+	require.Len(t, batches, 1)
+	assert.Equal(t, "last words", batches[0][0].Line)
+
+	require.NotPanics(t, p.finish)
+}
+
+func TestOutputPipeline_FinishDrainsBeforeReturning(t *testing.T) {
+	p := newOutputPipeline()
+	r := &batchRecorder{}
+	runPipeline(p, time.Hour, r)
+
+	p.stdout <- "before"
+
+	finished := make(chan struct{})
+
+	go func() {
+		p.finish()
+		close(finished)
+	}()
+
+	// Lines handed over after finish still make it out
+	p.stderr <- "after"
+	close(p.stdout)
+	close(p.stderr)
+
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("finish did not return")
+	}
+
+	var lines []string
+	for _, batch := range r.get() {
+		for _, line := range batch {
+			lines = append(lines, line.Line)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"before", "after"}, lines)
+}
+
+func TestOutputPipeline_FinishWithoutCommander(t *testing.T) {
+	// The execution failed before commander ever got the channels so
+	// nobody is going to close them
+	p := newOutputPipeline()
+	r := &batchRecorder{}
+	runPipeline(p, time.Hour, r)
+
+	start := time.Now()
+
+	require.NotPanics(t, func() {
+		p.finish()
+		p.finish()
+	})
+
+	assert.GreaterOrEqual(t, time.Since(start), outputDrainQuiet)
+	assert.Empty(t, r.get())
+}
+
+func TestExecutionManager_OutputPipeline(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	em.outputFlushInterval = 10 * time.Millisecond
+
+	// Nothing to stop yet
+	require.NotPanics(t, em.stopStreaming)
+
+	p := em.startOutputPipeline()
+	p.stdout <- "tuning"
+	close(p.stdout)
+	close(p.stderr)
+
+	em.stopStreaming()
+	require.NotPanics(t, em.stopStreaming)
+
+	lines := em.recentOutput.snapshot()
+	require.Len(t, lines, 1)
+	assert.Equal(t, "stdout", lines[0].Type)
+	assert.Equal(t, "tuning", lines[0].Line)
+
+	em.mu.RLock()
+	assert.Nil(t, em.output)
+	em.mu.RUnlock()
+}
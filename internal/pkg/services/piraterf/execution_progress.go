@@ -0,0 +1,253 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeRPITXExecutionProgress = dabluveees.EventType(
+		"rpitx.execution.progress",
+	)
+
+	// progressInterval is how often a Play Once broadcast reports progress.
+	progressInterval = time.Second
+
+	playbackItemIntro = "intro"
+	playbackItemMain  = "main"
+	playbackItemOutro = "outro"
+
+	percentFull = 100
+)
+
+// playbackItem is one part of what a Play Once broadcast plays.
+type playbackItem struct {
+	name     string  // intro, main or outro
+	duration float64 // seconds
+}
+
+// playbackTimeline lays a Play Once broadcast out in time so its progress
+// can be told from how long it's been on air.
+type playbackTimeline struct {
+	items []playbackItem
+	// total is when the broadcast ends in seconds - the audio plus the
+	// trailing silence, or the user timeout when that's shorter.
+	total float64
+}
+
+// progressReporter is the goroutine reporting progress of the current
+// execution.
+type progressReporter struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type rpitxExecutionProgressMessageData struct {
+	Elapsed     float64 `json:"elapsed"`   // seconds
+	Remaining   float64 `json:"remaining"` // seconds
+	Total       float64 `json:"total"`     // seconds
+	CurrentItem string  `json:"currentItem"`
+	Percent     float64 `json:"percent"`
+	Timestamp   int64   `json:"timestamp"`
+}
+
+// withProgress reports progress of a Play Once broadcast along timeline.
+func withProgress(timeline *playbackTimeline) executionOption {
+	return func(job *queuedExecution) {
+		job.progress = timeline
+	}
+}
+
+// playOnceTimeline measures the intro, main audio and outro of a Play Once
+// broadcast that ends after timeout seconds. The gap or crossfade after an
+// item counts towards it.
+func (s *PIrateRF) playOnceTimeline(
+	msg rpitxExecutionStartMessage,
+	timeout int,
+) (*playbackTimeline, error) {
+	var args struct {
+		Audio string `json:"audio"`
+	}
+
+	if err := json.Unmarshal(msg.Args, &args); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	parts := []struct {
+		name string
+		path *string
+	}{
+		{name: playbackItemIntro, path: msg.Intro},
+		{name: playbackItemMain, path: &args.Audio},
+		{name: playbackItemOutro, path: msg.Outro},
+	}
+
+	timeline := &playbackTimeline{total: float64(timeout)}
+	joins := msg.joinsOnly()
+
+	for _, part := range parts {
+		if part.path == nil || *part.path == "" {
+			continue
+		}
+
+		duration, err := s.audioDuration(*part.path, joins)
+		if err != nil {
+			return nil, ctxerrors.Wrapf(err, "failed to measure %s", part.name)
+		}
+
+		if len(timeline.items) > 0 {
+			timeline.items[len(timeline.items)-1].duration +=
+				joins.Gap - joins.Crossfade
+		}
+
+		timeline.items = append(timeline.items, playbackItem{
+			name:     part.name,
+			duration: duration,
+		})
+	}
+
+	return timeline, nil
+}
+
+// playOnceDuration is how long a Play Once broadcast of the audio, intro
+// and outro of msg lasts with its trailing silence, measured without
+// building anything.
+func (s *PIrateRF) playOnceDuration(
+	msg rpitxExecutionStartMessage,
+) (float64, error) {
+	timeline, err := s.playOnceTimeline(msg, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	duration := float64(playOnceSilenceSeconds)
+	for _, item := range timeline.items {
+		duration += item.duration
+	}
+
+	return duration, nil
+}
+
+// audioDuration measures an audio arg, a stored playlist is as long as its
+// items joined with the transitions.
+func (s *PIrateRF) audioDuration(
+	audio string,
+	transitions playlistTransitions,
+) (float64, error) {
+	pl, ok, err := s.playlistFromAudio(audio)
+	if err != nil {
+		return 0, err
+	}
+
+	if ok {
+		return s.playlistDuration(pl, transitions)
+	}
+
+	return s.getAudioDuration(audio)
+}
+
+// progressAt returns the progress after elapsed on air. Past the last item
+// (the trailing silence) it stays on the last one.
+func (t *playbackTimeline) progressAt(
+	elapsed time.Duration,
+) rpitxExecutionProgressMessageData {
+	seconds := min(elapsed.Seconds(), t.total)
+
+	progress := rpitxExecutionProgressMessageData{
+		Elapsed:   seconds,
+		Remaining: t.total - seconds,
+		Total:     t.total,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if t.total > 0 {
+		progress.Percent = seconds / t.total * percentFull
+	}
+
+	itemEnd := 0.0
+	for _, item := range t.items {
+		progress.CurrentItem = item.name
+		itemEnd += item.duration
+
+		if seconds < itemEnd {
+			break
+		}
+	}
+
+	return progress
+}
+
+// startProgress broadcasts progress of the current execution every
+// progressInterval until stopProgress.
+func (em *executionManager) startProgress(
+	timeline *playbackTimeline,
+	startedAt time.Time,
+) {
+	em.stopProgress()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reporter := &progressReporter{cancel: cancel, done: make(chan struct{})}
+
+	em.mu.Lock()
+	em.progress = reporter
+	em.mu.Unlock()
+
+	logrus.WithField("total", timeline.total).
+		Debug("reporting Play Once progress")
+
+	go func() {
+		defer close(reporter.done)
+
+		em.reportProgress(ctx, timeline, startedAt)
+	}()
+}
+
+// stopProgress stops the progress reports of the current execution and
+// waits for the last one to go out so none follow the stopped event.
+func (em *executionManager) stopProgress() {
+	em.mu.Lock()
+	reporter := em.progress
+	em.progress = nil
+	em.mu.Unlock()
+
+	if reporter == nil {
+		return
+	}
+
+	reporter.cancel()
+	<-reporter.done
+}
+
+func (em *executionManager) reportProgress(
+	ctx context.Context,
+	timeline *playbackTimeline,
+	startedAt time.Time,
+) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	em.sendProgressEvent(timeline.progressAt(0))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			em.sendProgressEvent(timeline.progressAt(now.Sub(startedAt)))
+		}
+	}
+}
+
+func (em *executionManager) sendProgressEvent(
+	progress rpitxExecutionProgressMessageData,
+) {
+	em.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeRPITXExecutionProgress,
+		progress,
+	))
+}
@@ -0,0 +1,147 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaybackTimeline_ProgressAt(t *testing.T) {
+	// 2s intro, 3s main, 4s outro and 2s of trailing silence
+	timeline := &playbackTimeline{
+		items: []playbackItem{
+			{name: playbackItemIntro, duration: 2},
+			{name: playbackItemMain, duration: 3},
+			{name: playbackItemOutro, duration: 4},
+		},
+		total: 11,
+	}
+
+	tests := []struct {
+		name      string
+		elapsed   time.Duration
+		item      string
+		remaining float64
+		percent   float64
+	}{
+		{name: "start", elapsed: 0, item: playbackItemIntro, remaining: 11},
+		{
+			name:      "main",
+			elapsed:   2500 * time.Millisecond,
+			item:      playbackItemMain,
+			remaining: 8.5,
+			percent:   2.5 / 11 * 100,
+		},
+		{
+			name:      "outro",
+			elapsed:   5 * time.Second,
+			item:      playbackItemOutro,
+			remaining: 6,
+			percent:   5.0 / 11 * 100,
+		},
+		{
+			name:      "trailing silence",
+			elapsed:   10 * time.Second,
+			item:      playbackItemOutro,
+			remaining: 1,
+			percent:   10.0 / 11 * 100,
+		},
+		{
+			name:    "past the end",
+			elapsed: time.Minute,
+			item:    playbackItemOutro,
+			percent: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := timeline.progressAt(tt.elapsed)
+			assert.Equal(t, tt.item, progress.CurrentItem)
+			assert.InDelta(t, tt.remaining, progress.Remaining, 0.001)
+			assert.InDelta(t, tt.percent, progress.Percent, 0.001)
+			assert.InDelta(t, 11, progress.Total, 0)
+		})
+	}
+}
+
+func TestPlayOnceTimeline(t *testing.T) {
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		commander:  &testMockCommander{tempDir: t.TempDir()},
+	}
+
+	intro := introFile
+	msg := rpitxExecutionStartMessage{
+		Args: json.RawMessage(
+			`{"freq":107.9,"audio":"` + mainAudioFile + `"}`,
+		),
+		PlayOnce: true,
+		Intro:    &intro,
+	}
+
+	timeline, err := service.playOnceTimeline(msg, 8)
+	require.NoError(t, err)
+	assert.InDelta(t, 8, timeline.total, 0)
+	assert.Equal(t, []playbackItem{
+		{name: playbackItemIntro, duration: 3},
+		{name: playbackItemMain, duration: 3},
+	}, timeline.items)
+
+	// The crossfade into the main audio comes off the intro
+	msg.playlistTransitions = playlistTransitions{Crossfade: 1, FadeIn: 2}
+
+	timeline, err = service.playOnceTimeline(msg, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []playbackItem{
+		{name: playbackItemIntro, duration: 2},
+		{name: playbackItemMain, duration: 3},
+	}, timeline.items)
+
+	_, err = service.playOnceTimeline(rpitxExecutionStartMessage{
+		Args: json.RawMessage(`nope`),
+	}, 8)
+	assert.Error(t, err)
+}
+
+func TestExecutionManager_Progress(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	// Nothing to stop yet
+	require.NotPanics(t, em.stopProgress)
+
+	timeline := &playbackTimeline{
+		items: []playbackItem{{name: playbackItemMain, duration: 5}},
+		total: 5,
+	}
+
+	em.startProgress(timeline, time.Now())
+
+	em.mu.RLock()
+	reporter := em.progress
+	em.mu.RUnlock()
+	require.NotNil(t, reporter)
+
+	em.stopProgress()
+
+	select {
+	case <-reporter.done:
+	default:
+		t.Fatal("stopProgress returned before the reporter did")
+	}
+
+	em.mu.RLock()
+	assert.Nil(t, em.progress)
+	em.mu.RUnlock()
+
+	require.NotPanics(t, em.stopProgress)
+}
@@ -0,0 +1,335 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQueueLength caps how many start requests can wait for the
+	// transmitter at the same time.
+	maxQueueLength = 50
+)
+
+// queuedExecution is a pending rpitx.execution.start request waiting for
+// the transmitter to become free.
+type queuedExecution struct {
+	id         uuid.UUID
+	ctx        context.Context //nolint:containedctx
+	moduleName gorpitx.ModuleName
+	args       json.RawMessage
+	timeout    int
+	client     *wshub.Client
+	callback   func() error
+	playOnce   bool
+	// deadManGrace arms the dead-man switch when > 0
+	deadManGrace time.Duration
+	progress     *playbackTimeline // reports Play Once progress when set
+	rdsTracks    *rdsTrackSchedule // changes RDS as tracks go by when set
+	// launch starts what has to run next to the module once it leaves the
+	// queue (optional)
+	launch     func() error
+	enqueuedAt time.Time
+}
+
+// executionOption tweaks a queued execution.
+type executionOption func(job *queuedExecution)
+
+// withPlayOnce marks a Play Once execution so its timeout reads as the
+// audio having finished rather than the timeout running out.
+func withPlayOnce() executionOption {
+	return func(job *queuedExecution) {
+		job.playOnce = true
+	}
+}
+
+// withDeadMan stops the execution once the initiating client has been
+// quiet for longer than grace.
+func withDeadMan(grace time.Duration) executionOption {
+	return func(job *queuedExecution) {
+		job.deadManGrace = grace
+	}
+}
+
+// withLaunch has launch run when the job leaves the queue, right before it
+// goes on air. The job doesn't go on air when launch fails and its callback
+// cleans up after whatever launch got to start.
+func withLaunch(launch func() error) executionOption {
+	return func(job *queuedExecution) {
+		job.launch = launch
+	}
+}
+
+func newQueuedExecution(
+	ctx context.Context,
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+	timeout int,
+	client *wshub.Client,
+	callback func() error,
+) *queuedExecution {
+	return &queuedExecution{
+		id:         uuid.New(),
+		ctx:        ctx,
+		moduleName: moduleName,
+		args:       args,
+		timeout:    timeout,
+		client:     client,
+		callback:   callback,
+		enqueuedAt: time.Now(),
+	}
+}
+
+// cleanup runs the job's cleanup callback (temp playlists, silence files)
+// for jobs that never made it on air.
+func (job *queuedExecution) cleanup() {
+	if job.callback == nil {
+		return
+	}
+
+	if err := job.callback(); err != nil {
+		logrus.WithError(err).
+			WithField("jobID", job.id).
+			Error("queued job cleanup failed")
+	}
+}
+
+// enqueueExecution appends a job to the queue and starts it right away if
+// the transmitter is free.
+func (em *executionManager) enqueueExecution(job *queuedExecution) error {
+	if em.shuttingDown.Load() {
+		logrus.WithFields(logrus.Fields{
+			"moduleName": job.moduleName,
+			"clientID":   job.client.ID(),
+		}).Warn("service shutting down, dropping execution request")
+
+		job.cleanup()
+
+		return nil
+	}
+
+	em.queueMu.Lock()
+
+	if len(em.queue) >= maxQueueLength {
+		em.queueMu.Unlock()
+
+		job.cleanup()
+		em.sendQueueErrorEvent(
+			"queue full",
+			"too many transmissions waiting in the queue",
+		)
+
+		return nil // Don't return error - just broadcast
+	}
+
+	em.queue = append(em.queue, job)
+	em.queueMu.Unlock()
+
+	if em.startNextQueued() {
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"jobID":      job.id,
+		"moduleName": job.moduleName,
+		"clientID":   job.client.ID(),
+	}).Debug("transmitter busy, execution queued")
+
+	em.sendQueueUpdatedEvent()
+
+	return nil
+}
+
+// startNextQueued pops the head of the queue and starts it if nothing is
+// currently executing. Returns true if a job was started. The job is made
+// the active execution before the queue is let go, so a shutdown coming in
+// right after finds it and stops it rather than it going on air once the
+// shutdown is done.
+func (em *executionManager) startNextQueued() bool {
+	em.queueMu.Lock()
+
+	if len(em.queue) == 0 || em.running.Load() || em.shuttingDown.Load() {
+		em.queueMu.Unlock()
+
+		return false
+	}
+
+	// Atomic state transition - only allow if idle
+	if !em.state.CompareAndSwap(
+		int32(executionStateIdle),
+		int32(executionStateExecuting),
+	) {
+		em.queueMu.Unlock()
+
+		return false
+	}
+
+	job := em.queue[0]
+	em.queue = slices.Delete(em.queue, 0, 1)
+	em.running.Store(true)
+
+	// Store initiating client
+	em.initiatingClient.Store(job.client.ID())
+
+	// Shutdown may have to wrap up itself if the execution doesn't in
+	// time, so make sure only one of them ever does
+	wrapUp := newExecutionWrapUp(job.callback)
+	done := make(chan struct{})
+
+	em.mu.Lock()
+	em.executionDone = done
+	em.activeWrapUp = wrapUp
+	em.mu.Unlock()
+
+	em.queueMu.Unlock()
+
+	em.launchExecution(job, wrapUp, done)
+
+	return true
+}
+
+func (em *executionManager) launchExecution(
+	job *queuedExecution,
+	wrapUp *executionWrapUp,
+	done chan struct{},
+) {
+	// Validate timeout
+	validTimeout := em.validateTimeout(job.timeout)
+
+	// Start execution in goroutine
+	go func() {
+		defer close(done)
+
+		em.executeModule(job, validTimeout, wrapUp)
+	}()
+}
+
+// dequeueExecution removes a single pending job from the queue.
+func (em *executionManager) dequeueExecution(jobID uuid.UUID) error {
+	em.queueMu.Lock()
+
+	idx := em.findQueuedExecution(jobID)
+	if idx < 0 {
+		em.queueMu.Unlock()
+
+		return ctxerrors.Wrapf(commonerrors.ErrNotFound, "job %s", jobID)
+	}
+
+	job := em.queue[idx]
+	em.queue = slices.Delete(em.queue, idx, idx+1)
+	em.queueMu.Unlock()
+
+	job.cleanup()
+	em.sendQueueUpdatedEvent()
+
+	return nil
+}
+
+// reorderExecution moves a pending job to the given position in the queue.
+// Out of range positions are clamped to the queue bounds.
+func (em *executionManager) reorderExecution(
+	jobID uuid.UUID,
+	position int,
+) error {
+	em.queueMu.Lock()
+
+	idx := em.findQueuedExecution(jobID)
+	if idx < 0 {
+		em.queueMu.Unlock()
+
+		return ctxerrors.Wrapf(commonerrors.ErrNotFound, "job %s", jobID)
+	}
+
+	job := em.queue[idx]
+	em.queue = slices.Delete(em.queue, idx, idx+1)
+	position = max(0, min(position, len(em.queue)))
+	em.queue = slices.Insert(em.queue, position, job)
+	em.queueMu.Unlock()
+
+	em.sendQueueUpdatedEvent()
+
+	return nil
+}
+
+// cancelQueuedExecutions drops every pending job and returns how many were
+// removed.
+func (em *executionManager) cancelQueuedExecutions() int {
+	em.queueMu.Lock()
+	jobs := em.queue
+	em.queue = nil
+	em.queueMu.Unlock()
+
+	for _, job := range jobs {
+		job.cleanup()
+	}
+
+	if len(jobs) > 0 {
+		em.sendQueueUpdatedEvent()
+	}
+
+	return len(jobs)
+}
+
+// findQueuedExecution returns the index of a job in the queue or -1.
+// Caller must hold queueMu.
+func (em *executionManager) findQueuedExecution(jobID uuid.UUID) int {
+	return slices.IndexFunc(em.queue, func(job *queuedExecution) bool {
+		return job.id == jobID
+	})
+}
+
+func (em *executionManager) getQueueSnapshot() []queueJobMessageData {
+	em.queueMu.Lock()
+	defer em.queueMu.Unlock()
+
+	jobs := make([]queueJobMessageData, 0, len(em.queue))
+	for position, job := range em.queue {
+		jobs = append(jobs, queueJobMessageData{
+			JobID:      job.id.String(),
+			Position:   position,
+			ModuleName: job.moduleName,
+			Args:       job.args,
+			Timeout:    job.timeout,
+			ClientID:   job.client.ID().String(),
+			EnqueuedAt: job.enqueuedAt.Unix(),
+		})
+	}
+
+	return jobs
+}
+
+func (em *executionManager) sendQueueUpdatedEvent() {
+	em.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeQueueUpdated,
+		queueUpdatedMessageData{
+			Jobs:      em.getQueueSnapshot(),
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (em *executionManager) sendQueueErrorEvent(errorType, message string) {
+	logrus.WithFields(logrus.Fields{
+		"errorType": errorType,
+		"message":   message,
+	}).Error("queue error occurred")
+
+	em.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeQueueError,
+		queueErrorMessageData{
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,377 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBusyExecutionManager returns an executionManager that looks like it is
+// already on air so new start requests end up in the queue.
+func newBusyExecutionManager(t *testing.T) *executionManager {
+	t.Helper()
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	t.Cleanup(hub.Close)
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	em.setState(executionStateExecuting)
+	em.running.Store(true)
+
+	return em
+}
+
+func queueJobIDs(em *executionManager) []string {
+	jobs := em.getQueueSnapshot()
+
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.JobID)
+	}
+
+	return ids
+}
+
+func TestExecutionManager_EnqueueWhileBusy(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	for range 3 {
+		require.NoError(t, em.startExecution(
+			context.Background(),
+			gorpitx.ModuleNamePICHIRP,
+			json.RawMessage(`{}`),
+			10,
+			client,
+			nil,
+		))
+	}
+
+	jobs := em.getQueueSnapshot()
+	require.Len(t, jobs, 3)
+
+	for i, job := range jobs {
+		assert.Equal(t, i, job.Position)
+		assert.Equal(t, gorpitx.ModuleNamePICHIRP, job.ModuleName)
+		assert.Equal(t, 10, job.Timeout)
+	}
+
+	assert.Equal(
+		t,
+		executionStateExecuting,
+		executionState(em.state.Load()),
+		"queuing must not touch the current execution state",
+	)
+}
+
+func TestExecutionManager_EnqueueQueueFull(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	for range maxQueueLength {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, client, nil,
+		))
+	}
+
+	var cleanedUp atomic.Bool
+
+	require.NoError(t, em.startExecution(
+		context.Background(), gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{}`), 0, client,
+		func() error {
+			cleanedUp.Store(true)
+
+			return nil
+		},
+	))
+
+	assert.Len(t, em.getQueueSnapshot(), maxQueueLength)
+	assert.True(t, cleanedUp.Load(), "rejected job should be cleaned up")
+}
+
+func TestExecutionManager_DequeueExecution(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	var cleanups atomic.Int32
+
+	callback := func() error {
+		cleanups.Add(1)
+
+		return nil
+	}
+
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, client, callback,
+		))
+	}
+
+	ids := queueJobIDs(em)
+	require.Len(t, ids, 2)
+
+	require.NoError(t, em.dequeueExecution(uuid.MustParse(ids[0])))
+	assert.Equal(t, []string{ids[1]}, queueJobIDs(em))
+	assert.Equal(t, int32(1), cleanups.Load())
+
+	err := em.dequeueExecution(uuid.New())
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestExecutionManager_ReorderExecution(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	for range 3 {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, client, nil,
+		))
+	}
+
+	ids := queueJobIDs(em)
+	require.Len(t, ids, 3)
+
+	tests := []struct {
+		name     string
+		jobID    string
+		position int
+		expected []string
+	}{
+		{
+			name:     "move last to front",
+			jobID:    ids[2],
+			position: 0,
+			expected: []string{ids[2], ids[0], ids[1]},
+		},
+		{
+			name:     "position past the end is clamped",
+			jobID:    ids[2],
+			position: 100,
+			expected: []string{ids[0], ids[1], ids[2]},
+		},
+		{
+			name:     "negative position is clamped",
+			jobID:    ids[1],
+			position: -5,
+			expected: []string{ids[1], ids[0], ids[2]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, em.reorderExecution(
+				uuid.MustParse(tt.jobID), tt.position,
+			))
+			assert.Equal(t, tt.expected, queueJobIDs(em))
+		})
+	}
+
+	err := em.reorderExecution(uuid.New(), 0)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestExecutionManager_CancelQueuedExecutions(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	var cleanups atomic.Int32
+
+	for range 3 {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, client,
+			func() error {
+				cleanups.Add(1)
+
+				return nil
+			},
+		))
+	}
+
+	assert.Equal(t, 3, em.cancelQueuedExecutions())
+	assert.Empty(t, em.getQueueSnapshot())
+	assert.Equal(t, int32(3), cleanups.Load())
+	assert.Equal(t, 0, em.cancelQueuedExecutions())
+}
+
+func TestExecutionManager_QueueDrainsAfterExecution(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	var finished atomic.Int32
+
+	// Invalid args make rpitx.Exec fail right away so each queued job
+	// finishes quickly and hands over to the next one
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(),
+			gorpitx.ModuleNamePIFMRDS,
+			json.RawMessage(`{"freq": 88.0}`),
+			0,
+			client,
+			func() error {
+				finished.Add(1)
+
+				return nil
+			},
+		))
+	}
+
+	require.Len(t, em.getQueueSnapshot(), 2)
+
+	// Simulate the current transmission finishing
+	em.cleanupAfterExecution(client, nil)
+
+	require.Eventually(t, func() bool {
+		return finished.Load() == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Empty(t, em.getQueueSnapshot())
+	assert.Eventually(t, func() bool {
+		return executionState(em.state.Load()) == executionStateIdle &&
+			!em.running.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestExecutionManager_LaunchFailure(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := wshub.NewClient()
+
+	var launches, cleanups atomic.Int32
+
+	// A job that can't launch never goes on air, it cleans up and hands
+	// over to the next one
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(),
+			gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{"frequency": 144500000}`),
+			0,
+			client,
+			func() error {
+				cleanups.Add(1)
+
+				return nil
+			},
+			withLaunch(func() error {
+				launches.Add(1)
+
+				return commonerrors.ErrNotFound
+			}),
+		))
+	}
+
+	em.cleanupAfterExecution(client, nil)
+
+	require.Eventually(t, func() bool {
+		return cleanups.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(2), launches.Load())
+	assert.Empty(t, em.getQueueSnapshot())
+	assert.Eventually(t, func() bool {
+		return executionState(em.state.Load()) == executionStateIdle &&
+			!em.running.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestExecutionManager_StopDuringLaunch(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	client := wshub.NewClient()
+
+	launching := make(chan struct{})
+	release := make(chan struct{})
+
+	var cleanups atomic.Int32
+
+	require.NoError(t, em.startExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		client,
+		countingCallback(&cleanups),
+		withLaunch(func() error {
+			close(launching)
+			<-release
+
+			return nil
+		}),
+	))
+
+	<-launching
+	require.NoError(t, em.stopExecution(client))
+	close(release)
+
+	// The mock never ends on its own, so cleaning up means it never went
+	// on air
+	require.Eventually(t, func() bool {
+		return cleanups.Load() == 1 && !em.running.Load()
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, executionStateIdle, executionState(em.state.Load()))
+	assert.Empty(t, em.recentOutput.snapshot())
+}
+
+func TestExecutionManager_ScheduledLaunchError(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	type scheduleError struct{ errorType, message string }
+
+	errs := make(chan scheduleError, 1)
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	em.scheduledClient = wshub.NewClientWithID(uuid.Nil)
+	em.scheduleErrorFn = func(errorType, message string) {
+		errs <- scheduleError{errorType, message}
+	}
+
+	require.NoError(t, em.startExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		em.scheduledClient,
+		nil,
+		withLaunch(func() error {
+			return ctxerrors.Wrap(commonerrors.ErrFailed, "mixer died")
+		}),
+	))
+
+	select {
+	case err := <-errs:
+		assert.Equal(t, "launch failed", err.errorType)
+		assert.Contains(t, err.message, "mixer died")
+		assert.NotContains(t, err.message, ".go:")
+	case <-time.After(2 * time.Second):
+		t.Fatal("launch error never reached the scheduler")
+	}
+
+	// Errors of clients that are there still go to them
+	em.sendClientError(wshub.NewClient(), "launch failed", commonerrors.ErrFailed)
+	assert.Empty(t, errs)
+}
@@ -0,0 +1,115 @@
+package piraterf
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+// executionWrapUp makes sure an execution gets wrapped up - stopped event
+// and cleanup callback - exactly once. That's normally the execution
+// goroutine, but shutdown does it itself when the goroutine doesn't get
+// there in time, and whoever comes second leaves it alone.
+type executionWrapUp struct {
+	once       sync.Once
+	byShutdown bool
+	callback   func() error
+}
+
+func newExecutionWrapUp(callback func() error) *executionWrapUp {
+	return &executionWrapUp{callback: callback}
+}
+
+// claim hands the wrap up to whoever asks first and reports whether that
+// was the caller - shutdown or the execution goroutine. A nil wrap up
+// belongs to the goroutine.
+func (w *executionWrapUp) claim(byShutdown bool) bool {
+	if w == nil {
+		return !byShutdown
+	}
+
+	w.once.Do(func() { w.byShutdown = byShutdown })
+
+	return w.byShutdown == byShutdown
+}
+
+// runCallback runs the cleanup callback if the caller has the wrap up.
+func (w *executionWrapUp) runCallback(byShutdown bool) {
+	if w == nil || w.callback == nil || !w.claim(byShutdown) {
+		return
+	}
+
+	if err := w.callback(); err != nil {
+		logrus.WithError(err).Error("callback failed")
+	}
+}
+
+// shutdown takes whatever is on air off air for good: queued jobs are
+// dropped (their temp files removed), new ones are refused and the active
+// execution is stopped. It waits until ctx is done for the execution to
+// wrap up on its own (stopped event, cleanup callback, history record) and
+// does the cleanup itself if it doesn't.
+func (em *executionManager) shutdown(ctx context.Context) error {
+	if !em.shuttingDown.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	if dropped := em.cancelQueuedExecutions(); dropped > 0 {
+		logrus.WithField("dropped", dropped).
+			Info("dropped queued executions on shutdown")
+	}
+
+	em.mu.RLock()
+	done := em.executionDone
+	wrapUp := em.activeWrapUp
+	active := em.active
+	em.mu.RUnlock()
+
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+
+	logrus.Info("stopping active transmission on shutdown")
+
+	if err := em.stopExecutionBy(
+		uuid.Nil, terminationReasonShutdown,
+	); err != nil {
+		logrus.WithError(err).Error("failed to stop active transmission")
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// The execution goroutine is stuck - clean up after it so we at least
+	// don't leave temp files behind and clients know we're off. Unless it
+	// already got to wrapping up, then the rest is up to it.
+	if wrapUp.claim(true) {
+		wrapUp.runCallback(true)
+
+		termination := executionTermination{reason: terminationReasonShutdown}
+		if active != nil {
+			termination.duration = time.Since(active.startedAt)
+		}
+
+		em.sendStoppedEvent(uuid.Nil, termination)
+	}
+
+	return ctxerrors.Wrap(
+		commonerrors.ErrTimeout,
+		"active transmission did not stop in time",
+	)
+}
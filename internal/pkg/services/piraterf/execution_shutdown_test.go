@@ -0,0 +1,223 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countingCallback(counter *atomic.Int32) func() error {
+	return func() error {
+		counter.Add(1)
+
+		return nil
+	}
+}
+
+func TestExecutionWrapUp(t *testing.T) {
+	var calls atomic.Int32
+
+	// The execution goroutine gets there first
+	wrapUp := newExecutionWrapUp(countingCallback(&calls))
+	assert.True(t, wrapUp.claim(false))
+	assert.False(t, wrapUp.claim(true))
+
+	wrapUp.runCallback(true)
+	assert.Zero(t, calls.Load())
+
+	wrapUp.runCallback(false)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Shutdown gets there first
+	wrapUp = newExecutionWrapUp(countingCallback(&calls))
+	wrapUp.runCallback(true)
+	wrapUp.runCallback(false)
+	assert.True(t, wrapUp.claim(true))
+	assert.False(t, wrapUp.claim(false))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Nothing to wrap up belongs to the goroutine
+	var none *executionWrapUp
+	assert.True(t, none.claim(false))
+	assert.False(t, none.claim(true))
+	none.runCallback(false)
+}
+
+func TestExecutionManager_ShutdownDropsQueue(t *testing.T) {
+	em := newBusyExecutionManager(t)
+	client := &wshub.Client{}
+
+	var cleaned atomic.Int32
+
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(),
+			gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{"frequency": 144500000}`),
+			0,
+			client,
+			countingCallback(&cleaned),
+		))
+	}
+
+	require.Len(t, em.getQueueSnapshot(), 2)
+	require.NoError(t, em.shutdown(context.Background()))
+
+	assert.Empty(t, em.getQueueSnapshot())
+	assert.Equal(t, int32(2), cleaned.Load())
+
+	// Requests coming in after shutdown are dropped and cleaned up too
+	require.NoError(t, em.startExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		client,
+		countingCallback(&cleaned),
+	))
+
+	assert.Empty(t, em.getQueueSnapshot())
+	assert.Equal(t, int32(3), cleaned.Load())
+
+	// Shutting down again is a no-op
+	require.NoError(t, em.shutdown(context.Background()))
+}
+
+func TestExecutionManager_ShutdownStopsActiveExecution(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	rpitx := gorpitx.GetInstance()
+	em := newExecutionManager(rpitx, hub)
+	client := &wshub.Client{}
+
+	var cleaned atomic.Int32
+
+	// In dev mode gorpitx runs a mock loop that never ends on its own
+	require.NoError(t, em.startExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		client,
+		countingCallback(&cleaned),
+	))
+
+	// Wait for the mock to actually be on air and talking
+	require.Eventually(t, func() bool {
+		return len(em.recentOutput.snapshot()) > 0
+	}, 3*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	require.NoError(t, em.shutdown(ctx))
+
+	assert.Equal(t, int32(1), cleaned.Load())
+	assert.Equal(t, executionStateIdle, executionState(em.state.Load()))
+	assert.False(t, em.running.Load())
+}
+
+func TestExecutionManager_ShutdownTimeout(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	var cleaned atomic.Int32
+
+	// Looks like an execution goroutine that doesn't return in time
+	wrapUp := newExecutionWrapUp(countingCallback(&cleaned))
+	em.executionDone = make(chan struct{})
+	em.activeWrapUp = wrapUp
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond,
+	)
+	defer cancel()
+
+	err := em.shutdown(ctx)
+	require.ErrorIs(t, err, commonerrors.ErrTimeout)
+	assert.Equal(t, int32(1), cleaned.Load())
+
+	// When it does finish, shutdown already wrapped up for it
+	assert.False(t, wrapUp.claim(false))
+	em.cleanupAfterExecution(wshub.NewClient(), wrapUp)
+	assert.Equal(t, int32(1), cleaned.Load())
+}
+
+func TestExecutionManager_ShutdownAsJobLeavesQueue(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	var cleaned atomic.Int32
+
+	release := make(chan struct{})
+	job := newQueuedExecution(
+		context.Background(),
+		gorpitx.ModuleNameTUNE,
+		json.RawMessage(`{"frequency": 144500000}`),
+		0,
+		wshub.NewClient(),
+		countingCallback(&cleaned),
+	)
+	withLaunch(func() error {
+		<-release
+
+		return nil
+	})(job)
+
+	em.queue = append(em.queue, job)
+
+	// Hold the job right after it left the queue, before it's the active
+	// execution
+	em.mu.Lock()
+
+	started := make(chan bool)
+	go func() { started <- em.startNextQueued() }()
+
+	require.Eventually(t, em.running.Load, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	shutdownErr := make(chan error)
+	go func() { shutdownErr <- em.shutdown(ctx) }()
+
+	require.Eventually(t, em.shuttingDown.Load, time.Second, time.Millisecond)
+	em.mu.Unlock()
+
+	require.True(t, <-started)
+
+	// Shutdown has to find the job and stop it
+	require.Eventually(t, em.stopRequested.Load, time.Second, time.Millisecond)
+	close(release)
+
+	require.NoError(t, <-shutdownErr)
+	assert.Equal(t, int32(1), cleaned.Load())
+	assert.Empty(t, em.recentOutput.snapshot())
+	assert.False(t, em.running.Load())
+}
+
+func TestStoppingClientIDString(t *testing.T) {
+	clientID := uuid.New()
+
+	assert.Equal(t, clientID.String(), stoppingClientIDString(clientID))
+	assert.Empty(t, stoppingClientIDString(uuid.Nil))
+}
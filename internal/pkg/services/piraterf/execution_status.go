@@ -0,0 +1,127 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/gorpitx"
+)
+
+const (
+	// statusOutputLines is how many recent output lines are kept for
+	// clients that join while something is on air.
+	statusOutputLines = 100
+)
+
+func (s executionState) String() string {
+	switch s {
+	case executionStateIdle:
+		return "idle"
+	case executionStateExecuting:
+		return "executing"
+	case executionStateStopping:
+		return "stopping"
+	default:
+		return "unknown"
+	}
+}
+
+// activeExecution describes what's currently on air.
+type activeExecution struct {
+	moduleName         gorpitx.ModuleName
+	args               json.RawMessage
+	initiatingClientID uuid.UUID
+	startedAt          time.Time
+}
+
+// outputRingBuffer keeps the last N output lines of the current execution.
+type outputRingBuffer struct {
+	lines []rpitxExecutionOutputLineMessageData
+	next  int
+	full  bool
+	mu    sync.Mutex
+}
+
+func newOutputRingBuffer(size int) *outputRingBuffer {
+	return &outputRingBuffer{
+		lines: make([]rpitxExecutionOutputLineMessageData, size),
+	}
+}
+
+func (b *outputRingBuffer) add(line rpitxExecutionOutputLineMessageData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// snapshot returns the buffered lines oldest first.
+func (b *outputRingBuffer) snapshot() []rpitxExecutionOutputLineMessageData {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append(
+			[]rpitxExecutionOutputLineMessageData{}, b.lines[:b.next]...,
+		)
+	}
+
+	lines := make([]rpitxExecutionOutputLineMessageData, 0, len(b.lines))
+	lines = append(lines, b.lines[b.next:]...)
+	lines = append(lines, b.lines[:b.next]...)
+
+	return lines
+}
+
+func (b *outputRingBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.next = 0
+	b.full = false
+}
+
+func (em *executionManager) setActiveExecution(active *activeExecution) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.active = active
+
+	if active != nil {
+		em.recentOutput.reset()
+	}
+}
+
+// getStatus returns a snapshot of the execution state for status events.
+func (em *executionManager) getStatus() rpitxExecutionStatusMessageData {
+	now := time.Now()
+	status := rpitxExecutionStatusMessageData{
+		State:       executionState(em.state.Load()).String(),
+		OutputLines: []rpitxExecutionOutputLineMessageData{},
+		Timestamp:   now.Unix(),
+	}
+
+	em.mu.RLock()
+	active := em.active
+	em.mu.RUnlock()
+
+	if active == nil {
+		return status
+	}
+
+	status.ModuleName = active.moduleName
+	status.Args = active.args
+	status.InitiatingClientID = active.initiatingClientID.String()
+	status.StartedAt = active.startedAt.Unix()
+	status.Elapsed = int64(now.Sub(active.startedAt).Seconds())
+	status.OutputLines = em.recentOutput.snapshot()
+
+	return status
+}
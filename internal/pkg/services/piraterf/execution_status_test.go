@@ -0,0 +1,105 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func outputLineTexts(lines []rpitxExecutionOutputLineMessageData) []string {
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, line.Line)
+	}
+
+	return texts
+}
+
+func TestOutputRingBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    int
+		expected []string
+	}{
+		{name: "empty", added: 0, expected: []string{}},
+		{name: "partially filled", added: 2, expected: []string{"0", "1"}},
+		{name: "exactly full", added: 3, expected: []string{"0", "1", "2"}},
+		{name: "wrapped", added: 5, expected: []string{"2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := newOutputRingBuffer(3)
+
+			for i := range tt.added {
+				buffer.add(rpitxExecutionOutputLineMessageData{
+					Line: strconv.Itoa(i),
+				})
+			}
+
+			assert.Equal(t, tt.expected, outputLineTexts(buffer.snapshot()))
+		})
+	}
+
+	buffer := newOutputRingBuffer(2)
+	buffer.add(rpitxExecutionOutputLineMessageData{Line: "old"})
+	buffer.reset()
+	assert.Empty(t, buffer.snapshot())
+}
+
+func TestExecutionManager_GetStatus(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+
+	idle := em.getStatus()
+	assert.Equal(t, "idle", idle.State)
+	assert.Empty(t, idle.ModuleName)
+	assert.Empty(t, idle.OutputLines)
+
+	clientID := uuid.New()
+	args := json.RawMessage(`{"frequency":144500000}`)
+
+	em.setState(executionStateExecuting)
+	em.setActiveExecution(&activeExecution{
+		moduleName:         gorpitx.ModuleNameTUNE,
+		args:               args,
+		initiatingClientID: clientID,
+		startedAt:          time.Now().Add(-5 * time.Second),
+	})
+	em.sendOutputLines(newOutputLine("stdout", "tuning"))
+
+	status := em.getStatus()
+	assert.Equal(t, "executing", status.State)
+	assert.Equal(t, gorpitx.ModuleNameTUNE, status.ModuleName)
+	assert.JSONEq(t, string(args), string(status.Args))
+	assert.Equal(t, clientID.String(), status.InitiatingClientID)
+	assert.GreaterOrEqual(t, status.Elapsed, int64(5))
+	require.Len(t, status.OutputLines, 1)
+	assert.Equal(t, "tuning", status.OutputLines[0].Line)
+
+	// A new execution starts with a clean output buffer
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNameMORSE,
+		startedAt:  time.Now(),
+	})
+	assert.Empty(t, em.getStatus().OutputLines)
+
+	em.setActiveExecution(nil)
+	assert.Empty(t, em.getStatus().ModuleName)
+}
+
+func TestExecutionState_String(t *testing.T) {
+	assert.Equal(t, "idle", executionStateIdle.String())
+	assert.Equal(t, "executing", executionStateExecuting.String())
+	assert.Equal(t, "stopping", executionStateStopping.String())
+	assert.Equal(t, "unknown", executionState(42).String())
+}
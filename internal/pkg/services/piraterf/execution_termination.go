@@ -0,0 +1,126 @@
+package piraterf
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+)
+
+// terminationReason says why an execution went off air. It's sent in
+// rpitx.execution.stopped and stored in history records.
+type terminationReason string
+
+const (
+	// terminationReasonUserStop - a client sent rpitx.execution.stop.
+	terminationReasonUserStop terminationReason = "user_stop"
+	// terminationReasonTimeout - the requested timeout ran out.
+	terminationReasonTimeout terminationReason = "timeout"
+	// terminationReasonPlayOnceComplete - Play Once audio finished.
+	terminationReasonPlayOnceComplete terminationReason = "play_once_complete"
+	// terminationReasonProcessExit - the process exited on its own.
+	terminationReasonProcessExit terminationReason = "process_exit"
+	// terminationReasonShutdown - the service is shutting down.
+	terminationReasonShutdown terminationReason = "shutdown"
+	// terminationReasonDeadMan - the dead-man switch tripped because the
+	// client that started the execution went quiet.
+	terminationReasonDeadMan terminationReason = "dead_man"
+	// terminationReasonError - the execution failed.
+	terminationReasonError terminationReason = "error"
+)
+
+// executionTermination is the outcome of an execution.
+type executionTermination struct {
+	reason   terminationReason
+	exitCode *int // nil when the process didn't exit by itself (signal)
+	duration time.Duration
+	err      error // only set for terminationReasonError
+}
+
+// stopRequested reports whether the execution was stopped on purpose
+// rather than ending by itself.
+func (t executionTermination) stopRequested() bool {
+	return t.reason == terminationReasonUserStop ||
+		t.reason == terminationReasonShutdown ||
+		t.reason == terminationReasonDeadMan
+}
+
+// classifyTermination works out why an execution ended from the error
+// rpitx.Exec returned and what we know about the execution itself: whether
+// a stop was requested, how long it ran against its timeout and whether it
+// was a Play Once run.
+func (em *executionManager) classifyTermination(
+	err error,
+	timeout time.Duration,
+	playOnce bool,
+	duration time.Duration,
+) executionTermination {
+	termination := executionTermination{duration: duration}
+
+	exitCode, exited := processExitCode(err)
+	termination.exitCode = exitCode
+
+	// Whatever the process did on its way out, we asked it to go
+	if em.stopRequested.Load() {
+		termination.reason = terminationReasonUserStop
+		if reason, ok := em.stopReason.Load().(terminationReason); ok {
+			termination.reason = reason
+		}
+
+		return termination
+	}
+
+	// gorpitx stops the process itself once the timeout is up. Depending on
+	// how the process handles SIGTERM that comes back as a timeout, a
+	// termination or a non-zero exit, so go by the clock as well.
+	timedOut := errors.Is(err, commonerrors.ErrTimeout) ||
+		(timeout > 0 && duration >= timeout && isStopError(err))
+
+	switch {
+	case timedOut && playOnce:
+		termination.reason = terminationReasonPlayOnceComplete
+	case timedOut:
+		termination.reason = terminationReasonTimeout
+	case exited:
+		termination.reason = terminationReasonProcessExit
+	default:
+		termination.reason = terminationReasonError
+		termination.err = err
+	}
+
+	return termination
+}
+
+// isStopError reports whether err is what a process being stopped looks
+// like - killed by a signal or exiting non-zero on SIGTERM.
+func isStopError(err error) bool {
+	return errors.Is(err, commonerrors.ErrTerminated) ||
+		errors.Is(err, commonerrors.ErrKilled) ||
+		errors.Is(err, commonerrors.ErrFailed)
+}
+
+// processExitCode returns the exit status of a process that exited by
+// itself. It reports false when the process was killed by a signal or
+// never ran. commander's non-zero exits unwrap to the *exec.ExitError.
+func processExitCode(err error) (*int, bool) {
+	if err == nil {
+		exitCode := 0
+
+		return &exitCode, true
+	}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		// -1 means it was killed by a signal
+		if exitError.ExitCode() < 0 {
+			return nil, false
+		}
+
+		exitCode := exitError.ExitCode()
+
+		return &exitCode, true
+	}
+
+	return nil, false
+}
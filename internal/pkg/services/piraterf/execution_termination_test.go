@@ -0,0 +1,225 @@
+package piraterf
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/commander"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitError runs a shell exiting with the given status to get a real
+// *exec.ExitError.
+func exitError(t *testing.T, script string) error {
+	t.Helper()
+
+	err := exec.Command("sh", "-c", script).Run()
+	require.Error(t, err)
+
+	return err
+}
+
+// commanderExitError runs a shell exiting with the given status through
+// commander the way gorpitx does, stderr and all.
+func commanderExitError(t *testing.T, script string) error {
+	t.Helper()
+
+	process, err := commander.New().Start(
+		context.Background(), "sh", []string{"-c", script},
+	)
+	require.NoError(t, err)
+
+	err = process.Wait()
+	require.Error(t, err)
+
+	return err
+}
+
+func TestProcessExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected *int
+		exited   bool
+	}{
+		{name: "clean exit", expected: intPtr(0), exited: true},
+		{
+			name:     "exec exit error",
+			err:      ctxerrors.Wrap(exitError(t, "exit 7"), "command failed"),
+			expected: intPtr(7),
+			exited:   true,
+		},
+		{
+			name: "commander non-zero exit",
+			err: ctxerrors.Wrap(
+				commanderExitError(t, "echo boom >&2; exit 3"),
+				"failed to wait for process",
+			),
+			expected: intPtr(3),
+			exited:   true,
+		},
+		{
+			// A status only in the message doesn't count
+			name: "failure without status",
+			err:  ctxerrors.Wrap(commonerrors.ErrFailed, "(exit 3): boom"),
+		},
+		{
+			name: "killed by signal",
+			err:  exitError(t, "kill -INT $$"),
+		},
+		{name: "terminated", err: commonerrors.ErrTerminated},
+		{name: "unrelated error", err: assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode, exited := processExitCode(tt.err)
+			assert.Equal(t, tt.exited, exited)
+			assert.Equal(t, tt.expected, exitCode)
+		})
+	}
+}
+
+func TestExecutionManager_ClassifyTermination(t *testing.T) {
+	exitFailure := ctxerrors.Wrap(exitError(t, "exit 1"), "process failed")
+	commanderExitFailure := commanderExitError(t, "exit 1")
+
+	tests := []struct {
+		name          string
+		err           error
+		timeout       time.Duration
+		playOnce      bool
+		duration      time.Duration
+		stopRequested bool
+		stopReason    terminationReason
+		reason        terminationReason
+		exitCode      *int
+		withError     bool
+	}{
+		{
+			name:     "process exits by itself",
+			duration: time.Second,
+			reason:   terminationReasonProcessExit,
+			exitCode: intPtr(0),
+		},
+		{
+			name:     "process exits non-zero by itself",
+			err:      exitFailure,
+			duration: time.Second,
+			reason:   terminationReasonProcessExit,
+			exitCode: intPtr(1),
+		},
+		{
+			name:     "process exits non-zero through commander",
+			err:      commanderExitFailure,
+			duration: time.Second,
+			reason:   terminationReasonProcessExit,
+			exitCode: intPtr(1),
+		},
+		{
+			name:          "user stop",
+			err:           commonerrors.ErrTerminated,
+			stopRequested: true,
+			reason:        terminationReasonUserStop,
+		},
+		{
+			name:          "user stop with non-zero exit",
+			err:           exitFailure,
+			stopRequested: true,
+			reason:        terminationReasonUserStop,
+			exitCode:      intPtr(1),
+		},
+		{
+			name:          "shutdown",
+			err:           commonerrors.ErrKilled,
+			stopRequested: true,
+			stopReason:    terminationReasonShutdown,
+			reason:        terminationReasonShutdown,
+		},
+		{
+			name:          "dead-man switch",
+			err:           commonerrors.ErrTerminated,
+			stopRequested: true,
+			stopReason:    terminationReasonDeadMan,
+			reason:        terminationReasonDeadMan,
+		},
+		{
+			name:     "timeout",
+			err:      commonerrors.ErrTimeout,
+			timeout:  10 * time.Second,
+			duration: 10 * time.Second,
+			reason:   terminationReasonTimeout,
+		},
+		{
+			name:     "play once complete",
+			err:      commonerrors.ErrTimeout,
+			timeout:  3 * time.Second,
+			playOnce: true,
+			duration: 3 * time.Second,
+			reason:   terminationReasonPlayOnceComplete,
+		},
+		{
+			name: "non-zero exit on timeout stop",
+			err: ctxerrors.Wrap(
+				commanderExitFailure, "process failed after timeout stop",
+			),
+			timeout:  5 * time.Second,
+			duration: 6 * time.Second,
+			reason:   terminationReasonTimeout,
+			exitCode: intPtr(1),
+		},
+		{
+			name:      "terminated without anyone asking",
+			err:       commonerrors.ErrTerminated,
+			timeout:   5 * time.Second,
+			duration:  time.Second,
+			reason:    terminationReasonError,
+			withError: true,
+		},
+		{
+			name:      "invalid args",
+			err:       ctxerrors.Wrap(assert.AnError, "failed to parse args"),
+			reason:    terminationReasonError,
+			withError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			em := newExecutionManager(gorpitx.GetInstance(), nil)
+			em.stopRequested.Store(tt.stopRequested)
+
+			if tt.stopReason != "" {
+				em.stopReason.Store(tt.stopReason)
+			}
+
+			termination := em.classifyTermination(
+				tt.err, tt.timeout, tt.playOnce, tt.duration,
+			)
+
+			assert.Equal(t, tt.reason, termination.reason)
+			assert.Equal(t, tt.exitCode, termination.exitCode)
+			assert.Equal(t, tt.duration, termination.duration)
+
+			if tt.withError {
+				assert.ErrorIs(t, termination.err, tt.err)
+			} else {
+				assert.NoError(t, termination.err)
+			}
+
+			assert.Equal(
+				t, tt.stopRequested, termination.stopRequested(),
+			)
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -30,7 +30,17 @@ func (s *PIrateRF) fileConversionPostprocessor(
 	case gorpitx.ModuleNameFSK:
 		return s.dataFilePostprocessor(response)
 	case gorpitx.ModuleNamePIFMRDS:
-		return s.audioConversionPostprocessor(response)
+		loudness, err := parseLoudnessOptions(request)
+		if err != nil {
+			return response, err
+		}
+
+		stereo, err := parseStereoOption(request, s.config.PreserveStereo)
+		if err != nil {
+			return response, err
+		}
+
+		return s.audioConversionPostprocessor(response, loudness, stereo)
 	case gorpitx.ModuleNameSPECTRUMPAINT, gorpitx.ModuleNamePISSSTV:
 		return s.imageConversionPostprocessor(response)
 	case gorpitx.ModuleNameSENDIQ:
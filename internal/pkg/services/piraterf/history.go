@@ -0,0 +1,618 @@
+package piraterf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	historyFilename             = "history.jsonl"
+	historyIndexFilename        = "history.index.jsonl"
+	historyRotatedFilename      = "history.1.jsonl"
+	historyRotatedIndexFilename = "history.1.index.jsonl"
+
+	// historyMaxFileSize is how big the history file gets before it's
+	// rotated out. One rotated file is kept so the history never takes
+	// more than about twice this on the SD card.
+	historyMaxFileSize = 32 * 1024 * 1024
+
+	// maxHistoryOutputLines caps how many stdout/stderr lines are kept per
+	// record so a long running transmission doesn't fill the SD card.
+	maxHistoryOutputLines = 1000
+
+	historyDefaultLimit = 50
+	historyMaxLimit     = 500
+
+	// historyMaxLineSize is the max size of a single index line when
+	// reading the index back.
+	historyMaxLineSize = 16 * 1024 * 1024
+)
+
+// historyRecord is a finished execution as stored in the history file.
+type historyRecord struct {
+	ID                 uuid.UUID          `json:"id"`
+	ModuleName         gorpitx.ModuleName `json:"moduleName"`
+	Args               json.RawMessage    `json:"args"`
+	InitiatingClientID string             `json:"initiatingClientId"`
+	StoppingClientID   string             `json:"stoppingClientId,omitempty"`
+	StartedAt          int64              `json:"startedAt"`
+	EndedAt            int64              `json:"endedAt"`
+	TerminationReason  terminationReason  `json:"terminationReason"`
+	ExitCode           *int               `json:"exitCode,omitempty"`
+	Error              string             `json:"error,omitempty"`
+	Stdout             []string           `json:"stdout"`
+	Stderr             []string           `json:"stderr"`
+	OutputTruncated    bool               `json:"outputTruncated,omitempty"`
+}
+
+// historySummary is a history record without the captured output, used
+// for listings.
+type historySummary struct {
+	ID                 uuid.UUID          `json:"id"`
+	ModuleName         gorpitx.ModuleName `json:"moduleName"`
+	Args               json.RawMessage    `json:"args"`
+	InitiatingClientID string             `json:"initiatingClientId"`
+	StoppingClientID   string             `json:"stoppingClientId,omitempty"`
+	StartedAt          int64              `json:"startedAt"`
+	EndedAt            int64              `json:"endedAt"`
+	TerminationReason  terminationReason  `json:"terminationReason"`
+	ExitCode           *int               `json:"exitCode,omitempty"`
+	Error              string             `json:"error,omitempty"`
+}
+
+func (r *historyRecord) summary() historySummary {
+	return historySummary{
+		ID:                 r.ID,
+		ModuleName:         r.ModuleName,
+		Args:               r.Args,
+		InitiatingClientID: r.InitiatingClientID,
+		StoppingClientID:   r.StoppingClientID,
+		StartedAt:          r.StartedAt,
+		EndedAt:            r.EndedAt,
+		TerminationReason:  r.TerminationReason,
+		ExitCode:           r.ExitCode,
+		Error:              r.Error,
+	}
+}
+
+// historyQuery filters and pages history listings. Zero values mean no
+// filtering. Results are always newest first.
+type historyQuery struct {
+	ModuleName gorpitx.ModuleName `json:"moduleName"`
+	Since      int64              `json:"since"` // unix time, inclusive
+	Until      int64              `json:"until"` // unix time, inclusive
+	Offset     int                `json:"offset"`
+	Limit      int                `json:"limit"`
+}
+
+func (q historyQuery) matches(r *historySummary) bool {
+	if q.ModuleName != "" && r.ModuleName != q.ModuleName {
+		return false
+	}
+
+	if q.Since != 0 && r.StartedAt < q.Since {
+		return false
+	}
+
+	if q.Until != 0 && r.StartedAt > q.Until {
+		return false
+	}
+
+	return true
+}
+
+func (q historyQuery) normalizedLimit() int {
+	if q.Limit <= 0 {
+		return historyDefaultLimit
+	}
+
+	return min(q.Limit, historyMaxLimit)
+}
+
+type historyPage struct {
+	Records []historySummary `json:"records"`
+	Total   int              `json:"total"`
+	Offset  int              `json:"offset"`
+	Limit   int              `json:"limit"`
+}
+
+// historyFiles is a history data file holding one full record per line
+// and the index of the records in it.
+type historyFiles struct {
+	data  string
+	index string
+}
+
+// historyIndexEntry is a record without its output and where to find the
+// full record in the data file, so listings never read the output.
+type historyIndexEntry struct {
+	historySummary
+
+	Offset int64 `json:"offset"`
+	Length int   `json:"length"`
+}
+
+// historyStore keeps finished executions in an append-only JSON lines file
+// next to an index of it. Once the file grows past maxFileSize it's rotated
+// out, replacing the one rotated out before it.
+type historyStore struct {
+	current     historyFiles
+	rotated     historyFiles
+	maxFileSize int64
+	mu          sync.Mutex
+}
+
+func newHistoryStore(dir string) *historyStore {
+	return &historyStore{
+		current: historyFiles{
+			data:  filepath.Join(dir, historyFilename),
+			index: filepath.Join(dir, historyIndexFilename),
+		},
+		rotated: historyFiles{
+			data:  filepath.Join(dir, historyRotatedFilename),
+			index: filepath.Join(dir, historyRotatedIndexFilename),
+		},
+		maxFileSize: historyMaxFileSize,
+	}
+}
+
+// load creates the history directory, moves the history over from
+// legacyPath if it's still there and indexes data files that aren't yet.
+func (h *historyStore) load(legacyPath string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(h.current.data), dirPerms); err != nil {
+		return ctxerrors.Wrap(err, "failed to create history directory")
+	}
+
+	if err := h.migrate(legacyPath); err != nil {
+		return err
+	}
+
+	for _, files := range []historyFiles{h.rotated, h.current} {
+		if err := files.ensureIndex(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrate moves a history file written before the history got its own
+// directory into it. Caller must hold mu.
+func (h *historyStore) migrate(legacyPath string) error {
+	if legacyPath == "" || fileExists(h.current.data) ||
+		!fileExists(legacyPath) {
+		return nil
+	}
+
+	if err := moveFile(legacyPath, h.current.data); err != nil {
+		return ctxerrors.Wrap(err, "failed to move history file")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"from": legacyPath,
+		"to":   h.current.data,
+	}).Info("Moved execution history")
+
+	return nil
+}
+
+func (h *historyStore) append(record *historyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal history record")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.rotateIfFull(); err != nil {
+		return err
+	}
+
+	offset, err := appendHistoryLine(h.current.data, data)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to write history record")
+	}
+
+	entry, err := json.Marshal(historyIndexEntry{
+		historySummary: record.summary(),
+		Offset:         offset,
+		Length:         len(data),
+	})
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal history index entry")
+	}
+
+	if _, err := appendHistoryLine(h.current.index, entry); err != nil {
+		return ctxerrors.Wrap(err, "failed to write history index entry")
+	}
+
+	return nil
+}
+
+// rotateIfFull rotates the current files out once the data file reached
+// maxFileSize. Caller must hold mu.
+func (h *historyStore) rotateIfFull() error {
+	info, err := os.Stat(h.current.data)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return ctxerrors.Wrap(err, "failed to stat history file")
+	}
+
+	if info.Size() < h.maxFileSize {
+		return nil
+	}
+
+	// Index first, a data file without one gets indexed again on load
+	if err := replaceFile(h.current.index, h.rotated.index); err != nil {
+		return ctxerrors.Wrap(err, "failed to rotate history index")
+	}
+
+	if err := replaceFile(h.current.data, h.rotated.data); err != nil {
+		return ctxerrors.Wrap(err, "failed to rotate history file")
+	}
+
+	return nil
+}
+
+// readIndex returns the index entries of the rotated and the current file
+// separately, oldest first.
+func (h *historyStore) readIndex() (
+	[]historyIndexEntry,
+	[]historyIndexEntry,
+	error,
+) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rotated, err := readHistoryIndex(h.rotated.index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := readHistoryIndex(h.current.index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rotated, current, nil
+}
+
+func (h *historyStore) list(query historyQuery) (historyPage, error) {
+	rotated, current, err := h.readIndex()
+	if err != nil {
+		return historyPage{}, err
+	}
+
+	entries := append(rotated, current...)
+	slices.Reverse(entries)
+
+	matching := make([]historySummary, 0, len(entries))
+
+	for _, entry := range entries {
+		if query.matches(&entry.historySummary) {
+			matching = append(matching, entry.historySummary)
+		}
+	}
+
+	limit := query.normalizedLimit()
+	offset := max(0, min(query.Offset, len(matching)))
+	end := min(offset+limit, len(matching))
+
+	return historyPage{
+		Records: matching[offset:end],
+		Total:   len(matching),
+		Offset:  offset,
+		Limit:   limit,
+	}, nil
+}
+
+func (h *historyStore) get(id uuid.UUID) (*historyRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, files := range []historyFiles{h.current, h.rotated} {
+		entries, err := readHistoryIndex(files.index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.ID == id {
+				return files.readRecord(entry)
+			}
+		}
+	}
+
+	return nil, ctxerrors.Wrapf(commonerrors.ErrNotFound, "history record %s", id)
+}
+
+// readRecord reads the full record an index entry points at.
+func (f historyFiles) readRecord(
+	entry historyIndexEntry,
+) (*historyRecord, error) {
+	file, err := os.Open(f.data)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to open history file")
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.WithError(err).Error("failed to close history file")
+		}
+	}()
+
+	data := make([]byte, entry.Length)
+	if _, err := file.ReadAt(data, entry.Offset); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to read history record")
+	}
+
+	var record historyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to parse history record")
+	}
+
+	return &record, nil
+}
+
+// ensureIndex indexes the data file if it has no index yet. Lines that
+// can't be parsed (e.g. a write cut short by a power loss) are skipped.
+func (f historyFiles) ensureIndex() error {
+	if !fileExists(f.data) || fileExists(f.index) {
+		return nil
+	}
+
+	file, err := os.Open(f.data)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to open history file")
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.WithError(err).Error("failed to close history file")
+		}
+	}()
+
+	var (
+		index  bytes.Buffer
+		offset int64
+	)
+
+	reader := bufio.NewReader(file)
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return ctxerrors.Wrap(readErr, "failed to read history file")
+		}
+
+		record := bytes.TrimSuffix(line, []byte{'\n'})
+		if entry, ok := indexHistoryLine(record, offset); ok {
+			index.Write(entry)
+			index.WriteByte('\n')
+		}
+
+		offset += int64(len(line))
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	// Write to a temp file first so a crash never leaves a half written file
+	tmpPath := f.index + ".tmp"
+	if err := os.WriteFile(tmpPath, index.Bytes(), filePerms); err != nil {
+		return ctxerrors.Wrap(err, "failed to write history index")
+	}
+
+	if err := os.Rename(tmpPath, f.index); err != nil {
+		return ctxerrors.Wrap(err, "failed to replace history index")
+	}
+
+	logrus.WithField("file", f.data).Info("Indexed execution history")
+
+	return nil
+}
+
+// indexHistoryLine returns the index entry of a data file line found at
+// offset, or false if the line isn't a record.
+func indexHistoryLine(line []byte, offset int64) ([]byte, bool) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, false
+	}
+
+	var record historyRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		logrus.WithError(err).Warn("Skipping corrupt history record")
+
+		return nil, false
+	}
+
+	entry, err := json.Marshal(historyIndexEntry{
+		historySummary: record.summary(),
+		Offset:         offset,
+		Length:         len(line),
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Skipping unindexable history record")
+
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// readHistoryIndex returns the entries of an index file, oldest first.
+// Lines that can't be parsed are skipped.
+func readHistoryIndex(indexPath string) ([]historyIndexEntry, error) {
+	file, err := os.Open(indexPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, ctxerrors.Wrap(err, "failed to open history index")
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.WithError(err).Error("failed to close history index")
+		}
+	}()
+
+	var entries []historyIndexEntry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, historyMaxLineSize)
+
+	for scanner.Scan() {
+		var entry historyIndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logrus.WithError(err).Warn("Skipping corrupt history index entry")
+
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to read history index")
+	}
+
+	return entries, nil
+}
+
+// appendHistoryLine appends data and a newline to a file and returns the
+// offset data was written at.
+func appendHistoryLine(filePath string, data []byte) (int64, error) {
+	file, err := os.OpenFile(
+		filePath,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		filePerms,
+	)
+	if err != nil {
+		return 0, ctxerrors.Wrap(err, "failed to open file")
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.WithError(err).Error("failed to close history file")
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, ctxerrors.Wrap(err, "failed to stat file")
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return 0, ctxerrors.Wrap(err, "failed to write file")
+	}
+
+	return info.Size(), nil
+}
+
+// replaceFile renames src over dst, a missing src removes dst.
+func replaceFile(src, dst string) error {
+	if fileExists(src) {
+		if err := os.Rename(src, dst); err != nil {
+			return ctxerrors.Wrap(err, "failed to rename file")
+		}
+
+		return nil
+	}
+
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ctxerrors.Wrap(err, "failed to remove file")
+	}
+
+	return nil
+}
+
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+
+	return err == nil
+}
+
+// executionRecorder collects what happens during a single execution and
+// turns it into a history record once it's over.
+type executionRecorder struct {
+	record *historyRecord
+	mu     sync.Mutex
+}
+
+func newExecutionRecorder(
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+	initiatingClientID uuid.UUID,
+	startedAt int64,
+) *executionRecorder {
+	return &executionRecorder{
+		record: &historyRecord{
+			ID:                 uuid.New(),
+			ModuleName:         moduleName,
+			Args:               args,
+			InitiatingClientID: initiatingClientID.String(),
+			StartedAt:          startedAt,
+			Stdout:             []string{},
+			Stderr:             []string{},
+		},
+	}
+}
+
+func (r *executionRecorder) addOutput(outputType, line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := &r.record.Stdout
+	if outputType == "stderr" {
+		lines = &r.record.Stderr
+	}
+
+	if len(*lines) >= maxHistoryOutputLines {
+		r.record.OutputTruncated = true
+
+		return
+	}
+
+	*lines = append(*lines, line)
+}
+
+func (r *executionRecorder) finish(
+	stoppingClientID string,
+	endedAt int64,
+	termination executionTermination,
+	errMsg string,
+) *historyRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.record.StoppingClientID = stoppingClientID
+	r.record.EndedAt = endedAt
+	r.record.TerminationReason = termination.reason
+	r.record.ExitCode = termination.exitCode
+	r.record.Error = errMsg
+
+	return r.record
+}
@@ -0,0 +1,275 @@
+package piraterf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHistoryStore returns a store with one record per module/start
+// time pair, appended in the given order.
+func newTestHistoryStore(
+	t *testing.T,
+	entries ...historyRecord,
+) *historyStore {
+	t.Helper()
+
+	store := newHistoryStore(t.TempDir())
+
+	for _, entry := range entries {
+		record := entry
+		if record.ID == uuid.Nil {
+			record.ID = uuid.New()
+		}
+
+		require.NoError(t, store.append(&record))
+	}
+
+	return store
+}
+
+func historySummaryStarts(page historyPage) []int64 {
+	starts := make([]int64, 0, len(page.Records))
+	for _, record := range page.Records {
+		starts = append(starts, record.StartedAt)
+	}
+
+	return starts
+}
+
+func TestHistoryStore_List(t *testing.T) {
+	store := newTestHistoryStore(t,
+		historyRecord{ModuleName: gorpitx.ModuleNamePIFMRDS, StartedAt: 100},
+		historyRecord{ModuleName: gorpitx.ModuleNameTUNE, StartedAt: 200},
+		historyRecord{ModuleName: gorpitx.ModuleNamePIFMRDS, StartedAt: 300},
+		historyRecord{ModuleName: gorpitx.ModuleNameMORSE, StartedAt: 400},
+	)
+
+	tests := []struct {
+		name           string
+		query          historyQuery
+		expectedStarts []int64
+		expectedTotal  int
+		expectedLimit  int
+	}{
+		{
+			name:           "everything newest first",
+			query:          historyQuery{},
+			expectedStarts: []int64{400, 300, 200, 100},
+			expectedTotal:  4,
+			expectedLimit:  historyDefaultLimit,
+		},
+		{
+			name:           "module filter",
+			query:          historyQuery{ModuleName: gorpitx.ModuleNamePIFMRDS},
+			expectedStarts: []int64{300, 100},
+			expectedTotal:  2,
+			expectedLimit:  historyDefaultLimit,
+		},
+		{
+			name:           "time window",
+			query:          historyQuery{Since: 200, Until: 300},
+			expectedStarts: []int64{300, 200},
+			expectedTotal:  2,
+			expectedLimit:  historyDefaultLimit,
+		},
+		{
+			name:           "paging",
+			query:          historyQuery{Offset: 1, Limit: 2},
+			expectedStarts: []int64{300, 200},
+			expectedTotal:  4,
+			expectedLimit:  2,
+		},
+		{
+			name:           "offset past the end",
+			query:          historyQuery{Offset: 10},
+			expectedStarts: []int64{},
+			expectedTotal:  4,
+			expectedLimit:  historyDefaultLimit,
+		},
+		{
+			name:           "limit is capped",
+			query:          historyQuery{Limit: historyMaxLimit + 1},
+			expectedStarts: []int64{400, 300, 200, 100},
+			expectedTotal:  4,
+			expectedLimit:  historyMaxLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.list(tt.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStarts, historySummaryStarts(page))
+			assert.Equal(t, tt.expectedTotal, page.Total)
+			assert.Equal(t, tt.expectedLimit, page.Limit)
+		})
+	}
+}
+
+func TestHistoryStore_Get(t *testing.T) {
+	id := uuid.New()
+	store := newTestHistoryStore(t, historyRecord{
+		ID:         id,
+		ModuleName: gorpitx.ModuleNameTUNE,
+		Stdout:     []string{"line 1"},
+		Stderr:     []string{},
+	})
+
+	record, err := store.get(id)
+	require.NoError(t, err)
+	assert.Equal(t, gorpitx.ModuleNameTUNE, record.ModuleName)
+	assert.Equal(t, []string{"line 1"}, record.Stdout)
+
+	_, err = store.get(uuid.New())
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestHistoryStore_MissingFile(t *testing.T) {
+	store := newHistoryStore(t.TempDir())
+
+	page, err := store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Records)
+	assert.Equal(t, 0, page.Total)
+}
+
+func TestHistoryStore_SkipsCorruptLines(t *testing.T) {
+	id := uuid.New()
+	store := newTestHistoryStore(t,
+		historyRecord{ModuleName: gorpitx.ModuleNameTUNE, StartedAt: 100},
+	)
+
+	for _, filePath := range []string{store.current.data, store.current.index} {
+		file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, filePerms)
+		require.NoError(t, err)
+		_, err = file.WriteString("{\"id\": \"trunc\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	require.NoError(t, store.append(&historyRecord{
+		ID: id, ModuleName: gorpitx.ModuleNameTUNE, StartedAt: 200,
+	}))
+
+	page, err := store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{200, 100}, historySummaryStarts(page))
+
+	record, err := store.get(id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), record.StartedAt)
+
+	// Indexing the data file again skips the corrupt line too
+	require.NoError(t, os.Remove(store.current.index))
+	require.NoError(t, store.load(""))
+
+	page, err = store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{200, 100}, historySummaryStarts(page))
+
+	record, err = store.get(id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), record.StartedAt)
+}
+
+func TestHistoryStore_ListReadsOnlyTheIndex(t *testing.T) {
+	store := newTestHistoryStore(t, historyRecord{
+		ModuleName: gorpitx.ModuleNameTUNE,
+		StartedAt:  100,
+		Stdout:     []string{"line 1"},
+	})
+
+	require.NoError(t, os.WriteFile(store.current.data, nil, filePerms))
+
+	page, err := store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100}, historySummaryStarts(page))
+}
+
+func TestHistoryStore_Rotation(t *testing.T) {
+	store := newHistoryStore(t.TempDir())
+	store.maxFileSize = 1
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, id := range ids {
+		require.NoError(t, store.append(&historyRecord{
+			ID:         id,
+			ModuleName: gorpitx.ModuleNameTUNE,
+			StartedAt:  int64(i + 1),
+			Stdout:     []string{"line"},
+		}))
+	}
+
+	page, err := store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, historySummaryStarts(page))
+
+	_, err = store.get(ids[0])
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	for _, id := range ids[1:] {
+		record, err := store.get(id)
+		require.NoError(t, err)
+		assert.Equal(t, id, record.ID)
+		assert.Equal(t, []string{"line"}, record.Stdout)
+	}
+}
+
+func TestHistoryStore_LoadMovesLegacyFile(t *testing.T) {
+	id := uuid.New()
+	legacy := newTestHistoryStore(t, historyRecord{
+		ID:         id,
+		ModuleName: gorpitx.ModuleNameTUNE,
+		StartedAt:  100,
+	})
+
+	store := newHistoryStore(filepath.Join(t.TempDir(), "history"))
+	require.NoError(t, store.load(legacy.current.data))
+
+	assert.NoFileExists(t, legacy.current.data)
+	assert.FileExists(t, store.current.index)
+
+	page, err := store.list(historyQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100}, historySummaryStarts(page))
+
+	record, err := store.get(id)
+	require.NoError(t, err)
+	assert.Equal(t, gorpitx.ModuleNameTUNE, record.ModuleName)
+}
+
+func TestExecutionRecorder(t *testing.T) {
+	clientID := uuid.New()
+	recorder := newExecutionRecorder(
+		gorpitx.ModuleNameTUNE, []byte(`{"frequency":1}`), clientID, 100,
+	)
+
+	for range maxHistoryOutputLines + 5 {
+		recorder.addOutput("stdout", "out")
+	}
+
+	recorder.addOutput("stderr", "err")
+
+	stoppingID := uuid.NewString()
+	record := recorder.finish(
+		stoppingID, 200,
+		executionTermination{reason: terminationReasonUserStop}, "",
+	)
+
+	assert.Len(t, record.Stdout, maxHistoryOutputLines)
+	assert.Equal(t, []string{"err"}, record.Stderr)
+	assert.True(t, record.OutputTruncated)
+	assert.Equal(t, clientID.String(), record.InitiatingClientID)
+	assert.Equal(t, stoppingID, record.StoppingClientID)
+	assert.Equal(t, int64(100), record.StartedAt)
+	assert.Equal(t, int64(200), record.EndedAt)
+	assert.Equal(t, terminationReasonUserStop, record.TerminationReason)
+}
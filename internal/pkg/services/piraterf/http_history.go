@@ -0,0 +1,148 @@
+package piraterf
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	historyQueryParamModule = "module"
+	historyQueryParamSince  = "since"
+	historyQueryParamUntil  = "until"
+	historyQueryParamOffset = "offset"
+	historyQueryParamLimit  = "limit"
+	historyPathParamID      = "id"
+
+	historyErrorCodeRecordNotFound aichteeteapee.ErrorCode = "RECORD_NOT_FOUND"
+)
+
+// historyListHandler serves GET /history?module=&since=&until=&offset=&limit=
+// where since/until are unix timestamps.
+func (s *PIrateRF) historyListHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		aichteeteapee.WriteJSON(w, http.StatusBadRequest, aichteeteapee.ErrorResponse{
+			Code:    aichteeteapee.ErrorCodeBadRequest,
+			Message: errorMessage(err),
+		})
+
+		return
+	}
+
+	page, err := s.history.list(query)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list execution history")
+		aichteeteapee.WriteJSON(
+			w,
+			http.StatusInternalServerError,
+			aichteeteapee.ErrorResponseInternalServerError,
+		)
+
+		return
+	}
+
+	aichteeteapee.WriteJSON(w, http.StatusOK, page)
+}
+
+// historyGetHandler serves GET /history/{id} with the full record including
+// the captured output.
+func (s *PIrateRF) historyGetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue(historyPathParamID))
+	if err != nil {
+		aichteeteapee.WriteJSON(w, http.StatusBadRequest, aichteeteapee.ErrorResponse{
+			Code:    aichteeteapee.ErrorCodeBadRequest,
+			Message: "invalid history record id",
+		})
+
+		return
+	}
+
+	record, err := s.history.get(id)
+	if err != nil {
+		if errors.Is(err, commonerrors.ErrNotFound) {
+			aichteeteapee.WriteJSON(w, http.StatusNotFound, aichteeteapee.ErrorResponse{
+				Code:    historyErrorCodeRecordNotFound,
+				Message: "history record not found",
+			})
+
+			return
+		}
+
+		logrus.WithError(err).Error("failed to get execution history record")
+		aichteeteapee.WriteJSON(
+			w,
+			http.StatusInternalServerError,
+			aichteeteapee.ErrorResponseInternalServerError,
+		)
+
+		return
+	}
+
+	aichteeteapee.WriteJSON(w, http.StatusOK, record)
+}
+
+func parseHistoryQuery(values url.Values) (historyQuery, error) {
+	query := historyQuery{
+		ModuleName: gorpitx.ModuleName(values.Get(historyQueryParamModule)),
+	}
+
+	intParams := []struct {
+		name   string
+		target *int64
+	}{
+		{historyQueryParamSince, &query.Since},
+		{historyQueryParamUntil, &query.Until},
+	}
+
+	for _, param := range intParams {
+		raw := values.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return historyQuery{}, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue, "%s must be a unix timestamp", param.name,
+			)
+		}
+
+		*param.target = value
+	}
+
+	pagingParams := []struct {
+		name   string
+		target *int
+	}{
+		{historyQueryParamOffset, &query.Offset},
+		{historyQueryParamLimit, &query.Limit},
+	}
+
+	for _, param := range pagingParams {
+		raw := values.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return historyQuery{}, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"%s must be a non-negative number", param.name,
+			)
+		}
+
+		*param.target = value
+	}
+
+	return query, nil
+}
@@ -0,0 +1,127 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryListHandler(t *testing.T) {
+	service := &PIrateRF{
+		history: newTestHistoryStore(t,
+			historyRecord{ModuleName: gorpitx.ModuleNamePIFMRDS, StartedAt: 100},
+			historyRecord{ModuleName: gorpitx.ModuleNameTUNE, StartedAt: 200},
+			historyRecord{ModuleName: gorpitx.ModuleNamePIFMRDS, StartedAt: 300},
+		),
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedStarts []int64
+	}{
+		{
+			name:           "no filters",
+			url:            "/history",
+			expectedStatus: http.StatusOK,
+			expectedStarts: []int64{300, 200, 100},
+		},
+		{
+			name:           "module and paging",
+			url:            "/history?module=pifmrds&limit=1&offset=1",
+			expectedStatus: http.StatusOK,
+			expectedStarts: []int64{100},
+		},
+		{
+			name:           "time window",
+			url:            "/history?since=150&until=250",
+			expectedStatus: http.StatusOK,
+			expectedStarts: []int64{200},
+		},
+		{
+			name:           "invalid since",
+			url:            "/history?since=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative limit",
+			url:            "/history?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			service.historyListHandler(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus != http.StatusOK {
+				var errResp aichteeteapee.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+				assert.NotContains(t, errResp.Message, ".go:")
+
+				return
+			}
+
+			var page historyPage
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			assert.Equal(t, tt.expectedStarts, historySummaryStarts(page))
+		})
+	}
+}
+
+func TestHistoryGetHandler(t *testing.T) {
+	id := uuid.New()
+	service := &PIrateRF{
+		history: newTestHistoryStore(t, historyRecord{
+			ID:         id,
+			ModuleName: gorpitx.ModuleNameTUNE,
+			Stdout:     []string{"tuned"},
+			Stderr:     []string{},
+		}),
+	}
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{name: "existing record", id: id.String(), expectedStatus: http.StatusOK},
+		{name: "unknown record", id: uuid.NewString(), expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "nope", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/history/"+tt.id, nil)
+			req.SetPathValue(historyPathParamID, tt.id)
+
+			w := httptest.NewRecorder()
+
+			service.historyGetHandler(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var record historyRecord
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &record))
+			assert.Equal(t, id, record.ID)
+			assert.Equal(t, []string{"tuned"}, record.Stdout)
+		})
+	}
+}
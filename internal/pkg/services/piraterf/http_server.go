@@ -12,7 +12,6 @@ import (
 
 	"github.com/psyb0t/aichteeteapee"
 	"github.com/psyb0t/aichteeteapee/server"
-	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
 	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wsunixbridge"
 	"github.com/psyb0t/aichteeteapee/server/middleware"
 	commonerrors "github.com/psyb0t/common-go/errors"
@@ -87,6 +86,16 @@ func (s *PIrateRF) getHTTPServerRouter() (*server.Router, error) {
 							),
 						),
 					},
+					{
+						Method:  http.MethodGet,
+						Path:    "/history",
+						Handler: s.historyListHandler,
+					},
+					{
+						Method:  http.MethodGet,
+						Path:    "/history/{" + historyPathParamID + "}",
+						Handler: s.historyGetHandler,
+					},
 				},
 			},
 			{
@@ -100,9 +109,12 @@ func (s *PIrateRF) getHTTPServerRouter() (*server.Router, error) {
 				},
 				Routes: []server.RouteConfig{
 					{
-						Method:  http.MethodGet,
-						Path:    "/ws",
-						Handler: wshub.UpgradeHandler(s.websocketHub),
+						Method: http.MethodGet,
+						Path:   "/ws",
+						Handler: joinAwareUpgradeHandler(
+							s.websocketHub,
+							s.handleClientJoined,
+						),
 					},
 					{
 						Method: http.MethodGet,
@@ -140,6 +140,26 @@ func (s *PIrateRF) convertImageToYUV(
 	return s.convertImageToYFormat(inputPath, outputPath, logger)
 }
 
+// convertImageCopyToYUV converts a copy of an image made in dir and leaves
+// the .Y file in there too, the image itself isn't touched.
+func (s *PIrateRF) convertImageCopyToYUV(
+	inputPath, dir string,
+	logger *logrus.Entry,
+) (string, error) {
+	copyPath := filepath.Join(dir, filepath.Base(inputPath))
+	if err := s.copyFileForTemp(inputPath, copyPath); err != nil {
+		return "", ctxerrors.Wrapf(err, "failed to copy image")
+	}
+
+	if strings.HasSuffix(copyPath, ".Y") {
+		return copyPath, nil
+	}
+
+	outputPath := strings.TrimSuffix(copyPath, filepath.Ext(copyPath)) + ".Y"
+
+	return s.convertImageToYFormat(copyPath, outputPath, logger)
+}
+
 func (s *PIrateRF) getImageOutputPath(inputPath string) string {
 	imagesUploadsDir := path.Join(s.config.FilesDir, imagesUploadsPath)
 	base := filepath.Base(inputPath)
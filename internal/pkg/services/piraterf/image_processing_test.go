@@ -602,7 +602,7 @@ func TestProcessImageModifications(t *testing.T) {
 			}
 
 			logger := logrus.WithField("test", "processImageModifications")
-			result, err := service.processImageModifications(argsJSON, logger)
+			result, err := service.processImageModifications(argsJSON, "", logger)
 
 			if tt.expectError {
 				assert.Error(t, err)
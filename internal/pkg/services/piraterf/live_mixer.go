@@ -0,0 +1,771 @@
+package piraterf
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// liveMixSocketInfix goes before the socket suffix of the mic socket to
+	// name the socket the mix comes out of.
+	liveMixSocketInfix = "_mix"
+	liveMixReadSize    = 4096 // bytes of mic read at once
+
+	defaultBedVolumeDB     = -12.0 // bed level under the mic
+	minBedVolumeDB         = -60.0
+	maxBedVolumeDB         = 0.0
+	defaultDuckDepthDB     = 15.0 // how far the bed drops while the mic talks
+	maxDuckDepthDB         = 60.0
+	defaultDuckThresholdDB = -40.0 // mic level that counts as talking
+	minDuckThresholdDB     = -80.0
+	maxDuckThresholdDB     = 0.0
+
+	duckAttackSeconds  = 0.01 // bed gets out of the way fast
+	duckReleaseSeconds = 0.5  // and comes back slowly
+	duckHoldSeconds    = 0.3  // gaps between words don't bring it back
+)
+
+// liveBedSettings is the music bed looped under the mic of a live
+// broadcast. Levels left out get the defaults.
+type liveBedSettings struct {
+	// Bed is a WAV or "playlist:<id>", empty for no bed
+	Bed string `json:"bed"`
+	// BedVolume is the bed level in dB
+	BedVolume *float64 `json:"bedVolume"`
+	// DuckDepth is how many dB the bed drops while the mic is talking
+	DuckDepth *float64 `json:"duckDepth"`
+	// DuckThreshold is the mic level in dBFS that counts as talking
+	DuckThreshold *float64 `json:"duckThreshold"`
+}
+
+// liveBedLevels is how loud the bed is and how it ducks.
+type liveBedLevels struct {
+	volume    float64 // dB
+	duckDepth float64 // dB
+	threshold float64 // dBFS
+}
+
+// levels returns the bed levels the settings ask for.
+func (b liveBedSettings) levels() (liveBedLevels, error) {
+	levels := liveBedLevels{
+		volume:    defaultBedVolumeDB,
+		duckDepth: defaultDuckDepthDB,
+		threshold: defaultDuckThresholdDB,
+	}
+
+	if b.BedVolume != nil {
+		levels.volume = *b.BedVolume
+	}
+
+	if b.DuckDepth != nil {
+		levels.duckDepth = *b.DuckDepth
+	}
+
+	if b.DuckThreshold != nil {
+		levels.threshold = *b.DuckThreshold
+	}
+
+	return levels, levels.validate()
+}
+
+func (l liveBedLevels) validate() error {
+	switch {
+	case l.volume < minBedVolumeDB || l.volume > maxBedVolumeDB:
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"bed volume must be between %g and %g dB, got %g",
+			minBedVolumeDB, maxBedVolumeDB, l.volume,
+		)
+	case l.duckDepth < 0 || l.duckDepth > maxDuckDepthDB:
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"duck depth must be between 0 and %g dB, got %g",
+			maxDuckDepthDB, l.duckDepth,
+		)
+	case l.threshold < minDuckThresholdDB || l.threshold > maxDuckThresholdDB:
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"duck threshold must be between %g and %g dBFS, got %g",
+			minDuckThresholdDB, maxDuckThresholdDB, l.threshold,
+		)
+	}
+
+	return nil
+}
+
+// liveAudioState holds the mixer of the live broadcast, so the controls
+// can get to it.
+type liveAudioState struct {
+	mu    sync.Mutex
+	mixer *liveMixer
+}
+
+func (l *liveAudioState) current() (*liveMixer, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.mixer, l.mixer != nil
+}
+
+func (l *liveAudioState) set(mixer *liveMixer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.mixer = mixer
+}
+
+// clear forgets the mixer unless a newer one took its place.
+func (l *liveAudioState) clear(mixer *liveMixer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.mixer == mixer {
+		l.mixer = nil
+	}
+}
+
+// liveMixer sits between the mic and audiosock-broadcast: it reads the mic
+// off the wsunixbridge socket, mixes the bed (if there is one) under it and
+// the SFX clips over it, and serves the mix on a socket of its own. The mic
+// sets the pace, the bed and clips play as fast as the mic comes in.
+type liveMixer struct {
+	mic        net.Conn
+	out        *pcmSocket
+	bed        *wavLoop
+	bedName    string
+	sampleRate int
+	tempFiles  []string
+	done       chan struct{}
+	// clipEnded is told about every clip that played to its end
+	clipEnded func(clip *liveClip)
+
+	mu       sync.Mutex
+	levels   liveBedLevels
+	duckGain float64 // 1 when the bed is all there
+	holdLeft int     // samples left before the bed comes back
+	clips    []*liveClip
+}
+
+// startLiveMixer connects to the mic socket and starts serving the mix. The
+// bed is optional, clips can go in as long as it runs.
+func (s *PIrateRF) startLiveMixer(
+	micSocketPath string,
+	sampleRate int,
+	bed liveBedSettings,
+	logger *logrus.Entry,
+) (*liveMixer, error) {
+	levels, err := bed.levels()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateLiveAudioSocket(micSocketPath); err != nil {
+		return nil, err
+	}
+
+	mixer := &liveMixer{
+		bedName:    bed.Bed,
+		sampleRate: sampleRate,
+		levels:     levels,
+		duckGain:   1,
+		done:       make(chan struct{}),
+		clipEnded: func(clip *liveClip) {
+			s.sendLiveSFXStoppedEvent(clip, liveSFXStopFinished)
+		},
+	}
+
+	if err := s.openLiveMixer(mixer, micSocketPath, logger); err != nil {
+		mixer.release()
+
+		return nil, err
+	}
+
+	s.liveAudio.set(mixer)
+	s.broadcastLiveBedStatus()
+
+	logger = logger.WithFields(logrus.Fields{
+		"bed":       bed.Bed,
+		"mixSocket": mixer.out.path,
+	})
+	logger.Info("Live mix started")
+
+	go func() {
+		defer close(mixer.done)
+
+		mixer.run(logger)
+		mixer.release()
+		s.liveAudio.clear(mixer)
+		s.broadcastLiveBedStatus()
+
+		for _, clip := range mixer.stopClips("") {
+			s.sendLiveSFXStoppedEvent(clip, liveSFXStopOffAir)
+		}
+
+		logger.Info("Live mix ended")
+	}()
+
+	return mixer, nil
+}
+
+// openLiveMixer opens the bed, the mix socket and the mic connection.
+// Whatever got opened is left on the mixer for release.
+func (s *PIrateRF) openLiveMixer(
+	mixer *liveMixer,
+	micSocketPath string,
+	logger *logrus.Entry,
+) error {
+	var err error
+
+	if mixer.bedName != "" {
+		if mixer.bed, err = s.openLiveBed(mixer, logger); err != nil {
+			return err
+		}
+	}
+
+	mixer.out, err = listenPCMSocket(
+		s.serviceCtx, liveMixSocketPath(micSocketPath),
+	)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{}
+
+	mixer.mic, err = dialer.DialContext(s.serviceCtx, "unix", micSocketPath)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to connect to live audio")
+	}
+
+	return nil
+}
+
+// liveMixSocketPath is where the mix of a mic socket comes out.
+func liveMixSocketPath(micSocketPath string) string {
+	return strings.TrimSuffix(micSocketPath, liveAudioSocketSuffix) +
+		liveMixSocketInfix + liveAudioSocketSuffix
+}
+
+// validateLiveBed checks the bed levels and that the bed is there, without
+// opening it.
+func (s *PIrateRF) validateLiveBed(bed liveBedSettings) error {
+	if _, err := bed.levels(); err != nil {
+		return err
+	}
+
+	if bed.Bed == "" {
+		return nil
+	}
+
+	if _, ok, err := s.playlistFromAudio(bed.Bed); err != nil || ok {
+		return err
+	}
+
+	return validateLiveWAV("bed", bed.Bed)
+}
+
+// openLiveBed opens the bed at the broadcast sample rate. Playlists get
+// rendered and files at another sample rate get resampled first, the temp
+// files go on the mixer.
+func (s *PIrateRF) openLiveBed(
+	mixer *liveMixer,
+	logger *logrus.Entry,
+) (*wavLoop, error) {
+	bedPath, err := s.expandPlaylistAudio(
+		mixer.bedName, playlistTransitions{}, executionTempDir, logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if bedPath != "" {
+		mixer.tempFiles = append(mixer.tempFiles, bedPath)
+	} else {
+		bedPath = mixer.bedName
+		if err := validateLiveWAV("bed", bedPath); err != nil {
+			return nil, err
+		}
+	}
+
+	bed, resampledPath, err := s.openLiveWAV("bed", bedPath, mixer.sampleRate)
+	if resampledPath != "" {
+		mixer.tempFiles = append(mixer.tempFiles, resampledPath)
+	}
+
+	return bed, err
+}
+
+// openLiveWAV opens a WAV at the sample rate of the live broadcast,
+// resampling it into a temp file first when it's at another one. The temp
+// file path comes back even when it fails, for the caller to remove.
+func (s *PIrateRF) openLiveWAV(
+	what, audioPath string,
+	sampleRate int,
+) (*wavLoop, string, error) {
+	audio, err := openWAVLoop(audioPath)
+	if err != nil || audio.format.sampleRate == sampleRate {
+		return audio, "", err
+	}
+
+	_ = audio.close()
+
+	resampledPath, err := s.resampleLiveAudio(what, audioPath, sampleRate)
+	if err != nil {
+		return nil, resampledPath, err
+	}
+
+	audio, err = openWAVLoop(resampledPath)
+
+	return audio, resampledPath, err
+}
+
+// resampleLiveAudio converts a WAV to mono at the sample rate of the live
+// broadcast into a temp file. The temp file path comes back even when it
+// fails, for the caller to remove.
+func (s *PIrateRF) resampleLiveAudio(
+	what, audioPath string,
+	sampleRate int,
+) (string, error) {
+	resampledPath := "/tmp/" + uuid.New().String() + constants.FileExtensionWAV
+
+	ctx, cancel := context.WithTimeout(s.serviceCtx, audioConversionTimeout)
+	defer cancel()
+
+	_, stderr, err := s.commander.Output(ctx, constants.ToolSox, []string{
+		audioPath,
+		"-r", strconv.Itoa(sampleRate),
+		"-b", audioBitDepth,
+		"-c", strconv.Itoa(liveAudioChannels),
+		resampledPath,
+	})
+	if err != nil {
+		return resampledPath, ctxerrors.Wrapf(
+			err, "failed to resample %s, stderr: %s", what, string(stderr),
+		)
+	}
+
+	return resampledPath, nil
+}
+
+// validateLiveWAV makes sure what goes into the live mix is a WAV that's
+// there.
+func validateLiveWAV(what, audioPath string) error {
+	if !strings.EqualFold(filepath.Ext(audioPath), constants.FileExtensionWAV) {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"the %s must be a WAV: %s",
+			what,
+			filepath.Base(audioPath),
+		)
+	}
+
+	if _, err := os.Stat(audioPath); err != nil {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrNotFound,
+			"%s does not exist: %s",
+			what,
+			audioPath,
+		)
+	}
+
+	return nil
+}
+
+// stop ends the mix and waits for it to let go of everything.
+func (m *liveMixer) stop() error {
+	_ = m.mic.Close()
+	<-m.done
+
+	return nil
+}
+
+// release closes whatever the mixer has open and removes its temp files.
+func (m *liveMixer) release() {
+	if m.mic != nil {
+		_ = m.mic.Close()
+	}
+
+	if m.out != nil {
+		m.out.close()
+	}
+
+	if m.bed != nil {
+		_ = m.bed.close()
+	}
+
+	for _, tempFile := range m.tempFiles {
+		_ = os.Remove(tempFile)
+	}
+}
+
+// run mixes until the mic goes away. A byte of a sample split between two
+// reads waits for the rest of it.
+func (m *liveMixer) run(logger *logrus.Entry) {
+	buf := make([]byte, liveMixReadSize)
+	pending := 0
+
+	for {
+		n, err := m.mic.Read(buf[pending:])
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.WithError(err).Warn("Live mix: mic read failed")
+			}
+
+			return
+		}
+
+		n += pending
+		whole := n - n%wavBytesPerSample
+
+		mixed, finished, err := m.mix(buf[:whole])
+		if err != nil {
+			logger.WithError(err).Error("Live mix: bed read failed")
+
+			return
+		}
+
+		m.out.write(mixed)
+
+		for _, clip := range finished {
+			m.clipEnded(clip)
+		}
+
+		pending = copy(buf, buf[whole:n])
+	}
+}
+
+// mix puts the bed under a chunk of mic samples and the clips over it. The
+// clips that played to their end come back.
+func (m *liveMixer) mix(micBytes []byte) ([]byte, []*liveClip, error) {
+	frames := len(micBytes) / wavBytesPerSample
+	mic := make([]float64, frames)
+
+	var sumSquares float64
+
+	for i := range mic {
+		mic[i] = float64(int16(
+			binary.LittleEndian.Uint16(micBytes[i*wavBytesPerSample:]),
+		)) / int16FullScale
+		sumSquares += mic[i] * mic[i]
+	}
+
+	bed := make([]float64, frames)
+	if m.bed != nil {
+		if err := m.bed.read(bed); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	micLevel := silenceFloorDB
+	if frames > 0 {
+		micLevel = levelDB(math.Sqrt(sumSquares / float64(frames)))
+	}
+
+	clips := make([]float64, frames)
+
+	m.mu.Lock()
+	gains := m.bedGains(frames, micLevel)
+	finished := m.mixClips(clips)
+	m.mu.Unlock()
+
+	for i := range mic {
+		mic[i] += bed[i]*gains[i] + clips[i]
+	}
+
+	return pcmBytes(mic), finished, nil
+}
+
+// pcmBytes turns samples into 16-bit PCM, clipping what's over full scale.
+func pcmBytes(samples []float64) []byte {
+	out := make([]byte, len(samples)*wavBytesPerSample)
+
+	for i, sample := range samples {
+		sample = max(-1, min(sample, (int16FullScale-1)/int16FullScale))
+
+		binary.LittleEndian.PutUint16(
+			out[i*wavBytesPerSample:],
+			uint16(int16(math.Round(sample*int16FullScale))), //nolint:gosec
+		)
+	}
+
+	return out
+}
+
+// bedGains returns the bed gain for every sample of a chunk, ducking when
+// the mic is over the threshold. Callers hold mu.
+func (m *liveMixer) bedGains(frames int, micLevel float64) []float64 {
+	volume := dbToGain(m.levels.volume)
+	ducked := dbToGain(-m.levels.duckDepth)
+
+	if micLevel >= m.levels.threshold {
+		m.holdLeft = int(duckHoldSeconds * float64(m.sampleRate))
+	}
+
+	attack := smoothingCoefficient(duckAttackSeconds, m.sampleRate)
+	release := smoothingCoefficient(duckReleaseSeconds, m.sampleRate)
+
+	gains := make([]float64, frames)
+
+	for i := range gains {
+		target, coefficient := 1.0, release
+		if m.holdLeft > 0 {
+			target, coefficient = ducked, attack
+			m.holdLeft--
+		}
+
+		m.duckGain += (target - m.duckGain) * coefficient
+		gains[i] = volume * m.duckGain
+	}
+
+	return gains
+}
+
+// setLevels changes the bed levels of the running mix.
+func (m *liveMixer) setLevels(change func(levels *liveBedLevels)) (
+	liveBedLevels, error,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	levels := m.levels
+	change(&levels)
+
+	if err := levels.validate(); err != nil {
+		return m.levels, err
+	}
+
+	m.levels = levels
+
+	return levels, nil
+}
+
+func (m *liveMixer) currentLevels() liveBedLevels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.levels
+}
+
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/decibelsPerAmplitudeDecade)
+}
+
+// smoothingCoefficient is how much of the way to its target a gain moves
+// every sample to get most of the way there in the given time.
+func smoothingCoefficient(seconds float64, sampleRate int) float64 {
+	return 1 - math.Exp(-1/(seconds*float64(sampleRate)))
+}
+
+// wavLoop reads a 16-bit PCM WAV as mono samples over and over.
+type wavLoop struct {
+	file   *os.File
+	reader *bufio.Reader
+	format wavFormat
+	left   int64 // bytes of samples left before it starts over
+}
+
+func openWAVLoop(path string) (*wavLoop, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to open WAV")
+	}
+
+	loop := &wavLoop{file: file, reader: bufio.NewReader(file)}
+
+	if err := loop.rewind(); err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	return loop, nil
+}
+
+// rewind goes back to the first sample.
+func (l *wavLoop) rewind() error {
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return ctxerrors.Wrap(err, "failed to rewind WAV")
+	}
+
+	l.reader.Reset(l.file)
+
+	format, dataSize, err := readWAVHeader(l.reader)
+	if err != nil {
+		return err
+	}
+
+	if dataSize < int64(format.blockAlign) {
+		return ctxerrors.Wrap(commonerrors.ErrInvalidValue, "the WAV is empty")
+	}
+
+	l.format = format
+	l.left = dataSize - dataSize%int64(format.blockAlign)
+
+	return nil
+}
+
+// read fills samples with the next frames, every channel mixed down to
+// one. It starts over at the end.
+func (l *wavLoop) read(samples []float64) error {
+	frame := make([]byte, l.format.blockAlign)
+
+	for i := range samples {
+		if l.left == 0 {
+			if err := l.rewind(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := io.ReadFull(l.reader, frame); err != nil {
+			// Shorter than its header says, start over from what's there
+			l.left = 0
+
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				if err := l.rewind(); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			return ctxerrors.Wrap(err, "failed to read WAV")
+		}
+
+		l.left -= int64(l.format.blockAlign)
+
+		var sum float64
+		for channel := range l.format.channels {
+			sum += float64(int16(binary.LittleEndian.Uint16(
+				frame[channel*wavBytesPerSample:],
+			)))
+		}
+
+		samples[i] = sum / float64(l.format.channels) / int16FullScale
+	}
+
+	return nil
+}
+
+func (l *wavLoop) close() error {
+	return l.file.Close()
+}
+
+// pcmSocket is a Unix socket serving raw samples to whoever connects, the
+// way wsunixbridge serves the mic.
+type pcmSocket struct {
+	path     string
+	listener net.Listener
+	joined   chan struct{} // a client connected since the last look
+
+	mu      sync.Mutex
+	clients []net.Conn
+}
+
+func listenPCMSocket(ctx context.Context, path string) (*pcmSocket, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, ctxerrors.Wrap(err, "failed to remove stale socket")
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to create socket")
+	}
+
+	socket := &pcmSocket{
+		path:     path,
+		listener: listener,
+		joined:   make(chan struct{}, 1),
+	}
+
+	go socket.accept()
+
+	return socket, nil
+}
+
+func (p *pcmSocket) accept() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		p.mu.Lock()
+		p.clients = append(p.clients, client)
+		p.mu.Unlock()
+
+		select {
+		case p.joined <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// waitClients waits until at least count clients are connected.
+func (p *pcmSocket) waitClients(ctx context.Context, count int) error {
+	for {
+		p.mu.Lock()
+		connected := len(p.clients)
+		p.mu.Unlock()
+
+		if connected >= count {
+			return nil
+		}
+
+		select {
+		case <-p.joined:
+		case <-ctx.Done():
+			return ctxerrors.Wrap(ctx.Err(), "gave up waiting for clients")
+		}
+	}
+}
+
+// write sends samples to every client and drops the ones that went away.
+func (p *pcmSocket) write(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kept := p.clients[:0]
+
+	for _, client := range p.clients {
+		if _, err := client.Write(data); err != nil {
+			_ = client.Close()
+
+			continue
+		}
+
+		kept = append(kept, client)
+	}
+
+	p.clients = kept
+}
+
+// close stops serving, hangs up on every client and removes the socket.
+func (p *pcmSocket) close() {
+	_ = p.listener.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, client := range p.clients {
+		_ = client.Close()
+	}
+
+	p.clients = nil
+
+	_ = os.Remove(p.path)
+}
@@ -0,0 +1,391 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func TestLiveBedSettingsLevels(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    liveBedSettings
+		expected    liveBedLevels
+		expectedErr error
+	}{
+		{
+			name: "defaults",
+			expected: liveBedLevels{
+				volume:    defaultBedVolumeDB,
+				duckDepth: defaultDuckDepthDB,
+				threshold: defaultDuckThresholdDB,
+			},
+		},
+		{
+			name: "everything set",
+			settings: liveBedSettings{
+				BedVolume:     floatPtr(0),
+				DuckDepth:     floatPtr(0),
+				DuckThreshold: floatPtr(-20),
+			},
+			expected: liveBedLevels{volume: 0, duckDepth: 0, threshold: -20},
+		},
+		{
+			name:        "bed louder than full scale",
+			settings:    liveBedSettings{BedVolume: floatPtr(3)},
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "negative duck depth",
+			settings:    liveBedSettings{DuckDepth: floatPtr(-1)},
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "threshold too low",
+			settings:    liveBedSettings{DuckThreshold: floatPtr(-100)},
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := tt.settings.levels()
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, levels)
+		})
+	}
+}
+
+func TestWAVLoop(t *testing.T) {
+	bedPath := filepath.Join(t.TempDir(), "bed.wav")
+	writeTestWAV(t, bedPath, 2, 8000, []int16{
+		16384, 0, // frame 1
+		-16384, -16384, // frame 2
+	})
+
+	loop, err := openWAVLoop(bedPath)
+	require.NoError(t, err)
+
+	defer func() { _ = loop.close() }()
+
+	samples := make([]float64, 5)
+	require.NoError(t, loop.read(samples))
+
+	// Channels get mixed down and it starts over at the end
+	assert.Equal(t, []float64{0.25, -0.5, 0.25, -0.5, 0.25}, samples)
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.wav")
+	writeTestWAV(t, emptyPath, 1, 8000, nil)
+
+	_, err = openWAVLoop(emptyPath)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestLiveMixerBedGains(t *testing.T) {
+	mixer := &liveMixer{
+		sampleRate: 1000,
+		levels:     liveBedLevels{volume: 0, duckDepth: 20, threshold: -30},
+		duckGain:   1,
+	}
+
+	// Quiet mic, the bed stays where it is
+	gains := mixer.bedGains(10, -60)
+	assert.InDelta(t, 1.0, gains[9], 0.0001)
+
+	// Talking ducks it by 20dB within the attack
+	gains = mixer.bedGains(100, -10)
+	assert.InDelta(t, 0.1, gains[99], 0.001)
+
+	// Over the hold it stays down, then it comes back slowly
+	gains = mixer.bedGains(200, -60)
+	assert.InDelta(t, 0.1, gains[199], 0.001)
+
+	gains = mixer.bedGains(100, -60)
+	assert.Greater(t, gains[99], 0.1)
+	assert.Less(t, gains[99], 0.5)
+
+	// Volume scales whatever the ducking leaves
+	mixer = &liveMixer{
+		sampleRate: 1000,
+		levels:     liveBedLevels{volume: -20, duckDepth: 20, threshold: -30},
+		duckGain:   1,
+	}
+	gains = mixer.bedGains(1, -60)
+	assert.InDelta(t, 0.1, gains[0], 0.0001)
+}
+
+// readPCM reads count samples off a socket.
+func readPCM(t *testing.T, conn net.Conn, count int) []int16 {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	buf := make([]byte, count*wavBytesPerSample)
+	_, err := io.ReadFull(conn, buf)
+	require.NoError(t, err)
+
+	samples := make([]int16, count)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(buf[i*2:]))
+	}
+
+	return samples
+}
+
+func TestStartLiveMixer(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	micSocketPath, micListener := listenLiveAudioSocket(
+		t, service.config.UploadDir,
+	)
+
+	bedPath := filepath.Join(t.TempDir(), "bed.wav")
+	writeTestWAV(t, bedPath, 1, 8000, []int16{10000, -10000})
+
+	mixer, err := service.startLiveMixer(
+		micSocketPath,
+		8000,
+		liveBedSettings{Bed: bedPath, BedVolume: floatPtr(-6)},
+		logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+
+	current, ok := service.liveAudio.current()
+	require.True(t, ok)
+	assert.Same(t, mixer, current)
+	assert.Equal(t,
+		filepath.Join(service.config.UploadDir, "conn_mix_output"),
+		mixer.out.path,
+	)
+
+	mic, err := micListener.Accept()
+	require.NoError(t, err)
+
+	// audiosock-broadcast connects to the mix like it would to the mic
+	out, err := (&net.Dialer{}).DialContext(
+		context.Background(), "unix", mixer.out.path,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = out.Close() }()
+
+	require.Eventually(t, func() bool {
+		mixer.out.mu.Lock()
+		defer mixer.out.mu.Unlock()
+
+		return len(mixer.out.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Silence on the mic leaves the bed at its volume
+	_, err = mic.Write(make([]byte, 8))
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]int16{5012, -5012, 5012, -5012},
+		readPCM(t, out, 4),
+	)
+
+	// The mic goes on top
+	_, err = mic.Write([]byte{0x10, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, []int16{5012 + 16}, readPCM(t, out, 1))
+
+	// The browser going away ends the mix and hangs up on the broadcast
+	require.NoError(t, mic.Close())
+
+	select {
+	case <-mixer.done:
+	case <-time.After(time.Second):
+		t.Fatal("mix didn't end on disconnect")
+	}
+
+	_, err = out.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	_, ok = service.liveAudio.current()
+	assert.False(t, ok)
+	assert.NoFileExists(t, mixer.out.path)
+}
+
+func TestStartLiveMixer_SFX(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	micSocketPath, micListener := listenLiveAudioSocket(
+		t, service.config.UploadDir,
+	)
+
+	// No bed, the mix is there for the SFX
+	mixer, err := service.startLiveMixer(
+		micSocketPath, 8000, liveBedSettings{}, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, mixer.bed)
+
+	ended := make(chan *liveClip, 1)
+	mixer.clipEnded = func(clip *liveClip) { ended <- clip }
+
+	mic, err := micListener.Accept()
+	require.NoError(t, err)
+
+	defer func() { _ = mic.Close() }()
+
+	out, err := (&net.Dialer{}).DialContext(
+		context.Background(), "unix", mixer.out.path,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = out.Close() }()
+
+	require.Eventually(t, func() bool {
+		mixer.out.mu.Lock()
+		defer mixer.out.mu.Unlock()
+
+		return len(mixer.out.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	clip := &liveClip{samples: []float64{0.5, -0.5}, gain: 0.5}
+	_, err = mixer.addClip(clip, false)
+	require.NoError(t, err)
+
+	// The clip goes over the mic and it's done when it ran out
+	_, err = mic.Write([]byte{0x10, 0x00, 0x10, 0x00, 0x10, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]int16{8192 + 16, -8192 + 16, 16},
+		readPCM(t, out, 3),
+	)
+
+	select {
+	case finished := <-ended:
+		assert.Same(t, clip, finished)
+	case <-time.After(time.Second):
+		t.Fatal("clip didn't end")
+	}
+
+	require.NoError(t, mixer.stop())
+}
+
+func TestStartLiveMixer_Errors(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	micSocketPath, _ := listenLiveAudioSocket(t, service.config.UploadDir)
+	logger := logrus.NewEntry(logrus.New())
+
+	_, err := service.startLiveMixer(
+		micSocketPath, 8000,
+		liveBedSettings{Bed: "/nowhere/bed.wav"}, logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	_, err = service.startLiveMixer(
+		micSocketPath, 8000,
+		liveBedSettings{Bed: "/nowhere/bed.mp3"}, logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	// A bed at another sample rate gets resampled first
+	bedPath := filepath.Join(t.TempDir(), "bed.wav")
+	writeTestWAV(t, bedPath, 1, 48000, []int16{1, 2})
+
+	mock := commander.NewMock()
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(bedPath),
+		commander.Exact("-r"), commander.Exact("8000"),
+		commander.Exact("-b"), commander.Exact(audioBitDepth),
+		commander.Exact("-c"), commander.Exact("1"),
+		commander.Regex(`^/tmp/.*\.wav$`),
+	).ReturnError(errors.New("sox exploded")) //nolint:err113
+
+	service.commander = mock
+
+	_, err = service.startLiveMixer(
+		micSocketPath, 8000, liveBedSettings{Bed: bedPath}, logger,
+	)
+	require.ErrorContains(t, err, "failed to resample bed")
+	require.NoError(t, mock.VerifyExpectations())
+
+	_, ok := service.liveAudio.current()
+	assert.False(t, ok)
+}
+
+func TestPrepareAudioSockExecution_Bed(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	micSocketPath, micListener := listenLiveAudioSocket(
+		t, service.config.UploadDir,
+	)
+
+	bedPath := filepath.Join(t.TempDir(), "bed.wav")
+	writeTestWAV(t, bedPath, 1, 8000, []int16{1, 2})
+
+	msg := audioSockStartMessage(t, micSocketPath, true)
+	msg.Bed = bedPath
+
+	prepared, err := service.prepareModuleExecution(
+		msg, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+	require.NoError(t, launchPrepared(t, prepared))
+
+	// audiosock-broadcast and the recording both get the mix
+	mixSocketPath := filepath.Join(service.config.UploadDir, "conn_mix_output")
+	assert.Contains(t, string(prepared.args), mixSocketPath)
+
+	mic, err := micListener.Accept()
+	require.NoError(t, err)
+
+	defer func() { _ = mic.Close() }()
+
+	mixer, ok := service.liveAudio.current()
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		mixer.out.mu.Lock()
+		defer mixer.out.mu.Unlock()
+
+		return len(mixer.out.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = mic.Write(make([]byte, 800))
+	require.NoError(t, err)
+
+	recordingsPath := filepath.Join(service.config.FilesDir, audioRecordingsPath)
+
+	require.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(recordingsPath, "live_*.wav"))
+		if len(matches) != 1 {
+			return false
+		}
+
+		info, err := os.Stat(matches[0])
+
+		return err == nil && info.Size() == wavPCMHeaderSize+800
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, prepared.callback())
+
+	matches, err := filepath.Glob(filepath.Join(recordingsPath, "live_*.wav"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	_, ok = service.liveAudio.current()
+	assert.False(t, ok)
+	assert.NoFileExists(t, mixSocketPath)
+}
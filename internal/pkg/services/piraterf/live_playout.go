@@ -0,0 +1,209 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+// audioPlayoutTick is how often the samples that are due get sent.
+const audioPlayoutTick = 20 * time.Millisecond
+
+// audioPlayout plays a WAV into a socket of its own at real time speed,
+// over and over like pifmrds does, standing in for the mic of
+// audiosock-broadcast.
+type audioPlayout struct {
+	// source has the audio, intro/outro and Play Once it's built from
+	source     rpitxExecutionStartMessage
+	socketPath string // named up front so the mix can be named after it
+	audio      *wavLoop
+	out        *pcmSocket
+	sampleRate int
+	tempFiles  []string
+	cancel     context.CancelFunc
+	done       chan struct{} // nil until it starts playing
+}
+
+// prepareAudioPlayout checks the audio in the args - stored playlist,
+// intro/outro - and works out the Play Once timeout the way pifmrds gets
+// it. Nothing gets built or opened before the broadcast leaves the queue.
+func (s *PIrateRF) prepareAudioPlayout(
+	msg *rpitxExecutionStartMessage,
+	prepared *preparedExecution,
+	audio string,
+	sampleRate int,
+	logger *logrus.Entry,
+) (*audioPlayout, error) {
+	timeout, err := s.validateAudioPlayout(msg, audio)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared.timeout = timeout
+	s.applyPlayOnce(msg, prepared, logger)
+
+	return &audioPlayout{
+		source:     *msg,
+		socketPath: s.audioPlayoutSocketPath(),
+		sampleRate: sampleRate,
+	}, nil
+}
+
+// validateAudioPlayout checks the audio of a playout and returns the
+// timeout it gets.
+func (s *PIrateRF) validateAudioPlayout(
+	msg *rpitxExecutionStartMessage,
+	audio string,
+) (int, error) {
+	if _, ok, _ := parsePlaylistAudio(audio); !ok {
+		if err := validateLiveWAV("audio", audio); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.validateAudio(*msg, audio)
+}
+
+// audioPlayoutSocketPath names a new playout socket like the mic sockets
+// wsunixbridge makes, next to them.
+func (s *PIrateRF) audioPlayoutSocketPath() string {
+	return filepath.Join(
+		s.config.UploadDir, uuid.New().String()+liveAudioSocketSuffix,
+	)
+}
+
+// openAudioPlayout builds what goes on air the way pifmrds gets it and
+// opens it at the broadcast sample rate along with the socket it gets
+// played into. Whatever got made is left on the playout for release.
+func (s *PIrateRF) openAudioPlayout(
+	playout *audioPlayout,
+	logger *logrus.Entry,
+) error {
+	_, tempPaths, finalArgs, err := s.processAudioModifications(
+		playout.source, playout.source.Timeout, logger,
+	)
+	playout.tempFiles = append(playout.tempFiles, tempPaths...)
+
+	if err != nil {
+		return ctxerrors.Wrap(err, "audio processing failed")
+	}
+
+	var args struct {
+		Audio string `json:"audio"`
+	}
+
+	if err := json.Unmarshal(finalArgs, &args); err != nil {
+		return ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	if err := validateLiveWAV("audio", args.Audio); err != nil {
+		return err
+	}
+
+	audio, resampledPath, err := s.openLiveWAV(
+		"audio", args.Audio, playout.sampleRate,
+	)
+	if resampledPath != "" {
+		playout.tempFiles = append(playout.tempFiles, resampledPath)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	playout.audio = audio
+
+	playout.out, err = listenPCMSocket(s.serviceCtx, playout.socketPath)
+
+	return err
+}
+
+// start plays the audio once the broadcast has listeners clients on its
+// socket, so the beginning doesn't go out to nobody.
+func (p *audioPlayout) start(
+	ctx context.Context,
+	broadcast *pcmSocket,
+	listeners int,
+	logger *logrus.Entry,
+) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		// The mix ends when its mic goes away
+		defer p.out.close()
+
+		if err := broadcast.waitClients(ctx, listeners); err != nil {
+			return
+		}
+
+		logger.Info("Audio playout started")
+
+		if err := p.run(ctx); err != nil {
+			logger.WithError(err).Error("Audio playout failed")
+		}
+	}()
+}
+
+// run sends whatever samples are due every tick until it gets stopped.
+func (p *audioPlayout) run(ctx context.Context) error {
+	ticker := time.NewTicker(audioPlayoutTick)
+	defer ticker.Stop()
+
+	startedAt := time.Now()
+
+	var sent int64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		due := int64(time.Since(startedAt).Seconds()*float64(p.sampleRate)) -
+			sent
+
+		samples := make([]float64, due)
+		if err := p.audio.read(samples); err != nil {
+			return err
+		}
+
+		p.out.write(pcmBytes(samples))
+		sent += due
+	}
+}
+
+// stop ends the playout and removes its temp files.
+func (p *audioPlayout) stop() error {
+	if p.done != nil {
+		p.cancel()
+		<-p.done
+	}
+
+	p.release()
+
+	return nil
+}
+
+// release closes whatever the playout has open and removes its temp files.
+func (p *audioPlayout) release() {
+	if p.out != nil {
+		p.out.close()
+	}
+
+	if p.audio != nil {
+		_ = p.audio.close()
+	}
+
+	for _, tempFile := range p.tempFiles {
+		_ = os.Remove(tempFile)
+	}
+}
@@ -0,0 +1,151 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func audioPlayoutStartMessage(
+	t *testing.T,
+	audioPath string,
+) *rpitxExecutionStartMessage {
+	t.Helper()
+
+	sampleRate := 8000
+	modulation := gorpitx.ModulationUSB
+
+	args, err := json.Marshal(audioSockArgs{
+		AudioSockBroadcast: gorpitx.AudioSockBroadcast{
+			Frequency:  7100000,
+			SampleRate: &sampleRate,
+			Modulation: &modulation,
+		},
+		Audio: audioPath,
+	})
+	require.NoError(t, err)
+
+	return &rpitxExecutionStartMessage{
+		ModuleName: gorpitx.ModuleNameAudioSockBroadcast,
+		Args:       args,
+	}
+}
+
+func TestPrepareAudioSockExecution_Playout(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	logger := logrus.NewEntry(logrus.New())
+
+	audioPath := filepath.Join(t.TempDir(), "net.wav")
+	writeTestWAV(t, audioPath, 1, 8000, []int16{100, 200, 300, 400})
+
+	prepared, err := service.prepareModuleExecution(
+		audioPlayoutStartMessage(t, audioPath), logger,
+	)
+	require.NoError(t, err)
+
+	var args audioSockArgs
+	require.NoError(t, json.Unmarshal(prepared.args, &args))
+	assert.Equal(t, audioPath, args.Audio)
+	assert.Equal(t, service.config.UploadDir, filepath.Dir(args.SocketPath))
+	assert.True(t, strings.HasSuffix(
+		args.SocketPath, liveMixSocketInfix+liveAudioSocketSuffix,
+	))
+
+	// Nothing gets opened before the broadcast leaves the queue
+	opened, err := filepath.Glob(filepath.Join(service.config.UploadDir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, opened)
+
+	require.NoError(t, launchPrepared(t, prepared))
+
+	// Nothing gets played before audiosock-broadcast is listening, then it
+	// plays from the top and over again
+	conn, err := (&net.Dialer{}).DialContext(
+		context.Background(), "unix", args.SocketPath,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t,
+		[]int16{100, 200, 300, 400, 100, 200, 300, 400},
+		readPCM(t, conn, 8),
+	)
+
+	require.NoError(t, prepared.callback())
+
+	leftovers, err := filepath.Glob(
+		filepath.Join(service.config.UploadDir, "*"),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestPrepareAudioSockExecution_PlayoutErrors(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	logger := logrus.NewEntry(logrus.New())
+
+	_, err := service.prepareModuleExecution(
+		audioPlayoutStartMessage(t, filepath.Join(t.TempDir(), "gone.wav")),
+		logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	_, err = service.prepareModuleExecution(
+		audioPlayoutStartMessage(t, "/files/audio/uploads/net.mp3"), logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	audioPath := filepath.Join(t.TempDir(), "net.wav")
+	writeTestWAV(t, audioPath, 1, 8000, []int16{100})
+
+	msg := audioPlayoutStartMessage(t, audioPath)
+	intro := filepath.Join(t.TempDir(), "intro.wav")
+	msg.Intro = &intro
+
+	_, err = service.prepareModuleExecution(msg, logger)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+	require.ErrorContains(t, err, "intro does not exist")
+
+	leftovers, err := filepath.Glob(
+		filepath.Join(service.config.UploadDir, "*"),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestPCMSocketWaitClients(t *testing.T) {
+	socket, err := listenPCMSocket(
+		context.Background(), filepath.Join(t.TempDir(), "pcm_output"),
+	)
+	require.NoError(t, err)
+
+	defer socket.close()
+
+	conn, err := (&net.Dialer{}).DialContext(
+		context.Background(), "unix", socket.path,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, socket.waitClients(ctx, 1))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, socket.waitClients(ctx, 2), context.DeadlineExceeded)
+}
@@ -0,0 +1,529 @@
+package piraterf
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	liveRecordingPrefix     = "live_"
+	liveRecordingTimeFormat = "2006-01-02_15-04-05"
+	// liveRecordingAttempts is how many names get tried when recordings
+	// start within the same second.
+	liveRecordingAttempts = 100
+	// liveRecordingGrace is how long a recording gets to join the mix and,
+	// once the broadcast is over, to write what the mix sent it.
+	liveRecordingGrace = time.Second
+
+	// liveAudioSampleRate is what the mic sends and audiosock-broadcast
+	// plays when the args don't say.
+	liveAudioSampleRate = 48000
+	liveAudioChannels   = 1
+	// liveAudioSocketSuffix ends the name of the socket wsunixbridge writes
+	// the mic into.
+	liveAudioSocketSuffix = "_output"
+
+	wavPCMHeaderSize = 44 // RIFF header, 16 byte fmt chunk, data chunk header
+	wavMaxDataSize   = math.MaxUint32 - wavPCMHeaderSize + wavChunkHeaderSize
+)
+
+// wavWriter streams 16-bit PCM into a WAV. The sizes in the header get
+// filled in on Close, until then the file says it's empty.
+type wavWriter struct {
+	file       *os.File
+	sampleRate int
+	channels   int
+	dataSize   int64
+}
+
+// createWAVWriter creates a new WAV, it never writes over another file.
+func createWAVWriter(
+	path string,
+	sampleRate, channels int,
+) (*wavWriter, error) {
+	file, err := os.OpenFile(
+		path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerms,
+	)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to create WAV")
+	}
+
+	w := &wavWriter{file: file, sampleRate: sampleRate, channels: channels}
+
+	if _, err := file.Write(w.header()); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+
+		return nil, ctxerrors.Wrap(err, "failed to write WAV header")
+	}
+
+	return w, nil
+}
+
+// wavPCMHeader is the 44 byte header of a plain PCM WAV.
+type wavPCMHeader struct {
+	RIFF          [4]byte
+	RIFFSize      uint32
+	WAVEFmt       [8]byte
+	FmtSize       uint32
+	Format        uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+	Data          [4]byte
+	DataSize      uint32
+}
+
+// header is the header for the samples written so far.
+func (w *wavWriter) header() []byte {
+	blockAlign := w.channels * wavBytesPerSample
+	riffSize := wavPCMHeaderSize - wavChunkHeaderSize + w.dataSize
+
+	//nolint:gosec // the format is tiny and the data is capped at 4GB
+	header := wavPCMHeader{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		RIFFSize:      uint32(riffSize),
+		WAVEFmt:       [8]byte{'W', 'A', 'V', 'E', 'f', 'm', 't', ' '},
+		FmtSize:       wavFmtMinSize,
+		Format:        wavFormatPCM,
+		Channels:      uint16(w.channels),
+		SampleRate:    uint32(w.sampleRate),
+		ByteRate:      uint32(w.sampleRate * blockAlign),
+		BlockAlign:    uint16(blockAlign),
+		BitsPerSample: wavBitsPerSample,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(w.dataSize),
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, wavPCMHeaderSize))
+	_ = binary.Write(buf, binary.LittleEndian, header)
+
+	return buf.Bytes()
+}
+
+// Write appends samples. A WAV can't hold more than 4GB so it stops there.
+func (w *wavWriter) Write(p []byte) (int, error) {
+	if w.dataSize+int64(len(p)) > wavMaxDataSize {
+		return 0, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue, "WAV size limit reached",
+		)
+	}
+
+	n, err := w.file.Write(p)
+	w.dataSize += int64(n)
+
+	if err != nil {
+		return n, ctxerrors.Wrap(err, "failed to write samples")
+	}
+
+	return n, nil
+}
+
+// duration is how many seconds of audio were written.
+func (w *wavWriter) duration() float64 {
+	frames := w.dataSize / int64(w.channels*wavBytesPerSample)
+
+	return float64(frames) / float64(w.sampleRate)
+}
+
+// Close drops the half frame a cut off stream can end with, fills in the
+// header and closes the file.
+func (w *wavWriter) Close() error {
+	blockAlign := int64(w.channels * wavBytesPerSample)
+	w.dataSize -= w.dataSize % blockAlign
+
+	err := w.file.Truncate(wavPCMHeaderSize + w.dataSize)
+	if err == nil {
+		_, err = w.file.WriteAt(w.header(), 0)
+	}
+
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to finalise WAV")
+	}
+
+	return nil
+}
+
+// liveRecording tees the mic of a live broadcast into a WAV until the
+// browser disconnects or the broadcast is over.
+type liveRecording struct {
+	conn net.Conn
+	done chan struct{}
+}
+
+// stop ends the recording and waits for the WAV to be finalised. What the
+// mix sent before hanging up still goes in.
+func (r *liveRecording) stop() error {
+	select {
+	case <-r.done:
+	case <-time.After(liveRecordingGrace):
+	}
+
+	_ = r.conn.Close()
+	<-r.done
+
+	return nil
+}
+
+// audioSockArgs are the audiosock-broadcast args along with the audio
+// file PIrateRF plays into it instead of the mic.
+type audioSockArgs struct {
+	gorpitx.AudioSockBroadcast
+
+	// Audio is a WAV or "playlist:<id>", empty for the mic
+	Audio string `json:"audio"`
+}
+
+// liveBroadcast is what runs next to audiosock-broadcast while it's on air
+// - the audio playout, the mix and the recording. The mix and the
+// recording only start when the broadcast leaves the queue.
+type liveBroadcast struct {
+	micSocketPath string // the mic, or the socket the audio gets played into
+	sampleRate    int
+	bed           liveBedSettings
+	record        bool
+	playout       *audioPlayout // nil for the mic
+	logger        *logrus.Entry
+
+	mu    sync.Mutex
+	stops []func() error
+}
+
+// started adds what has to be stopped once the broadcast is over.
+func (b *liveBroadcast) started(stop func() error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stops = append(b.stops, stop)
+}
+
+// stop stops whatever got started, in the order it started.
+func (b *liveBroadcast) stop() error {
+	b.mu.Lock()
+	stops := b.stops
+	b.stops = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for _, stop := range stops {
+		errs = append(errs, stop())
+	}
+
+	return errors.Join(errs...)
+}
+
+// prepareAudioSockExecution points audiosock-broadcast at the mix of the
+// mic - or of the audio file played instead of it - for the bed and SFX.
+// The mix and the recording get started by the launch, a broadcast waiting
+// in the queue leaves whatever is on air alone.
+func (s *PIrateRF) prepareAudioSockExecution(
+	msg *rpitxExecutionStartMessage,
+	prepared *preparedExecution,
+	logger *logrus.Entry,
+) (*preparedExecution, error) {
+	var args audioSockArgs
+	if err := json.Unmarshal(msg.Args, &args); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	broadcast := &liveBroadcast{
+		micSocketPath: args.SocketPath,
+		sampleRate:    liveAudioSampleRate,
+		bed:           msg.liveBedSettings,
+		record:        msg.Record,
+		logger:        logger,
+	}
+
+	if args.SampleRate != nil && *args.SampleRate > 0 {
+		broadcast.sampleRate = *args.SampleRate
+	}
+
+	if err := s.validateLiveBed(msg.liveBedSettings); err != nil {
+		return nil, s.audioSockSetupFailed("live mix", err, logger)
+	}
+
+	prepared.callback = broadcast.stop
+
+	if args.Audio != "" {
+		playout, err := s.prepareAudioPlayout(
+			msg, prepared, args.Audio, broadcast.sampleRate, logger,
+		)
+		if err != nil {
+			return nil, s.audioSockSetupFailed("audio playout", err, logger)
+		}
+
+		broadcast.playout = playout
+		broadcast.micSocketPath = playout.socketPath
+	} else if err := s.validateLiveAudioSocket(args.SocketPath); err != nil {
+		return nil, s.audioSockSetupFailed("live mix", err, logger)
+	}
+
+	var err error
+
+	if prepared.args, err = withArg(
+		prepared.args,
+		"socketPath",
+		liveMixSocketPath(broadcast.micSocketPath),
+	); err != nil {
+		prepared.cleanup()
+
+		return nil, err
+	}
+
+	prepared.opts = append(prepared.opts, withLaunch(func() error {
+		return s.launchLiveBroadcast(broadcast)
+	}))
+
+	return prepared, nil
+}
+
+// launchLiveBroadcast starts the mix, the recording and the playout of a
+// broadcast leaving the queue. They're all part of the broadcast: when one
+// can't start nothing goes on air.
+func (s *PIrateRF) launchLiveBroadcast(broadcast *liveBroadcast) error {
+	logger := broadcast.logger
+
+	if broadcast.playout != nil {
+		broadcast.started(broadcast.playout.stop)
+
+		if err := s.openAudioPlayout(broadcast.playout, logger); err != nil {
+			return ctxerrors.Wrap(err, "audio playout setup failed")
+		}
+	}
+
+	mixer, err := s.startLiveMixer(
+		broadcast.micSocketPath,
+		broadcast.sampleRate,
+		broadcast.bed,
+		logger,
+	)
+	if err != nil {
+		return ctxerrors.Wrap(err, "live mix setup failed")
+	}
+
+	broadcast.started(mixer.stop)
+
+	if broadcast.record {
+		recording, err := s.startLiveRecording(
+			mixer.out.path, broadcast.sampleRate, logger,
+		)
+		if err != nil {
+			return ctxerrors.Wrap(err, "live recording setup failed")
+		}
+
+		broadcast.started(recording.stop)
+
+		// The recording gets the broadcast from its first sample
+		ctx, cancel := context.WithTimeout(s.serviceCtx, liveRecordingGrace)
+		err = mixer.out.waitClients(ctx, 1)
+
+		cancel()
+
+		if err != nil {
+			return ctxerrors.Wrap(err, "live recording setup failed")
+		}
+	}
+
+	if broadcast.playout != nil {
+		// audiosock-broadcast and the recording
+		listeners := 1
+		if broadcast.record {
+			listeners++
+		}
+
+		broadcast.playout.start(s.serviceCtx, mixer.out, listeners, logger)
+	}
+
+	return nil
+}
+
+// audioSockSetupFailed logs why the live broadcast isn't going on air, the
+// requesting client gets told by whoever started it.
+func (s *PIrateRF) audioSockSetupFailed(
+	what string,
+	err error,
+	logger *logrus.Entry,
+) error {
+	logger.WithError(err).Errorf("%s setup failed", what)
+
+	return ctxerrors.Wrapf(err, "%s setup failed", what)
+}
+
+// startLiveRecording connects to the socket wsunixbridge writes the mic into
+// - next to audiosock-broadcast, which gets the same audio - and writes
+// everything that comes out of it into a new WAV under recordings.
+func (s *PIrateRF) startLiveRecording(
+	socketPath string,
+	sampleRate int,
+	logger *logrus.Entry,
+) (*liveRecording, error) {
+	if err := s.validateLiveAudioSocket(socketPath); err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{}
+
+	conn, err := dialer.DialContext(s.serviceCtx, "unix", socketPath)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to connect to live audio")
+	}
+
+	wav, err := createLiveRecordingWAV(
+		filepath.Join(s.config.FilesDir, audioRecordingsPath),
+		sampleRate,
+		time.Now(),
+	)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	recording := &liveRecording{conn: conn, done: make(chan struct{})}
+
+	// Going down finalises the WAV like a disconnect does
+	stopOnShutdown := context.AfterFunc(s.serviceCtx, func() {
+		_ = conn.Close()
+	})
+
+	logger = logger.WithField("recording", wav.file.Name())
+	logger.Info("Live recording started")
+
+	go func() {
+		defer close(recording.done)
+		defer stopOnShutdown()
+
+		_, err := io.Copy(wav, conn)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.WithError(err).Warn("Live recording cut short")
+		}
+
+		s.finishLiveRecording(wav, logger)
+	}()
+
+	return recording, nil
+}
+
+// validateLiveAudioSocket makes sure a socket path is one of the mic
+// sockets wsunixbridge made and not something else on the box.
+func (s *PIrateRF) validateLiveAudioSocket(socketPath string) error {
+	if socketPath == "" {
+		return ctxerrors.Wrap(
+			commonerrors.ErrRequiredFieldNotSet, "socketPath",
+		)
+	}
+
+	socketPath = filepath.Clean(socketPath)
+
+	if filepath.Dir(socketPath) != filepath.Clean(s.config.UploadDir) ||
+		!strings.HasSuffix(socketPath, liveAudioSocketSuffix) {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"not a live audio socket: %s",
+			socketPath,
+		)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil || info.Mode().Type() != os.ModeSocket {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrNotFound,
+			"live audio socket is gone: %s",
+			socketPath,
+		)
+	}
+
+	return nil
+}
+
+// createLiveRecordingWAV creates the WAV for a recording started at the
+// given time, named after it.
+func createLiveRecordingWAV(
+	dir string,
+	sampleRate int,
+	startedAt time.Time,
+) (*wavWriter, error) {
+	if err := os.MkdirAll(dir, dirPerms); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to create recordings directory")
+	}
+
+	name := liveRecordingPrefix + startedAt.Format(liveRecordingTimeFormat)
+
+	for attempt := range liveRecordingAttempts {
+		fileName := name
+		if attempt > 0 {
+			fileName += "_" + strconv.Itoa(attempt+1)
+		}
+
+		wav, err := createWAVWriter(
+			filepath.Join(dir, fileName+constants.FileExtensionWAV),
+			sampleRate,
+			liveAudioChannels,
+		)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+
+		return wav, err
+	}
+
+	return nil, ctxerrors.Wrapf(
+		commonerrors.ErrInvalidValue,
+		"too many recordings named %s",
+		name,
+	)
+}
+
+// finishLiveRecording finalises the WAV and tells everybody it's there. A
+// recording that never got any audio is thrown away.
+func (s *PIrateRF) finishLiveRecording(wav *wavWriter, logger *logrus.Entry) {
+	recordingPath := wav.file.Name()
+
+	if err := wav.Close(); err != nil {
+		logger.WithError(err).Error("Failed to finalise live recording")
+		s.sendRecordingErrorEvent(
+			filepath.Base(recordingPath),
+			"recording failed",
+			errorMessage(err),
+		)
+
+		return
+	}
+
+	if wav.dataSize == 0 {
+		_ = os.Remove(recordingPath)
+
+		logger.Info("Live recording discarded, no audio came in")
+
+		return
+	}
+
+	cacheAudioPeaks(recordingPath)
+
+	logger.WithField("duration", wav.duration()).Info("Live recording saved")
+	s.sendRecordingSavedEvent(recordingPath, wav.duration())
+}
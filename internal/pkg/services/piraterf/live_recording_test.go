@@ -0,0 +1,380 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAVWriter(t *testing.T) {
+	wavPath := filepath.Join(t.TempDir(), "live.wav")
+
+	wav, err := createWAVWriter(wavPath, 8000, 1)
+	require.NoError(t, err)
+
+	// A second of samples plus half of one more from a stream cut short
+	_, err = wav.Write(make([]byte, 16001))
+	require.NoError(t, err)
+	require.NoError(t, wav.Close())
+
+	peaks, err := computeAudioPeaks(wavPath)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, peaks.Duration, 0.0001)
+	assert.Equal(t, 8000, peaks.SampleRate)
+	assert.Equal(t, 1, peaks.Channels)
+
+	info, err := os.Stat(wavPath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(wavPCMHeaderSize+16000), info.Size())
+
+	// Never over another file
+	_, err = createWAVWriter(wavPath, 8000, 1)
+	require.ErrorIs(t, err, os.ErrExist)
+}
+
+func TestCreateLiveRecordingWAV(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), recordingsDir)
+	startedAt := time.Date(2025, 3, 4, 5, 6, 7, 0, time.Local)
+
+	first, err := createLiveRecordingWAV(dir, 48000, startedAt)
+	require.NoError(t, err)
+	require.NoError(t, first.Close())
+	assert.Equal(t,
+		filepath.Join(dir, "live_2025-03-04_05-06-07.wav"),
+		first.file.Name(),
+	)
+
+	second, err := createLiveRecordingWAV(dir, 48000, startedAt)
+	require.NoError(t, err)
+	require.NoError(t, second.Close())
+	assert.Equal(t,
+		filepath.Join(dir, "live_2025-03-04_05-06-07_2.wav"),
+		second.file.Name(),
+	)
+}
+
+// listenLiveAudioSocket stands in for the socket wsunixbridge writes the mic
+// into and returns the path with the listener.
+func listenLiveAudioSocket(
+	t *testing.T,
+	uploadDir string,
+) (string, net.Listener) {
+	t.Helper()
+
+	socketPath := filepath.Join(uploadDir, "conn"+liveAudioSocketSuffix)
+
+	listener, err := (&net.ListenConfig{}).Listen(
+		context.Background(), "unix", socketPath,
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return socketPath, listener
+}
+
+func TestValidateLiveAudioSocket(t *testing.T) {
+	uploadDir := t.TempDir()
+	socketPath, _ := listenLiveAudioSocket(t, uploadDir)
+
+	regularFile := filepath.Join(uploadDir, "file"+liveAudioSocketSuffix)
+	require.NoError(t, os.WriteFile(regularFile, nil, filePerms))
+
+	service := &PIrateRF{config: Config{UploadDir: uploadDir}}
+
+	tests := []struct {
+		name        string
+		socketPath  string
+		expectedErr error
+	}{
+		{name: "mic socket", socketPath: socketPath},
+		{
+			name:        "empty",
+			expectedErr: commonerrors.ErrRequiredFieldNotSet,
+		},
+		{
+			name:        "outside the sockets dir",
+			socketPath:  filepath.Join(t.TempDir(), "conn_output"),
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "reader socket",
+			socketPath:  filepath.Join(uploadDir, "conn_input"),
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "sneaking out",
+			socketPath:  uploadDir + "/../etc/conn_output",
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "gone",
+			socketPath:  filepath.Join(uploadDir, "gone_output"),
+			expectedErr: commonerrors.ErrNotFound,
+		},
+		{
+			name:        "not a socket",
+			socketPath:  regularFile,
+			expectedErr: commonerrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.validateLiveAudioSocket(tt.socketPath)
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func newLiveRecordingTestService(t *testing.T) *PIrateRF {
+	t.Helper()
+
+	hub := wshub.NewHub("test")
+	t.Cleanup(hub.Close)
+
+	return &PIrateRF{
+		serviceCtx:       context.Background(),
+		config:           Config{FilesDir: t.TempDir(), UploadDir: t.TempDir()},
+		websocketHub:     hub,
+		executionManager: newExecutionManager(gorpitx.GetInstance(), hub),
+	}
+}
+
+// launchPrepared starts what a prepared execution starts when it leaves
+// the queue.
+func launchPrepared(t *testing.T, prepared *preparedExecution) error {
+	t.Helper()
+
+	job := &queuedExecution{}
+	for _, opt := range prepared.opts {
+		opt(job)
+	}
+
+	require.NotNil(t, job.launch)
+
+	return job.launch()
+}
+
+func audioSockStartMessage(
+	t *testing.T,
+	socketPath string,
+	record bool,
+) *rpitxExecutionStartMessage {
+	t.Helper()
+
+	sampleRate := 8000
+
+	args, err := json.Marshal(gorpitx.AudioSockBroadcast{
+		SocketPath: socketPath,
+		Frequency:  27225000,
+		SampleRate: &sampleRate,
+	})
+	require.NoError(t, err)
+
+	return &rpitxExecutionStartMessage{
+		ModuleName: gorpitx.ModuleNameAudioSockBroadcast,
+		Args:       args,
+		Record:     record,
+	}
+}
+
+func TestPrepareAudioSockExecution_Record(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	socketPath, listener := listenLiveAudioSocket(t, service.config.UploadDir)
+
+	prepared, err := service.prepareModuleExecution(
+		audioSockStartMessage(t, socketPath, true), logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, prepared.callback)
+	require.NoError(t, launchPrepared(t, prepared))
+
+	mixer, ok := service.liveAudio.current()
+	require.True(t, ok)
+
+	// The mic goes to the recording like it goes to audiosock-broadcast
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	_, err = conn.Write(make([]byte, 8000))
+	require.NoError(t, err)
+
+	// The browser going away ends the mix
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-mixer.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mix didn't end on disconnect")
+	}
+
+	// The broadcast stopping finalises the recording with all of it
+	require.NoError(t, prepared.callback())
+
+	recordingsPath := filepath.Join(service.config.FilesDir, audioRecordingsPath)
+
+	matches, err := filepath.Glob(filepath.Join(recordingsPath, "live_*.wav"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	info, err := os.Stat(matches[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(wavPCMHeaderSize+8000), info.Size())
+
+	peaks, ok := loadAudioPeaks(matches[0])
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, peaks.Duration, 0.0001)
+	assert.Equal(t, 8000, peaks.SampleRate)
+}
+
+func TestStartLiveRecording_Disconnect(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	socketPath, listener := listenLiveAudioSocket(t, service.config.UploadDir)
+
+	recording, err := service.startLiveRecording(
+		socketPath, 8000, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	_, err = conn.Write(make([]byte, 16000))
+	require.NoError(t, err)
+
+	// The browser going away closes the socket and ends the recording
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-recording.done:
+	case <-time.After(time.Second):
+		t.Fatal("recording didn't end on disconnect")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(
+		service.config.FilesDir, audioRecordingsPath, "live_*.wav",
+	))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	peaks, err := computeAudioPeaks(matches[0])
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, peaks.Duration, 0.0001)
+
+	// Stopping after the fact is harmless
+	require.NoError(t, recording.stop())
+}
+
+func TestStartLiveRecording_NoAudio(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	socketPath, listener := listenLiveAudioSocket(t, service.config.UploadDir)
+
+	recording, err := service.startLiveRecording(
+		socketPath, 8000, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, recording.stop())
+
+	matches, err := filepath.Glob(filepath.Join(
+		service.config.FilesDir, audioRecordingsPath, "*",
+	))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestPrepareAudioSockExecution(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	socketPath, listener := listenLiveAudioSocket(t, service.config.UploadDir)
+	logger := logrus.NewEntry(logrus.New())
+
+	// Without recording the broadcast still goes through the mix, for SFX
+	prepared, err := service.prepareModuleExecution(
+		audioSockStartMessage(t, socketPath, false), logger,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, prepared.callback)
+
+	mixSocketPath := filepath.Join(service.config.UploadDir, "conn_mix_output")
+	assert.Contains(t, string(prepared.args), mixSocketPath)
+
+	// Nothing starts before the broadcast leaves the queue
+	assert.NoFileExists(t, mixSocketPath)
+
+	_, ok := service.liveAudio.current()
+	assert.False(t, ok)
+
+	require.NoError(t, launchPrepared(t, prepared))
+	assert.FileExists(t, mixSocketPath)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	require.NoError(t, prepared.callback())
+	assert.NoFileExists(t, mixSocketPath)
+
+	// Something that isn't a mic socket keeps it off air
+	_, err = service.prepareModuleExecution(
+		audioSockStartMessage(t, "/nowhere_output", false), logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
+
+func TestPrepareAudioSockExecution_LeavesLiveMixAlone(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	socketPath, listener := listenLiveAudioSocket(t, service.config.UploadDir)
+	logger := logrus.NewEntry(logrus.New())
+
+	onAir, err := service.startLiveMixer(
+		socketPath, 8000, liveBedSettings{}, logger,
+	)
+	require.NoError(t, err)
+
+	mic, err := listener.Accept()
+	require.NoError(t, err)
+
+	defer func() { _ = mic.Close() }()
+
+	// A broadcast that's queued and then dropped never touches the mix of
+	// the one on air
+	prepared, err := service.prepareModuleExecution(
+		audioSockStartMessage(t, socketPath, true), logger,
+	)
+	require.NoError(t, err)
+	prepared.cleanup()
+
+	current, ok := service.liveAudio.current()
+	require.True(t, ok)
+	assert.Same(t, onAir, current)
+
+	out, err := (&net.Dialer{}).DialContext(
+		context.Background(), "unix", onAir.out.path,
+	)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	require.NoError(t, onAir.stop())
+}
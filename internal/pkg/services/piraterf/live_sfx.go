@@ -0,0 +1,184 @@
+package piraterf
+
+import (
+	"os"
+
+	"github.com/google/uuid"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+)
+
+const (
+	defaultSFXGainDB = 0.0
+	minSFXGainDB     = -60.0
+	maxSFXGainDB     = 12.0 // SFX can go over the mic, the mix gets clipped
+	// liveSFXMaxSeconds is the longest clip, they get loaded whole.
+	liveSFXMaxSeconds = 60
+	// liveSFXMaxClips is how many clips can play over each other.
+	liveSFXMaxClips = 16
+
+	// Why a clip stopped playing.
+	liveSFXStopFinished = "finished"
+	liveSFXStopStopped  = "stopped"
+	liveSFXStopOffAir   = "off air"
+)
+
+// liveClip is an SFX playing over the live mic.
+type liveClip struct {
+	id       string
+	path     string
+	samples  []float64
+	position int
+	gain     float64 // amplitude factor
+}
+
+func (c *liveClip) duration(sampleRate int) float64 {
+	return float64(len(c.samples)) / float64(sampleRate)
+}
+
+// validateSFXGain makes sure a clip gain in dB is one that can be played.
+func validateSFXGain(gain float64) error {
+	if gain < minSFXGainDB || gain > maxSFXGainDB {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"SFX gain must be between %g and %g dB, got %g",
+			minSFXGainDB, maxSFXGainDB, gain,
+		)
+	}
+
+	return nil
+}
+
+// loadLiveClip reads a whole SFX as mono samples at the sample rate of the
+// live broadcast, resampling it first when it's at another one.
+func (s *PIrateRF) loadLiveClip(
+	clipPath string,
+	sampleRate int,
+	gain float64,
+) (*liveClip, error) {
+	if err := validateSFXGain(gain); err != nil {
+		return nil, err
+	}
+
+	if err := validateLiveWAV("SFX", clipPath); err != nil {
+		return nil, err
+	}
+
+	clip, resampledPath, err := s.openLiveWAV("SFX", clipPath, sampleRate)
+	if resampledPath != "" {
+		defer func() { _ = os.Remove(resampledPath) }()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = clip.close() }()
+
+	frames := clip.left / int64(clip.format.blockAlign)
+	if frames > int64(liveSFXMaxSeconds*sampleRate) {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"SFX can be up to %d seconds long: %s",
+			liveSFXMaxSeconds,
+			clipPath,
+		)
+	}
+
+	samples := make([]float64, frames)
+	if err := clip.read(samples); err != nil {
+		return nil, err
+	}
+
+	return &liveClip{
+		id:      uuid.New().String(),
+		path:    clipPath,
+		samples: samples,
+		gain:    dbToGain(gain),
+	}, nil
+}
+
+// addClip starts playing a clip over the mix. Exclusive stops whatever was
+// playing first, the stopped clips come back.
+func (m *liveMixer) addClip(
+	clip *liveClip,
+	exclusive bool,
+) ([]*liveClip, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stopped []*liveClip
+
+	if exclusive {
+		stopped = m.clips
+		m.clips = nil
+	}
+
+	if len(m.clips) >= liveSFXMaxClips {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"%d SFX are already playing",
+			len(m.clips),
+		)
+	}
+
+	m.clips = append(m.clips, clip)
+
+	return stopped, nil
+}
+
+// stopClips stops the clip with the given ID, or all of them when it's
+// empty, and returns the ones that got stopped.
+func (m *liveMixer) stopClips(id string) []*liveClip {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stopped []*liveClip
+
+	kept := m.clips[:0]
+
+	for _, clip := range m.clips {
+		if id == "" || clip.id == id {
+			stopped = append(stopped, clip)
+
+			continue
+		}
+
+		kept = append(kept, clip)
+	}
+
+	m.clips = kept
+
+	return stopped
+}
+
+// mixClips adds the next samples of every clip into samples and drops the
+// clips that played to their end, which come back. Callers hold mu.
+func (m *liveMixer) mixClips(samples []float64) []*liveClip {
+	var finished []*liveClip
+
+	kept := m.clips[:0]
+
+	for _, clip := range m.clips {
+		for i := range samples {
+			if clip.position == len(clip.samples) {
+				break
+			}
+
+			samples[i] += clip.samples[clip.position] * clip.gain
+			clip.position++
+		}
+
+		if clip.position == len(clip.samples) {
+			finished = append(finished, clip)
+
+			continue
+		}
+
+		kept = append(kept, clip)
+	}
+
+	m.clips = kept
+
+	return finished
+}
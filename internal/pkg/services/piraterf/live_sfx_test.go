@@ -0,0 +1,111 @@
+package piraterf
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLiveClip(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+
+	clipPath := filepath.Join(t.TempDir(), "scream.wav")
+	writeTestWAV(t, clipPath, 2, 8000, []int16{
+		16384, 0,
+		-16384, -16384,
+	})
+
+	clip, err := service.loadLiveClip(clipPath, 8000, -20)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.25, -0.5}, clip.samples)
+	assert.InDelta(t, 0.1, clip.gain, 0.0001)
+	assert.InDelta(t, 0.00025, clip.duration(8000), 0.0000001)
+	assert.NotEmpty(t, clip.id)
+
+	_, err = service.loadLiveClip(clipPath, 8000, 13)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = service.loadLiveClip("/nowhere/scream.mp3", 8000, 0)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = service.loadLiveClip("/nowhere/scream.wav", 8000, 0)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+
+	// Clips get loaded whole, long ones are turned away
+	longPath := filepath.Join(t.TempDir(), "long.wav")
+	writeTestWAV(t, longPath, 1, 1, make([]int16, liveSFXMaxSeconds+1))
+
+	_, err = service.loadLiveClip(longPath, 1, 0)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	// Clips at another sample rate get resampled first
+	mock := commander.NewMock()
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(clipPath),
+		commander.Exact("-r"), commander.Exact("48000"),
+		commander.Exact("-b"), commander.Exact(audioBitDepth),
+		commander.Exact("-c"), commander.Exact("1"),
+		commander.Regex(`^/tmp/.*\.wav$`),
+	).ReturnError(errors.New("sox exploded")) //nolint:err113
+
+	service.commander = mock
+
+	_, err = service.loadLiveClip(clipPath, 48000, 0)
+	require.ErrorContains(t, err, "failed to resample SFX")
+	require.NoError(t, mock.VerifyExpectations())
+}
+
+func TestLiveMixerClips(t *testing.T) {
+	mixer := &liveMixer{sampleRate: 8000}
+
+	short := &liveClip{id: "short", samples: []float64{0.5, 0.5}, gain: 1}
+	long := &liveClip{id: "long", samples: []float64{0.1, 0.1, 0.1}, gain: 0.5}
+
+	_, err := mixer.addClip(short, false)
+	require.NoError(t, err)
+	_, err = mixer.addClip(long, false)
+	require.NoError(t, err)
+
+	// Overlapping clips add up, each at its own gain
+	samples := make([]float64, 2)
+	finished := mixer.mixClips(samples)
+	assert.InDeltaSlice(t, []float64{0.55, 0.55}, samples, 0.0001)
+	assert.Equal(t, []*liveClip{short}, finished)
+
+	samples = make([]float64, 2)
+	finished = mixer.mixClips(samples)
+	assert.InDeltaSlice(t, []float64{0.05, 0}, samples, 0.0001)
+	assert.Equal(t, []*liveClip{long}, finished)
+	assert.Empty(t, mixer.clips)
+
+	// Stopping one leaves the others playing
+	first := &liveClip{id: "first", samples: []float64{1}}
+	second := &liveClip{id: "second", samples: []float64{1}}
+	_, _ = mixer.addClip(first, false)
+	_, _ = mixer.addClip(second, false)
+
+	assert.Equal(t, []*liveClip{first}, mixer.stopClips("first"))
+	assert.Empty(t, mixer.stopClips("first"))
+	assert.Equal(t, []*liveClip{second}, mixer.clips)
+
+	// Exclusive clips cut off whatever was playing
+	third := &liveClip{id: "third", samples: []float64{1}}
+	stopped, err := mixer.addClip(third, true)
+	require.NoError(t, err)
+	assert.Equal(t, []*liveClip{second}, stopped)
+	assert.Equal(t, []*liveClip{third}, mixer.stopClips(""))
+
+	for range liveSFXMaxClips {
+		_, err = mixer.addClip(&liveClip{samples: []float64{1}}, false)
+		require.NoError(t, err)
+	}
+
+	_, err = mixer.addClip(&liveClip{samples: []float64{1}}, false)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+}
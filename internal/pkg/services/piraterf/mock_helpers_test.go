@@ -127,6 +127,34 @@ func (m *fileCreatingMockCommander) Start(
 	return proc, err
 }
 
+// Output creates the WAV an ffmpeg or sox run writes to - the last WAV
+// in its args.
+func (m *fileCreatingMockCommander) Output(
+	ctx context.Context,
+	name string,
+	args []string,
+	opts ...commander.Option,
+) ([]byte, []byte, error) {
+	stdout, stderr, err := m.MockCommander.Output(ctx, name, args, opts...)
+	if err != nil || (name != "ffmpeg" && name != constants.ToolSox) {
+		return stdout, stderr, err
+	}
+
+	for i := len(args) - 1; i >= 0; i-- {
+		if filepath.Ext(args[i]) != constants.FileExtensionWAV {
+			continue
+		}
+
+		if err := os.WriteFile(args[i], []byte("fake wav"), 0o600); err != nil {
+			return stdout, stderr, err
+		}
+
+		break
+	}
+
+	return stdout, stderr, nil
+}
+
 type testMockCommander struct {
 	tempDir string
 }
@@ -261,6 +289,14 @@ func (m *testMockCommander) Output(
 		return []byte("3.000000"), []byte(""), nil
 	}
 
+	// Handle ffmpeg runs writing a WAV: the output path is the last arg
+	if name == "ffmpeg" && len(args) > 0 &&
+		filepath.Ext(args[len(args)-1]) == constants.FileExtensionWAV {
+		err := os.WriteFile(args[len(args)-1], []byte("fake wav"), 0o600)
+
+		return nil, nil, err
+	}
+
 	return nil, nil, ctxerrors.New(
 		"unexpected command: " + name +
 			" with args: " + fmt.Sprintf("%v", args),
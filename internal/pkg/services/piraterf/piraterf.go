@@ -5,6 +5,7 @@ import (
 	"context"
 	"os"
 	"path"
+	"strconv"
 	"sync"
 	"text/template"
 
@@ -17,24 +18,27 @@ import (
 )
 
 const (
-	ServiceName       = "PIrateRF"
-	audioFilesDir     = "audio"
-	uploadsSubdir     = "uploads"
-	audioSFXDir       = "sfx"
-	audioUploadsPath  = audioFilesDir + "/" + uploadsSubdir
-	imagesFilesDir    = "images"
-	imagesUploadsPath = imagesFilesDir + "/" + uploadsSubdir
-	dataFilesDir      = "data"
-	dataUploadsPath   = dataFilesDir + "/" + uploadsSubdir
-	iqsFilesDir       = "iqs"
-	iqsUploadsPath    = iqsFilesDir + "/" + uploadsSubdir
-	presetsDir        = "presets"
-	envJSFilename     = "env.js"
-	envJSTemplate     = `window.PIrateRFConfig = {
+	ServiceName         = "PIrateRF"
+	audioFilesDir       = "audio"
+	uploadsSubdir       = "uploads"
+	audioSFXDir         = "sfx"
+	audioUploadsPath    = audioFilesDir + "/" + uploadsSubdir
+	recordingsDir       = "recordings"
+	audioRecordingsPath = audioFilesDir + "/" + recordingsDir
+	imagesFilesDir      = "images"
+	imagesUploadsPath   = imagesFilesDir + "/" + uploadsSubdir
+	dataFilesDir        = "data"
+	dataUploadsPath     = dataFilesDir + "/" + uploadsSubdir
+	iqsFilesDir         = "iqs"
+	iqsUploadsPath      = iqsFilesDir + "/" + uploadsSubdir
+	presetsDir          = "presets"
+	envJSFilename       = "env.js"
+	envJSTemplate       = `window.PIrateRFConfig = {
   paths: {
     files: "/files",
     audioUploadFiles: "/files/` + audioFilesDir + `/` + uploadsSubdir + `",
     audioSFXFiles: "/files/` + audioFilesDir + `/` + audioSFXDir + `",
+    audioRecordingFiles: "/files/` + audioRecordingsPath + `",
     imageUploadFiles: "/files/` + imagesFilesDir + `/` + uploadsSubdir + `",
     dataUploadFiles: "/files/` + dataFilesDir + `/` + uploadsSubdir + `",
     iqUploadFiles: "/files/` + iqsFilesDir + `/` + uploadsSubdir + `",
@@ -44,6 +48,7 @@ const (
     audioFiles: "` + audioFilesDir + `",
     audioUploads: "` + audioFilesDir + `/` + uploadsSubdir + `",
     audioSFX: "` + audioFilesDir + `/` + audioSFXDir + `",
+    audioRecordings: "` + audioRecordingsPath + `",
     imageFiles: "` + imagesFilesDir + `",
     imageUploads: "` + imagesFilesDir + `/` + uploadsSubdir + `",
     dataFiles: "` + dataFilesDir + `",
@@ -55,12 +60,16 @@ const (
     audioUploads: "{{.FilesDir}}/` + audioFilesDir + `/` +
 		uploadsSubdir + `",
     audioSFX: "{{.FilesDir}}/` + audioFilesDir + `/` + audioSFXDir + `",
+    audioRecordings: "{{.FilesDir}}/` + audioRecordingsPath + `",
     imageUploads: "{{.FilesDir}}/` + imagesFilesDir + `/` +
 		uploadsSubdir + `",
     dataUploads: "{{.FilesDir}}/` + dataFilesDir + `/` +
 		uploadsSubdir + `",
     iqUploads: "{{.FilesDir}}/` + iqsFilesDir + `/` +
 		uploadsSubdir + `"
+  },
+  audio: {
+    preserveStereo: {{.PreserveStereo}}
   }
 };
 `
@@ -68,6 +77,9 @@ const (
 	audioSampleRate = "48000" // 48kHz sample rate
 	audioBitDepth   = "16"    // 16-bit depth
 	audioChannels   = "1"     // mono (1 channel)
+	// audioChannelsStereo is for uploads kept in stereo and what playlists
+	// with any of them in get.
+	audioChannelsStereo = "2"
 
 	// File and directory permissions.
 	// readable/executable by all for web serving.
@@ -81,6 +93,12 @@ type PIrateRF struct {
 	httpServer       *server.Server
 	websocketHub     wshub.Hub
 	executionManager *executionManager
+	scheduler        *scheduler
+	playlists        *playlistStore
+	history          *historyStore
+	bandPlan         *bandPlan
+	sendiqLive       sendiqLiveState
+	liveAudio        liveAudioState
 	commander        commander.Commander
 	serviceCtx       context.Context //nolint:containedctx
 	// need service ctx to pass down to process execution
@@ -119,8 +137,45 @@ func NewWithConfig(config Config) (*PIrateRF, error) {
 		return nil, ctxerrors.Wrap(err, "failed to generate env.js config")
 	}
 
+	bandPlan, err := loadBandPlan(path.Join(s.config.FilesDir, bandPlanFilename))
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to load band plan")
+	}
+
+	s.bandPlan = bandPlan
+
+	s.history = newHistoryStore(s.config.HistoryDir)
+
+	// The history used to live in FilesDir, which is served over HTTP
+	err = s.history.load(path.Join(s.config.FilesDir, historyFilename))
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to load execution history")
+	}
+
 	s.setupWebsocketHub()
 	s.executionManager = newExecutionManager(s.rpitx, s.websocketHub)
+	s.executionManager.history = s.history
+	s.executionManager.outputFlushInterval = s.config.OutputFlushInterval
+	s.scheduler = newScheduler(
+		path.Join(s.config.FilesDir, schedulesFilename),
+		s.websocketHub,
+		s.runScheduledPreset,
+	)
+	s.executionManager.scheduledClient = s.scheduler.client
+	s.executionManager.scheduleErrorFn = s.scheduler.sendErrorEvent
+
+	if err := s.scheduler.load(); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to load schedules")
+	}
+
+	s.playlists = newPlaylistStore(
+		path.Join(s.config.FilesDir, playlistsFilename),
+		path.Join(s.config.FilesDir, audioFilesDir),
+	)
+
+	if err := s.playlists.load(); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to load playlists")
+	}
 
 	if err := s.setupHTTPServer(); err != nil {
 		return nil, ctxerrors.Wrap(err, "could not setup http server")
@@ -145,6 +200,9 @@ func (s *PIrateRF) Run(ctx context.Context) error {
 		}
 	}()
 
+	go s.scheduler.run(ctx)
+	go s.backfillAudioPeaks(ctx)
+
 	router, err := s.getHTTPServerRouter()
 	if err != nil {
 		return ctxerrors.Wrap(err, "failed to get HTTP server router")
@@ -178,6 +236,17 @@ func (s *PIrateRF) Stop(ctx context.Context) error {
 
 		close(s.doneCh)
 
+		// Get off air while the hub is still up to tell everyone about it.
+		// ctx is usually already cancelled by the time we get here.
+		shutdownCtx, cancel := context.WithTimeout(
+			context.WithoutCancel(ctx), shutdownTimeout,
+		)
+		defer cancel()
+
+		if err := s.executionManager.shutdown(shutdownCtx); err != nil {
+			logrus.Errorf("failed to stop active transmission: %v", err)
+		}
+
 		if err := s.httpServer.Stop(ctx); err != nil {
 			logrus.Errorf("failed to stop http server: %v", err)
 		}
@@ -208,6 +277,7 @@ func (s *PIrateRF) ensureFilesDirsExist() error {
 		{[]string{audioFilesDir}, "audio directory"},
 		{[]string{audioFilesDir, uploadsSubdir}, "audio uploads directory"},
 		{[]string{audioFilesDir, audioSFXDir}, "audio SFX directory"},
+		{[]string{audioRecordingsPath}, "audio recordings directory"},
 		{[]string{imagesFilesDir}, "images directory"},
 		{[]string{imagesFilesDir, uploadsSubdir}, "images uploads directory"},
 		{[]string{dataFilesDir}, "data directory"},
@@ -251,7 +321,8 @@ func (s *PIrateRF) generateEnvJS() error {
 	var buf bytes.Buffer
 
 	templateData := map[string]string{
-		"FilesDir": s.config.FilesDir,
+		"FilesDir":       s.config.FilesDir,
+		"PreserveStereo": strconv.FormatBool(s.config.PreserveStereo),
 	}
 
 	if err := tmpl.Execute(&buf, templateData); err != nil {
@@ -399,6 +399,7 @@ func TestPIrateRF_generateEnvJS(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Contains(t, string(content), "window.PIrateRFConfig")
 				assert.Contains(t, string(content), config.FilesDir)
+				assert.Contains(t, string(content), "preserveStereo: false")
 			},
 		},
 	}
@@ -0,0 +1,524 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	playlistsFilename = "playlists.json"
+
+	// playlistAudioPrefix marks a PIFMRDS audio arg that names a playlist
+	// instead of a file, like "playlist:<id>".
+	playlistAudioPrefix = "playlist:"
+
+	playlistMaxNameLength = 64
+	playlistMinGain       = -30.0 // dB
+	playlistMaxGain       = 12.0  // dB
+)
+
+// playlistItem is one file of a playlist. File is relative to files/audio
+// so playlists survive the files dir moving around.
+type playlistItem struct {
+	File string  `json:"file"`
+	Gain float64 `json:"gain"` // dB
+}
+
+// playlist is an ordered list of audio files that only becomes a WAV when
+// it goes on air.
+type playlist struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	Items     []playlistItem `json:"items"`
+	CreatedAt int64          `json:"createdAt"`
+	UpdatedAt int64          `json:"updatedAt"`
+}
+
+// playlistStore keeps the playlists on disk. Every change is saved right
+// away and rolled back when saving fails.
+type playlistStore struct {
+	filePath  string
+	audioDir  string
+	playlists []*playlist
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+func newPlaylistStore(filePath, audioDir string) *playlistStore {
+	return &playlistStore{
+		filePath: filePath,
+		audioDir: audioDir,
+		now:      time.Now,
+	}
+}
+
+// load reads the persisted playlists. A missing file means no playlists.
+func (ps *playlistStore) load() error {
+	data, err := os.ReadFile(ps.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return ctxerrors.Wrap(err, "failed to read playlists file")
+	}
+
+	var playlists []*playlist
+	if err := json.Unmarshal(data, &playlists); err != nil {
+		return ctxerrors.Wrap(err, "failed to parse playlists file")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.playlists = playlists
+
+	logrus.Infof("Loaded %d playlists", len(ps.playlists))
+
+	return nil
+}
+
+// save writes the playlists to disk. Caller must hold mu.
+func (ps *playlistStore) save() error {
+	data, err := json.MarshalIndent(ps.playlists, "", "  ")
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal playlists")
+	}
+
+	// Write to a temp file first so a crash never leaves a half written file
+	tmpPath := ps.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, filePerms); err != nil {
+		return ctxerrors.Wrap(err, "failed to write playlists file")
+	}
+
+	if err := os.Rename(tmpPath, ps.filePath); err != nil {
+		return ctxerrors.Wrap(err, "failed to replace playlists file")
+	}
+
+	return nil
+}
+
+func (ps *playlistStore) list() []playlist {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	playlists := make([]playlist, 0, len(ps.playlists))
+	for _, pl := range ps.playlists {
+		playlists = append(playlists, pl.clone())
+	}
+
+	return playlists
+}
+
+func (ps *playlistStore) get(id uuid.UUID) (playlist, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	idx, err := ps.index(id)
+	if err != nil {
+		return playlist{}, err
+	}
+
+	return ps.playlists[idx].clone(), nil
+}
+
+func (ps *playlistStore) create(
+	name string,
+	items []playlistItem,
+) (playlist, error) {
+	items, err := ps.resolveItems(items)
+	if err != nil {
+		return playlist{}, err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.validateName(name, uuid.Nil); err != nil {
+		return playlist{}, err
+	}
+
+	now := ps.now().Unix()
+	pl := &playlist{
+		ID:        uuid.New(),
+		Name:      name,
+		Items:     items,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	ps.playlists = append(ps.playlists, pl)
+
+	if err := ps.save(); err != nil {
+		ps.playlists = ps.playlists[:len(ps.playlists)-1]
+
+		return playlist{}, err
+	}
+
+	return pl.clone(), nil
+}
+
+// update replaces the items of a playlist.
+func (ps *playlistStore) update(
+	id uuid.UUID,
+	items []playlistItem,
+) (playlist, error) {
+	items, err := ps.resolveItems(items)
+	if err != nil {
+		return playlist{}, err
+	}
+
+	return ps.modify(id, func(pl *playlist) error {
+		pl.Items = items
+
+		return nil
+	})
+}
+
+func (ps *playlistStore) rename(id uuid.UUID, name string) (playlist, error) {
+	return ps.modify(id, func(pl *playlist) error {
+		if err := ps.validateName(name, id); err != nil {
+			return err
+		}
+
+		pl.Name = name
+
+		return nil
+	})
+}
+
+// reorder moves the item at index to position, both zero-based.
+func (ps *playlistStore) reorder(
+	id uuid.UUID,
+	index, position int,
+) (playlist, error) {
+	return ps.modify(id, func(pl *playlist) error {
+		count := len(pl.Items)
+		if index < 0 || index >= count || position < 0 || position >= count {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"index and position must be between 0 and %d",
+				count-1,
+			)
+		}
+
+		item := pl.Items[index]
+		pl.Items = slices.Delete(pl.Items, index, index+1)
+		pl.Items = slices.Insert(pl.Items, position, item)
+
+		return nil
+	})
+}
+
+func (ps *playlistStore) remove(id uuid.UUID) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	idx, err := ps.index(id)
+	if err != nil {
+		return err
+	}
+
+	removed := ps.playlists[idx]
+	ps.playlists = slices.Delete(ps.playlists, idx, idx+1)
+
+	if err := ps.save(); err != nil {
+		ps.playlists = slices.Insert(ps.playlists, idx, removed)
+
+		return err
+	}
+
+	return nil
+}
+
+// modify applies change to a copy of the playlist and keeps it only when
+// it's valid and saved.
+func (ps *playlistStore) modify(
+	id uuid.UUID,
+	change func(pl *playlist) error,
+) (playlist, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	idx, err := ps.index(id)
+	if err != nil {
+		return playlist{}, err
+	}
+
+	original := ps.playlists[idx]
+	modified := original.clone()
+
+	if err := change(&modified); err != nil {
+		return playlist{}, err
+	}
+
+	modified.UpdatedAt = ps.now().Unix()
+	ps.playlists[idx] = &modified
+
+	if err := ps.save(); err != nil {
+		ps.playlists[idx] = original
+
+		return playlist{}, err
+	}
+
+	return modified.clone(), nil
+}
+
+// index returns where the playlist is. Caller must hold mu.
+func (ps *playlistStore) index(id uuid.UUID) (int, error) {
+	idx := slices.IndexFunc(ps.playlists, func(pl *playlist) bool {
+		return pl.ID == id
+	})
+	if idx < 0 {
+		return 0, ctxerrors.Wrapf(commonerrors.ErrNotFound, "playlist %s", id)
+	}
+
+	return idx, nil
+}
+
+// validateName checks a name is usable and not taken by another playlist
+// than self. Caller must hold mu.
+func (ps *playlistStore) validateName(name string, self uuid.UUID) error {
+	if strings.TrimSpace(name) == "" {
+		return ctxerrors.Wrap(commonerrors.ErrRequiredFieldNotSet, "name")
+	}
+
+	if utf8.RuneCountInString(name) > playlistMaxNameLength {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"name is longer than %d characters",
+			playlistMaxNameLength,
+		)
+	}
+
+	for _, pl := range ps.playlists {
+		if pl.ID != self && pl.Name == name {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"playlist %s already exists",
+				name,
+			)
+		}
+	}
+
+	return nil
+}
+
+// resolveItems checks every item points at an existing file under
+// files/audio and has a sane gain, and stores its path relative to it.
+func (ps *playlistStore) resolveItems(
+	items []playlistItem,
+) ([]playlistItem, error) {
+	resolved := make([]playlistItem, 0, len(items))
+
+	for i, item := range items {
+		file, err := ps.relativeAudioPath(item.File)
+		if err != nil {
+			return nil, ctxerrors.Wrapf(err, "item %d", i)
+		}
+
+		if _, err := os.Stat(filepath.Join(ps.audioDir, file)); err != nil {
+			return nil, ctxerrors.Wrapf(
+				commonerrors.ErrFileNotFound,
+				"item %d: %s",
+				i,
+				item.File,
+			)
+		}
+
+		if item.Gain < playlistMinGain || item.Gain > playlistMaxGain {
+			return nil, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"item %d: gain must be between %g and %g dB",
+				i,
+				playlistMinGain,
+				playlistMaxGain,
+			)
+		}
+
+		resolved = append(resolved, playlistItem{File: file, Gain: item.Gain})
+	}
+
+	return resolved, nil
+}
+
+// relativeAudioPath takes a file the way the UI knows it - an HTTP path
+// like /files/audio/uploads/a.wav, a server path or one already relative
+// to files/audio - and returns it relative to files/audio. Anything outside
+// of it is refused.
+func (ps *playlistStore) relativeAudioPath(file string) (string, error) {
+	invalid := ctxerrors.Wrapf(
+		commonerrors.ErrInvalidValue,
+		"not an audio file: %s",
+		file,
+	)
+
+	audioDir := filepath.Clean(ps.audioDir)
+	httpPrefix := "/files/" + audioFilesDir + "/"
+	inAudioDir := strings.HasPrefix(
+		filepath.Clean(file),
+		audioDir+string(filepath.Separator),
+	)
+
+	switch {
+	case strings.HasPrefix(file, httpPrefix):
+		file = strings.TrimPrefix(file, httpPrefix)
+	case filepath.IsAbs(file) || inAudioDir:
+		absAudioDir, err := filepath.Abs(audioDir)
+		if err != nil {
+			return "", invalid
+		}
+
+		absFile, err := filepath.Abs(file)
+		if err != nil {
+			return "", invalid
+		}
+
+		rel, err := filepath.Rel(absAudioDir, absFile)
+		if err != nil {
+			return "", invalid
+		}
+
+		file = rel
+	}
+
+	file = filepath.Clean(file)
+	if file == "." || file == ".." || filepath.IsAbs(file) ||
+		strings.HasPrefix(file, ".."+string(filepath.Separator)) {
+		return "", invalid
+	}
+
+	return filepath.ToSlash(file), nil
+}
+
+// expandPlaylistAudio renders the playlist a PIFMRDS audio arg names into a
+// temporary WAV in dir with every item at its gain, joined with the
+// transitions. It returns an empty path when the audio arg is a plain file.
+func (s *PIrateRF) expandPlaylistAudio(
+	audio string,
+	transitions playlistTransitions,
+	dir string,
+	logger *logrus.Entry,
+) (string, error) {
+	pl, ok, err := s.playlistFromAudio(audio)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	if len(pl.Items) == 0 {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"playlist %s is empty",
+			pl.Name,
+		)
+	}
+
+	// sox applies -v to the input right after it
+	inputs := make([]string, 0, len(pl.Items)*3) //nolint:mnd
+	for _, item := range pl.Items {
+		if item.Gain != 0 {
+			inputs = append(inputs, "-v", strconv.FormatFloat(
+				math.Pow(10, item.Gain/decibelsPerAmplitudeDecade),
+				'f', 4, 64,
+			))
+		}
+
+		inputs = append(inputs, filepath.Join(s.playlists.audioDir, item.File))
+	}
+
+	logger.WithFields(logrus.Fields{
+		"playlistID":   pl.ID,
+		"playlistName": pl.Name,
+		"items":        len(pl.Items),
+	}).Debug("Expanding playlist")
+
+	playlistPath, err := s.createPlaylistFromFiles(
+		uuid.New().String()+constants.FileExtensionWAV,
+		inputs,
+		transitions,
+		dir,
+	)
+	if err != nil {
+		return "", ctxerrors.Wrapf(err, "failed to expand playlist %s", pl.Name)
+	}
+
+	return playlistPath, nil
+}
+
+// playlistDuration adds up the durations of the playlist items joined with
+// the transitions.
+func (s *PIrateRF) playlistDuration(
+	pl playlist,
+	transitions playlistTransitions,
+) (float64, error) {
+	durations := make([]float64, 0, len(pl.Items))
+
+	for _, item := range pl.Items {
+		duration, err := s.getAudioDuration(
+			filepath.Join(s.playlists.audioDir, item.File),
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		durations = append(durations, duration)
+	}
+
+	return transitions.joinedDuration(durations), nil
+}
+
+// playlistFromAudio returns the playlist a PIFMRDS audio arg names, if it
+// names one.
+func (s *PIrateRF) playlistFromAudio(audio string) (playlist, bool, error) {
+	id, ok, err := parsePlaylistAudio(audio)
+	if err != nil || !ok {
+		return playlist{}, ok, err
+	}
+
+	pl, err := s.playlists.get(id)
+	if err != nil {
+		return playlist{}, true, err
+	}
+
+	return pl, true, nil
+}
+
+func (pl playlist) clone() playlist {
+	pl.Items = slices.Clone(pl.Items)
+
+	return pl
+}
+
+// parsePlaylistAudio returns the playlist ID a PIFMRDS audio arg names.
+func parsePlaylistAudio(audio string) (uuid.UUID, bool, error) {
+	ref, ok := strings.CutPrefix(audio, playlistAudioPrefix)
+	if !ok {
+		return uuid.Nil, false, nil
+	}
+
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		return uuid.Nil, true, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid playlist reference: %s",
+			audio,
+		)
+	}
+
+	return id, true, nil
+}
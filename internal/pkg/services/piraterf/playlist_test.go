@@ -0,0 +1,278 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPlaylistStore returns a store over a files dir holding
+// audio/uploads/a.wav, audio/uploads/b.wav and audio/sfx/c.wav.
+func newTestPlaylistStore(t *testing.T) (*playlistStore, string) {
+	t.Helper()
+
+	filesDir := t.TempDir()
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+
+	for _, file := range []string{"uploads/a.wav", "uploads/b.wav", "sfx/c.wav"} {
+		path := filepath.Join(audioDir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte("wav"), 0o600))
+	}
+
+	store := newPlaylistStore(
+		filepath.Join(filesDir, playlistsFilename),
+		audioDir,
+	)
+	store.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	return store, filesDir
+}
+
+func TestPlaylistStore_CRUD(t *testing.T) {
+	store, filesDir := newTestPlaylistStore(t)
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+
+	pl, err := store.create("morning show", []playlistItem{
+		{File: "/files/audio/uploads/a.wav"},
+		{File: filepath.Join(audioDir, "uploads", "b.wav"), Gain: -3},
+		{File: "sfx/c.wav", Gain: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []playlistItem{
+		{File: "uploads/a.wav"},
+		{File: "uploads/b.wav", Gain: -3},
+		{File: "sfx/c.wav", Gain: 2},
+	}, pl.Items)
+	assert.Equal(t, int64(1700000000), pl.CreatedAt)
+
+	_, err = store.create("morning show", nil)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue, "names are unique")
+
+	pl, err = store.reorder(pl.ID, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "sfx/c.wav", pl.Items[0].File)
+	assert.Equal(t, "uploads/b.wav", pl.Items[2].File)
+
+	_, err = store.reorder(pl.ID, 3, 0)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	pl, err = store.rename(pl.ID, "evening show")
+	require.NoError(t, err)
+	assert.Equal(t, "evening show", pl.Name)
+
+	_, err = store.rename(pl.ID, "  ")
+	require.ErrorIs(t, err, commonerrors.ErrRequiredFieldNotSet)
+
+	pl, err = store.update(pl.ID, []playlistItem{{File: "uploads/b.wav"}})
+	require.NoError(t, err)
+	assert.Len(t, pl.Items, 1)
+
+	// Everything made it to disk
+	reloaded := newPlaylistStore(store.filePath, store.audioDir)
+	require.NoError(t, reloaded.load())
+	require.Len(t, reloaded.list(), 1)
+	assert.Equal(t, pl, reloaded.list()[0])
+
+	require.NoError(t, store.remove(pl.ID))
+	assert.Empty(t, store.list())
+
+	require.ErrorIs(t, store.remove(pl.ID), commonerrors.ErrNotFound)
+	_, err = store.get(pl.ID)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestPlaylistStore_ResolveItems(t *testing.T) {
+	store, _ := newTestPlaylistStore(t)
+
+	tests := []struct {
+		name        string
+		item        playlistItem
+		expectError error
+	}{
+		{
+			name:        "missing file",
+			item:        playlistItem{File: "uploads/nope.wav"},
+			expectError: commonerrors.ErrFileNotFound,
+		},
+		{
+			name:        "outside the audio dir",
+			item:        playlistItem{File: "../playlists.json"},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "absolute path outside the audio dir",
+			item:        playlistItem{File: "/etc/passwd"},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "gain too high",
+			item:        playlistItem{File: "uploads/a.wav", Gain: 40},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.resolveItems([]playlistItem{tt.item})
+			require.ErrorIs(t, err, tt.expectError)
+		})
+	}
+}
+
+func TestPlaylistStore_LoadMissingFile(t *testing.T) {
+	store, _ := newTestPlaylistStore(t)
+
+	require.NoError(t, store.load())
+	assert.Empty(t, store.list())
+
+	require.NoError(t, os.WriteFile(store.filePath, []byte("nope"), 0o600))
+	require.Error(t, store.load())
+}
+
+func TestParsePlaylistAudio(t *testing.T) {
+	id := uuid.New()
+
+	parsed, ok, err := parsePlaylistAudio(playlistAudioPrefix + id.String())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, id, parsed)
+
+	_, ok, err = parsePlaylistAudio("/files/audio/uploads/a.wav")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = parsePlaylistAudio(playlistAudioPrefix + "nope")
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+	assert.True(t, ok)
+}
+
+func TestExpandPlaylistAudio(t *testing.T) {
+	store, filesDir := newTestPlaylistStore(t)
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+
+	pl, err := store.create("show", []playlistItem{
+		{File: "uploads/a.wav"},
+		{File: "uploads/b.wav", Gain: -6},
+	})
+	require.NoError(t, err)
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(filepath.Join(audioDir, "uploads", "a.wav")),
+		commander.Exact("-v"), commander.Exact("0.5012"),
+		commander.Exact(filepath.Join(audioDir, "uploads", "b.wav")),
+		commander.Exact("-r"), commander.Exact(audioSampleRate),
+		commander.Exact("-b"), commander.Exact(audioBitDepth),
+		commander.Exact("-c"), commander.Exact(audioChannels),
+		commander.Regex(`^/tmp/.+\.wav$`),
+	)
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: filesDir},
+		commander:  mock,
+		playlists:  store,
+	}
+	logger := logrus.NewEntry(logrus.New())
+
+	expanded, err := service.expandPlaylistAudio(
+		playlistAudioPrefix+pl.ID.String(),
+		playlistTransitions{},
+		executionTempDir,
+		logger,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mock.VerifyExpectations())
+	assert.FileExists(t, expanded)
+
+	t.Cleanup(func() { _ = os.Remove(expanded) })
+
+	// Plain files are left alone
+	expanded, err = service.expandPlaylistAudio(
+		"/tmp/a.wav", playlistTransitions{}, executionTempDir, logger,
+	)
+	require.NoError(t, err)
+	assert.Empty(t, expanded)
+
+	// Empty and unknown playlists can't go on air
+	empty, err := store.create("empty", nil)
+	require.NoError(t, err)
+
+	_, err = service.expandPlaylistAudio(
+		playlistAudioPrefix+empty.ID.String(),
+		playlistTransitions{},
+		executionTempDir,
+		logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+
+	_, err = service.expandPlaylistAudio(
+		playlistAudioPrefix+uuid.New().String(),
+		playlistTransitions{},
+		executionTempDir,
+		logger,
+	)
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestProcessAudioModifications_Playlist(t *testing.T) {
+	store, filesDir := newTestPlaylistStore(t)
+
+	pl, err := store.create("show", []playlistItem{
+		{File: "uploads/a.wav"},
+		{File: "uploads/b.wav"},
+	})
+	require.NoError(t, err)
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: filesDir},
+		commander:  &testMockCommander{tempDir: t.TempDir()},
+		playlists:  store,
+	}
+
+	msg := rpitxExecutionStartMessage{
+		Args: json.RawMessage(
+			`{"freq":107.9,"audio":"` + playlistAudioPrefix + pl.ID.String() +
+				`"}`,
+		),
+		PlayOnce: true,
+	}
+
+	timeout, tempPaths, finalArgs, err := service.processAudioModifications(
+		msg, 0, logrus.NewEntry(logrus.New()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		for _, path := range tempPaths {
+			_ = os.Remove(path)
+		}
+	})
+
+	// The expanded playlist and its silence padded copy
+	require.Len(t, tempPaths, 2)
+	assert.Positive(t, timeout)
+
+	var args map[string]any
+	require.NoError(t, json.Unmarshal(finalArgs, &args))
+	assert.Equal(t, tempPaths[1], args["audio"])
+
+	// Both items count towards the Play Once progress
+	timeline, err := service.playOnceTimeline(msg, timeout)
+	require.NoError(t, err)
+	assert.Equal(t, []playbackItem{{name: playbackItemMain, duration: 6}},
+		timeline.items)
+}
@@ -0,0 +1,342 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+)
+
+// Preset form fields that are not module args.
+const (
+	presetFieldTimeout  = "timeout"
+	presetFieldPlayOnce = "playOnce"
+	presetFieldIntro    = "introSelect"
+	presetFieldOutro    = "outroSelect"
+	presetFieldAutoRT   = "autoRT"
+	presetFieldAutoPS   = "autoPS"
+
+	// Some forms store the frequency as "freq" while the module expects
+	// "frequency".
+	presetFieldFreq      = "freq"
+	presetFieldFrequency = "frequency"
+)
+
+// moduleArgsType returns the gorpitx args struct type for a module.
+func moduleArgsType(moduleName gorpitx.ModuleName) (reflect.Type, bool) {
+	var args any
+
+	switch moduleName {
+	case gorpitx.ModuleNamePIFMRDS:
+		args = gorpitx.PIFMRDS{}
+	case gorpitx.ModuleNameTUNE:
+		args = gorpitx.TUNE{}
+	case gorpitx.ModuleNameMORSE:
+		args = gorpitx.MORSE{}
+	case gorpitx.ModuleNameSPECTRUMPAINT:
+		args = gorpitx.SPECTRUMPAINT{}
+	case gorpitx.ModuleNamePICHIRP:
+		args = gorpitx.PICHIRP{}
+	case gorpitx.ModuleNamePOCSAG:
+		args = gorpitx.POCSAG{}
+	case gorpitx.ModuleNameFT8:
+		args = gorpitx.FT8{}
+	case gorpitx.ModuleNamePISSSTV:
+		args = gorpitx.PISSTV{}
+	case gorpitx.ModuleNamePIRTTY:
+		args = gorpitx.PIRTTY{}
+	case gorpitx.ModuleNameFSK:
+		args = gorpitx.FSK{}
+	case gorpitx.ModuleNameAudioSockBroadcast:
+		args = audioSockArgs{}
+	case gorpitx.ModuleNameSENDIQ:
+		args = gorpitx.SENDIQ{}
+	default:
+		return nil, false
+	}
+
+	return reflect.TypeOf(args), true
+}
+
+// presetToExecutionMessage turns a saved preset into an execution start
+// message. Presets saved by the frontend hold raw form state (every value
+// is a string) so values are converted to whatever the module args struct
+// expects. Presets that already look like an rpitx.execution.start payload
+// (they have an "args" object) are used as-is.
+func presetToExecutionMessage(
+	moduleName gorpitx.ModuleName,
+	presetData []byte,
+) (*rpitxExecutionStartMessage, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(presetData, &raw); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to parse preset JSON")
+	}
+
+	if _, ok := raw["args"]; ok {
+		var msg rpitxExecutionStartMessage
+		if err := json.Unmarshal(presetData, &msg); err != nil {
+			return nil, ctxerrors.Wrap(err, "failed to parse preset message")
+		}
+
+		msg.ModuleName = moduleName
+
+		return &msg, nil
+	}
+
+	argsType, ok := moduleArgsType(moduleName)
+	if !ok {
+		return nil, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"unknown module: %s",
+			moduleName,
+		)
+	}
+
+	var formState map[string]any
+	if err := json.Unmarshal(presetData, &formState); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to parse preset form state")
+	}
+
+	msg := &rpitxExecutionStartMessage{ModuleName: moduleName}
+
+	if err := applyPresetExecutionFields(msg, formState); err != nil {
+		return nil, err
+	}
+
+	args, err := json.Marshal(convertFormFields(formState, argsType))
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to marshal preset args")
+	}
+
+	msg.Args = args
+
+	return msg, nil
+}
+
+// applyPresetExecutionFields moves the non-arg form fields (timeout,
+// play once, auto RDS, intro/outro, transitions) into the message and
+// removes them from the form state.
+func applyPresetExecutionFields(
+	msg *rpitxExecutionStartMessage,
+	formState map[string]any,
+) error {
+	if value, ok := formState[presetFieldTimeout]; ok {
+		delete(formState, presetFieldTimeout)
+
+		timeout, err := formValueToInt(value)
+		if err != nil {
+			return ctxerrors.Wrap(err, "invalid preset timeout")
+		}
+
+		msg.Timeout = timeout
+	}
+
+	if value, ok := formState[presetFieldPlayOnce].(bool); ok {
+		msg.PlayOnce = value
+	}
+
+	delete(formState, presetFieldPlayOnce)
+
+	if value, ok := formState[presetFieldAutoRT].(bool); ok {
+		msg.AutoRT = value
+	}
+
+	delete(formState, presetFieldAutoRT)
+
+	if value, ok := formState[presetFieldAutoPS].(bool); ok {
+		msg.AutoPS = value
+	}
+
+	delete(formState, presetFieldAutoPS)
+
+	if value, ok := formState[presetFieldIntro].(string); ok && value != "" {
+		msg.Intro = &value
+	}
+
+	delete(formState, presetFieldIntro)
+
+	if value, ok := formState[presetFieldOutro].(string); ok && value != "" {
+		msg.Outro = &value
+	}
+
+	delete(formState, presetFieldOutro)
+
+	return applyPresetTransitions(msg, formState)
+}
+
+// applyPresetTransitions moves the playlist transition fields (crossfade,
+// gap, fadeIn, fadeOut) into the message and removes them from the form
+// state.
+func applyPresetTransitions(
+	msg *rpitxExecutionStartMessage,
+	formState map[string]any,
+) error {
+	transitionsType := reflect.TypeFor[playlistTransitions]()
+
+	transitions, err := json.Marshal(
+		convertFormFields(formState, transitionsType),
+	)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal preset transitions")
+	}
+
+	for field := range jsonFieldTypes(transitionsType) {
+		delete(formState, field)
+	}
+
+	if err := json.Unmarshal(transitions, &msg.playlistTransitions); err != nil {
+		return ctxerrors.Wrap(err, "invalid preset transitions")
+	}
+
+	return nil
+}
+
+func formValueToInt(value any) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return 0, nil
+		}
+
+		parsed, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue, "not a number: %q", v,
+			)
+		}
+
+		return parsed, nil
+	default:
+		return 0, ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue, "unexpected value type %T", value,
+		)
+	}
+}
+
+// convertFormFields converts form state values to the types of the
+// matching json fields of the given struct type. Empty strings and fields
+// the struct doesn't know about are dropped.
+func convertFormFields(
+	formState map[string]any,
+	structType reflect.Type,
+) map[string]any {
+	fieldTypes := jsonFieldTypes(structType)
+	result := make(map[string]any, len(formState))
+
+	for key, value := range formState {
+		fieldType, ok := fieldTypes[key]
+		if !ok && key == presetFieldFreq {
+			key = presetFieldFrequency
+			fieldType, ok = fieldTypes[key]
+		}
+
+		if !ok {
+			continue
+		}
+
+		converted, keep := convertFormValue(value, fieldType)
+		if !keep {
+			continue
+		}
+
+		result[key] = converted
+	}
+
+	return result
+}
+
+func convertFormValue(value any, targetType reflect.Type) (any, bool) {
+	for targetType.Kind() == reflect.Pointer {
+		targetType = targetType.Elem()
+	}
+
+	switch v := value.(type) {
+	case string:
+		return convertFormString(v, targetType)
+	case []any:
+		if targetType.Kind() != reflect.Slice {
+			return v, true
+		}
+
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			if converted, keep := convertFormValue(
+				item, targetType.Elem(),
+			); keep {
+				items = append(items, converted)
+			}
+		}
+
+		return items, true
+	case map[string]any:
+		if targetType.Kind() != reflect.Struct {
+			return v, true
+		}
+
+		return convertFormFields(v, targetType), true
+	default:
+		return value, true
+	}
+}
+
+func convertFormString(value string, targetType reflect.Type) (any, bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return nil, false
+	}
+
+	switch targetType.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+			// Leave it as a string and let the module report it
+			return value, true
+		}
+
+		return json.Number(trimmed), true
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return value, true
+		}
+
+		return parsed, true
+	default:
+		return value, true
+	}
+}
+
+// jsonFieldTypes maps json field names of a struct to their types.
+func jsonFieldTypes(structType reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, structType.NumField())
+
+	for i := range structType.NumField() {
+		field := structType.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		// Embedded structs get their fields flattened in like JSON does
+		if field.Anonymous && name == "" &&
+			field.Type.Kind() == reflect.Struct {
+			maps.Copy(fields, jsonFieldTypes(field.Type))
+
+			continue
+		}
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
@@ -0,0 +1,188 @@
+package piraterf
+
+import (
+	"testing"
+
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresetToExecutionMessage(t *testing.T) {
+	tests := []struct {
+		name            string
+		moduleName      gorpitx.ModuleName
+		preset          string
+		expectedArgs    string
+		expectedTimeout int
+		expectedIntro   string
+		expectError     bool
+	}{
+		{
+			name:       "pifmrds form state",
+			moduleName: gorpitx.ModuleNamePIFMRDS,
+			preset: `{
+				"freq": "107.9",
+				"audio": "/files/audio/uploads/song.wav",
+				"pi": "1234",
+				"ps": "PIRATE",
+				"rt": "",
+				"ppm": "",
+				"timeout": "60",
+				"introSelect": "/files/audio/sfx/intro.wav",
+				"outroSelect": ""
+			}`,
+			expectedArgs: `{
+				"freq": 107.9,
+				"audio": "/files/audio/uploads/song.wav",
+				"pi": "1234",
+				"ps": "PIRATE"
+			}`,
+			expectedTimeout: 60,
+			expectedIntro:   "/files/audio/sfx/intro.wav",
+		},
+		{
+			name:         "freq alias for frequency",
+			moduleName:   gorpitx.ModuleNameTUNE,
+			preset:       `{"freq": "144500000", "ppm": "1.5"}`,
+			expectedArgs: `{"frequency": 144500000, "ppm": 1.5}`,
+		},
+		{
+			name:       "nested pocsag messages",
+			moduleName: gorpitx.ModuleNamePOCSAG,
+			preset: `{
+				"frequency": "152000000",
+				"numericMode": false,
+				"messages": [
+					{"address": "123", "message": "hello", "functionBits": ""}
+				]
+			}`,
+			expectedArgs: `{
+				"frequency": 152000000,
+				"numericMode": false,
+				"messages": [{"address": 123, "message": "hello"}]
+			}`,
+		},
+		{
+			name:       "unknown fields are dropped",
+			moduleName: gorpitx.ModuleNameAudioSockBroadcast,
+			preset: `{
+				"frequency": "7100000",
+				"bufferSize": "4096",
+				"modulation": "USB"
+			}`,
+			expectedArgs: `{"frequency": 7100000, "modulation": "USB"}`,
+		},
+		{
+			name:       "audiosock audio file",
+			moduleName: gorpitx.ModuleNameAudioSockBroadcast,
+			preset: `{
+				"frequency": "1611000",
+				"modulation": "AM",
+				"audio": "/files/audio/uploads/net.wav",
+				"introSelect": "/files/audio/sfx/intro.wav"
+			}`,
+			expectedArgs: `{
+				"frequency": 1611000,
+				"modulation": "AM",
+				"audio": "/files/audio/uploads/net.wav"
+			}`,
+			expectedIntro: "/files/audio/sfx/intro.wav",
+		},
+		{
+			name:       "execution message shape",
+			moduleName: gorpitx.ModuleNamePICHIRP,
+			preset: `{
+				"args": {"frequency": 100000000, "bandwidth": 1000, "time": 1},
+				"timeout": 10
+			}`,
+			expectedArgs:    `{"frequency": 100000000, "bandwidth": 1000, "time": 1}`,
+			expectedTimeout: 10,
+		},
+		{
+			name:        "invalid timeout",
+			moduleName:  gorpitx.ModuleNamePIFMRDS,
+			preset:      `{"timeout": "soon"}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid json",
+			moduleName:  gorpitx.ModuleNamePIFMRDS,
+			preset:      `{`,
+			expectError: true,
+		},
+		{
+			name:        "unknown module",
+			moduleName:  "nope",
+			preset:      `{}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := presetToExecutionMessage(tt.moduleName, []byte(tt.preset))
+			if tt.expectError {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.moduleName, msg.ModuleName)
+			assert.JSONEq(t, tt.expectedArgs, string(msg.Args))
+			assert.Equal(t, tt.expectedTimeout, msg.Timeout)
+
+			if tt.expectedIntro == "" {
+				assert.Nil(t, msg.Intro)
+			} else {
+				require.NotNil(t, msg.Intro)
+				assert.Equal(t, tt.expectedIntro, *msg.Intro)
+			}
+
+			assert.Nil(t, msg.Outro)
+		})
+	}
+}
+
+func TestPresetToExecutionMessage_Transitions(t *testing.T) {
+	msg, err := presetToExecutionMessage(gorpitx.ModuleNamePIFMRDS, []byte(`{
+		"freq": "107.9",
+		"audio": "/files/audio/uploads/song.wav",
+		"crossfade": "2.5",
+		"gap": "",
+		"fadeIn": "1",
+		"fadeOut": "3"
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t,
+		playlistTransitions{Crossfade: 2.5, FadeIn: 1, FadeOut: 3},
+		msg.playlistTransitions,
+	)
+	assert.JSONEq(t,
+		`{"freq": 107.9, "audio": "/files/audio/uploads/song.wav"}`,
+		string(msg.Args),
+	)
+
+	_, err = presetToExecutionMessage(
+		gorpitx.ModuleNamePIFMRDS,
+		[]byte(`{"freq": "107.9", "crossfade": "soon"}`),
+	)
+	require.Error(t, err)
+}
+
+func TestPresetToExecutionMessage_AutoRDS(t *testing.T) {
+	msg, err := presetToExecutionMessage(gorpitx.ModuleNamePIFMRDS, []byte(`{
+		"freq": "107.9",
+		"audio": "/files/audio/uploads/song.wav",
+		"autoRT": true,
+		"autoPS": false
+	}`))
+	require.NoError(t, err)
+	assert.True(t, msg.AutoRT)
+	assert.False(t, msg.AutoPS)
+	assert.JSONEq(t,
+		`{"freq": 107.9, "audio": "/files/audio/uploads/song.wav"}`,
+		string(msg.Args),
+	)
+}
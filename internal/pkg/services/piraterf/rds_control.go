@@ -0,0 +1,273 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeRDSPSSet        dabluveees.EventType = "rds.ps.set"
+	eventTypeRDSPSSetSuccess dabluveees.EventType = "rds.ps.set.success"
+	eventTypeRDSPSSetError   dabluveees.EventType = "rds.ps.set.error"
+	eventTypeRDSRTSet        dabluveees.EventType = "rds.rt.set"
+	eventTypeRDSRTSetSuccess dabluveees.EventType = "rds.rt.set.success"
+	eventTypeRDSRTSetError   dabluveees.EventType = "rds.rt.set.error"
+
+	// Same limits gorpitx checks PS and RT against when starting pifmrds.
+	rdsPSMaxLength = 8
+	rdsRTMaxLength = 64
+
+	rdsControlPipePerms = 0o600
+	rdsControlPipeArg   = "controlPipe"
+)
+
+// rdsText describes one of the texts pifmrds can change on air.
+type rdsText struct {
+	command      string // control pipe command, "PS" or "RT"
+	maxLength    int
+	allowEmpty   bool // an empty RT clears it, an empty PS makes no sense
+	successEvent dabluveees.EventType
+	errorEvent   dabluveees.EventType
+}
+
+type rdsSetMessage struct {
+	Text string `json:"text"`
+}
+
+type rdsSetSuccessMessageData struct {
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type rdsSetErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func rdsPSText() rdsText {
+	return rdsText{
+		command:      "PS",
+		maxLength:    rdsPSMaxLength,
+		successEvent: eventTypeRDSPSSetSuccess,
+		errorEvent:   eventTypeRDSPSSetError,
+	}
+}
+
+func rdsRTText() rdsText {
+	return rdsText{
+		command:      "RT",
+		maxLength:    rdsRTMaxLength,
+		allowEmpty:   true,
+		successEvent: eventTypeRDSRTSetSuccess,
+		errorEvent:   eventTypeRDSRTSetError,
+	}
+}
+
+// validate returns a user facing reason why text can't be sent, or an
+// empty string when it's fine.
+func (t rdsText) validate(text string) string {
+	switch {
+	case strings.ContainsAny(text, "\r\n"):
+		return t.command + " text cannot contain line breaks"
+	case len(text) > t.maxLength:
+		return t.command + " text is too long"
+	case !t.allowEmpty && strings.TrimSpace(text) == "":
+		return t.command + " text cannot be empty"
+	default:
+		return ""
+	}
+}
+
+func (s *PIrateRF) handleRDSPSSet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.setRDSText(client, event, rdsPSText())
+}
+
+func (s *PIrateRF) handleRDSRTSet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.setRDSText(client, event, rdsRTText())
+}
+
+// setRDSText writes a PS or RT change into the control pipe of the FM
+// broadcast on air. Everybody gets told about the new text so their forms
+// stay in sync, errors only go back to whoever asked.
+func (s *PIrateRF) setRDSText(
+	client *wshub.Client,
+	event *dabluveees.Event,
+	text rdsText,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	var msg rdsSetMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		sendRDSSetError(client, text, "invalid request", "invalid message")
+
+		return nil
+	}
+
+	if reason := text.validate(msg.Text); reason != "" {
+		sendRDSSetError(client, text, "invalid text", reason)
+
+		return nil
+	}
+
+	controlPipe, ok := s.executionManager.activeRDSControlPipe()
+	if !ok {
+		sendRDSSetError(
+			client, text, "not on air", "no FM broadcast is on air",
+		)
+
+		return nil
+	}
+
+	if err := writeRDSCommand(controlPipe, text.command, msg.Text); err != nil {
+		logger.WithError(err).Error("failed to write RDS command")
+		sendRDSSetError(
+			client, text, "control pipe write failed",
+			"pifmrds is not listening on its control pipe",
+		)
+
+		return nil
+	}
+
+	logger.WithFields(logrus.Fields{
+		"command": text.command,
+		"text":    msg.Text,
+	}).Info("RDS text updated")
+
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		text.successEvent,
+		rdsSetSuccessMessageData{
+			Text:      msg.Text,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+
+	return nil
+}
+
+func sendRDSSetError(
+	client *wshub.Client,
+	text rdsText,
+	errorType, message string,
+) {
+	client.SendEvent(dabluveees.NewEvent(
+		text.errorEvent,
+		rdsSetErrorMessageData{
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+// writeRDSCommand sends one command line to the pifmrds control pipe. The
+// pipe is opened non-blocking so we fail right away instead of hanging when
+// nothing is reading it.
+func writeRDSCommand(controlPipe, command, text string) error {
+	pipe, err := os.OpenFile(
+		controlPipe, os.O_WRONLY|syscall.O_NONBLOCK, 0,
+	)
+	if err != nil {
+		if errors.Is(err, syscall.ENXIO) {
+			return ctxerrors.Wrap(
+				commonerrors.ErrFailed, "control pipe has no reader",
+			)
+		}
+
+		return ctxerrors.Wrap(err, "failed to open control pipe")
+	}
+	defer func() { _ = pipe.Close() }()
+
+	// One write per command so it lands in the pipe in one piece
+	if _, err := pipe.WriteString(command + " " + text + "\n"); err != nil {
+		return ctxerrors.Wrap(err, "failed to write to control pipe")
+	}
+
+	return nil
+}
+
+// activeRDSControlPipe returns the control pipe of the FM broadcast on air.
+func (em *executionManager) activeRDSControlPipe() (string, bool) {
+	em.mu.RLock()
+	active := em.active
+	em.mu.RUnlock()
+
+	if active == nil || active.moduleName != gorpitx.ModuleNamePIFMRDS {
+		return "", false
+	}
+
+	var args struct {
+		ControlPipe string `json:"controlPipe"`
+	}
+
+	if err := json.Unmarshal(active.args, &args); err != nil {
+		return "", false
+	}
+
+	return args.ControlPipe, args.ControlPipe != ""
+}
+
+// createRDSControlPipe makes a FIFO in dir for pifmrds to read PS/RT
+// changes from and puts it in the args. It returns the FIFO path so it can
+// be removed once the broadcast is over, or an empty path when the args
+// already name a control pipe of their own which we leave alone.
+func (s *PIrateRF) createRDSControlPipe(
+	args json.RawMessage,
+	dir string,
+	logger *logrus.Entry,
+) (json.RawMessage, string, error) {
+	var argsMap map[string]any
+	if err := json.Unmarshal(args, &argsMap); err != nil {
+		return args, "", ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	if pipe, ok := argsMap[rdsControlPipeArg].(string); ok && pipe != "" {
+		return args, "", nil
+	}
+
+	controlPipe := s.generateRDSControlPipePath(dir)
+	if err := syscall.Mkfifo(controlPipe, rdsControlPipePerms); err != nil {
+		return args, "", ctxerrors.Wrap(err, "failed to create control pipe")
+	}
+
+	argsMap[rdsControlPipeArg] = controlPipe
+
+	modifiedArgs, err := json.Marshal(argsMap)
+	if err != nil {
+		return args, controlPipe, ctxerrors.Wrap(err, "failed to marshal args")
+	}
+
+	logger.WithField("controlPipe", controlPipe).
+		Debug("created RDS control pipe")
+
+	return modifiedArgs, controlPipe, nil
+}
+
+func (s *PIrateRF) generateRDSControlPipePath(dir string) string {
+	return filepath.Join(dir, uuid.New().String()+"_rds.ctl")
+}
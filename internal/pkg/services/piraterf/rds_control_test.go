@@ -0,0 +1,132 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRDSText_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  rdsText
+		value string
+		valid bool
+	}{
+		{name: "ps", text: rdsPSText(), value: "PIRATE", valid: true},
+		{name: "ps max length", text: rdsPSText(), value: "12345678", valid: true},
+		{name: "ps too long", text: rdsPSText(), value: "123456789"},
+		{name: "ps empty", text: rdsPSText(), value: "  "},
+		{name: "rt", text: rdsRTText(), value: "Now playing", valid: true},
+		{name: "rt empty clears", text: rdsRTText(), value: "", valid: true},
+		{
+			name:  "rt max length",
+			text:  rdsRTText(),
+			value: strings.Repeat("x", rdsRTMaxLength),
+			valid: true,
+		},
+		{
+			name:  "rt too long",
+			text:  rdsRTText(),
+			value: strings.Repeat("x", rdsRTMaxLength+1),
+		},
+		{name: "command injection", text: rdsRTText(), value: "hi\nPS OWNED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.text.validate(tt.value)
+			if tt.valid {
+				assert.Empty(t, reason)
+			} else {
+				assert.NotEmpty(t, reason)
+			}
+		})
+	}
+}
+
+func TestWriteRDSCommand(t *testing.T) {
+	controlPipe := filepath.Join(t.TempDir(), "rds.ctl")
+	require.NoError(t, syscall.Mkfifo(controlPipe, rdsControlPipePerms))
+
+	// Nobody reading means pifmrds isn't there
+	require.Error(t, writeRDSCommand(controlPipe, "PS", "PIRATE"))
+
+	// Read it the way pifmrds does
+	reader, err := os.OpenFile(
+		controlPipe, os.O_RDONLY|syscall.O_NONBLOCK, 0,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = reader.Close() }()
+
+	require.NoError(t, writeRDSCommand(controlPipe, "RT", "Now playing"))
+
+	buf := make([]byte, 64)
+	n, err := reader.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "RT Now playing\n", string(buf[:n]))
+}
+
+func TestCreateRDSControlPipe(t *testing.T) {
+	service := &PIrateRF{}
+	logger := logrus.NewEntry(logrus.New())
+
+	args, controlPipe, err := service.createRDSControlPipe(
+		json.RawMessage(`{"freq":107.9,"audio":"a.wav"}`), t.TempDir(), logger,
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, controlPipe)
+
+	defer func() { _ = os.Remove(controlPipe) }()
+
+	info, err := os.Stat(controlPipe)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeNamedPipe, info.Mode().Type())
+
+	var argsMap map[string]any
+	require.NoError(t, json.Unmarshal(args, &argsMap))
+	assert.Equal(t, controlPipe, argsMap[rdsControlPipeArg])
+	assert.Equal(t, "a.wav", argsMap["audio"])
+
+	// A control pipe given by the caller is left alone
+	ownArgs := json.RawMessage(`{"freq":107.9,"controlPipe":"/tmp/mine"}`)
+	args, controlPipe, err = service.createRDSControlPipe(
+		ownArgs, t.TempDir(), logger,
+	)
+	require.NoError(t, err)
+	assert.Empty(t, controlPipe)
+	assert.JSONEq(t, string(ownArgs), string(args))
+}
+
+func TestExecutionManager_ActiveRDSControlPipe(t *testing.T) {
+	em := newExecutionManager(gorpitx.GetInstance(), nil)
+
+	_, ok := em.activeRDSControlPipe()
+	assert.False(t, ok)
+
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNameTUNE,
+		args:       json.RawMessage(`{"controlPipe":"/tmp/x"}`),
+	})
+
+	_, ok = em.activeRDSControlPipe()
+	assert.False(t, ok)
+
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNamePIFMRDS,
+		args:       json.RawMessage(`{"freq":107.9,"controlPipe":"/tmp/x"}`),
+	})
+
+	controlPipe, ok := em.activeRDSControlPipe()
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/x", controlPipe)
+}
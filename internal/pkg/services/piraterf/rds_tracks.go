@@ -0,0 +1,333 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+// rdsTrack is where a track starts in what pifmrds plays and what RDS
+// should say while it's on. Empty texts are left alone.
+type rdsTrack struct {
+	start float64 // seconds into the audio
+	rt    string
+	ps    string
+}
+
+// rdsTrackSchedule tells when RDS has to change as the tracks go by.
+type rdsTrackSchedule struct {
+	tracks []rdsTrack
+	cycle  float64 // seconds until the audio loops, 0 when it doesn't
+}
+
+// rdsTrackUpdater is the goroutine changing RDS for the current execution.
+type rdsTrackUpdater struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// withRDSTracks changes RDS along schedule while the execution is on air.
+func withRDSTracks(schedule *rdsTrackSchedule) executionOption {
+	return func(job *queuedExecution) {
+		job.rdsTracks = schedule
+	}
+}
+
+// playedTracks lists the files an FM broadcast plays in order: the intro,
+// the main audio or the items of the stored playlist it names and the
+// outro.
+func (s *PIrateRF) playedTracks(
+	msg rpitxExecutionStartMessage,
+) ([]string, error) {
+	var args struct {
+		Audio string `json:"audio"`
+	}
+
+	if err := json.Unmarshal(msg.Args, &args); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	var tracks []string
+
+	if msg.Intro != nil && *msg.Intro != "" {
+		tracks = append(tracks, *msg.Intro)
+	}
+
+	pl, ok, err := s.playlistFromAudio(args.Audio)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		for _, item := range pl.Items {
+			tracks = append(
+				tracks, filepath.Join(s.playlists.audioDir, item.File),
+			)
+		}
+	} else if args.Audio != "" {
+		tracks = append(tracks, args.Audio)
+	}
+
+	if msg.Outro != nil && *msg.Outro != "" {
+		tracks = append(tracks, *msg.Outro)
+	}
+
+	return tracks, nil
+}
+
+// trackStarts returns when each track starts, in seconds into the joined
+// audio, and how long it all lasts.
+func (s *PIrateRF) trackStarts(
+	tracks []string,
+	transitions playlistTransitions,
+) ([]float64, float64, error) {
+	starts := make([]float64, len(tracks))
+	durations := make([]float64, len(tracks))
+
+	for i, track := range tracks {
+		duration, err := s.getAudioDuration(track)
+		if err != nil {
+			return nil, 0, ctxerrors.Wrapf(err, "failed to measure %s", track)
+		}
+
+		durations[i] = duration
+
+		if i > 0 {
+			starts[i] = starts[i-1] + durations[i-1] +
+				transitions.Gap - transitions.Crossfade
+		}
+	}
+
+	return starts, transitions.joinedDuration(durations), nil
+}
+
+// applyAutoRDS puts the RT (and PS with AutoPS) of the first track into the
+// args and returns the schedule changing them as the other tracks come on.
+// The texts come from the tracks' metadata sidecars, tracks without one
+// leave RDS as it is. The schedule is nil when RDS never has to change.
+func (s *PIrateRF) applyAutoRDS(
+	msg rpitxExecutionStartMessage,
+	args json.RawMessage,
+	logger *logrus.Entry,
+) (json.RawMessage, *rdsTrackSchedule) {
+	tracks, err := s.playedTracks(msg)
+	if err != nil {
+		logger.WithError(err).Warn("Auto RDS: can't tell what's playing")
+
+		return args, nil
+	}
+
+	schedule := &rdsTrackSchedule{}
+	starts := make([]float64, len(tracks))
+
+	if len(tracks) > 1 {
+		var total float64
+
+		starts, total, err = s.trackStarts(tracks, msg.joinsOnly())
+		if err != nil {
+			logger.WithError(err).
+				Warn("Auto RDS: only the first track gets named")
+
+			tracks = tracks[:1]
+			starts = []float64{0}
+		} else if !msg.PlayOnce {
+			schedule.cycle = total
+		}
+	}
+
+	for i, track := range tracks {
+		meta, ok := loadAudioMetadata(track)
+		if !ok {
+			continue
+		}
+
+		rdsTrack := rdsTrack{start: starts[i]}
+		if msg.AutoRT {
+			rdsTrack.rt = meta.radioText()
+		}
+
+		if msg.AutoPS {
+			rdsTrack.ps = meta.programService()
+		}
+
+		if rdsTrack.rt != "" || rdsTrack.ps != "" {
+			schedule.tracks = append(schedule.tracks, rdsTrack)
+		}
+	}
+
+	if len(schedule.tracks) == 0 {
+		logger.Debug("Auto RDS: no track metadata")
+
+		return args, nil
+	}
+
+	args = applyFirstRDSTrack(args, schedule.tracks[0], logger)
+
+	// Nothing left to change when the only texts are the ones going on air
+	// first and the audio doesn't loop back to them
+	if schedule.cycle == 0 && len(schedule.tracks) == 1 &&
+		schedule.tracks[0].start == 0 {
+		return args, nil
+	}
+
+	return args, schedule
+}
+
+// applyFirstRDSTrack puts the texts of the track playing first into the
+// args pifmrds starts with.
+func applyFirstRDSTrack(
+	args json.RawMessage,
+	track rdsTrack,
+	logger *logrus.Entry,
+) json.RawMessage {
+	if track.start > 0 {
+		return args
+	}
+
+	var argsMap map[string]any
+	if err := json.Unmarshal(args, &argsMap); err != nil {
+		return args
+	}
+
+	if track.rt != "" {
+		argsMap["rt"] = track.rt
+	}
+
+	if track.ps != "" {
+		argsMap["ps"] = track.ps
+	}
+
+	modifiedArgs, err := json.Marshal(argsMap)
+	if err != nil {
+		return args
+	}
+
+	logger.WithFields(logrus.Fields{
+		"rt": track.rt,
+		"ps": track.ps,
+	}).Debug("Auto RDS: texts of the first track")
+
+	return modifiedArgs
+}
+
+// startRDSTracks changes RDS along schedule until stopRDSTracks.
+func (em *executionManager) startRDSTracks(
+	schedule *rdsTrackSchedule,
+	startedAt time.Time,
+) {
+	em.stopRDSTracks()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updater := &rdsTrackUpdater{cancel: cancel, done: make(chan struct{})}
+
+	em.mu.Lock()
+	em.rdsTracks = updater
+	em.mu.Unlock()
+
+	go func() {
+		defer close(updater.done)
+
+		em.runRDSTracks(ctx, schedule, startedAt)
+	}()
+}
+
+// stopRDSTracks stops the RDS changes of the current execution.
+func (em *executionManager) stopRDSTracks() {
+	em.mu.Lock()
+	updater := em.rdsTracks
+	em.rdsTracks = nil
+	em.mu.Unlock()
+
+	if updater == nil {
+		return
+	}
+
+	updater.cancel()
+	<-updater.done
+}
+
+// runRDSTracks waits for every track to come on and sends its texts. The
+// first pass skips what pifmrds started with.
+func (em *executionManager) runRDSTracks(
+	ctx context.Context,
+	schedule *rdsTrackSchedule,
+	startedAt time.Time,
+) {
+	for cycleStart := 0.0; ; cycleStart += schedule.cycle {
+		for _, track := range schedule.tracks {
+			if cycleStart == 0 && track.start == 0 {
+				continue
+			}
+
+			at := startedAt.Add(
+				time.Duration((cycleStart + track.start) * float64(time.Second)),
+			)
+
+			timer := time.NewTimer(time.Until(at))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+
+				return
+			case <-timer.C:
+			}
+
+			em.sendRDSTrack(track)
+		}
+
+		if schedule.cycle <= 0 {
+			return
+		}
+	}
+}
+
+// sendRDSTrack writes the texts of a track that just came on into the
+// control pipe and tells everybody.
+func (em *executionManager) sendRDSTrack(track rdsTrack) {
+	controlPipe, ok := em.activeRDSControlPipe()
+	if !ok {
+		return
+	}
+
+	texts := []struct {
+		text rdsText
+		body string
+	}{
+		{text: rdsRTText(), body: track.rt},
+		{text: rdsPSText(), body: track.ps},
+	}
+
+	for _, t := range texts {
+		if t.body == "" {
+			continue
+		}
+
+		err := writeRDSCommand(controlPipe, t.text.command, t.body)
+		if err != nil {
+			logrus.WithError(err).
+				WithField("command", t.text.command).
+				Warn("Auto RDS: failed to update track text")
+
+			continue
+		}
+
+		em.hub.BroadcastToAll(dabluveees.NewEvent(
+			t.text.successEvent,
+			rdsSetSuccessMessageData{
+				Text:      t.body,
+				Timestamp: time.Now().Unix(),
+			},
+		))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"rt": track.rt,
+		"ps": track.ps,
+	}).Info("Auto RDS: track changed")
+}
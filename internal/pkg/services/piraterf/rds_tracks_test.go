@@ -0,0 +1,183 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAutoRDS_Playlist(t *testing.T) {
+	store, filesDir := newTestPlaylistStore(t)
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+
+	require.NoError(t, writeAudioMetadata(
+		filepath.Join(audioDir, "uploads", "a.wav"),
+		audioMetadata{Title: "Hack the Planet", Artist: "Zero Cool"},
+	))
+	require.NoError(t, writeAudioMetadata(
+		filepath.Join(audioDir, "uploads", "b.wav"),
+		audioMetadata{Title: "Mess with the Best"},
+	))
+
+	pl, err := store.create("show", []playlistItem{
+		{File: "uploads/a.wav"},
+		{File: "uploads/b.wav"},
+		{File: "sfx/c.wav"},
+	})
+	require.NoError(t, err)
+
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: filesDir},
+		commander:  &testMockCommander{tempDir: t.TempDir()},
+		playlists:  store,
+	}
+
+	msg := rpitxExecutionStartMessage{
+		Args: json.RawMessage(
+			`{"freq":107.9,"audio":"` + playlistAudioPrefix + pl.ID.String() +
+				`","rt":"old"}`,
+		),
+		playlistTransitions: playlistTransitions{Crossfade: 1, FadeOut: 2},
+		AutoRT:              true,
+	}
+
+	args, schedule := service.applyAutoRDS(
+		msg, msg.Args, logrus.NewEntry(logrus.New()),
+	)
+
+	// Every track lasts 3s and the joins lose a second each
+	require.NotNil(t, schedule)
+	assert.Equal(t, []rdsTrack{
+		{start: 0, rt: "Zero Cool - Hack the Planet"},
+		{start: 2, rt: "Mess with the Best"},
+	}, schedule.tracks)
+	assert.InDelta(t, 7.0, schedule.cycle, 0.001)
+
+	var argsMap map[string]any
+	require.NoError(t, json.Unmarshal(args, &argsMap))
+	assert.Equal(t, "Zero Cool - Hack the Planet", argsMap["rt"])
+	assert.NotContains(t, argsMap, "ps")
+
+	// Played once, RDS still changes when the second track comes on
+	msg.PlayOnce = true
+
+	_, schedule = service.applyAutoRDS(
+		msg, msg.Args, logrus.NewEntry(logrus.New()),
+	)
+	require.NotNil(t, schedule)
+	assert.Len(t, schedule.tracks, 2)
+	assert.Zero(t, schedule.cycle)
+}
+
+func TestApplyAutoRDS_SingleFile(t *testing.T) {
+	audioPath := filepath.Join(t.TempDir(), "song.wav")
+	require.NoError(t, os.WriteFile(audioPath, []byte("wav"), 0o600))
+	require.NoError(t, writeAudioMetadata(
+		audioPath,
+		audioMetadata{Title: "Hack the Planet", Artist: "Zero Cool"},
+	))
+
+	service := &PIrateRF{serviceCtx: context.Background()}
+	logger := logrus.NewEntry(logrus.New())
+
+	msg := rpitxExecutionStartMessage{
+		Args:     json.RawMessage(`{"freq":107.9,"audio":"` + audioPath + `"}`),
+		PlayOnce: true,
+		AutoRT:   true,
+		AutoPS:   true,
+	}
+
+	// Nothing changes after the start, the args say it all
+	args, schedule := service.applyAutoRDS(msg, msg.Args, logger)
+	assert.Nil(t, schedule)
+
+	var argsMap map[string]any
+	require.NoError(t, json.Unmarshal(args, &argsMap))
+	assert.Equal(t, "Zero Cool - Hack the Planet", argsMap["rt"])
+	assert.Equal(t, "Zero Coo", argsMap["ps"])
+
+	// Files without metadata leave the args alone
+	require.NoError(t, removeAudioSidecars(audioPath))
+
+	args, schedule = service.applyAutoRDS(msg, msg.Args, logger)
+	assert.Nil(t, schedule)
+	assert.JSONEq(t, string(msg.Args), string(args))
+}
+
+func TestExecutionManager_RDSTracks(t *testing.T) {
+	controlPipe := filepath.Join(t.TempDir(), "rds.ctl")
+	require.NoError(t, syscall.Mkfifo(controlPipe, rdsControlPipePerms))
+
+	reader, err := os.OpenFile(
+		controlPipe, os.O_RDONLY|syscall.O_NONBLOCK, 0,
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = reader.Close() }()
+
+	em := newExecutionManager(gorpitx.GetInstance(), wshub.NewHub("test"))
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNamePIFMRDS,
+		args:       json.RawMessage(`{"controlPipe":"` + controlPipe + `"}`),
+	})
+
+	// The first track is already in the args, only the second gets sent
+	em.startRDSTracks(&rdsTrackSchedule{
+		tracks: []rdsTrack{
+			{start: 0, rt: "First"},
+			{start: 0.05, rt: "Second", ps: "SECOND"},
+		},
+	}, time.Now())
+
+	require.Eventually(t, func() bool {
+		em.mu.RLock()
+		defer em.mu.RUnlock()
+
+		select {
+		case <-em.rdsTracks.done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	buf := make([]byte, 128)
+	n, err := reader.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "RT Second\nPS SECOND\n", string(buf[:n]))
+
+	em.stopRDSTracks()
+
+	// Stopping cuts a long wait short
+	em.startRDSTracks(&rdsTrackSchedule{
+		tracks: []rdsTrack{{start: 3600, rt: "Later"}},
+	}, time.Now())
+
+	stopped := make(chan struct{})
+
+	go func() {
+		em.stopRDSTracks()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("RDS track updater didn't stop")
+	}
+
+	em.mu.RLock()
+	assert.Nil(t, em.rdsTracks)
+	em.mu.RUnlock()
+}
@@ -0,0 +1,418 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	schedulesFilename = "schedules.json"
+
+	// schedulerIdleWait is how long the scheduler sleeps when there's
+	// nothing scheduled. Any change to the schedule list wakes it up anyway.
+	schedulerIdleWait = time.Hour
+)
+
+// schedule is a preset that gets transmitted automatically, either
+// repeatedly on a cron expression or once at a given time.
+type schedule struct {
+	ID         uuid.UUID          `json:"id"`
+	ModuleName gorpitx.ModuleName `json:"moduleName"`
+	PresetName string             `json:"presetName"`
+	Cron       string             `json:"cron,omitempty"`
+	At         int64              `json:"at,omitempty"` // one-shot unix time
+	CreatedAt  int64              `json:"createdAt"`
+	LastRunAt  int64              `json:"lastRunAt,omitempty"`
+	NextRunAt  int64              `json:"nextRunAt,omitempty"`
+
+	cron *cronExpression
+}
+
+func (sc *schedule) isOneShot() bool {
+	return sc.Cron == ""
+}
+
+// computeNextRun sets NextRunAt to the first run strictly after the given
+// time. Returns false if the schedule will never run again.
+func (sc *schedule) computeNextRun(after time.Time) bool {
+	if sc.isOneShot() {
+		if sc.At <= after.Unix() {
+			sc.NextRunAt = 0
+
+			return false
+		}
+
+		sc.NextRunAt = sc.At
+
+		return true
+	}
+
+	next, ok := sc.cron.next(after)
+	if !ok {
+		sc.NextRunAt = 0
+
+		return false
+	}
+
+	sc.NextRunAt = next.Unix()
+
+	return true
+}
+
+// scheduler keeps the schedule list on disk and starts due presets through
+// runFn. Scheduled runs go through the transmission queue like any other
+// start request so they never interrupt what's on air.
+type scheduler struct {
+	filePath  string
+	hub       wshub.Hub
+	runFn     func(sc schedule) error
+	client    *wshub.Client // initiating client of scheduled executions
+	schedules []*schedule
+	mu        sync.Mutex
+	wakeCh    chan struct{}
+	now       func() time.Time
+}
+
+func newScheduler(
+	filePath string,
+	hub wshub.Hub,
+	runFn func(sc schedule) error,
+) *scheduler {
+	return &scheduler{
+		filePath: filePath,
+		hub:      hub,
+		runFn:    runFn,
+		client:   wshub.NewClientWithID(uuid.Nil),
+		wakeCh:   make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// newSchedule validates the given cron expression or one-shot time and
+// returns a schedule ready to be added. Exactly one of cronExpr and at
+// must be set.
+func newSchedule(
+	moduleName gorpitx.ModuleName,
+	presetName string,
+	cronExpr string,
+	at int64,
+	now time.Time,
+) (*schedule, error) {
+	if (cronExpr == "") == (at == 0) {
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue,
+			"exactly one of cron or at must be set",
+		)
+	}
+
+	sc := &schedule{
+		ID:         uuid.New(),
+		ModuleName: moduleName,
+		PresetName: presetName,
+		Cron:       cronExpr,
+		At:         at,
+		CreatedAt:  now.Unix(),
+	}
+
+	if err := sc.parse(); err != nil {
+		return nil, err
+	}
+
+	if !sc.computeNextRun(now) {
+		return nil, ctxerrors.Wrap(
+			commonerrors.ErrInvalidValue,
+			"schedule would never run",
+		)
+	}
+
+	return sc, nil
+}
+
+func (sc *schedule) parse() error {
+	if sc.isOneShot() {
+		return nil
+	}
+
+	expr, err := parseCronExpression(sc.Cron)
+	if err != nil {
+		return err
+	}
+
+	sc.cron = expr
+
+	return nil
+}
+
+// load reads the persisted schedules. A missing file means no schedules.
+// One-shot schedules whose time passed while we were down are dropped.
+func (sch *scheduler) load() error {
+	data, err := os.ReadFile(sch.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return ctxerrors.Wrap(err, "failed to read schedules file")
+	}
+
+	var schedules []*schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return ctxerrors.Wrap(err, "failed to parse schedules file")
+	}
+
+	now := sch.now()
+
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	sch.schedules = make([]*schedule, 0, len(schedules))
+
+	for _, sc := range schedules {
+		logger := logrus.WithField("scheduleID", sc.ID)
+
+		if err := sc.parse(); err != nil {
+			logger.WithError(err).Warn("Dropping invalid schedule")
+
+			continue
+		}
+
+		if !sc.computeNextRun(now) {
+			logger.Info("Dropping expired schedule")
+
+			continue
+		}
+
+		sch.schedules = append(sch.schedules, sc)
+	}
+
+	logrus.Infof("Loaded %d schedules", len(sch.schedules))
+
+	return sch.save()
+}
+
+// save writes the schedules to disk. Caller must hold mu.
+func (sch *scheduler) save() error {
+	data, err := json.MarshalIndent(sch.schedules, "", "  ")
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to marshal schedules")
+	}
+
+	// Write to a temp file first so a crash never leaves a half written file
+	tmpPath := sch.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, filePerms); err != nil {
+		return ctxerrors.Wrap(err, "failed to write schedules file")
+	}
+
+	if err := os.Rename(tmpPath, sch.filePath); err != nil {
+		return ctxerrors.Wrap(err, "failed to replace schedules file")
+	}
+
+	return nil
+}
+
+func (sch *scheduler) add(sc *schedule) error {
+	sch.mu.Lock()
+
+	sch.schedules = append(sch.schedules, sc)
+
+	if err := sch.save(); err != nil {
+		sch.schedules = sch.schedules[:len(sch.schedules)-1]
+		sch.mu.Unlock()
+
+		return err
+	}
+
+	sch.mu.Unlock()
+
+	sch.wake()
+	sch.sendUpdatedEvent()
+
+	return nil
+}
+
+func (sch *scheduler) remove(id uuid.UUID) error {
+	sch.mu.Lock()
+
+	idx := slices.IndexFunc(sch.schedules, func(sc *schedule) bool {
+		return sc.ID == id
+	})
+	if idx < 0 {
+		sch.mu.Unlock()
+
+		return ctxerrors.Wrapf(commonerrors.ErrNotFound, "schedule %s", id)
+	}
+
+	removed := sch.schedules[idx]
+	sch.schedules = slices.Delete(sch.schedules, idx, idx+1)
+
+	if err := sch.save(); err != nil {
+		sch.schedules = slices.Insert(sch.schedules, idx, removed)
+		sch.mu.Unlock()
+
+		return err
+	}
+
+	sch.mu.Unlock()
+
+	sch.wake()
+	sch.sendUpdatedEvent()
+
+	return nil
+}
+
+func (sch *scheduler) list() []schedule {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	schedules := make([]schedule, 0, len(sch.schedules))
+	for _, sc := range sch.schedules {
+		schedules = append(schedules, *sc)
+	}
+
+	return schedules
+}
+
+func (sch *scheduler) wake() {
+	select {
+	case sch.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// nextWait returns how long to sleep until the next schedule is due.
+func (sch *scheduler) nextWait() time.Duration {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	var next int64
+
+	for _, sc := range sch.schedules {
+		if next == 0 || sc.NextRunAt < next {
+			next = sc.NextRunAt
+		}
+	}
+
+	if next == 0 {
+		return schedulerIdleWait
+	}
+
+	return max(0, time.Unix(next, 0).Sub(sch.now()))
+}
+
+// run fires due schedules until the context is done.
+func (sch *scheduler) run(ctx context.Context) {
+	logrus.Debug("Scheduler started")
+
+	for {
+		timer := time.NewTimer(sch.nextWait())
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logrus.Debug("Scheduler stopped")
+
+			return
+		case <-sch.wakeCh:
+			timer.Stop()
+		case <-timer.C:
+			sch.runDueSchedules()
+		}
+	}
+}
+
+// runDueSchedules starts every schedule whose time has come, moves cron
+// schedules to their next run and drops finished one-shots.
+func (sch *scheduler) runDueSchedules() {
+	now := sch.now()
+
+	sch.mu.Lock()
+
+	var due []schedule
+
+	remaining := make([]*schedule, 0, len(sch.schedules))
+
+	for _, sc := range sch.schedules {
+		if sc.NextRunAt > now.Unix() {
+			remaining = append(remaining, sc)
+
+			continue
+		}
+
+		sc.LastRunAt = now.Unix()
+		due = append(due, *sc)
+
+		if sc.computeNextRun(now) {
+			remaining = append(remaining, sc)
+		}
+	}
+
+	if len(due) == 0 {
+		sch.mu.Unlock()
+
+		return
+	}
+
+	sch.schedules = remaining
+
+	if err := sch.save(); err != nil {
+		logrus.WithError(err).Error("Failed to save schedules")
+	}
+
+	sch.mu.Unlock()
+
+	for _, sc := range due {
+		logger := logrus.WithFields(logrus.Fields{
+			"scheduleID": sc.ID,
+			"moduleName": sc.ModuleName,
+			"presetName": sc.PresetName,
+		})
+
+		logger.Info("Running scheduled preset")
+
+		if err := sch.runFn(sc); err != nil {
+			logger.WithError(err).Error("Scheduled preset failed to start")
+			sch.sendErrorEvent("run failed", errorMessage(err))
+		}
+	}
+
+	sch.sendUpdatedEvent()
+}
+
+func (sch *scheduler) sendUpdatedEvent() {
+	sch.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeScheduleUpdated,
+		scheduleUpdatedMessageData{
+			Schedules: sch.list(),
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (sch *scheduler) sendErrorEvent(errorType, message string) {
+	logrus.WithFields(logrus.Fields{
+		"errorType": errorType,
+		"message":   message,
+	}).Error("schedule error occurred")
+
+	sch.hub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeScheduleError,
+		scheduleErrorMessageData{
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
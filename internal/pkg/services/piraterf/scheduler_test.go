@@ -0,0 +1,250 @@
+package piraterf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRuns struct {
+	mu   sync.Mutex
+	runs []schedule
+}
+
+func (r *recordedRuns) run(sc schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs = append(r.runs, sc)
+
+	return nil
+}
+
+func (r *recordedRuns) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.runs)
+}
+
+func newTestScheduler(
+	t *testing.T,
+	now time.Time,
+	runs *recordedRuns,
+) *scheduler {
+	t.Helper()
+
+	hub := wshub.NewHub("test")
+	t.Cleanup(hub.Close)
+
+	sch := newScheduler(
+		filepath.Join(t.TempDir(), schedulesFilename), hub, runs.run,
+	)
+	sch.now = func() time.Time { return now }
+
+	return sch
+}
+
+func TestNewSchedule(t *testing.T) {
+	now := time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		cron        string
+		at          int64
+		expectedErr error
+		expectedRun int64
+	}{
+		{
+			name:        "cron schedule",
+			cron:        "0 12 * * *",
+			expectedRun: time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC).Unix(),
+		},
+		{
+			name:        "one-shot schedule",
+			at:          now.Add(time.Hour).Unix(),
+			expectedRun: now.Add(time.Hour).Unix(),
+		},
+		{
+			name:        "neither cron nor at",
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "both cron and at",
+			cron:        "* * * * *",
+			at:          now.Add(time.Hour).Unix(),
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "one-shot in the past",
+			at:          now.Add(-time.Hour).Unix(),
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "invalid cron",
+			cron:        "not a cron",
+			expectedErr: commonerrors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := newSchedule(
+				gorpitx.ModuleNameTUNE, "test", tt.cron, tt.at, now,
+			)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRun, sc.NextRunAt)
+			assert.Equal(t, now.Unix(), sc.CreatedAt)
+		})
+	}
+}
+
+func TestScheduler_PersistsAcrossRestarts(t *testing.T) {
+	now := time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)
+	runs := &recordedRuns{}
+	sch := newTestScheduler(t, now, runs)
+
+	cronSchedule, err := newSchedule(
+		gorpitx.ModuleNameTUNE, "beacon", "0 * * * *", 0, now,
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(cronSchedule))
+
+	oneShot, err := newSchedule(
+		gorpitx.ModuleNamePIFMRDS, "show", "", now.Add(time.Minute).Unix(), now,
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(oneShot))
+
+	// Restart two hours later: the one-shot was missed and gets dropped
+	restarted := newScheduler(sch.filePath, sch.hub, runs.run)
+	restarted.now = func() time.Time { return now.Add(2 * time.Hour) }
+	require.NoError(t, restarted.load())
+
+	schedules := restarted.list()
+	require.Len(t, schedules, 1)
+	assert.Equal(t, cronSchedule.ID, schedules[0].ID)
+	assert.Equal(
+		t,
+		time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC).Unix(),
+		schedules[0].NextRunAt,
+	)
+}
+
+func TestScheduler_LoadMissingFile(t *testing.T) {
+	sch := newTestScheduler(t, time.Now(), &recordedRuns{})
+
+	require.NoError(t, sch.load())
+	assert.Empty(t, sch.list())
+}
+
+func TestScheduler_LoadInvalidFile(t *testing.T) {
+	sch := newTestScheduler(t, time.Now(), &recordedRuns{})
+	require.NoError(t, os.WriteFile(sch.filePath, []byte("{"), filePerms))
+
+	require.Error(t, sch.load())
+}
+
+func TestScheduler_Remove(t *testing.T) {
+	now := time.Now()
+	sch := newTestScheduler(t, now, &recordedRuns{})
+
+	sc, err := newSchedule(gorpitx.ModuleNameTUNE, "beacon", "@daily", 0, now)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(sc))
+
+	require.NoError(t, sch.remove(sc.ID))
+	assert.Empty(t, sch.list())
+
+	err = sch.remove(uuid.New())
+	require.ErrorIs(t, err, commonerrors.ErrNotFound)
+}
+
+func TestScheduler_RunDueSchedules(t *testing.T) {
+	now := time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)
+	runs := &recordedRuns{}
+	sch := newTestScheduler(t, now, runs)
+
+	recurring, err := newSchedule(
+		gorpitx.ModuleNameTUNE, "beacon", "*/5 * * * *", 0, now,
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(recurring))
+
+	oneShot, err := newSchedule(
+		gorpitx.ModuleNameTUNE, "once", "", now.Add(2*time.Minute).Unix(), now,
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(oneShot))
+
+	later, err := newSchedule(
+		gorpitx.ModuleNameTUNE, "later", "0 20 * * *", 0, now,
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(later))
+
+	sch.now = func() time.Time { return now.Add(5 * time.Minute) }
+	sch.runDueSchedules()
+
+	require.Equal(t, 2, runs.count())
+	assert.Equal(t, recurring.ID, runs.runs[0].ID)
+	assert.Equal(t, oneShot.ID, runs.runs[1].ID)
+
+	schedules := sch.list()
+	require.Len(t, schedules, 2, "one-shot should be removed after it ran")
+	assert.Equal(t, recurring.ID, schedules[0].ID)
+	assert.Equal(t, now.Add(5*time.Minute).Unix(), schedules[0].LastRunAt)
+	assert.Equal(t, now.Add(10*time.Minute).Unix(), schedules[0].NextRunAt)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	runs := &recordedRuns{}
+	sch := newTestScheduler(t, time.Now(), runs)
+	sch.now = time.Now
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		sch.run(ctx)
+	}()
+
+	sc, err := newSchedule(
+		gorpitx.ModuleNameTUNE, "soon", "", time.Now().Add(time.Second).Unix(),
+		time.Now(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, sch.add(sc))
+
+	require.Eventually(t, func() bool {
+		return runs.count() == 1
+	}, 3*time.Second, 20*time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after context cancel")
+	}
+}
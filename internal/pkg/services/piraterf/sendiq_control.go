@@ -0,0 +1,617 @@
+package piraterf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	eventTypeSENDIQFrequencySet dabluveees.EventType = "sendiq.frequency.set"
+	eventTypeSENDIQPowerSet     dabluveees.EventType = "sendiq.power.set"
+	eventTypeSENDIQCarrier      dabluveees.EventType = "sendiq.carrier"
+	eventTypeSENDIQResume       dabluveees.EventType = "sendiq.resume"
+	eventTypeSENDIQStatus       dabluveees.EventType = "sendiq.status"
+	eventTypeSENDIQControlError dabluveees.EventType = "sendiq.control.error"
+
+	sendiqSharedMemTokenArg = "sharedMemToken"
+	sendiqFreqArg           = "freq"
+	sendiqPowerArg          = "power"
+	sendiqIQTypeArg         = "iqType"
+	// sendiq only takes runtime commands with float samples - it forces
+	// the IQ type to float when it gets a token.
+	sendiqControlIQType = "float"
+	// sendiqDefaultIQType is what sendiq reads without an iqType arg.
+	sendiqDefaultIQType = "i16"
+	// sendiqDefaultPower is what sendiq drives at without a power arg.
+	sendiqDefaultPower = 0.1
+
+	// The control block sendiq attaches to with -m <token> is its C struct
+	// {bool IsUpdated; int Com; float Data;} - the layout the runtime
+	// control section of the gorpitx README packs with struct '?if'. An
+	// update flag, the command at 4 and its float32 argument at 8. sendiq
+	// clears the flag once it's applied the command.
+	sendiqControlSize          = 1024
+	sendiqControlCommandOffset = 4
+	sendiqControlValueOffset   = 8
+	sendiqControlLayoutSize    = 12
+	sendiqControlPerms         = 0o600
+	sendiqControlKeyAttempts   = 10
+)
+
+// sendiqCommand is a command in the sendiq control block.
+type sendiqCommand int32
+
+// The commands sendiq knows. It has no way to pause: carrier mode stops the
+// capture going out but keeps transmitting an unmodulated carrier, IQ mode
+// puts the capture back on.
+const (
+	sendiqCommandIQ        sendiqCommand = 1111
+	sendiqCommandCarrier   sendiqCommand = 2222
+	sendiqCommandPower     sendiqCommand = 3333
+	sendiqCommandFrequency sendiqCommand = 4444
+)
+
+type sendiqFrequencySetMessage struct {
+	Frequency float64 `json:"frequency"`
+}
+
+type sendiqPowerSetMessage struct {
+	Power float64 `json:"power"`
+}
+
+type sendiqStatusMessageData struct {
+	OnAir          bool    `json:"onAir"`
+	SharedMemToken int     `json:"sharedMemToken,omitempty"`
+	Frequency      float64 `json:"frequency,omitempty"`
+	Power          float64 `json:"power,omitempty"`
+	Carrier        bool    `json:"carrier"`
+	Timestamp      int64   `json:"timestamp"`
+}
+
+type sendiqControlErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// sendiqLiveValues is what the IQ replay on air is doing right now.
+type sendiqLiveValues struct {
+	token     int
+	frequency float64
+	power     float64
+	carrier   bool // unmodulated carrier instead of the capture
+}
+
+// sendiqLiveState tracks the live values of the IQ replay on air. It also
+// serialises control commands so two of them never interleave in the
+// control block.
+type sendiqLiveState struct {
+	mu     sync.Mutex
+	values sendiqLiveValues
+}
+
+// sendiqChange is a control command and the live values once it's applied.
+type sendiqChange struct {
+	command sendiqCommand
+	value   float64
+	values  sendiqLiveValues
+}
+
+// sendiqControlRejection says why a control command wasn't sent.
+type sendiqControlRejection struct {
+	errorType string
+	message   string
+}
+
+func (s *PIrateRF) handleSENDIQFrequencySet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.controlSENDIQ(client, event, s.sendiqFrequencyChange)
+}
+
+func (s *PIrateRF) handleSENDIQPowerSet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.controlSENDIQ(client, event, sendiqPowerChange)
+}
+
+// handleSENDIQCarrier switches the replay to an unmodulated carrier. It
+// stays on air.
+func (s *PIrateRF) handleSENDIQCarrier(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.controlSENDIQ(client, event, sendiqCarrierChange(true))
+}
+
+func (s *PIrateRF) handleSENDIQResume(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.controlSENDIQ(client, event, sendiqCarrierChange(false))
+}
+
+// handleSENDIQStatus sends the live values of the IQ replay on air to
+// whoever asked.
+func (s *PIrateRF) handleSENDIQStatus(
+	_ wshub.Hub,
+	client *wshub.Client,
+	_ *dabluveees.Event,
+) error {
+	status := sendiqStatusMessageData{Timestamp: time.Now().Unix()}
+
+	args, token, rejection := s.executionManager.activeSENDIQControl()
+	if rejection == nil {
+		s.sendiqLive.mu.Lock()
+		status = newSENDIQStatus(s.sendiqLive.current(token, args))
+		s.sendiqLive.mu.Unlock()
+	}
+
+	client.SendEvent(dabluveees.NewEvent(eventTypeSENDIQStatus, status))
+
+	return nil
+}
+
+// controlSENDIQ writes a runtime command into the control block of the IQ
+// replay on air. Everybody gets the new live values, errors only go back
+// to whoever asked.
+func (s *PIrateRF) controlSENDIQ(
+	client *wshub.Client,
+	event *dabluveees.Event,
+	change func(
+		values sendiqLiveValues,
+		args json.RawMessage,
+		data json.RawMessage,
+	) (sendiqChange, *sendiqControlRejection),
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	args, token, rejection := s.executionManager.activeSENDIQControl()
+	if rejection != nil {
+		sendSENDIQControlError(client, rejection)
+
+		return nil
+	}
+
+	s.sendiqLive.mu.Lock()
+	defer s.sendiqLive.mu.Unlock()
+
+	current := s.sendiqLive.current(token, args)
+
+	next, rejection := change(current, args, event.Data)
+	if rejection != nil {
+		sendSENDIQControlError(client, rejection)
+
+		return nil
+	}
+
+	if err := writeSENDIQCommand(token, next.command, next.value); err != nil {
+		logger.WithError(err).Error("failed to write SENDIQ command")
+		sendSENDIQControlError(client, &sendiqControlRejection{
+			errorType: "control write failed",
+			message:   "sendiq's control block is gone",
+		})
+
+		return nil
+	}
+
+	s.sendiqLive.values = next.values
+
+	logger.WithFields(logrus.Fields{
+		"command": next.command,
+		"value":   next.value,
+	}).Info("SENDIQ runtime command sent")
+
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeSENDIQStatus,
+		newSENDIQStatus(next.values),
+	))
+
+	return nil
+}
+
+// sendiqFrequencyChange retunes the replay. The new frequency goes through
+// the band plan and gorpitx validation just like a fresh start would.
+func (s *PIrateRF) sendiqFrequencyChange(
+	values sendiqLiveValues,
+	args json.RawMessage,
+	data json.RawMessage,
+) (sendiqChange, *sendiqControlRejection) {
+	var msg sendiqFrequencySetMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return sendiqChange{}, invalidSENDIQRequest()
+	}
+
+	newArgs, err := withArg(args, sendiqFreqArg, msg.Frequency)
+	if err != nil {
+		return sendiqChange{}, invalidSENDIQRequest()
+	}
+
+	if violation := s.bandPlan.check(
+		gorpitx.ModuleNameSENDIQ, newArgs,
+	); violation != nil {
+		return sendiqChange{}, &sendiqControlRejection{
+			errorType: "band plan violation",
+			message:   violation.message(),
+		}
+	}
+
+	if _, rejection := parseSENDIQArgs(newArgs); rejection != nil {
+		return sendiqChange{}, rejection
+	}
+
+	// sendiq takes it as a float32, so that's what goes on air
+	values.frequency = float64(float32(msg.Frequency))
+
+	return sendiqChange{
+		command: sendiqCommandFrequency,
+		value:   values.frequency,
+		values:  values,
+	}, nil
+}
+
+// sendiqPowerChange changes the drive level. gorpitx clamps it to the
+// range sendiq takes so the live value is whatever it settled on.
+func sendiqPowerChange(
+	values sendiqLiveValues,
+	args json.RawMessage,
+	data json.RawMessage,
+) (sendiqChange, *sendiqControlRejection) {
+	var msg sendiqPowerSetMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return sendiqChange{}, invalidSENDIQRequest()
+	}
+
+	newArgs, err := withArg(args, sendiqPowerArg, msg.Power)
+	if err != nil {
+		return sendiqChange{}, invalidSENDIQRequest()
+	}
+
+	module, rejection := parseSENDIQArgs(newArgs)
+	if rejection != nil {
+		return sendiqChange{}, rejection
+	}
+
+	values.power = *module.Power
+
+	return sendiqChange{
+		command: sendiqCommandPower,
+		value:   values.power,
+		values:  values,
+	}, nil
+}
+
+func sendiqCarrierChange(carrier bool) func(
+	values sendiqLiveValues,
+	args json.RawMessage,
+	data json.RawMessage,
+) (sendiqChange, *sendiqControlRejection) {
+	return func(
+		values sendiqLiveValues,
+		_ json.RawMessage,
+		_ json.RawMessage,
+	) (sendiqChange, *sendiqControlRejection) {
+		command := sendiqCommandIQ
+		if carrier {
+			command = sendiqCommandCarrier
+		}
+
+		values.carrier = carrier
+
+		return sendiqChange{command: command, values: values}, nil
+	}
+}
+
+// current returns the live values for the replay with the given token,
+// starting over from its start args when a new replay went on air. Callers
+// hold mu.
+func (l *sendiqLiveState) current(
+	token int,
+	args json.RawMessage,
+) sendiqLiveValues {
+	if l.values.token == token {
+		return l.values
+	}
+
+	l.values = sendiqLiveValues{token: token, power: sendiqDefaultPower}
+
+	var module gorpitx.SENDIQ
+	if err := json.Unmarshal(args, &module); err != nil {
+		return l.values
+	}
+
+	l.values.frequency = module.Freq
+	if module.Power != nil {
+		l.values.power = *module.Power
+	}
+
+	return l.values
+}
+
+func newSENDIQStatus(values sendiqLiveValues) sendiqStatusMessageData {
+	return sendiqStatusMessageData{
+		OnAir:          true,
+		SharedMemToken: values.token,
+		Frequency:      values.frequency,
+		Power:          values.power,
+		Carrier:        values.carrier,
+		Timestamp:      time.Now().Unix(),
+	}
+}
+
+func parseSENDIQArgs(
+	args json.RawMessage,
+) (*gorpitx.SENDIQ, *sendiqControlRejection) {
+	module := &gorpitx.SENDIQ{}
+	if _, _, err := module.ParseArgs(args); err != nil {
+		return nil, &sendiqControlRejection{
+			errorType: "invalid value",
+			message:   errorMessage(err),
+		}
+	}
+
+	return module, nil
+}
+
+// withArg returns args with one field set to value.
+func withArg(
+	args json.RawMessage,
+	name string,
+	value any,
+) (json.RawMessage, error) {
+	var argsMap map[string]any
+	if err := json.Unmarshal(args, &argsMap); err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	argsMap[name] = value
+
+	newArgs, err := json.Marshal(argsMap)
+	if err != nil {
+		return nil, ctxerrors.Wrap(err, "failed to marshal args")
+	}
+
+	return newArgs, nil
+}
+
+func invalidSENDIQRequest() *sendiqControlRejection {
+	return &sendiqControlRejection{
+		errorType: "invalid request",
+		message:   "invalid message",
+	}
+}
+
+func sendSENDIQControlError(
+	client *wshub.Client,
+	rejection *sendiqControlRejection,
+) {
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeSENDIQControlError,
+		sendiqControlErrorMessageData{
+			Error:     rejection.errorType,
+			Message:   rejection.message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+// writeSENDIQCommand puts one command into the control block of token.
+func writeSENDIQCommand(
+	token int,
+	command sendiqCommand,
+	value float64,
+) error {
+	id, err := unix.SysvShmGet(token, 0, 0)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to find control block")
+	}
+
+	block, err := unix.SysvShmAttach(id, 0, 0)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to attach control block")
+	}
+	defer func() { _ = unix.SysvShmDetach(block) }()
+
+	if len(block) < sendiqControlLayoutSize {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"control block is only %d bytes", len(block),
+		)
+	}
+
+	binary.NativeEndian.PutUint32(
+		block[sendiqControlCommandOffset:], uint32(command),
+	)
+	binary.NativeEndian.PutUint32(
+		block[sendiqControlValueOffset:], math.Float32bits(float32(value)),
+	)
+
+	// The flag goes up last, atomically, so sendiq never picks up a half
+	// written command. The rest of its word is struct padding.
+	atomic.StoreUint32(
+		(*uint32)(unsafe.Pointer(&block[0])), //nolint:gosec
+		binary.NativeEndian.Uint32([]byte{1, 0, 0, 0}),
+	)
+
+	return nil
+}
+
+// activeSENDIQControl returns the args and control token of the IQ replay
+// on air, or why it can't be controlled.
+func (em *executionManager) activeSENDIQControl() (
+	json.RawMessage, int, *sendiqControlRejection,
+) {
+	em.mu.RLock()
+	active := em.active
+	em.mu.RUnlock()
+
+	notOnAir := &sendiqControlRejection{
+		errorType: "not on air",
+		message:   "no IQ replay with runtime control is on air",
+	}
+
+	if active == nil || active.moduleName != gorpitx.ModuleNameSENDIQ {
+		return nil, 0, notOnAir
+	}
+
+	var args sendiqControlArgs
+	if err := json.Unmarshal(active.args, &args); err != nil {
+		return nil, 0, notOnAir
+	}
+
+	if args.SharedMemToken != 0 {
+		return active.args, args.SharedMemToken, nil
+	}
+
+	if rejection := args.rejection(); rejection != nil {
+		return nil, 0, rejection
+	}
+
+	return nil, 0, notOnAir
+}
+
+// sendiqControlArgs are the sendiq args that decide whether a replay can be
+// controlled live.
+type sendiqControlArgs struct {
+	SharedMemToken int    `json:"sharedMemToken"`
+	IQType         string `json:"iqType"`
+}
+
+// rejection says why a replay started with these args gets no control
+// block, or nil when it gets one or names its own.
+func (a sendiqControlArgs) rejection() *sendiqControlRejection {
+	if a.SharedMemToken != 0 {
+		return nil
+	}
+
+	iqType := a.IQType
+	if iqType == "" {
+		iqType = sendiqDefaultIQType
+	}
+
+	if iqType == sendiqControlIQType {
+		return nil
+	}
+
+	return &sendiqControlRejection{
+		errorType: "unsupported iq type",
+		message: "runtime control needs a float capture, this one is " +
+			iqType,
+	}
+}
+
+// sendiqStartRejection returns why a replay started with args can't be
+// controlled live, so whoever starts it hears about it right away instead
+// of on the first command.
+func sendiqStartRejection(args json.RawMessage) *sendiqControlRejection {
+	var controlArgs sendiqControlArgs
+	if err := json.Unmarshal(args, &controlArgs); err != nil {
+		return nil
+	}
+
+	return controlArgs.rejection()
+}
+
+// createSENDIQControl makes a shared memory control block for sendiq and
+// puts its token in the args. It returns a func that removes the block
+// once the replay is over, or nil when there's nothing to remove: the args
+// already name a token of their own, or the capture isn't float and giving
+// sendiq a token would make it read the samples wrong.
+func (s *PIrateRF) createSENDIQControl(
+	args json.RawMessage,
+	logger *logrus.Entry,
+) (json.RawMessage, func() error, error) {
+	var argsMap map[string]any
+	if err := json.Unmarshal(args, &argsMap); err != nil {
+		return args, nil, ctxerrors.Wrap(err, "failed to unmarshal args")
+	}
+
+	if token, ok := argsMap[sendiqSharedMemTokenArg]; ok && token != nil {
+		return args, nil, nil
+	}
+
+	if rejection := sendiqStartRejection(args); rejection != nil {
+		logger.WithField("iqType", argsMap[sendiqIQTypeArg]).
+			Debug("runtime control needs a float capture, no token")
+
+		return args, nil, nil
+	}
+
+	token, id, err := allocateSENDIQControl()
+	if err != nil {
+		return args, nil, err
+	}
+
+	remove := func() error {
+		if _, err := unix.SysvShmCtl(id, unix.IPC_RMID, nil); err != nil {
+			return ctxerrors.Wrap(err, "failed to remove control block")
+		}
+
+		logger.WithField("token", token).Debug("removed SENDIQ control block")
+
+		return nil
+	}
+
+	argsMap[sendiqSharedMemTokenArg] = token
+
+	modifiedArgs, err := json.Marshal(argsMap)
+	if err != nil {
+		return args, remove, ctxerrors.Wrap(err, "failed to marshal args")
+	}
+
+	logger.WithField("token", token).Debug("created SENDIQ control block")
+
+	return modifiedArgs, remove, nil
+}
+
+// allocateSENDIQControl creates a control block under a random unused
+// token.
+func allocateSENDIQControl() (int, int, error) {
+	for range sendiqControlKeyAttempts {
+		token := int(rand.Int32N(math.MaxInt32) + 1) //nolint:gosec
+
+		id, err := unix.SysvShmGet(
+			token,
+			sendiqControlSize,
+			unix.IPC_CREAT|unix.IPC_EXCL|sendiqControlPerms,
+		)
+		if errors.Is(err, unix.EEXIST) {
+			continue
+		}
+
+		if err != nil {
+			return 0, 0, ctxerrors.Wrap(err, "failed to create control block")
+		}
+
+		return token, id, nil
+	}
+
+	return 0, 0, ctxerrors.Wrap(
+		commonerrors.ErrFailed, "no free control block token",
+	)
+}
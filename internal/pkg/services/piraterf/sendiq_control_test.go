@@ -0,0 +1,280 @@
+package piraterf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// readSENDIQControl reads the control block the way sendiq does and
+// clears its update flag like sendiq does once it's applied the command.
+func readSENDIQControl(t *testing.T, token int) (sendiqCommand, float64) {
+	t.Helper()
+
+	block := attachSENDIQControl(t, token)
+	require.Equal(t, byte(1), block[0], "update flag should be up")
+	block[0] = 0
+
+	return sendiqCommand(int32(binary.NativeEndian.Uint32(
+			block[sendiqControlCommandOffset:],
+		))),
+		float64(math.Float32frombits(
+			binary.NativeEndian.Uint32(block[sendiqControlValueOffset:]),
+		))
+}
+
+func attachSENDIQControl(t *testing.T, token int) []byte {
+	t.Helper()
+
+	id, err := unix.SysvShmGet(token, 0, 0)
+	require.NoError(t, err)
+
+	block, err := unix.SysvShmAttach(id, 0, 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = unix.SysvShmDetach(block) })
+
+	return block
+}
+
+func TestWriteSENDIQCommand_Layout(t *testing.T) {
+	token, id, err := allocateSENDIQControl()
+	require.NoError(t, err)
+
+	defer func() { _, _ = unix.SysvShmCtl(id, unix.IPC_RMID, nil) }()
+
+	require.NoError(t, writeSENDIQCommand(
+		token, sendiqCommandFrequency, 144500000,
+	))
+
+	// What struct.pack('?if', True, 4444, 144500000.0) gives in the
+	// runtime control example of the gorpitx README
+	expected := []byte{1, 0, 0, 0}
+	expected = binary.NativeEndian.AppendUint32(expected, 4444)
+	expected = binary.NativeEndian.AppendUint32(
+		expected, math.Float32bits(144500000),
+	)
+
+	assert.Equal(t, expected, attachSENDIQControl(t, token)[:len(expected)])
+}
+
+func TestCreateSENDIQControl(t *testing.T) {
+	service := &PIrateRF{}
+	logger := logrus.NewEntry(logrus.New())
+
+	args, remove, err := service.createSENDIQControl(
+		json.RawMessage(`{"freq":434000000,"inputFile":"a.iq","iqType":"float"}`),
+		logger,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, remove)
+
+	var argsMap map[string]any
+	require.NoError(t, json.Unmarshal(args, &argsMap))
+	assert.Equal(t, "a.iq", argsMap["inputFile"])
+
+	token := int(argsMap[sendiqSharedMemTokenArg].(float64))
+	assert.NotZero(t, token)
+
+	_, err = unix.SysvShmGet(token, 0, 0)
+	require.NoError(t, err, "control block should exist")
+
+	require.NoError(t, remove())
+
+	_, err = unix.SysvShmGet(token, 0, 0)
+	require.ErrorIs(t, err, unix.ENOENT, "control block should be gone")
+
+	// A token given by the caller is left alone
+	ownArgs := json.RawMessage(`{"freq":434000000,"sharedMemToken":42}`)
+	args, remove, err = service.createSENDIQControl(ownArgs, logger)
+	require.NoError(t, err)
+	assert.Nil(t, remove)
+	assert.JSONEq(t, string(ownArgs), string(args))
+
+	// A token would make sendiq read an i16 capture as float
+	i16Args := json.RawMessage(`{"freq":434000000,"iqType":"i16"}`)
+	args, remove, err = service.createSENDIQControl(i16Args, logger)
+	require.NoError(t, err)
+	assert.Nil(t, remove)
+	assert.JSONEq(t, string(i16Args), string(args))
+}
+
+func TestPrepareSENDIQExecution_ControlRejection(t *testing.T) {
+	service := &PIrateRF{}
+	logger := logrus.NewEntry(logrus.New())
+
+	tests := []struct {
+		name           string
+		args           string
+		expectRejected string // iq type in the rejection, empty for none
+	}{
+		{name: "default i16", args: `{"freq":434000000}`, expectRejected: "i16"},
+		{
+			name:           "u8",
+			args:           `{"freq":434000000,"iqType":"u8"}`,
+			expectRejected: "u8",
+		},
+		{name: "float", args: `{"freq":434000000,"iqType":"float"}`},
+		{name: "own token", args: `{"freq":434000000,"sharedMemToken":42}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prepared, err := service.prepareModuleExecution(
+				&rpitxExecutionStartMessage{
+					ModuleName: gorpitx.ModuleNameSENDIQ,
+					Args:       json.RawMessage(tt.args),
+				},
+				logger,
+			)
+			require.NoError(t, err)
+
+			defer prepared.cleanup()
+
+			if tt.expectRejected == "" {
+				assert.Nil(t, prepared.controlRejection)
+
+				return
+			}
+
+			require.NotNil(t, prepared.controlRejection)
+			assert.Equal(
+				t, "unsupported iq type", prepared.controlRejection.errorType,
+			)
+			assert.Contains(
+				t, prepared.controlRejection.message, tt.expectRejected,
+			)
+		})
+	}
+}
+
+func TestSENDIQControl(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	em := newExecutionManager(gorpitx.GetInstance(), hub)
+	service := &PIrateRF{websocketHub: hub, executionManager: em}
+	client := wshub.NewClient()
+
+	send := func(
+		handler func(wshub.Hub, *wshub.Client, *dabluveees.Event) error,
+		eventType dabluveees.EventType,
+		data any,
+	) {
+		t.Helper()
+		require.NoError(t, handler(
+			hub, client, dabluveees.NewEvent(eventType, data),
+		))
+	}
+
+	// Nothing on air - nothing gets written
+	send(
+		service.handleSENDIQCarrier, eventTypeSENDIQCarrier, map[string]any{},
+	)
+	assert.Zero(t, service.sendiqLive.values.token)
+
+	// Captures that aren't float never get a token, so they're refused
+	// outright
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNameSENDIQ,
+		args:       json.RawMessage(`{"freq":434000000}`),
+	})
+
+	_, _, rejection := em.activeSENDIQControl()
+	require.NotNil(t, rejection)
+	assert.Equal(t, "unsupported iq type", rejection.errorType)
+	assert.Contains(t, rejection.message, sendiqDefaultIQType)
+
+	inputFile := filepath.Join(t.TempDir(), "capture.iq")
+	require.NoError(t, os.WriteFile(inputFile, []byte{0, 0, 0, 0}, 0o600))
+
+	args, remove, err := service.createSENDIQControl(json.RawMessage(
+		`{"freq":434000000,"iqType":"float","power":1.5,"inputFile":"`+
+			inputFile+`"}`,
+	), logrus.NewEntry(logrus.New()))
+	require.NoError(t, err)
+
+	defer func() { _ = remove() }()
+
+	em.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNameSENDIQ,
+		args:       args,
+	})
+
+	_, token, rejection := em.activeSENDIQControl()
+	require.Nil(t, rejection)
+
+	send(
+		service.handleSENDIQFrequencySet, eventTypeSENDIQFrequencySet,
+		sendiqFrequencySetMessage{Frequency: 433920000},
+	)
+
+	command, value := readSENDIQControl(t, token)
+	assert.Equal(t, sendiqCommandFrequency, command)
+	assert.InDelta(t, 433920000, value, 0)
+	assert.InDelta(t, 433920000, service.sendiqLive.values.frequency, 0)
+	assert.InDelta(t, 1.5, service.sendiqLive.values.power, 0)
+
+	// Out of range values never reach sendiq
+	send(
+		service.handleSENDIQFrequencySet, eventTypeSENDIQFrequencySet,
+		sendiqFrequencySetMessage{Frequency: -1},
+	)
+
+	assert.Zero(t, attachSENDIQControl(t, token)[0])
+
+	send(
+		service.handleSENDIQPowerSet, eventTypeSENDIQPowerSet,
+		sendiqPowerSetMessage{Power: 3},
+	)
+
+	command, value = readSENDIQControl(t, token)
+	assert.Equal(t, sendiqCommandPower, command)
+	assert.InDelta(t, 3, value, 0)
+
+	send(service.handleSENDIQCarrier, eventTypeSENDIQCarrier, map[string]any{})
+
+	command, _ = readSENDIQControl(t, token)
+	assert.Equal(t, sendiqCommandCarrier, command)
+	assert.True(t, service.sendiqLive.values.carrier)
+
+	send(service.handleSENDIQResume, eventTypeSENDIQResume, map[string]any{})
+
+	command, _ = readSENDIQControl(t, token)
+	assert.Equal(t, sendiqCommandIQ, command)
+	assert.False(t, service.sendiqLive.values.carrier)
+}
+
+func TestSENDIQLiveState_Current(t *testing.T) {
+	var live sendiqLiveState
+
+	values := live.current(
+		7, json.RawMessage(`{"freq":434000000,"sharedMemToken":7}`),
+	)
+	assert.Equal(t, 7, values.token)
+	assert.InDelta(t, 434000000, values.frequency, 0)
+	assert.InDelta(t, sendiqDefaultPower, values.power, 0)
+
+	// Same replay keeps its live values
+	live.values.carrier = true
+	assert.True(t, live.current(7, nil).carrier)
+
+	// A new replay starts over
+	values = live.current(
+		8, json.RawMessage(`{"freq":144000000,"power":2,"sharedMemToken":8}`),
+	)
+	assert.False(t, values.carrier)
+	assert.InDelta(t, 144000000, values.frequency, 0)
+	assert.InDelta(t, 2, values.power, 0)
+}
@@ -19,6 +19,59 @@ func (s *PIrateRF) setupWebsocketHub() {
 		s.handleRPITXExecutionStop,
 	)
 
+	s.websocketHub.RegisterEventHandler(
+		eventTypeRPITXExecutionStatus,
+		s.handleRPITXExecutionStatus,
+	)
+
+	// Transmission queue handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeQueueEnqueue,
+		s.handleQueueEnqueue,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeQueueDequeue,
+		s.handleQueueDequeue,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeQueueReorder,
+		s.handleQueueReorder,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeQueueCancel,
+		s.handleQueueCancel,
+	)
+
+	// Scheduler handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeScheduleCreate,
+		s.handleScheduleCreate,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeScheduleList,
+		s.handleScheduleList,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeScheduleDelete,
+		s.handleScheduleDelete,
+	)
+
+	// Execution history handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeHistoryList,
+		s.handleHistoryList,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeHistoryGet,
+		s.handleHistoryGet,
+	)
+
 	// File operation handlers
 	s.websocketHub.RegisterEventHandler(
 		eventTypeFileRename,
@@ -36,6 +89,53 @@ func (s *PIrateRF) setupWebsocketHub() {
 		s.handleAudioPlaylistCreate,
 	)
 
+	s.websocketHub.RegisterEventHandler(
+		eventTypeAudioTrim,
+		s.handleAudioTrim,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeAudioSilenceStrip,
+		s.handleAudioSilenceStrip,
+	)
+
+	// Stored playlist handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistCreate,
+		s.handlePlaylistCreate,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistList,
+		s.handlePlaylistList,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistUpdate,
+		s.handlePlaylistUpdate,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistRename,
+		s.handlePlaylistRename,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistReorder,
+		s.handlePlaylistReorder,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypePlaylistDelete,
+		s.handlePlaylistDelete,
+	)
+
+	// Text-to-speech handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeTTSGenerate,
+		s.handleTTSGenerate,
+	)
+
 	// Preset operation handlers
 	s.websocketHub.RegisterEventHandler(
 		eventTypePresetLoad,
@@ -57,9 +157,97 @@ func (s *PIrateRF) setupWebsocketHub() {
 		s.handlePresetDelete,
 	)
 
+	s.websocketHub.RegisterEventHandler(
+		eventTypeRPITXExecutionDryRun,
+		s.handleRPITXExecutionDryRun,
+	)
+
+	// Live RDS handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeRDSPSSet,
+		s.handleRDSPSSet,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeRDSRTSet,
+		s.handleRDSRTSet,
+	)
+
+	// Live audio handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeLiveBedVolumeSet,
+		s.handleLiveBedVolumeSet,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeLiveBedDuckSet,
+		s.handleLiveBedDuckSet,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeLiveBedStatus,
+		s.handleLiveBedStatus,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeLiveSFXPlay,
+		s.handleLiveSFXPlay,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeLiveSFXStop,
+		s.handleLiveSFXStop,
+	)
+
+	// Live SENDIQ handlers
+	s.websocketHub.RegisterEventHandler(
+		eventTypeSENDIQFrequencySet,
+		s.handleSENDIQFrequencySet,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeSENDIQPowerSet,
+		s.handleSENDIQPowerSet,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeSENDIQCarrier,
+		s.handleSENDIQCarrier,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeSENDIQResume,
+		s.handleSENDIQResume,
+	)
+
+	s.websocketHub.RegisterEventHandler(
+		eventTypeSENDIQStatus,
+		s.handleSENDIQStatus,
+	)
+
 	// Echo handlers (for heartbeat)
 	s.websocketHub.RegisterEventHandler(
 		dabluveees.EventTypeEchoRequest,
-		wshub.EventTypeEchoRequestHandler,
+		s.handleEchoRequest,
 	)
 }
+
+// handleClientJoined brings a freshly connected client up to date with
+// whatever is currently on air.
+func (s *PIrateRF) handleClientJoined(client *wshub.Client) {
+	// A client coming back under its old ID is alive again
+	s.executionManager.heartbeat(client.ID())
+	s.executionManager.sendStatusEvent(client)
+}
+
+// handleEchoRequest answers the frontend heartbeat and feeds the dead-man
+// switch with it.
+func (s *PIrateRF) handleEchoRequest(
+	hub wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	s.executionManager.heartbeat(client.ID())
+
+	return wshub.EventTypeEchoRequestHandler(hub, client, event)
+}
@@ -29,6 +29,8 @@ const (
 type audioPlaylistCreateMessage struct {
 	PlaylistFileName string   `json:"playlistFileName"` // Name for the output file
 	Files            []string `json:"files"`            // Array of full file paths
+	// crossfade, gap, fadeIn and fadeOut in seconds (all optional)
+	playlistTransitions
 }
 
 type audioPlaylistCreateSuccessMessageData struct {
@@ -76,7 +78,11 @@ func (s *PIrateRF) handleAudioPlaylistCreate(
 	}
 
 	// Create playlist by concatenating all files
-	outputPath, err := s.createPlaylistFromFiles(msg.PlaylistFileName, filePaths)
+	outputPath, err := s.createPlaylistFromFiles(
+		msg.PlaylistFileName,
+		filePaths,
+		msg.playlistTransitions,
+	)
 	if err != nil {
 		logger.WithError(err).Error("failed to create playlist")
 		s.sendAudioPlaylistCreateErrorEvent(
@@ -88,6 +94,8 @@ func (s *PIrateRF) handleAudioPlaylistCreate(
 		return nil
 	}
 
+	cacheAudioPeaks(outputPath)
+
 	logger.Infof("Audio playlist created successfully: %s", outputPath)
 	s.sendAudioPlaylistCreateSuccessEvent(msg.PlaylistFileName, outputPath)
 
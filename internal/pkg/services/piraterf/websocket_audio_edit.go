@@ -0,0 +1,231 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeAudioTrim = dabluveees.EventType(
+		"audio.trim",
+	)
+	eventTypeAudioTrimSuccess = dabluveees.EventType(
+		"audio.trim.success",
+	)
+	eventTypeAudioTrimError = dabluveees.EventType(
+		"audio.trim.error",
+	)
+	eventTypeAudioSilenceStrip = dabluveees.EventType(
+		"audio.silence.strip",
+	)
+	eventTypeAudioSilenceStripSuccess = dabluveees.EventType(
+		"audio.silence.strip.success",
+	)
+	eventTypeAudioSilenceStripError = dabluveees.EventType(
+		"audio.silence.strip.error",
+	)
+)
+
+type audioTrimMessage struct {
+	FilePath string `json:"filePath"` // HTTP or file system path of the WAV
+	audioTrim
+	audioEditOutput
+}
+
+type audioSilenceStripMessage struct {
+	FilePath string `json:"filePath"` // HTTP or file system path of the WAV
+	audioSilenceStrip
+	audioEditOutput
+}
+
+type audioEditSuccessMessageData struct {
+	FileName  string  `json:"fileName"` // The file that was edited
+	FilePath  string  `json:"filePath"` // Where the result went
+	Replaced  bool    `json:"replaced"`
+	Duration  float64 `json:"duration,omitempty"` // seconds
+	Timestamp int64   `json:"timestamp"`
+}
+
+type audioEditErrorMessageData struct {
+	FileName  string `json:"fileName"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (s *PIrateRF) handleAudioTrim(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Audio trim requested")
+
+	var msg audioTrimMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		logger.WithError(err).Error("failed to unmarshal audio trim message")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioTrimError,
+			msg.FilePath,
+			"invalid request",
+			err.Error(),
+		)
+
+		return nil
+	}
+
+	inputPath, err := s.audioEditInputPath(msg.FilePath)
+	if err != nil {
+		logger.WithError(err).Error("refused to edit audio")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioTrimError,
+			msg.FilePath,
+			"invalid file",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	outputPath, err := s.trimAudioFile(
+		inputPath,
+		msg.audioTrim,
+		msg.audioEditOutput,
+	)
+	if err != nil {
+		logger.WithError(err).Error("failed to trim audio")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioTrimError,
+			msg.FilePath,
+			"trim failed",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	logger.WithFields(logrus.Fields{
+		"start": msg.Start,
+		"end":   msg.End,
+		"cut":   msg.Cut,
+	}).Infof("Audio trimmed: %s", outputPath)
+	s.sendAudioEditSuccessEvent(
+		eventTypeAudioTrimSuccess,
+		msg.FilePath,
+		outputPath,
+		msg.Replace,
+	)
+
+	return nil
+}
+
+func (s *PIrateRF) handleAudioSilenceStrip(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Audio silence strip requested")
+
+	var msg audioSilenceStripMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		logger.WithError(err).
+			Error("failed to unmarshal audio silence strip message")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioSilenceStripError,
+			msg.FilePath,
+			"invalid request",
+			err.Error(),
+		)
+
+		return nil
+	}
+
+	inputPath, err := s.audioEditInputPath(msg.FilePath)
+	if err != nil {
+		logger.WithError(err).Error("refused to edit audio")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioSilenceStripError,
+			msg.FilePath,
+			"invalid file",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	outputPath, err := s.stripAudioSilence(
+		inputPath,
+		msg.audioSilenceStrip,
+		msg.audioEditOutput,
+	)
+	if err != nil {
+		logger.WithError(err).Error("failed to strip audio silence")
+		s.sendAudioEditErrorEvent(
+			eventTypeAudioSilenceStripError,
+			msg.FilePath,
+			"strip failed",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	logger.Infof("Audio silence stripped: %s", outputPath)
+	s.sendAudioEditSuccessEvent(
+		eventTypeAudioSilenceStripSuccess,
+		msg.FilePath,
+		outputPath,
+		msg.Replace,
+	)
+
+	return nil
+}
+
+// Event sending functions for audio edit operations.
+func (s *PIrateRF) sendAudioEditSuccessEvent(
+	eventType dabluveees.EventType,
+	fileName, filePath string,
+	replaced bool,
+) {
+	data := audioEditSuccessMessageData{
+		FileName:  fileName,
+		FilePath:  filePath,
+		Replaced:  replaced,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if peaks, ok := loadAudioPeaks(filePath); ok {
+		data.Duration = peaks.Duration
+	}
+
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(eventType, data))
+}
+
+func (s *PIrateRF) sendAudioEditErrorEvent(
+	eventType dabluveees.EventType,
+	fileName, errorType, message string,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventType,
+		audioEditErrorMessageData{
+			FileName:  fileName,
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
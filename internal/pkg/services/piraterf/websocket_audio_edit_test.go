@@ -0,0 +1,154 @@
+package piraterf
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/goenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleAudioTrim(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	filesDir := t.TempDir()
+	inputPath := filepath.Join(filesDir, audioUploadsPath, "song.wav")
+	writeTestWAV(t, inputPath, 1, 1000, make([]int16, 3000))
+	writeTestWAV(t, filepath.Join(filesDir, "outside.wav"), 1, 1000, nil)
+
+	_, err := writeAudioPeaks(inputPath)
+	require.NoError(t, err)
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(inputPath),
+		commander.Any(),
+		commander.Exact("trim"),
+		commander.Exact("0"),
+		commander.Exact("=1.000"),
+		commander.Exact("=2.000"),
+	)
+
+	service := &PIrateRF{
+		serviceCtx:   context.Background(),
+		config:       Config{FilesDir: filesDir},
+		commander:    mock,
+		websocketHub: hub,
+	}
+
+	tests := []struct {
+		name string
+		data any
+	}{
+		{name: "invalid json", data: invalidJSONData},
+		{
+			name: "missing file",
+			data: audioTrimMessage{FilePath: "/files/audio/uploads/nope.wav"},
+		},
+		{
+			name: "outside uploads and sfx",
+			data: audioTrimMessage{
+				FilePath:        "/files/audio/uploads/../../outside.wav",
+				audioTrim:       audioTrim{Start: 1, End: 2},
+				audioEditOutput: audioEditOutput{Replace: true},
+			},
+		},
+		{
+			name: "cut",
+			data: audioTrimMessage{
+				FilePath:  "/files/audio/uploads/song.wav",
+				audioTrim: audioTrim{Start: 1, End: 2, Cut: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, service.handleAudioTrim(
+				hub,
+				wshub.NewClient(),
+				dabluveees.NewEvent(eventTypeAudioTrim, tt.data),
+			))
+		})
+	}
+
+	require.NoError(t, mock.VerifyExpectations())
+	assert.FileExists(t, filepath.Join(filesDir, audioUploadsPath, "song_cut.wav"))
+	assert.Len(t, mock.CallOrder(), 1)
+}
+
+func TestHandleAudioSilenceStrip(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	filesDir := t.TempDir()
+	inputPath := filepath.Join(filesDir, audioUploadsPath, "song.wav")
+	writeTestWAV(t, inputPath, 1, 1000, make([]int16, 3000))
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.ExpectWithMatchers(constants.ToolSox,
+		commander.Exact(inputPath),
+		commander.Any(),
+		commander.Exact("silence"), commander.Exact("1"),
+		commander.Exact("0.250"), commander.Exact("-45.0d"),
+		commander.Exact("reverse"),
+		commander.Exact("silence"), commander.Exact("1"),
+		commander.Exact("0.250"), commander.Exact("-45.0d"),
+		commander.Exact("reverse"),
+	)
+
+	service := &PIrateRF{
+		serviceCtx:   context.Background(),
+		config:       Config{FilesDir: filesDir},
+		commander:    mock,
+		websocketHub: hub,
+	}
+
+	tests := []struct {
+		name string
+		data any
+	}{
+		{name: "invalid json", data: invalidJSONData},
+		{
+			name: "bad threshold",
+			data: audioSilenceStripMessage{
+				FilePath:          "/files/audio/uploads/song.wav",
+				audioSilenceStrip: audioSilenceStrip{Threshold: 3},
+			},
+		},
+		{
+			name: "strip",
+			data: audioSilenceStripMessage{
+				FilePath: "/files/audio/uploads/song.wav",
+				audioSilenceStrip: audioSilenceStrip{
+					Threshold: -45, MinDuration: 0.25,
+				},
+				audioEditOutput: audioEditOutput{OutputFileName: "clean"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, service.handleAudioSilenceStrip(
+				hub,
+				wshub.NewClient(),
+				dabluveees.NewEvent(eventTypeAudioSilenceStrip, tt.data),
+			))
+		})
+	}
+
+	require.NoError(t, mock.VerifyExpectations())
+	assert.FileExists(t, filepath.Join(filesDir, audioUploadsPath, "clean.wav"))
+}
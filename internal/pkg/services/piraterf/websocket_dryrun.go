@@ -0,0 +1,418 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeRPITXExecutionDryRun = dabluveees.EventType(
+		"rpitx.execution.dryrun",
+	)
+	eventTypeRPITXExecutionDryRunResult = dabluveees.EventType(
+		"rpitx.execution.dryrun.result",
+	)
+
+	// dryRunScratchDirPattern names the dir a dry run makes its temp files
+	// in, it's gone once the dry run is done.
+	dryRunScratchDirPattern = "piraterf_dryrun_"
+
+	// dryRunMaxStdin caps how much of a module's stdin ends up in the result.
+	dryRunMaxStdin = 64 * 1024
+
+	// shellSafeChars never need quoting in a shell.
+	shellSafeChars = "abcdefghijklmnopqrstuvwxyz" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:=+,@%"
+)
+
+// errorLocation matches the [file:line in func] locations ctxerrors adds.
+var errorLocation = regexp.MustCompile( //nolint:gochecknoglobals
+	` \[[^\[\]]+:\d+ in [^\[\]]+\]`,
+)
+
+type dryRunFieldError struct {
+	Field   string `json:"field"` // empty when it's not about a single field
+	Message string `json:"message"`
+}
+
+type rpitxExecutionDryRunResultMessageData struct {
+	ModuleName  gorpitx.ModuleName `json:"moduleName"`
+	Valid       bool               `json:"valid"`
+	Errors      []dryRunFieldError `json:"errors"`
+	Args        json.RawMessage    `json:"args,omitempty"` // as sent
+	Timeout     int                `json:"timeout"`        // final, seconds
+	Command     []string           `json:"command,omitempty"`
+	CommandLine string             `json:"commandLine,omitempty"`
+	Env         []string           `json:"env,omitempty"`
+	Stdin       string             `json:"stdin,omitempty"`
+	Timestamp   int64              `json:"timestamp"`
+}
+
+// handleRPITXExecutionDryRun takes the same message as
+// rpitx.execution.start and runs it through everything a real start goes
+// through - module check, band plan, the prepare step with its audio/image
+// rewriting, the module's own argument parsing - without putting anything
+// on air. The result only goes back to whoever asked, with triggeredBy set
+// to the request's ID.
+func (s *PIrateRF) handleRPITXExecutionDryRun(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("RPITX execution dry run requested")
+
+	result := s.dryRunExecution(event.Data, logger)
+
+	reply := dabluveees.NewEvent(eventTypeRPITXExecutionDryRunResult, result)
+	reply.TriggeredBy = &event.ID
+
+	client.SendEvent(reply)
+
+	return nil
+}
+
+// dryRunExecution checks a start request. The prepare step runs for real
+// with its temp files made in a scratch dir, so the command has the args
+// rpitx would get - converted image, control pipe, temp playlist, silence
+// padding, control block token - and whatever got made is removed again.
+func (s *PIrateRF) dryRunExecution(
+	data json.RawMessage,
+	logger *logrus.Entry,
+) rpitxExecutionDryRunResultMessageData {
+	result := rpitxExecutionDryRunResultMessageData{
+		Errors:    []dryRunFieldError{},
+		Timestamp: time.Now().Unix(),
+	}
+
+	var msg rpitxExecutionStartMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		result.Errors = append(result.Errors, dryRunFieldError{
+			Message: "invalid message: " + errorMessage(err),
+		})
+
+		return result
+	}
+
+	result.ModuleName = msg.ModuleName
+	result.Args = msg.Args
+	result.Timeout = msg.Timeout
+
+	if _, ok := newDryRunModule(msg.ModuleName); !ok {
+		result.Errors = append(result.Errors, dryRunFieldError{
+			Field:   "moduleName",
+			Message: "unknown module",
+		})
+
+		return result
+	}
+
+	if violation := s.bandPlan.check(msg.ModuleName, msg.Args); violation != nil {
+		result.Errors = append(result.Errors, dryRunFieldError{
+			Field:   frequencyField(msg.ModuleName),
+			Message: violation.message(),
+		})
+	}
+
+	scratchDir, err := os.MkdirTemp("", dryRunScratchDirPattern)
+	if err != nil {
+		result.Errors = append(result.Errors, dryRunFieldError{
+			Message: "setup failed: " + errorMessage(err),
+		})
+
+		return result
+	}
+
+	defer removeDryRunScratchDir(scratchDir, logger)
+
+	msg.scratchDir = scratchDir
+	args := msg.Args
+
+	prepared, err := s.prepareModuleExecution(&msg, logger)
+	if err != nil {
+		// Keep going with the args as sent so we still get to check the
+		// rest of them
+		result.Errors = append(result.Errors, dryRunFieldError{
+			Field:   preparedField(msg.ModuleName),
+			Message: errorMessage(err),
+		})
+	} else {
+		// Not everything lives in the scratch dir - the SENDIQ control
+		// block doesn't
+		defer prepared.cleanup()
+
+		args = prepared.args
+		result.Timeout = prepared.timeout
+	}
+
+	parsedArgs, stdin, fieldErrors := parseDryRunArgs(msg.ModuleName, args)
+	result.Errors = append(result.Errors, fieldErrors...)
+
+	if stdin != nil {
+		result.Stdin = readDryRunStdin(stdin)
+	}
+
+	if len(result.Errors) > 0 {
+		return result
+	}
+
+	result.Valid = true
+
+	name, cmdArgs, env := s.rpitx.Command(msg.ModuleName, parsedArgs)
+	result.Command = append([]string{name}, cmdArgs...)
+	result.Env = env
+	result.CommandLine = shellJoin(result.Env, result.Command)
+
+	return result
+}
+
+func removeDryRunScratchDir(scratchDir string, logger *logrus.Entry) {
+	if err := os.RemoveAll(scratchDir); err != nil {
+		logger.WithError(err).WithField("scratchDir", scratchDir).
+			Warn("Failed to remove dry run scratch dir")
+	}
+}
+
+// parseDryRunArgs runs the module's ParseArgs. Modules stop at the first bad
+// field so we take that field out and go again to find the next one, until
+// the args parse or we can't tell which field is to blame. A bad required
+// field still hides whatever the module checks after it.
+func parseDryRunArgs(
+	moduleName gorpitx.ModuleName,
+	args json.RawMessage,
+) ([]string, io.Reader, []dryRunFieldError) {
+	var argsMap map[string]any
+	if err := json.Unmarshal(args, &argsMap); err != nil {
+		return nil, nil, []dryRunFieldError{{
+			Field:   "args",
+			Message: errorMessage(err),
+		}}
+	}
+
+	var fieldErrors []dryRunFieldError
+
+	seen := map[string]bool{}
+
+	for {
+		current, err := json.Marshal(argsMap)
+		if err != nil {
+			return nil, nil, append(fieldErrors, dryRunFieldError{
+				Field:   "args",
+				Message: errorMessage(err),
+			})
+		}
+
+		// Modules unmarshal on top of what they had, so a field we took out
+		// would still be there in a reused one
+		module, _ := newDryRunModule(moduleName)
+
+		parsedArgs, stdin, err := module.ParseArgs(current)
+		if err == nil {
+			if len(fieldErrors) > 0 {
+				closeDryRunStdin(stdin)
+
+				return nil, nil, fieldErrors
+			}
+
+			return parsedArgs, stdin, nil
+		}
+
+		field := errorField(module, argsMap, err)
+
+		// Taking the field out just got it reported again - it's required
+		// and we already said what's wrong with it
+		if seen[field] {
+			return nil, nil, fieldErrors
+		}
+
+		fieldErrors = append(fieldErrors, dryRunFieldError{
+			Field:   field,
+			Message: errorMessage(err),
+		})
+
+		if field == "" {
+			return nil, nil, fieldErrors
+		}
+
+		seen[field] = true
+
+		delete(argsMap, field)
+	}
+}
+
+// errorField works out which field a module's validation error is about.
+// JSON type errors say so, otherwise the error names the field in plain
+// words or mentions the offending value ("file: x.wav: file not found").
+func errorField(
+	module gorpitx.Module,
+	argsMap map[string]any,
+	err error,
+) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return strings.Split(typeErr.Field, ".")[0]
+	}
+
+	msg := errorMessage(err)
+
+	// Validation messages start with what they're about - "PS text must
+	// be...", "sample rate must be..." - somewhere along the wrap chain
+	for part := range strings.SplitSeq(msg, ": ") {
+		if field := fieldNamedBy(module, part); field != "" {
+			return field
+		}
+	}
+
+	for name, value := range argsMap {
+		if str, ok := value.(string); ok && str != "" &&
+			strings.Contains(msg, str) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// fieldNamedBy returns the module field the text starts with. The longest
+// name wins so "freq" doesn't grab a "frequency" message meant for another
+// field.
+func fieldNamedBy(module gorpitx.Module, text string) string {
+	normalizedText := normalizeFieldName(text)
+	field := ""
+
+	for _, name := range moduleFieldNames(module) {
+		if strings.HasPrefix(normalizedText, normalizeFieldName(name)) &&
+			len(name) > len(field) {
+			field = name
+		}
+	}
+
+	return field
+}
+
+// moduleFieldNames returns the JSON names of a module's args.
+func moduleFieldNames(module gorpitx.Module) []string {
+	moduleType := reflect.TypeOf(module)
+	if moduleType.Kind() == reflect.Pointer {
+		moduleType = moduleType.Elem()
+	}
+
+	if moduleType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return slices.Collect(maps.Keys(jsonFieldTypes(moduleType)))
+}
+
+func normalizeFieldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+
+		return r
+	}, strings.ToLower(s))
+}
+
+// errorMessage returns the error text without the [file:line in func]
+// locations ctxerrors adds - they mean nothing to whoever reads the result.
+func errorMessage(err error) string {
+	return errorLocation.ReplaceAllString(err.Error(), "")
+}
+
+// newDryRunModule returns a fresh module to parse args with. The instances
+// inside gorpitx keep the last parsed args so we stay away from them while
+// something may be on air.
+func newDryRunModule(name gorpitx.ModuleName) (gorpitx.Module, bool) {
+	argsType, ok := moduleArgsType(name)
+	if !ok {
+		return nil, false
+	}
+
+	module, ok := reflect.New(argsType).Interface().(gorpitx.Module)
+
+	return module, ok
+}
+
+// frequencyField is the args field executionFrequency reads.
+func frequencyField(moduleName gorpitx.ModuleName) string {
+	switch moduleName {
+	case gorpitx.ModuleNamePIFMRDS, gorpitx.ModuleNameSENDIQ:
+		return "freq"
+	default:
+		return "frequency"
+	}
+}
+
+// preparedField is the args field prepare step errors are about, empty
+// when they can be about more than one.
+func preparedField(moduleName gorpitx.ModuleName) string {
+	switch moduleName {
+	case gorpitx.ModuleNamePIFMRDS:
+		return "audio"
+	case gorpitx.ModuleNameSPECTRUMPAINT:
+		return "pictureFile"
+	default:
+		return ""
+	}
+}
+
+func readDryRunStdin(stdin io.Reader) string {
+	defer closeDryRunStdin(stdin)
+
+	data, err := io.ReadAll(io.LimitReader(stdin, dryRunMaxStdin))
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+func closeDryRunStdin(stdin io.Reader) {
+	if closer, ok := stdin.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// shellJoin builds a command line that can be pasted into a shell, env
+// assignments first.
+func shellJoin(env, command []string) string {
+	words := make([]string, 0, len(env)+len(command))
+
+	for _, assignment := range env {
+		name, value, _ := strings.Cut(assignment, "=")
+		words = append(words, name+"="+shellQuote(value))
+	}
+
+	for _, arg := range command {
+		words = append(words, shellQuote(arg))
+	}
+
+	return strings.Join(words, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, shellSafeChars) == "" {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
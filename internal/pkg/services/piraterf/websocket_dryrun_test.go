@@ -0,0 +1,318 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// dryRunRPITX returns gorpitx building commands the way they run on the Pi
+// rather than the dev mock loop.
+func dryRunRPITX(t *testing.T) *gorpitx.RPITX {
+	t.Helper()
+
+	// Set ENV=dev to avoid root check
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	rpitx := gorpitx.GetInstance()
+
+	t.Setenv(goenv.EnvVarName, goenv.Prod)
+
+	return rpitx
+}
+
+func TestDryRunExecution(t *testing.T) {
+	rpitx := dryRunRPITX(t)
+
+	audioFile := filepath.Join(t.TempDir(), "song.wav")
+	require.NoError(t, os.WriteFile(audioFile, []byte("RIFF"), filePerms))
+
+	tests := []struct {
+		name    string
+		plan    *bandPlan
+		data    string
+		valid   bool
+		fields  []string
+		command string // how the command ends
+		env     string // what the env starts with
+	}{
+		{
+			name:    "valid tune",
+			data:    `{"moduleName":"tune","args":{"frequency":144500000}}`,
+			valid:   true,
+			command: "/tune -f 144500000",
+		},
+		{
+			name: "script module gets its env",
+			data: `{"moduleName":"fsk","args":{"frequency":144500000,` +
+				`"inputType":"text","text":"hi"}}`,
+			valid:   true,
+			command: "stdbuf -oL /tmp/fsk.sh 50 144500000",
+			env:     "RPITX_PATH=",
+		},
+		{
+			name:   "unknown module",
+			data:   `{"moduleName":"nope","args":{}}`,
+			fields: []string{"moduleName"},
+		},
+		{
+			name:   "invalid message",
+			data:   `{"moduleName":`,
+			fields: []string{""},
+		},
+		{
+			name: "every bad optional field is reported",
+			data: `{"moduleName":"pifmrds","args":{"freq":107.9,` +
+				`"audio":"` + audioFile + `","pi":"zz","ps":"WAYTOOLONG"}}`,
+			fields: []string{"pi", "ps"},
+		},
+		{
+			name:   "wrong type",
+			data:   `{"moduleName":"morse","args":{"frequency":"high"}}`,
+			fields: []string{"frequency"},
+		},
+		{
+			name: "band plan",
+			plan: testBandPlan(),
+			data: `{"moduleName":"morse","args":{"frequency":433000000,` +
+				`"rate":20,"message":"CQ"}}`,
+			fields: []string{"frequency"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &PIrateRF{bandPlan: tt.plan, rpitx: rpitx}
+
+			result := service.dryRunExecution(
+				json.RawMessage(tt.data), logrus.NewEntry(logrus.New()),
+			)
+
+			assert.Equal(t, tt.valid, result.Valid)
+
+			fields := make([]string, 0, len(result.Errors))
+			for _, fieldErr := range result.Errors {
+				fields = append(fields, fieldErr.Field)
+				assert.NotContains(t, fieldErr.Message, ".go:")
+			}
+
+			if !tt.valid {
+				assert.Equal(t, tt.fields, fields)
+				assert.Empty(t, result.CommandLine)
+
+				return
+			}
+
+			assert.Empty(t, fields)
+			assert.Equal(t, []string{"stdbuf", "-oL"}, result.Command[:2])
+			assert.True(
+				t,
+				strings.HasSuffix(strings.Join(result.Command, " "), tt.command),
+				result.Command,
+			)
+
+			if tt.env == "" {
+				assert.Empty(t, result.Env)
+
+				return
+			}
+
+			require.Len(t, result.Env, 1)
+			assert.True(t, strings.HasPrefix(result.Env[0], tt.env))
+			assert.True(t, strings.HasPrefix(result.CommandLine, tt.env))
+		})
+	}
+}
+
+// argAfter returns the arg following flag in a command.
+func argAfter(t *testing.T, command []string, flag string) string {
+	t.Helper()
+
+	i := slices.Index(command, flag)
+	require.NotEqual(t, -1, i, "%s not in %v", flag, command)
+	require.Less(t, i+1, len(command))
+
+	return command[i+1]
+}
+
+func TestDryRunExecution_RunsPrepareStep(t *testing.T) {
+	rpitx := dryRunRPITX(t)
+
+	store, filesDir := newTestPlaylistStore(t)
+	audioDir := filepath.Join(filesDir, audioFilesDir)
+	audioFile := filepath.Join(audioDir, "uploads", "a.wav")
+
+	pl, err := store.create("Set", []playlistItem{
+		{File: "uploads/a.wav"},
+		{File: "uploads/b.wav"},
+	})
+	require.NoError(t, err)
+
+	picture := filepath.Join(filesDir, imagesUploadsPath, "pic.png")
+	require.NoError(t, os.MkdirAll(filepath.Dir(picture), dirPerms))
+	require.NoError(t, os.WriteFile(picture, []byte("PNG"), filePerms))
+
+	iqFile := filepath.Join(t.TempDir(), "capture.iq")
+	require.NoError(t, os.WriteFile(iqFile, []byte("IQ"), filePerms))
+
+	uploadDir := t.TempDir()
+	micSocketPath, _ := listenLiveAudioSocket(t, uploadDir)
+
+	tests := []struct {
+		name     string
+		data     string
+		commands func(mock *fileCreatingMockCommander)
+		check    func(t *testing.T, command []string)
+	}{
+		{
+			name: "pifmrds gets a control pipe",
+			data: `{"moduleName":"pifmrds","args":{"freq":107.9,"audio":"` +
+				audioFile + `"}}`,
+			check: func(t *testing.T, command []string) {
+				t.Helper()
+
+				assert.Equal(t, audioFile, argAfter(t, command, "-audio"))
+				assert.NoFileExists(t, argAfter(t, command, "-ctl"))
+			},
+		},
+		{
+			name: "stored playlist is rendered",
+			data: `{"moduleName":"pifmrds","args":{"freq":107.9,"audio":"` +
+				playlistAudioPrefix + pl.ID.String() + `"}}`,
+			commands: func(mock *fileCreatingMockCommander) {
+				mock.ExpectWithMatchers(constants.ToolSox,
+					commander.Exact(audioFile),
+					commander.Exact(filepath.Join(audioDir, "uploads", "b.wav")),
+					commander.Exact("-r"), commander.Exact(audioSampleRate),
+					commander.Exact("-b"), commander.Exact(audioBitDepth),
+					commander.Exact("-c"), commander.Exact(audioChannels),
+					commander.Regex(dryRunScratchDirPattern+`.+\.wav$`),
+				)
+			},
+			check: func(t *testing.T, command []string) {
+				t.Helper()
+
+				rendered := argAfter(t, command, "-audio")
+				assert.Contains(t, rendered, dryRunScratchDirPattern)
+				assert.NoFileExists(t, rendered)
+			},
+		},
+		{
+			name: "spectrumpaint picture is converted from a copy",
+			data: `{"moduleName":"spectrumpaint","args":{"pictureFile":"` +
+				picture + `","frequency":433920000}}`,
+			commands: func(mock *fileCreatingMockCommander) {
+				matchers := []commander.ArgumentMatcher{
+					commander.Regex(dryRunScratchDirPattern + `.+/pic\.png$`),
+				}
+				for range 11 {
+					matchers = append(matchers, commander.Any())
+				}
+
+				mock.ExpectWithMatchers("convert", append(
+					matchers, commander.Regex(`\.yuv$`),
+				)...)
+			},
+			check: func(t *testing.T, command []string) {
+				t.Helper()
+
+				converted := command[len(command)-2]
+				assert.Contains(t, converted, dryRunScratchDirPattern)
+				assert.True(t, strings.HasSuffix(converted, ".Y"), converted)
+				assert.NoFileExists(t, converted)
+				assert.FileExists(t, picture)
+			},
+		},
+		{
+			name: "sendiq gets a control block",
+			data: `{"moduleName":"sendiq","args":{"inputFile":"` + iqFile +
+				`","freq":144500000,"iqType":"float"}}`,
+			check: func(t *testing.T, command []string) {
+				t.Helper()
+
+				token, err := strconv.Atoi(argAfter(t, command, "-m"))
+				require.NoError(t, err)
+
+				_, err = unix.SysvShmGet(token, 0, 0)
+				assert.ErrorIs(t, err, unix.ENOENT)
+			},
+		},
+		{
+			name: "live mix isn't started",
+			data: `{"moduleName":"audiosock-broadcast","args":{` +
+				`"socketPath":"` + micSocketPath + `","frequency":7100000}}`,
+			check: func(t *testing.T, _ []string) {
+				t.Helper()
+
+				uploads, err := filepath.Glob(filepath.Join(uploadDir, "*"))
+				require.NoError(t, err)
+				assert.Equal(t, []string{micSocketPath}, uploads)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &fileCreatingMockCommander{
+				MockCommander: *commander.NewMock(),
+			}
+			if tt.commands != nil {
+				tt.commands(mock)
+			}
+
+			// No execution manager - anything trying to go on air would panic
+			service := &PIrateRF{
+				serviceCtx: context.Background(),
+				config:     Config{FilesDir: filesDir, UploadDir: uploadDir},
+				commander:  mock,
+				playlists:  store,
+				rpitx:      rpitx,
+			}
+
+			result := service.dryRunExecution(
+				json.RawMessage(tt.data), logrus.NewEntry(logrus.New()),
+			)
+			require.True(t, result.Valid, result.Errors)
+			require.NoError(t, mock.VerifyExpectations())
+
+			// The args come back as sent
+			var msg rpitxExecutionStartMessage
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &msg))
+			assert.JSONEq(t, string(msg.Args), string(result.Args))
+
+			tt.check(t, result.Command)
+		})
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(
+		t,
+		`A='$HOME/x' stdbuf -oL /bin/x -m 'it'\''s me' ''`,
+		shellJoin(
+			[]string{"A=$HOME/x"},
+			[]string{"stdbuf", "-oL", "/bin/x", "-m", "it's me", ""},
+		),
+	)
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := ctxerrors.Wrap(ctxerrors.New("inner"), "outer")
+
+	assert.Equal(t, "outer: inner", errorMessage(err))
+}
@@ -101,6 +101,10 @@ func (s *PIrateRF) handleFileRename(
 		return nil
 	}
 
+	if err := renameAudioSidecars(oldPath, newPath); err != nil {
+		logger.WithError(err).Warn("failed to rename audio sidecars")
+	}
+
 	logger.Infof("File renamed from %s to %s", msg.FilePath, msg.NewName)
 	s.sendFileRenameSuccessEvent(msg.FilePath, msg.NewName)
 
@@ -199,6 +203,10 @@ func (s *PIrateRF) handleFileDelete(
 		return nil
 	}
 
+	if err := removeAudioSidecars(filePath); err != nil {
+		logger.WithError(err).Warn("failed to remove audio sidecars")
+	}
+
 	logger.Infof("File deleted: %s", msg.FilePath)
 	s.sendFileDeleteSuccessEvent(msg.FilePath)
 
@@ -0,0 +1,145 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeHistoryList        dabluveees.EventType = "history.list"
+	eventTypeHistoryListSuccess dabluveees.EventType = "history.list.success"
+	eventTypeHistoryGet         dabluveees.EventType = "history.get"
+	eventTypeHistoryGetSuccess  dabluveees.EventType = "history.get.success"
+	eventTypeHistoryError       dabluveees.EventType = "history.error"
+)
+
+type historyGetMessage struct {
+	ID string `json:"id"`
+}
+
+type historyListSuccessMessageData struct {
+	historyPage
+
+	Timestamp int64 `json:"timestamp"`
+}
+
+type historyGetSuccessMessageData struct {
+	Record    *historyRecord `json:"record"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+type historyErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// handleHistoryList answers with a page of past executions. The request
+// data is an optional historyQuery.
+func (s *PIrateRF) handleHistoryList(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("History list requested")
+
+	var query historyQuery
+	if len(event.Data) > 0 {
+		if err := json.Unmarshal(event.Data, &query); err != nil {
+			s.sendHistoryErrorEvent(client, "invalid request", err.Error())
+
+			return nil
+		}
+	}
+
+	page, err := s.history.list(query)
+	if err != nil {
+		s.sendHistoryErrorEvent(client, "read failed", err.Error())
+
+		return nil
+	}
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeHistoryListSuccess,
+		historyListSuccessMessageData{
+			historyPage: page,
+			Timestamp:   time.Now().Unix(),
+		},
+	))
+
+	return nil
+}
+
+func (s *PIrateRF) handleHistoryGet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("History record requested")
+
+	var msg historyGetMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendHistoryErrorEvent(client, "invalid request", err.Error())
+
+		return nil
+	}
+
+	id, err := uuid.Parse(msg.ID)
+	if err != nil {
+		s.sendHistoryErrorEvent(client, "invalid request", err.Error())
+
+		return nil
+	}
+
+	record, err := s.history.get(id)
+	if err != nil {
+		s.sendHistoryErrorEvent(client, "record not found", err.Error())
+
+		return nil
+	}
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeHistoryGetSuccess,
+		historyGetSuccessMessageData{
+			Record:    record,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+
+	return nil
+}
+
+func (s *PIrateRF) sendHistoryErrorEvent(
+	client *wshub.Client,
+	errorType, message string,
+) {
+	logrus.WithFields(logrus.Fields{
+		"errorType": errorType,
+		"message":   message,
+	}).Error("history error occurred")
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeHistoryError,
+		historyErrorMessageData{
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
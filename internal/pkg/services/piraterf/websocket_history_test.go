@@ -0,0 +1,75 @@
+package piraterf
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleHistoryEvents(t *testing.T) {
+	id := uuid.New()
+	service := &PIrateRF{
+		history: newTestHistoryStore(t, historyRecord{
+			ID:         id,
+			ModuleName: gorpitx.ModuleNameTUNE,
+		}),
+	}
+
+	tests := []struct {
+		name      string
+		eventType dabluveees.EventType
+		handler   wshub.EventHandler
+		data      any
+	}{
+		{
+			name:      "list without query",
+			eventType: eventTypeHistoryList,
+			handler:   service.handleHistoryList,
+		},
+		{
+			name:      "list with query",
+			eventType: eventTypeHistoryList,
+			handler:   service.handleHistoryList,
+			data:      map[string]any{"moduleName": "tune", "limit": 10},
+		},
+		{
+			name:      "list with invalid query",
+			eventType: eventTypeHistoryList,
+			handler:   service.handleHistoryList,
+			data:      map[string]any{"limit": "ten"},
+		},
+		{
+			name:      "get existing record",
+			eventType: eventTypeHistoryGet,
+			handler:   service.handleHistoryGet,
+			data:      map[string]any{"id": id.String()},
+		},
+		{
+			name:      "get unknown record",
+			eventType: eventTypeHistoryGet,
+			handler:   service.handleHistoryGet,
+			data:      map[string]any{"id": uuid.NewString()},
+		},
+		{
+			name:      "get invalid id",
+			eventType: eventTypeHistoryGet,
+			handler:   service.handleHistoryGet,
+			data:      map[string]any{"id": "nope"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := dabluveees.NewEvent(tt.eventType, tt.data)
+
+			require.NotPanics(t, func() {
+				err := tt.handler(nil, wshub.NewClient(), event)
+				require.NoError(t, err)
+			})
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package piraterf
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/sirupsen/logrus"
+)
+
+// joinAwareUpgradeHandler upgrades websocket requests like
+// wshub.UpgradeHandler and tells us when a client joins. The upgrade
+// handler registers the client first and attaches the connection right
+// after, so the callback runs once it's done and the new connection is
+// actually there - anything sent before that would go nowhere.
+func joinAwareUpgradeHandler(
+	hub wshub.Hub,
+	onClientJoined func(client *wshub.Client),
+	opts ...wshub.UpgradeHandlerOption,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		joining := &joiningHub{Hub: hub}
+
+		wshub.UpgradeHandler(joining, opts...)(w, r)
+
+		if joining.client == nil {
+			return
+		}
+
+		if joining.client.ConnectionCount() <= joining.connectionsBefore {
+			logrus.WithField("clientID", joining.client.ID()).
+				Debug("client joined without a connection, skipping callback")
+
+			return
+		}
+
+		onClientJoined(joining.client)
+	}
+}
+
+// joiningHub hands one upgrade request to the hub and remembers which
+// client it joined and how many connections that client had before.
+type joiningHub struct {
+	wshub.Hub
+
+	client            *wshub.Client
+	connectionsBefore int
+}
+
+func (h *joiningHub) AddClient(client *wshub.Client) {
+	h.client = client
+	h.connectionsBefore = client.ConnectionCount()
+
+	h.Hub.AddClient(client)
+}
+
+func (h *joiningHub) GetOrCreateClient(
+	clientID uuid.UUID,
+	opts ...wshub.ClientOption,
+) (*wshub.Client, bool) {
+	client, created := h.Hub.GetOrCreateClient(clientID, opts...)
+
+	h.client = client
+	h.connectionsBefore = client.ConnectionCount()
+
+	return client, created
+}
@@ -0,0 +1,64 @@
+package piraterf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinAwareUpgradeHandler_SendsStatusOnJoin(t *testing.T) {
+	service := &PIrateRF{rpitx: gorpitx.GetInstance()}
+	service.setupWebsocketHub()
+
+	defer service.websocketHub.Close()
+
+	service.executionManager = newExecutionManager(
+		service.rpitx, service.websocketHub,
+	)
+	service.executionManager.setState(executionStateExecuting)
+	service.executionManager.setActiveExecution(&activeExecution{
+		moduleName: gorpitx.ModuleNameTUNE,
+		startedAt:  time.Now(),
+	})
+
+	server := httptest.NewServer(joinAwareUpgradeHandler(
+		service.websocketHub, service.handleClientJoined,
+	))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "generated client id", url: wsURL},
+		{name: "explicit client id", url: wsURL + "?clientID=" + uuid.NewString()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(tt.url, nil)
+			require.NoError(t, err)
+
+			defer func() { _ = resp.Body.Close() }()
+			defer func() { _ = conn.Close() }()
+
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+
+			var event dabluveees.Event
+			require.NoError(t, conn.ReadJSON(&event))
+			assert.Equal(t, eventTypeRPITXExecutionStatus, event.Type)
+			assert.Contains(t, string(event.Data), `"state":"executing"`)
+			assert.Contains(t, string(event.Data), `"moduleName":"tune"`)
+		})
+	}
+}
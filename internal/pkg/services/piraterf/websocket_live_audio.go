@@ -1,10 +1,127 @@
 package piraterf
 
 import (
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
 	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wsunixbridge"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	eventTypeRecordingSaved = dabluveees.EventType(
+		"recording.saved",
+	)
+	eventTypeRecordingError = dabluveees.EventType(
+		"recording.error",
+	)
+	eventTypeLiveBedVolumeSet = dabluveees.EventType(
+		"live.bed.volume.set",
+	)
+	eventTypeLiveBedDuckSet = dabluveees.EventType(
+		"live.bed.duck.set",
+	)
+	eventTypeLiveBedStatus = dabluveees.EventType(
+		"live.bed.status",
+	)
+	eventTypeLiveBedError = dabluveees.EventType(
+		"live.bed.error",
+	)
+	eventTypeLiveSFXPlay = dabluveees.EventType(
+		"live.sfx.play",
+	)
+	eventTypeLiveSFXStop = dabluveees.EventType(
+		"live.sfx.stop",
+	)
+	eventTypeLiveSFXStarted = dabluveees.EventType(
+		"live.sfx.started",
+	)
+	eventTypeLiveSFXStopped = dabluveees.EventType(
+		"live.sfx.stopped",
+	)
+	eventTypeLiveSFXError = dabluveees.EventType(
+		"live.sfx.error",
+	)
+)
+
+type recordingSavedMessageData struct {
+	FileName  string  `json:"fileName"`
+	FilePath  string  `json:"filePath"`
+	Duration  float64 `json:"duration"` // seconds
+	Timestamp int64   `json:"timestamp"`
+}
+
+type recordingErrorMessageData struct {
+	FileName  string `json:"fileName"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type liveBedVolumeSetMessage struct {
+	Volume float64 `json:"volume"` // dB
+}
+
+type liveBedDuckSetMessage struct {
+	Depth     *float64 `json:"depth"`     // dB, nil leaves it as it is
+	Threshold *float64 `json:"threshold"` // dBFS, nil leaves it as it is
+}
+
+type liveBedStatusMessageData struct {
+	OnAir         bool    `json:"onAir"`
+	Bed           string  `json:"bed,omitempty"`
+	Volume        float64 `json:"volume"`        // dB
+	DuckDepth     float64 `json:"duckDepth"`     // dB
+	DuckThreshold float64 `json:"duckThreshold"` // dBFS
+	Timestamp     int64   `json:"timestamp"`
+}
+
+type liveBedErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type liveSFXPlayMessage struct {
+	File string   `json:"file"` // HTTP or file system path of the WAV
+	Gain *float64 `json:"gain"` // dB, 0 when left out
+	// Exclusive stops the clips already playing first
+	Exclusive bool `json:"exclusive"`
+}
+
+type liveSFXStopMessage struct {
+	ID string `json:"id"` // empty stops every clip
+}
+
+type liveSFXStartedMessageData struct {
+	ID        string  `json:"id"`
+	FileName  string  `json:"fileName"`
+	FilePath  string  `json:"filePath"`
+	Gain      float64 `json:"gain"`     // dB
+	Duration  float64 `json:"duration"` // seconds
+	Timestamp int64   `json:"timestamp"`
+}
+
+type liveSFXStoppedMessageData struct {
+	ID        string `json:"id"`
+	FileName  string `json:"fileName"`
+	Reason    string `json:"reason"` // finished, stopped or off air
+	Timestamp int64  `json:"timestamp"`
+}
+
+type liveSFXErrorMessageData struct {
+	FileName  string `json:"fileName,omitempty"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
 func (s *PIrateRF) handleLiveAudioConnection(
 	connection *wsunixbridge.Connection,
 ) error {
@@ -19,3 +136,322 @@ func (s *PIrateRF) handleLiveAudioConnection(
 
 	return nil
 }
+
+func (s *PIrateRF) handleLiveBedVolumeSet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	var msg liveBedVolumeSetMessage
+
+	return s.controlLiveBed(client, event, &msg, func(levels *liveBedLevels) {
+		levels.volume = msg.Volume
+	})
+}
+
+func (s *PIrateRF) handleLiveBedDuckSet(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	var msg liveBedDuckSetMessage
+
+	return s.controlLiveBed(client, event, &msg, func(levels *liveBedLevels) {
+		if msg.Depth != nil {
+			levels.duckDepth = *msg.Depth
+		}
+
+		if msg.Threshold != nil {
+			levels.threshold = *msg.Threshold
+		}
+	})
+}
+
+// handleLiveBedStatus sends the bed of the live broadcast to whoever asked.
+func (s *PIrateRF) handleLiveBedStatus(
+	_ wshub.Hub,
+	client *wshub.Client,
+	_ *dabluveees.Event,
+) error {
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeLiveBedStatus,
+		s.liveBedStatus(),
+	))
+
+	return nil
+}
+
+// controlLiveBed changes the bed levels of the live broadcast. Everybody
+// gets the new levels, errors only go back to whoever asked.
+func (s *PIrateRF) controlLiveBed(
+	client *wshub.Client,
+	event *dabluveees.Event,
+	msg any,
+	change func(levels *liveBedLevels),
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	mixer, ok := s.liveAudio.current()
+	if !ok || mixer.bed == nil {
+		sendLiveBedError(client, "not on air", "no live broadcast has a bed")
+
+		return nil
+	}
+
+	if err := json.Unmarshal(event.Data, msg); err != nil {
+		sendLiveBedError(client, "invalid request", "invalid message")
+
+		return nil
+	}
+
+	levels, err := mixer.setLevels(change)
+	if err != nil {
+		sendLiveBedError(client, "invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	logger.WithFields(logrus.Fields{
+		"volume":        levels.volume,
+		"duckDepth":     levels.duckDepth,
+		"duckThreshold": levels.threshold,
+	}).Info("Live bed levels changed")
+
+	s.broadcastLiveBedStatus()
+
+	return nil
+}
+
+func (s *PIrateRF) broadcastLiveBedStatus() {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeLiveBedStatus,
+		s.liveBedStatus(),
+	))
+}
+
+func (s *PIrateRF) liveBedStatus() liveBedStatusMessageData {
+	mixer, ok := s.liveAudio.current()
+	if !ok || mixer.bed == nil {
+		return liveBedStatusMessageData{Timestamp: time.Now().Unix()}
+	}
+
+	levels := mixer.currentLevels()
+
+	return liveBedStatusMessageData{
+		OnAir:         true,
+		Bed:           mixer.bedName,
+		Volume:        levels.volume,
+		DuckDepth:     levels.duckDepth,
+		DuckThreshold: levels.threshold,
+		Timestamp:     time.Now().Unix(),
+	}
+}
+
+func sendLiveBedError(client *wshub.Client, errorType, message string) {
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeLiveBedError,
+		liveBedErrorMessageData{
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+// handleLiveSFXPlay plays an SFX over the mic of the live broadcast.
+func (s *PIrateRF) handleLiveSFXPlay(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	var msg liveSFXPlayMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		sendLiveSFXError(client, "", "invalid request", "invalid message")
+
+		return nil
+	}
+
+	mixer, ok := s.liveAudio.current()
+	if !ok {
+		sendLiveSFXError(client, msg.File, "not on air", "no live broadcast")
+
+		return nil
+	}
+
+	gain := defaultSFXGainDB
+	if msg.Gain != nil {
+		gain = *msg.Gain
+	}
+
+	clipPath, err := s.liveSFXPath(msg.File)
+	if err != nil {
+		sendLiveSFXError(client, msg.File, "invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	clip, err := s.loadLiveClip(clipPath, mixer.sampleRate, gain)
+	if err != nil {
+		logger.WithError(err).Error("failed to load SFX")
+		sendLiveSFXError(client, msg.File, "invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	stopped, err := mixer.addClip(clip, msg.Exclusive)
+	if err != nil {
+		sendLiveSFXError(client, msg.File, "too many SFX", errorMessage(err))
+
+		return nil
+	}
+
+	for _, stoppedClip := range stopped {
+		s.sendLiveSFXStoppedEvent(stoppedClip, liveSFXStopStopped)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"sfx":  clip.path,
+		"gain": gain,
+	}).Info("Live SFX playing")
+	s.sendLiveSFXStartedEvent(clip, gain, mixer.sampleRate)
+
+	return nil
+}
+
+// liveSFXPath resolves the file a live SFX request names, HTTP or file
+// system path. Only audio files can be fired, whatever resolves to
+// somewhere else gets refused.
+func (s *PIrateRF) liveSFXPath(filePath string) (string, error) {
+	clipPath, ok, err := s.filesPathUnder(filePath, audioFilesDir)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"only audio files can be played as SFX: %s",
+			filePath,
+		)
+	}
+
+	return clipPath, nil
+}
+
+// handleLiveSFXStop stops one SFX of the live broadcast or all of them.
+func (s *PIrateRF) handleLiveSFXStop(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	var msg liveSFXStopMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		sendLiveSFXError(client, "", "invalid request", "invalid message")
+
+		return nil
+	}
+
+	mixer, ok := s.liveAudio.current()
+	if !ok {
+		sendLiveSFXError(client, "", "not on air", "no live broadcast")
+
+		return nil
+	}
+
+	stopped := mixer.stopClips(msg.ID)
+	if msg.ID != "" && len(stopped) == 0 {
+		sendLiveSFXError(client, "", "not found", "that SFX isn't playing")
+
+		return nil
+	}
+
+	for _, clip := range stopped {
+		s.sendLiveSFXStoppedEvent(clip, liveSFXStopStopped)
+	}
+
+	return nil
+}
+
+func sendLiveSFXError(
+	client *wshub.Client,
+	fileName, errorType, message string,
+) {
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeLiveSFXError,
+		liveSFXErrorMessageData{
+			FileName:  fileName,
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+// Event sending functions for live SFX.
+func (s *PIrateRF) sendLiveSFXStartedEvent(
+	clip *liveClip,
+	gain float64,
+	sampleRate int,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeLiveSFXStarted,
+		liveSFXStartedMessageData{
+			ID:        clip.id,
+			FileName:  filepath.Base(clip.path),
+			FilePath:  clip.path,
+			Gain:      gain,
+			Duration:  clip.duration(sampleRate),
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (s *PIrateRF) sendLiveSFXStoppedEvent(clip *liveClip, reason string) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeLiveSFXStopped,
+		liveSFXStoppedMessageData{
+			ID:        clip.id,
+			FileName:  filepath.Base(clip.path),
+			Reason:    reason,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+// Event sending functions for live recordings.
+func (s *PIrateRF) sendRecordingSavedEvent(
+	filePath string,
+	duration float64,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeRecordingSaved,
+		recordingSavedMessageData{
+			FileName:  filepath.Base(filePath),
+			FilePath:  filePath,
+			Duration:  duration,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (s *PIrateRF) sendRecordingErrorEvent(
+	fileName, errorType, message string,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeRecordingError,
+		recordingErrorMessageData{
+			FileName:  fileName,
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,218 @@
+package piraterf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveBedControl(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	client := wshub.NewClient()
+
+	send := func(
+		handler func(wshub.Hub, *wshub.Client, *dabluveees.Event) error,
+		eventType dabluveees.EventType,
+		data any,
+	) {
+		t.Helper()
+		require.NoError(t, handler(
+			service.websocketHub, client, dabluveees.NewEvent(eventType, data),
+		))
+	}
+
+	// Nothing on air - nothing to change
+	send(
+		service.handleLiveBedVolumeSet, eventTypeLiveBedVolumeSet,
+		liveBedVolumeSetMessage{Volume: -6},
+	)
+	assert.False(t, service.liveBedStatus().OnAir)
+
+	mixer := &liveMixer{
+		levels: liveBedLevels{
+			volume:    defaultBedVolumeDB,
+			duckDepth: defaultDuckDepthDB,
+			threshold: defaultDuckThresholdDB,
+		},
+	}
+	service.liveAudio.set(mixer)
+
+	// On air without a bed - nothing to change either
+	send(
+		service.handleLiveBedVolumeSet, eventTypeLiveBedVolumeSet,
+		liveBedVolumeSetMessage{Volume: -6},
+	)
+	assert.InDelta(t, defaultBedVolumeDB, mixer.currentLevels().volume, 0)
+	assert.False(t, service.liveBedStatus().OnAir)
+
+	mixer.bed = &wavLoop{}
+	mixer.bedName = "bed.wav"
+
+	send(
+		service.handleLiveBedVolumeSet, eventTypeLiveBedVolumeSet,
+		liveBedVolumeSetMessage{Volume: -6},
+	)
+	assert.InDelta(t, -6, mixer.currentLevels().volume, 0)
+
+	// Only what's in the message changes
+	send(
+		service.handleLiveBedDuckSet, eventTypeLiveBedDuckSet,
+		liveBedDuckSetMessage{Depth: floatPtr(20)},
+	)
+
+	levels := mixer.currentLevels()
+	assert.InDelta(t, 20, levels.duckDepth, 0)
+	assert.InDelta(t, defaultDuckThresholdDB, levels.threshold, 0)
+
+	// Out of range values leave the levels alone
+	send(
+		service.handleLiveBedDuckSet, eventTypeLiveBedDuckSet,
+		liveBedDuckSetMessage{Depth: floatPtr(10), Threshold: floatPtr(5)},
+	)
+	send(
+		service.handleLiveBedVolumeSet, eventTypeLiveBedVolumeSet,
+		liveBedVolumeSetMessage{Volume: 6},
+	)
+	assert.Equal(t, liveBedLevels{
+		volume:    -6,
+		duckDepth: 20,
+		threshold: defaultDuckThresholdDB,
+	}, mixer.currentLevels())
+
+	status := service.liveBedStatus()
+	assert.True(t, status.OnAir)
+	assert.Equal(t, "bed.wav", status.Bed)
+	assert.InDelta(t, -6, status.Volume, 0)
+}
+
+func TestLiveSFXControl(t *testing.T) {
+	service := newLiveRecordingTestService(t)
+	client := wshub.NewClient()
+
+	send := func(
+		handler func(wshub.Hub, *wshub.Client, *dabluveees.Event) error,
+		eventType dabluveees.EventType,
+		data any,
+	) {
+		t.Helper()
+		require.NoError(t, handler(
+			service.websocketHub, client, dabluveees.NewEvent(eventType, data),
+		))
+	}
+
+	sfxDir := filepath.Join(service.config.FilesDir, audioFilesDir, audioSFXDir)
+	require.NoError(t, os.MkdirAll(sfxDir, 0o750))
+	writeTestWAV(t, filepath.Join(sfxDir, "glitch.wav"), 1, 8000, []int16{1})
+
+	sfxPath := "/files/" + audioFilesDir + "/" + audioSFXDir + "/glitch.wav"
+
+	// Nothing on air - nothing to play it over
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: sfxPath},
+	)
+
+	mixer := &liveMixer{sampleRate: 8000}
+	service.liveAudio.set(mixer)
+
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: sfxPath, Gain: floatPtr(-6)},
+	)
+	require.Len(t, mixer.clips, 1)
+	assert.Equal(t, filepath.Join(sfxDir, "glitch.wav"), mixer.clips[0].path)
+	assert.InDelta(t, 0.5012, mixer.clips[0].gain, 0.0001)
+
+	// Bad clips never make it into the mix
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: sfxPath, Gain: floatPtr(20)},
+	)
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: "/files/audio/sfx/gone.wav"},
+	)
+
+	// Nor do WAVs from outside files/audio
+	outside := filepath.Join(t.TempDir(), "secret.wav")
+	writeTestWAV(t, outside, 1, 8000, []int16{1})
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: outside},
+	)
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: "/files/audio/../../" + outside},
+	)
+	require.Len(t, mixer.clips, 1)
+
+	first := mixer.clips[0]
+
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: sfxPath},
+	)
+	require.Len(t, mixer.clips, 2)
+
+	send(
+		service.handleLiveSFXStop, eventTypeLiveSFXStop,
+		liveSFXStopMessage{ID: first.id},
+	)
+	require.Len(t, mixer.clips, 1)
+	assert.NotEqual(t, first.id, mixer.clips[0].id)
+
+	send(
+		service.handleLiveSFXPlay, eventTypeLiveSFXPlay,
+		liveSFXPlayMessage{File: sfxPath, Exclusive: true},
+	)
+	require.Len(t, mixer.clips, 1)
+
+	send(
+		service.handleLiveSFXStop, eventTypeLiveSFXStop,
+		liveSFXStopMessage{},
+	)
+	assert.Empty(t, mixer.clips)
+}
+
+func TestLiveSFXPath(t *testing.T) {
+	filesDir := t.TempDir()
+	service := &PIrateRF{config: Config{FilesDir: filesDir}}
+
+	allowed := map[string]string{
+		"/files/audio/sfx/horn.wav": filepath.Join(
+			filesDir, audioFilesDir, audioSFXDir, "horn.wav",
+		),
+		"/files/audio/uploads/song.wav": filepath.Join(
+			filesDir, audioUploadsPath, "song.wav",
+		),
+		filepath.Join(filesDir, audioRecordingsPath, "live.wav"): filepath.Join(
+			filesDir, audioRecordingsPath, "live.wav",
+		),
+	}
+
+	for filePath, expected := range allowed {
+		clipPath, err := service.liveSFXPath(filePath)
+		require.NoError(t, err, filePath)
+		assert.Equal(t, expected, clipPath)
+	}
+
+	refused := []string{
+		"/files/audio/../../etc/x.wav",
+		"/files/audio",
+		"/files/images/uploads/x.wav",
+		"/etc/x.wav",
+		filepath.Join(filesDir, audioFilesDir, "..", "x.wav"),
+		"../x.wav",
+	}
+
+	for _, filePath := range refused {
+		_, err := service.liveSFXPath(filePath)
+		require.ErrorIs(t, err, commonerrors.ErrInvalidValue, filePath)
+	}
+}
@@ -0,0 +1,284 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypePlaylistCreate  dabluveees.EventType = "playlist.create"
+	eventTypePlaylistList    dabluveees.EventType = "playlist.list"
+	eventTypePlaylistUpdate  dabluveees.EventType = "playlist.update"
+	eventTypePlaylistRename  dabluveees.EventType = "playlist.rename"
+	eventTypePlaylistReorder dabluveees.EventType = "playlist.reorder"
+	eventTypePlaylistDelete  dabluveees.EventType = "playlist.delete"
+	eventTypePlaylistUpdated dabluveees.EventType = "playlist.updated"
+	eventTypePlaylistError   dabluveees.EventType = "playlist.error"
+)
+
+type playlistCreateMessage struct {
+	Name  string         `json:"name"`
+	Items []playlistItem `json:"items"`
+}
+
+type playlistUpdateMessage struct {
+	ID    string         `json:"id"`
+	Items []playlistItem `json:"items"` // replaces all the items
+}
+
+type playlistRenameMessage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type playlistReorderMessage struct {
+	ID       string `json:"id"`
+	Index    int    `json:"index"`    // zero-based item to move
+	Position int    `json:"position"` // zero-based target position
+}
+
+type playlistDeleteMessage struct {
+	ID string `json:"id"`
+}
+
+type playlistUpdatedMessageData struct {
+	Playlists []playlist `json:"playlists"`
+	Timestamp int64      `json:"timestamp"`
+}
+
+type playlistErrorMessageData struct {
+	ID        string `json:"id,omitempty"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (s *PIrateRF) handlePlaylistCreate(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := playlistEventLogger(event)
+	logger.Debug("Playlist create requested")
+
+	var msg playlistCreateMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendPlaylistErrorEvent("", "invalid request", err.Error())
+
+		return nil
+	}
+
+	pl, err := s.playlists.create(msg.Name, msg.Items)
+	if err != nil {
+		s.sendPlaylistErrorEvent("", "create failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Playlist created: %s (%s)", pl.ID, pl.Name)
+	s.sendPlaylistUpdatedEvent()
+
+	return nil
+}
+
+func (s *PIrateRF) handlePlaylistList(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	playlistEventLogger(event).Debug("Playlist list requested")
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypePlaylistUpdated,
+		playlistUpdatedMessageData{
+			Playlists: s.playlists.list(),
+			Timestamp: time.Now().Unix(),
+		},
+	))
+
+	return nil
+}
+
+func (s *PIrateRF) handlePlaylistUpdate(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := playlistEventLogger(event)
+	logger.Debug("Playlist update requested")
+
+	var msg playlistUpdateMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendPlaylistErrorEvent("", "invalid request", err.Error())
+
+		return nil
+	}
+
+	id, ok := s.parsePlaylistID(msg.ID)
+	if !ok {
+		return nil
+	}
+
+	if _, err := s.playlists.update(id, msg.Items); err != nil {
+		s.sendPlaylistErrorEvent(msg.ID, "update failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Playlist updated: %s", id)
+	s.sendPlaylistUpdatedEvent()
+
+	return nil
+}
+
+func (s *PIrateRF) handlePlaylistRename(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := playlistEventLogger(event)
+	logger.Debug("Playlist rename requested")
+
+	var msg playlistRenameMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendPlaylistErrorEvent("", "invalid request", err.Error())
+
+		return nil
+	}
+
+	id, ok := s.parsePlaylistID(msg.ID)
+	if !ok {
+		return nil
+	}
+
+	if _, err := s.playlists.rename(id, msg.Name); err != nil {
+		s.sendPlaylistErrorEvent(msg.ID, "rename failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Playlist renamed: %s to %s", id, msg.Name)
+	s.sendPlaylistUpdatedEvent()
+
+	return nil
+}
+
+func (s *PIrateRF) handlePlaylistReorder(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := playlistEventLogger(event)
+	logger.Debug("Playlist reorder requested")
+
+	var msg playlistReorderMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendPlaylistErrorEvent("", "invalid request", err.Error())
+
+		return nil
+	}
+
+	id, ok := s.parsePlaylistID(msg.ID)
+	if !ok {
+		return nil
+	}
+
+	if _, err := s.playlists.reorder(id, msg.Index, msg.Position); err != nil {
+		s.sendPlaylistErrorEvent(msg.ID, "reorder failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Playlist reordered: %s item %d to %d",
+		id, msg.Index, msg.Position)
+	s.sendPlaylistUpdatedEvent()
+
+	return nil
+}
+
+func (s *PIrateRF) handlePlaylistDelete(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := playlistEventLogger(event)
+	logger.Debug("Playlist delete requested")
+
+	var msg playlistDeleteMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.sendPlaylistErrorEvent("", "invalid request", err.Error())
+
+		return nil
+	}
+
+	id, ok := s.parsePlaylistID(msg.ID)
+	if !ok {
+		return nil
+	}
+
+	if err := s.playlists.remove(id); err != nil {
+		s.sendPlaylistErrorEvent(msg.ID, "delete failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Playlist deleted: %s", id)
+	s.sendPlaylistUpdatedEvent()
+
+	return nil
+}
+
+func playlistEventLogger(event *dabluveees.Event) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+}
+
+// parsePlaylistID parses a playlist ID from a request, telling everybody
+// when it's no good.
+func (s *PIrateRF) parsePlaylistID(rawID string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		s.sendPlaylistErrorEvent(rawID, "invalid request", err.Error())
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// Event sending functions for playlist operations.
+func (s *PIrateRF) sendPlaylistUpdatedEvent() {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypePlaylistUpdated,
+		playlistUpdatedMessageData{
+			Playlists: s.playlists.list(),
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (s *PIrateRF) sendPlaylistErrorEvent(id, errorType, message string) {
+	logrus.WithFields(logrus.Fields{
+		"playlistID": id,
+		"errorType":  errorType,
+		"message":    message,
+	}).Error("playlist error occurred")
+
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypePlaylistError,
+		playlistErrorMessageData{
+			ID:        id,
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,92 @@
+package piraterf
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylistHandlers(t *testing.T) {
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	store, _ := newTestPlaylistStore(t)
+	service := &PIrateRF{websocketHub: hub, playlists: store}
+	client := wshub.NewClient()
+
+	send := func(
+		handler func(wshub.Hub, *wshub.Client, *dabluveees.Event) error,
+		eventType dabluveees.EventType,
+		data any,
+	) {
+		t.Helper()
+		require.NoError(t, handler(
+			hub, client, dabluveees.NewEvent(eventType, data),
+		))
+	}
+
+	send(service.handlePlaylistCreate, eventTypePlaylistCreate,
+		playlistCreateMessage{
+			Name: "show",
+			Items: []playlistItem{
+				{File: "uploads/a.wav"},
+				{File: "uploads/b.wav", Gain: -3},
+			},
+		},
+	)
+
+	playlists := store.list()
+	require.Len(t, playlists, 1)
+
+	id := playlists[0].ID.String()
+
+	send(service.handlePlaylistReorder, eventTypePlaylistReorder,
+		playlistReorderMessage{ID: id, Index: 1, Position: 0},
+	)
+	send(service.handlePlaylistRename, eventTypePlaylistRename,
+		playlistRenameMessage{ID: id, Name: "night show"},
+	)
+
+	pl := store.list()[0]
+	assert.Equal(t, "night show", pl.Name)
+	assert.Equal(t, "uploads/b.wav", pl.Items[0].File)
+
+	send(service.handlePlaylistUpdate, eventTypePlaylistUpdate,
+		playlistUpdateMessage{ID: id, Items: []playlistItem{
+			{File: "sfx/c.wav", Gain: 1},
+		}},
+	)
+	assert.Equal(t,
+		[]playlistItem{{File: "sfx/c.wav", Gain: 1}},
+		store.list()[0].Items,
+	)
+
+	send(service.handlePlaylistList, eventTypePlaylistList, map[string]any{})
+
+	// Bad requests change nothing
+	send(service.handlePlaylistCreate, eventTypePlaylistCreate,
+		invalidJSONData,
+	)
+	send(service.handlePlaylistCreate, eventTypePlaylistCreate,
+		playlistCreateMessage{
+			Name:  "broken",
+			Items: []playlistItem{{File: "uploads/nope.wav"}},
+		},
+	)
+	send(service.handlePlaylistRename, eventTypePlaylistRename,
+		playlistRenameMessage{ID: "nope", Name: "x"},
+	)
+	send(service.handlePlaylistDelete, eventTypePlaylistDelete,
+		playlistDeleteMessage{ID: uuid.New().String()},
+	)
+	assert.Len(t, store.list(), 1)
+
+	send(service.handlePlaylistDelete, eventTypePlaylistDelete,
+		playlistDeleteMessage{ID: id},
+	)
+	assert.Empty(t, store.list())
+}
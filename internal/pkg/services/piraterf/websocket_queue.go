@@ -0,0 +1,157 @@
+package piraterf
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeQueueEnqueue dabluveees.EventType = "queue.enqueue"
+	eventTypeQueueDequeue dabluveees.EventType = "queue.dequeue"
+	eventTypeQueueReorder dabluveees.EventType = "queue.reorder"
+	eventTypeQueueCancel  dabluveees.EventType = "queue.cancel"
+	eventTypeQueueUpdated dabluveees.EventType = "queue.updated"
+	eventTypeQueueError   dabluveees.EventType = "queue.error"
+)
+
+type queueDequeueMessage struct {
+	JobID string `json:"jobId"`
+}
+
+type queueReorderMessage struct {
+	JobID    string `json:"jobId"`
+	Position int    `json:"position"` // zero-based target position
+}
+
+type queueJobMessageData struct {
+	JobID      string             `json:"jobId"`
+	Position   int                `json:"position"`
+	ModuleName gorpitx.ModuleName `json:"moduleName"`
+	Args       json.RawMessage    `json:"args"`
+	Timeout    int                `json:"timeout"`
+	ClientID   string             `json:"clientId"`
+	EnqueuedAt int64              `json:"enqueuedAt"`
+}
+
+type queueUpdatedMessageData struct {
+	Jobs      []queueJobMessageData `json:"jobs"`
+	Timestamp int64                 `json:"timestamp"`
+}
+
+type queueErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// handleQueueEnqueue accepts the same payload as rpitx.execution.start.
+// Start requests are always queued, so this goes through the exact same
+// pipeline (audio/image processing included) and simply waits its turn.
+func (s *PIrateRF) handleQueueEnqueue(
+	hub wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	return s.handleRPITXExecutionStart(hub, client, event)
+}
+
+func (s *PIrateRF) handleQueueDequeue(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Queue dequeue requested")
+
+	var msg queueDequeueMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.executionManager.sendQueueErrorEvent("invalid request", err.Error())
+
+		return nil
+	}
+
+	jobID, err := uuid.Parse(msg.JobID)
+	if err != nil {
+		s.executionManager.sendQueueErrorEvent("invalid request", err.Error())
+
+		return nil
+	}
+
+	if err := s.executionManager.dequeueExecution(jobID); err != nil {
+		s.executionManager.sendQueueErrorEvent("job not found", err.Error())
+
+		return nil
+	}
+
+	logger.Infof("Queued job removed: %s", jobID)
+
+	return nil
+}
+
+func (s *PIrateRF) handleQueueReorder(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Queue reorder requested")
+
+	var msg queueReorderMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.executionManager.sendQueueErrorEvent("invalid request", err.Error())
+
+		return nil
+	}
+
+	jobID, err := uuid.Parse(msg.JobID)
+	if err != nil {
+		s.executionManager.sendQueueErrorEvent("invalid request", err.Error())
+
+		return nil
+	}
+
+	if err := s.executionManager.reorderExecution(
+		jobID, msg.Position,
+	); err != nil {
+		s.executionManager.sendQueueErrorEvent("job not found", err.Error())
+
+		return nil
+	}
+
+	logger.Infof("Queued job %s moved to position %d", jobID, msg.Position)
+
+	return nil
+}
+
+func (s *PIrateRF) handleQueueCancel(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Queue cancel requested")
+
+	removed := s.executionManager.cancelQueuedExecutions()
+
+	logger.Infof("Queue cancelled, %d jobs removed", removed)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueueTestService(t *testing.T) *PIrateRF {
+	t.Helper()
+
+	em := newBusyExecutionManager(t)
+
+	return &PIrateRF{
+		websocketHub:     em.hub,
+		executionManager: em,
+		serviceCtx:       context.Background(),
+	}
+}
+
+func TestHandleQueueEnqueue(t *testing.T) {
+	service := newQueueTestService(t)
+	service.rpitx = gorpitx.GetInstance()
+
+	event := dabluveees.NewEvent(eventTypeQueueEnqueue, map[string]any{
+		"moduleName": gorpitx.ModuleNamePICHIRP,
+		"args": map[string]any{
+			"frequency": 100000000,
+			"bandwidth": 1000000,
+			"time":      5.0,
+		},
+		"timeout": 10,
+	})
+
+	err := service.handleQueueEnqueue(
+		service.websocketHub, &wshub.Client{}, event,
+	)
+	require.NoError(t, err)
+
+	jobs := service.executionManager.getQueueSnapshot()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, gorpitx.ModuleNamePICHIRP, jobs[0].ModuleName)
+}
+
+func TestHandleQueueDequeueAndReorder(t *testing.T) {
+	service := newQueueTestService(t)
+	em := service.executionManager
+
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, &wshub.Client{}, nil,
+		))
+	}
+
+	ids := queueJobIDs(em)
+	require.Len(t, ids, 2)
+
+	tests := []struct {
+		name      string
+		eventType dabluveees.EventType
+		handler   wshub.EventHandler
+		data      any
+		expected  []string
+	}{
+		{
+			name:      "invalid dequeue job id is ignored",
+			eventType: eventTypeQueueDequeue,
+			handler:   service.handleQueueDequeue,
+			data:      map[string]any{"jobId": "not-a-uuid"},
+			expected:  ids,
+		},
+		{
+			name:      "unknown dequeue job id is ignored",
+			eventType: eventTypeQueueDequeue,
+			handler:   service.handleQueueDequeue,
+			data:      map[string]any{"jobId": uuid.NewString()},
+			expected:  ids,
+		},
+		{
+			name:      "reorder moves job",
+			eventType: eventTypeQueueReorder,
+			handler:   service.handleQueueReorder,
+			data:      map[string]any{"jobId": ids[1], "position": 0},
+			expected:  []string{ids[1], ids[0]},
+		},
+		{
+			name:      "dequeue removes job",
+			eventType: eventTypeQueueDequeue,
+			handler:   service.handleQueueDequeue,
+			data:      map[string]any{"jobId": ids[1]},
+			expected:  []string{ids[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := dabluveees.NewEvent(tt.eventType, tt.data)
+
+			err := tt.handler(service.websocketHub, &wshub.Client{}, event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, queueJobIDs(em))
+		})
+	}
+}
+
+func TestHandleQueueCancel(t *testing.T) {
+	service := newQueueTestService(t)
+	em := service.executionManager
+
+	for range 2 {
+		require.NoError(t, em.startExecution(
+			context.Background(), gorpitx.ModuleNameTUNE,
+			json.RawMessage(`{}`), 0, &wshub.Client{}, nil,
+		))
+	}
+
+	event := dabluveees.NewEvent(eventTypeQueueCancel, nil)
+
+	err := service.handleQueueCancel(service.websocketHub, &wshub.Client{}, event)
+	require.NoError(t, err)
+	assert.Empty(t, em.getQueueSnapshot())
+}
@@ -3,8 +3,10 @@ package piraterf
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -12,6 +14,7 @@ import (
 	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
 	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
 	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
 	"github.com/psyb0t/ctxerrors"
 	"github.com/psyb0t/gorpitx"
 	"github.com/sirupsen/logrus"
@@ -36,10 +39,24 @@ const (
 	eventTypeRPITXExecutionOutputLine = dabluveees.EventType(
 		"rpitx.execution.output-line",
 	)
+	eventTypeRPITXExecutionStatus = dabluveees.EventType(
+		"rpitx.execution.status",
+	)
+	eventTypeRPITXExecutionRejected = dabluveees.EventType(
+		"rpitx.execution.rejected",
+	)
 
 	// Audio duration rounding offset for converting float to int
 	// seconds.
 	durationRoundingOffset = 0.5
+
+	// Silence added after the audio in Play Once mode so it isn't cut off.
+	playOnceSilenceSeconds = 2
+
+	// Where the temp files made for an execution go - the expanded
+	// playlist, the intro/outro playlist, the silence padded copy and the
+	// RDS control pipe.
+	executionTempDir = "/tmp"
 )
 
 type rpitxExecutionStartMessage struct {
@@ -49,6 +66,32 @@ type rpitxExecutionStartMessage struct {
 	PlayOnce   bool               `json:"playOnce"` // use duration as timeout
 	Intro      *string            `json:"intro"`    // intro file path (optional)
 	Outro      *string            `json:"outro"`    // outro file path (optional)
+	// DeadMan stops the execution when this client goes quiet for
+	// DeadManGrace seconds (0 = default)
+	DeadMan      bool `json:"deadMan"`
+	DeadManGrace int  `json:"deadManGrace"`
+	// crossfade, gap, fadeIn and fadeOut in seconds for the intro/outro
+	// and stored playlists (all optional)
+	playlistTransitions
+	// AutoRT and AutoPS fill RDS from the metadata of the track on air
+	AutoRT bool `json:"autoRT"`
+	AutoPS bool `json:"autoPS"`
+	// Record tees audiosock-broadcast into files/audio/recordings
+	Record bool `json:"record"`
+	// music bed looped under audiosock-broadcast (optional)
+	liveBedSettings
+	// scratchDir takes the temp files of a dry run instead of
+	// executionTempDir, images get converted from a copy in there
+	scratchDir string
+}
+
+// tempDir is where the temp files made for the execution go.
+func (m rpitxExecutionStartMessage) tempDir() string {
+	if m.scratchDir != "" {
+		return m.scratchDir
+	}
+
+	return executionTempDir
 }
 
 type rpitxExecutionStartedMessageData struct {
@@ -59,9 +102,12 @@ type rpitxExecutionStartedMessageData struct {
 }
 
 type rpitxExecutionStoppedMessageData struct {
-	InitiatingClientID string `json:"initiatingClientId"`
-	StoppingClientID   string `json:"stoppingClientId"`
-	Timestamp          int64  `json:"timestamp"`
+	InitiatingClientID string            `json:"initiatingClientId"`
+	StoppingClientID   string            `json:"stoppingClientId"`
+	Reason             terminationReason `json:"reason"`
+	ExitCode           *int              `json:"exitCode"` // null if signalled
+	Duration           float64           `json:"duration"` // seconds on air
+	Timestamp          int64             `json:"timestamp"`
 }
 
 type rpitxExecutionErrorMessageData struct {
@@ -70,12 +116,31 @@ type rpitxExecutionErrorMessageData struct {
 	Timestamp int64  `json:"timestamp"`
 }
 
+type rpitxExecutionRejectedMessageData struct {
+	Error      string             `json:"error"`
+	Message    string             `json:"message"`
+	ModuleName gorpitx.ModuleName `json:"moduleName"`
+	Frequency  float64            `json:"frequency,omitempty"` // Hz
+	Timestamp  int64              `json:"timestamp"`
+}
+
 type rpitxExecutionOutputLineMessageData struct {
 	Type      string `json:"type"`
 	Line      string `json:"line"`
 	Timestamp int64  `json:"timestamp"`
 }
 
+type rpitxExecutionStatusMessageData struct {
+	State              string                                `json:"state"`
+	ModuleName         gorpitx.ModuleName                    `json:"moduleName,omitempty"`
+	Args               json.RawMessage                       `json:"args,omitempty"`
+	InitiatingClientID string                                `json:"initiatingClientId,omitempty"`
+	StartedAt          int64                                 `json:"startedAt,omitempty"`
+	Elapsed            int64                                 `json:"elapsed"` // seconds on air
+	OutputLines        []rpitxExecutionOutputLineMessageData `json:"outputLines"`
+	Timestamp          int64                                 `json:"timestamp"`
+}
+
 func (s *PIrateRF) handleRPITXExecutionStart(
 	_ wshub.Hub,
 	client *wshub.Client,
@@ -97,6 +162,12 @@ func (s *PIrateRF) handleRPITXExecutionStart(
 		return s.handleModuleValidationError(err, msg.ModuleName, logger)
 	}
 
+	if violation := s.bandPlan.check(msg.ModuleName, msg.Args); violation != nil {
+		s.sendBandPlanRejection(client, violation, logger)
+
+		return nil
+	}
+
 	return s.processModuleExecution(msg, client, logger)
 }
 
@@ -129,115 +200,250 @@ func (s *PIrateRF) handleModuleValidationError(
 	return ctxerrors.Wrap(err, "module validation failed")
 }
 
+// preparedExecution is a start request after PIrateRF did its part -
+// audio and image rewriting, the RDS control pipe, Play Once timeout - and
+// is ready to be handed to rpitx.
+type preparedExecution struct {
+	moduleName gorpitx.ModuleName
+	args       json.RawMessage
+	timeout    int
+	callback   func() error // removes the temp files, nil when there are none
+	opts       []executionOption
+	// controlRejection says why a sendiq replay gets no live control
+	controlRejection *sendiqControlRejection
+}
+
+// cleanup removes whatever temp files were made for an execution that's
+// not going to run.
+func (p *preparedExecution) cleanup() {
+	if p.callback != nil {
+		_ = p.callback()
+	}
+}
+
 func (s *PIrateRF) processModuleExecution(
 	msg *rpitxExecutionStartMessage,
 	client *wshub.Client,
 	logger *logrus.Entry,
 ) error {
-	finalTimeout := msg.Timeout
-	finalArgs := msg.Args
+	prepared, err := s.prepareModuleExecution(msg, logger)
+	if err != nil {
+		s.executionManager.sendClientError(client, "setup failed", err)
+
+		return err
+	}
+
+	err = s.executionManager.startExecution(
+		s.serviceCtx,
+		prepared.moduleName,
+		prepared.args,
+		prepared.timeout,
+		client,
+		prepared.callback,
+		prepared.opts...,
+	)
+	if err != nil {
+		return err
+	}
+
+	if prepared.controlRejection != nil {
+		sendSENDIQControlError(client, prepared.controlRejection)
+	}
+
+	return nil
+}
+
+func (s *PIrateRF) prepareModuleExecution(
+	msg *rpitxExecutionStartMessage,
+	logger *logrus.Entry,
+) (*preparedExecution, error) {
+	prepared := &preparedExecution{
+		moduleName: msg.ModuleName,
+		args:       msg.Args,
+		timeout:    msg.Timeout,
+		opts:       s.executionOptions(msg),
+	}
 
 	switch msg.ModuleName {
 	case gorpitx.ModuleNamePIFMRDS:
-		return s.handlePIFMRDSExecution(msg, finalTimeout, client, logger)
+		return s.preparePIFMRDSExecution(msg, prepared, logger)
 	case gorpitx.ModuleNameSPECTRUMPAINT:
-		return s.handleSPECTRUMPAINTExecution(msg, finalTimeout, client, logger)
+		return s.prepareSPECTRUMPAINTExecution(msg, prepared, logger)
+	case gorpitx.ModuleNameSENDIQ:
+		return s.prepareSENDIQExecution(prepared, logger)
+	case gorpitx.ModuleNameAudioSockBroadcast:
+		return s.prepareAudioSockExecution(msg, prepared, logger)
 	case gorpitx.ModuleNamePICHIRP:
-		return s.handlePICHIRPExecution(msg, finalTimeout, client, logger)
+		logger.Debug("Processing PICHIRP execution request")
 	case gorpitx.ModuleNamePOCSAG:
-		return s.handlePOCSAGExecution(msg, finalTimeout, client, logger)
-	default:
-		return s.executionManager.startExecution(
-			s.serviceCtx, msg.ModuleName, finalArgs, finalTimeout, client, nil,
-		)
+		logger.Debug("Processing POCSAG execution request")
 	}
+
+	return prepared, nil
 }
 
-func (s *PIrateRF) handlePIFMRDSExecution(
+func (s *PIrateRF) preparePIFMRDSExecution(
 	msg *rpitxExecutionStartMessage,
-	finalTimeout int,
-	client *wshub.Client,
+	prepared *preparedExecution,
 	logger *logrus.Entry,
-) error {
-	processedTimeout, cleanupPath, finalArgs, err := s.processAudioModifications(
-		*msg, finalTimeout, logger,
+) (*preparedExecution, error) {
+	processedTimeout, cleanupPaths, finalArgs, err := s.processAudioModifications(
+		*msg, msg.Timeout, logger,
 	)
+
+	prepared.callback = s.createCleanupCallback(cleanupPaths, logger)
+
 	if err != nil {
 		logger.WithError(err).Error("Audio processing failed")
 
-		return ctxerrors.Wrap(err, "audio processing failed")
-	}
+		// Don't leave half-built temp files behind
+		prepared.cleanup()
 
-	callback := s.createCleanupCallback(cleanupPath, logger)
+		return nil, ctxerrors.Wrap(err, "audio processing failed")
+	}
 
-	return s.executionManager.startExecution(
-		s.serviceCtx, msg.ModuleName, finalArgs, processedTimeout, client, callback,
+	finalArgs, controlPipe, err := s.createRDSControlPipe(
+		finalArgs, msg.tempDir(), logger,
 	)
+	if controlPipe != "" {
+		prepared.callback = s.createCleanupCallback(
+			append(cleanupPaths, controlPipe), logger,
+		)
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("RDS control pipe setup failed")
+		prepared.cleanup()
+
+		return nil, ctxerrors.Wrap(err, "RDS control pipe setup failed")
+	}
+
+	if msg.AutoRT || msg.AutoPS {
+		var schedule *rdsTrackSchedule
+
+		finalArgs, schedule = s.applyAutoRDS(*msg, finalArgs, logger)
+		if schedule != nil {
+			prepared.opts = append(prepared.opts, withRDSTracks(schedule))
+		}
+	}
+
+	prepared.args = finalArgs
+	prepared.timeout = processedTimeout
+	s.applyPlayOnce(msg, prepared, logger)
+
+	return prepared, nil
 }
 
-func (s *PIrateRF) handleSPECTRUMPAINTExecution(
+// applyPlayOnce has a Play Once execution end when its timeout is up, which
+// is when the audio played through, and report how far it got.
+func (s *PIrateRF) applyPlayOnce(
 	msg *rpitxExecutionStartMessage,
-	finalTimeout int,
-	client *wshub.Client,
+	prepared *preparedExecution,
 	logger *logrus.Entry,
-) error {
-	modifiedArgs, err := s.processImageModifications(msg.Args, logger)
+) {
+	if !msg.PlayOnce {
+		return
+	}
+
+	prepared.opts = append(prepared.opts, withPlayOnce())
+
+	// Progress is nice to have, the broadcast goes on air without it
+	timeline, err := s.playOnceTimeline(*msg, prepared.timeout)
 	if err != nil {
-		logger.WithError(err).Error("Image processing failed")
+		logger.WithError(err).Warn("Play Once: no progress reports")
 
-		return ctxerrors.Wrap(err, "image processing failed")
+		return
 	}
 
-	return s.executionManager.startExecution(
-		s.serviceCtx, msg.ModuleName, modifiedArgs, finalTimeout, client, nil,
-	)
+	prepared.opts = append(prepared.opts, withProgress(timeline))
 }
 
-func (s *PIrateRF) handlePICHIRPExecution(
+func (s *PIrateRF) prepareSPECTRUMPAINTExecution(
 	msg *rpitxExecutionStartMessage,
-	finalTimeout int,
-	client *wshub.Client,
+	prepared *preparedExecution,
 	logger *logrus.Entry,
-) error {
-	logger.Debug("Processing PICHIRP execution request")
+) (*preparedExecution, error) {
+	modifiedArgs, err := s.processImageModifications(
+		msg.Args, msg.scratchDir, logger,
+	)
+	if err != nil {
+		logger.WithError(err).Error("Image processing failed")
+
+		return nil, ctxerrors.Wrap(err, "image processing failed")
+	}
 
-	return s.executionManager.startExecution(
-		s.serviceCtx, msg.ModuleName, msg.Args, finalTimeout, client, nil,
+	prepared.args = modifiedArgs
+
+	return prepared, nil
+}
+
+// prepareSENDIQExecution gives the replay its live control block.
+func (s *PIrateRF) prepareSENDIQExecution(
+	prepared *preparedExecution,
+	logger *logrus.Entry,
+) (*preparedExecution, error) {
+	finalArgs, removeControl, err := s.createSENDIQControl(
+		prepared.args, logger,
 	)
+	prepared.callback = removeControl
+
+	if err != nil {
+		logger.WithError(err).Error("SENDIQ control block setup failed")
+		prepared.cleanup()
+
+		return nil, ctxerrors.Wrap(err, "SENDIQ control block setup failed")
+	}
+
+	prepared.args = finalArgs
+	prepared.controlRejection = sendiqStartRejection(finalArgs)
+
+	return prepared, nil
 }
 
-func (s *PIrateRF) handlePOCSAGExecution(
+// executionOptions returns the options every module gets from the start
+// message.
+func (s *PIrateRF) executionOptions(
 	msg *rpitxExecutionStartMessage,
-	finalTimeout int,
-	client *wshub.Client,
-	logger *logrus.Entry,
-) error {
-	logger.Debug("Processing POCSAG execution request")
+) []executionOption {
+	var opts []executionOption
+	if msg.DeadMan {
+		opts = append(opts, withDeadMan(deadManGrace(msg.DeadManGrace)))
+	}
 
-	return s.executionManager.startExecution(
-		s.serviceCtx, msg.ModuleName, msg.Args, finalTimeout, client, nil,
-	)
+	return opts
 }
 
+// createCleanupCallback returns a callback removing every temporary audio
+// file created for an execution (temp playlist, silence padded copy). It
+// keeps going when one of them fails and returns all the errors.
 func (s *PIrateRF) createCleanupCallback(
-	cleanupPath string, logger *logrus.Entry,
+	cleanupPaths []string, logger *logrus.Entry,
 ) func() error {
-	if cleanupPath == "" {
+	if len(cleanupPaths) == 0 {
 		return nil
 	}
 
 	return func() error {
-		if err := os.Remove(cleanupPath); err != nil {
-			logger.WithError(err).WithField("path", cleanupPath).Warn(
-				"Failed to cleanup temporary audio file",
-			)
+		var errs []error
 
-			return ctxerrors.Wrap(err, "failed to remove temporary audio file")
-		}
+		for _, cleanupPath := range cleanupPaths {
+			if err := os.Remove(cleanupPath); err != nil {
+				logger.WithError(err).WithField("path", cleanupPath).Warn(
+					"Failed to cleanup temporary audio file",
+				)
 
-		logger.WithField("path", cleanupPath).Debug("Cleaned up temporary audio file")
+				errs = append(errs, ctxerrors.Wrap(
+					err, "failed to remove temporary audio file",
+				))
 
-		return nil
+				continue
+			}
+
+			logger.WithField("path", cleanupPath).
+				Debug("Cleaned up temporary audio file")
+		}
+
+		return errors.Join(errs...)
 	}
 }
 
@@ -256,6 +462,23 @@ func (s *PIrateRF) handleRPITXExecutionStop(
 	return s.executionManager.stopExecution(client)
 }
 
+// handleRPITXExecutionStatus answers the requesting client with a snapshot
+// of what's on air. The same event is sent to every client when it joins.
+func (s *PIrateRF) handleRPITXExecutionStatus(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	}).Debug("RPITX execution status requested")
+
+	s.executionManager.sendStatusEvent(client)
+
+	return nil
+}
+
 func (s *PIrateRF) getAudioDurationWithSox(audioFile string) (float64, error) {
 	// Use sox to get audio duration
 	stdout, stderr, err := s.commander.Output(
@@ -282,20 +505,58 @@ func (s *PIrateRF) getAudioDurationWithSox(audioFile string) (float64, error) {
 	return duration, nil
 }
 
+// processAudioModifications expands stored playlists and builds the
+// intro/outro playlist and the Play Once silence padding. It returns the
+// final timeout, the temporary files to remove once the execution is over
+// and the final args. The temporary files are returned even on error so the
+// caller can clean them up.
 func (s *PIrateRF) processAudioModifications(
 	msg rpitxExecutionStartMessage,
 	originalTimeout int,
 	logger *logrus.Entry,
-) (int, string, json.RawMessage, error) {
+) (int, []string, json.RawMessage, error) {
 	var argsMap map[string]any
 	if err := json.Unmarshal(msg.Args, &argsMap); err != nil {
-		return originalTimeout, "", msg.Args,
+		return originalTimeout, nil, msg.Args,
 			ctxerrors.Wrap(err, "failed to unmarshal args")
 	}
 
 	audioFile, ok := argsMap["audio"].(string)
 	if !ok || audioFile == "" {
-		return originalTimeout, "", msg.Args, nil
+		return originalTimeout, nil, msg.Args, nil
+	}
+
+	var tempPaths []string
+
+	// Expand a stored playlist first so everything after gets a plain WAV,
+	// leaving the fades to the intro/outro playlist when there is one
+	expandTransitions := msg.playlistTransitions
+	if hasIntroOutro(msg) {
+		expandTransitions = expandTransitions.joinsOnly()
+	}
+
+	expandedPath, err := s.expandPlaylistAudio(
+		audioFile,
+		expandTransitions,
+		msg.tempDir(),
+		logger,
+	)
+	if err != nil {
+		return originalTimeout, nil, msg.Args, err
+	}
+
+	if expandedPath != "" {
+		audioFile = expandedPath
+		tempPaths = append(tempPaths, expandedPath)
+		argsMap["audio"] = expandedPath
+
+		expandedArgs, err := json.Marshal(argsMap)
+		if err != nil {
+			return originalTimeout, tempPaths, msg.Args,
+				ctxerrors.Wrap(err, "failed to marshal expanded args")
+		}
+
+		msg.Args = expandedArgs
 	}
 
 	// Handle intro/outro playlist creation
@@ -306,30 +567,33 @@ func (s *PIrateRF) processAudioModifications(
 		logger,
 	)
 	if err != nil {
-		return originalTimeout, "", msg.Args, err
+		return originalTimeout, tempPaths, msg.Args, err
 	}
 
 	// Update audioFile to use playlist if one was created
 	if tempPlaylistPath != "" {
 		audioFile = tempPlaylistPath
+		tempPaths = append(tempPaths, tempPlaylistPath)
 	}
 
 	// Add silence for Play Once mode
-	finalAudioFile, cleanupPaths, finalArgs, err := s.processPlayOnceSilence(
+	_, cleanupPaths, finalArgs, err := s.processPlayOnceSilence(
 		msg,
 		audioFile,
-		tempPlaylistPath,
+		tempPaths,
 		modifiedArgs,
 		logger,
 	)
 	if err != nil {
-		return originalTimeout, tempPlaylistPath, modifiedArgs, err
+		return originalTimeout, cleanupPaths, modifiedArgs, err
 	}
 
-	// Handle Play Once timeout calculation
+	// Handle Play Once timeout calculation. The audio before the silence
+	// is measured since uploads have their duration cached.
 	finalTimeout, err := s.processPlayOnceTimeout(
 		msg,
-		finalAudioFile,
+		audioFile,
+		playOnceSilenceSeconds,
 		originalTimeout,
 		logger,
 	)
@@ -347,7 +611,7 @@ func (s *PIrateRF) processIntroOutro(
 	logger *logrus.Entry,
 ) (string, json.RawMessage, error) {
 	// Create playlist if intro/outro specified
-	if msg.Intro == nil && msg.Outro == nil {
+	if !hasIntroOutro(msg) {
 		return "", msg.Args, nil
 	}
 
@@ -355,6 +619,8 @@ func (s *PIrateRF) processIntroOutro(
 		audioFile,
 		msg.Intro,
 		msg.Outro,
+		msg.playlistTransitions,
+		msg.tempDir(),
 		logger,
 	)
 	if err != nil {
@@ -381,9 +647,16 @@ func (s *PIrateRF) processIntroOutro(
 	return playlistPath, modifiedArgs, nil
 }
 
+func hasIntroOutro(msg rpitxExecutionStartMessage) bool {
+	return msg.Intro != nil || msg.Outro != nil
+}
+
+// processPlayOnceTimeout works out how long a Play Once execution of
+// audioFile followed by padding seconds of silence lasts.
 func (s *PIrateRF) processPlayOnceTimeout(
 	msg rpitxExecutionStartMessage,
 	audioFile string,
+	padding float64,
 	originalTimeout int,
 	logger *logrus.Entry,
 ) (int, error) {
@@ -392,45 +665,125 @@ func (s *PIrateRF) processPlayOnceTimeout(
 		return originalTimeout, nil
 	}
 
-	duration, err := s.getAudioDurationWithSox(audioFile)
+	duration, err := s.getAudioDuration(audioFile)
 	if err != nil {
 		logger.WithError(err).Error("Play Once: failed to get audio duration")
 
 		return originalTimeout, ctxerrors.Wrap(err, "failed to get audio duration")
 	}
 
-	audioDurationSeconds := int(duration + durationRoundingOffset) // Round up
+	finalTimeout := playOnceTimeout(duration+padding, msg.Timeout)
+
 	logger.WithFields(logrus.Fields{
 		"audioFile":       audioFile,
 		"duration":        duration,
 		"originalTimeout": msg.Timeout,
+		"finalTimeout":    finalTimeout,
 	}).Debug("Play Once: got audio duration")
 
-	// If no timeout set (0), use audio duration
-	if msg.Timeout == 0 {
-		logger.WithField("finalTimeout", audioDurationSeconds).
-			Debug("Play Once: using audio duration (no timeout set)")
+	return finalTimeout, nil
+}
 
-		return audioDurationSeconds, nil
+// playOnceTimeout is when a Play Once broadcast lasting duration seconds
+// ends: once the audio played through, or at the user timeout when that's
+// shorter. No user timeout (0) means the audio duration.
+func playOnceTimeout(duration float64, userTimeout int) int {
+	audioDurationSeconds := int(duration + durationRoundingOffset) // Round up
+
+	if userTimeout == 0 || audioDurationSeconds < userTimeout {
+		return audioDurationSeconds
+	}
+
+	return userTimeout
+}
+
+// validateAudio checks that the audio, intro and outro of a start message
+// are there without building anything out of them, a stored playlist has
+// to have items. It returns the timeout, which Play Once takes from how
+// long they play.
+func (s *PIrateRF) validateAudio(
+	msg rpitxExecutionStartMessage,
+	audio string,
+) (int, error) {
+	if err := s.validateAudioSources(msg, audio); err != nil {
+		return 0, err
+	}
+
+	if !msg.PlayOnce {
+		return msg.Timeout, nil
+	}
+
+	duration, err := s.playOnceDuration(msg)
+	if err != nil {
+		return 0, ctxerrors.Wrap(err, "failed to get audio duration")
+	}
+
+	return playOnceTimeout(duration, msg.Timeout), nil
+}
+
+// validateAudioSources checks that the files the audio gets built from are
+// there.
+func (s *PIrateRF) validateAudioSources(
+	msg rpitxExecutionStartMessage,
+	audio string,
+) error {
+	pl, ok, err := s.playlistFromAudio(audio)
+	if err != nil {
+		return err
+	}
+
+	if ok && len(pl.Items) == 0 {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue, "playlist %s is empty", pl.Name,
+		)
 	}
 
-	// Use the lower of audio duration or user timeout
-	if audioDurationSeconds < msg.Timeout {
-		logger.WithField("finalTimeout", audioDurationSeconds).Debug(
-			"Play Once: using audio duration (shorter than timeout)")
+	type audioSource struct {
+		what string
+		path *string
+	}
 
-		return audioDurationSeconds, nil
+	sources := []audioSource{
+		{what: "audio", path: &audio},
+		{what: "intro", path: msg.Intro},
+		{what: "outro", path: msg.Outro},
 	}
 
-	logger.WithField("finalTimeout", msg.Timeout).
-		Debug("Play Once: using user timeout (shorter than audio)")
+	if ok {
+		sources = sources[1:]
 
-	return msg.Timeout, nil
+		for _, item := range pl.Items {
+			itemPath := filepath.Join(s.playlists.audioDir, item.File)
+			sources = append(sources, audioSource{
+				what: pl.Name + " item",
+				path: &itemPath,
+			})
+		}
+	}
+
+	for _, source := range sources {
+		if source.path == nil || *source.path == "" {
+			continue
+		}
+
+		if _, err := os.Stat(*source.path); err != nil {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrNotFound,
+				"%s does not exist: %s",
+				source.what,
+				*source.path,
+			)
+		}
+	}
+
+	return nil
 }
 
 func (s *PIrateRF) createTempPlaylist(
 	mainAudio string,
 	intro, outro *string,
+	transitions playlistTransitions,
+	dir string,
 	logger *logrus.Entry,
 ) (string, error) {
 	// Generate unique filename for temporary playlist
@@ -460,8 +813,12 @@ func (s *PIrateRF) createTempPlaylist(
 		"filePaths": filePaths,
 	}).Debug("Creating temporary playlist using existing createPlaylistFromFiles")
 
-	// Use existing function with /tmp directory for temporary playlist
-	playlistPath, err := s.createPlaylistFromFiles(playlistName, filePaths, "/tmp")
+	playlistPath, err := s.createPlaylistFromFiles(
+		playlistName,
+		filePaths,
+		transitions,
+		dir,
+	)
 	if err != nil {
 		return "", ctxerrors.Wrapf(err, "failed to create temporary playlist")
 	}
@@ -473,39 +830,44 @@ func (s *PIrateRF) createTempPlaylist(
 }
 
 // processPlayOnceSilence adds 2 seconds of silence to audio file for Play
-// Once mode.
+// Once mode. The silence padded copy is added to the temp files to clean
+// up, next to the temp playlist it may have been made from.
 func (s *PIrateRF) processPlayOnceSilence(
 	msg rpitxExecutionStartMessage,
 	audioFile string,
-	existingTempPath string,
+	tempPaths []string,
 	modifiedArgs json.RawMessage,
 	logger *logrus.Entry,
-) (string, string, json.RawMessage, error) {
+) (string, []string, json.RawMessage, error) {
 	if !msg.PlayOnce {
-		return audioFile, existingTempPath, modifiedArgs, nil
+		return audioFile, tempPaths, modifiedArgs, nil
 	}
 
-	silenceAudioPath := s.generateSilenceFilePath()
+	silenceAudioPath := s.generateSilenceFilePath(msg.tempDir())
 	if err := s.addSilenceToAudio(
 		audioFile,
 		silenceAudioPath,
 		logger,
 	); err != nil {
-		return audioFile, existingTempPath, modifiedArgs, err
+		return audioFile, tempPaths, modifiedArgs, err
 	}
 
+	tempPaths = append(tempPaths, silenceAudioPath)
+
 	finalArgs, err := s.updateArgsWithSilenceFile(modifiedArgs, silenceAudioPath)
 	if err != nil {
-		return audioFile, existingTempPath, modifiedArgs, err
+		return audioFile, tempPaths, modifiedArgs, err
 	}
 
-	return silenceAudioPath, silenceAudioPath, finalArgs, nil
+	return silenceAudioPath, tempPaths, finalArgs, nil
 }
 
-func (s *PIrateRF) generateSilenceFilePath() string {
+func (s *PIrateRF) generateSilenceFilePath(dir string) string {
 	playlistID := uuid.New().String()
 
-	return "/tmp/" + playlistID + "_with_silence" + constants.FileExtensionWAV
+	return filepath.Join(
+		dir, playlistID+"_with_silence"+constants.FileExtensionWAV,
+	)
 }
 
 func (s *PIrateRF) addSilenceToAudio(
@@ -514,7 +876,7 @@ func (s *PIrateRF) addSilenceToAudio(
 	logger.WithFields(logrus.Fields{
 		"originalAudio": audioFile,
 		"silenceFile":   silenceAudioPath,
-	}).Debug("Creating audio file with 2 seconds of silence for Play Once mode")
+	}).Debug("Creating audio file with silence for Play Once mode")
 
 	ctx, cancel := context.WithTimeout(s.serviceCtx, audioConversionTimeout)
 	defer cancel()
@@ -522,7 +884,7 @@ func (s *PIrateRF) addSilenceToAudio(
 	process, err := s.commander.Start(ctx, "sox", []string{
 		audioFile,
 		silenceAudioPath,
-		"pad", "0", "2",
+		"pad", "0", strconv.Itoa(playOnceSilenceSeconds),
 	})
 	if err != nil {
 		return ctxerrors.Wrapf(err, "failed to start sox silence addition")
@@ -585,8 +947,11 @@ func (s *PIrateRF) validateModuleInDev(
 }
 
 // processImageModifications handles image conversion for SPECTRUMPAINT module.
+// With a scratch dir the image is converted from a copy in there and left
+// where it is.
 func (s *PIrateRF) processImageModifications(
 	args json.RawMessage,
+	scratchDir string,
 	logger *logrus.Entry,
 ) (json.RawMessage, error) {
 	var argsMap map[string]any
@@ -599,8 +964,15 @@ func (s *PIrateRF) processImageModifications(
 		return args, nil
 	}
 
+	convert := s.convertImageToYUV
+	if scratchDir != "" {
+		convert = func(inputPath string, logger *logrus.Entry) (string, error) {
+			return s.convertImageCopyToYUV(inputPath, scratchDir, logger)
+		}
+	}
+
 	// Convert image to YUV format if needed
-	convertedPath, err := s.convertImageToYUV(pictureFile, logger)
+	convertedPath, err := convert(pictureFile, logger)
 	if err != nil {
 		return args, ctxerrors.Wrap(err, "failed to convert image")
 	}
@@ -68,7 +68,7 @@ func TestProcessAudioModifications(t *testing.T) {
 			Outro:    nil,
 		}
 
-		finalTimeout, tempPath, finalArgs, err := service.processAudioModifications(
+		finalTimeout, tempPaths, finalArgs, err := service.processAudioModifications(
 			msg,
 			0,
 			logger,
@@ -77,14 +77,14 @@ func TestProcessAudioModifications(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(
 			t,
-			3,
+			3+playOnceSilenceSeconds,
 			finalTimeout,
-			"Timeout matches audio duration",
+			"Timeout matches audio duration plus the silence",
 		)
-		assert.NotEmpty(t, tempPath, "Temp file should be created")
+		require.Len(t, tempPaths, 1, "Temp file should be created")
 		assert.Contains(
 			t,
-			tempPath,
+			tempPaths[0],
 			"_with_silence",
 			"Temp path should contain silence file",
 		)
@@ -114,6 +114,57 @@ func TestProcessAudioModifications(t *testing.T) {
 	})
 }
 
+func TestProcessAudioModifications_IntroOutroPlayOnceCleanup(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+
+	tempDir := t.TempDir()
+	service := &PIrateRF{
+		serviceCtx: context.Background(),
+		config:     Config{FilesDir: tempDir},
+		commander:  &testMockCommander{tempDir: tempDir},
+	}
+
+	intro := introFile
+	outro := outroFile
+	argsJSON, err := json.Marshal(map[string]any{
+		"freq":  431.0,
+		"audio": mainAudioFile,
+	})
+	require.NoError(t, err)
+
+	msg := rpitxExecutionStartMessage{
+		Args:     argsJSON,
+		PlayOnce: true,
+		Intro:    &intro,
+		Outro:    &outro,
+	}
+	logger := logrus.WithField("test", "processAudioModifications")
+
+	_, tempPaths, _, err := service.processAudioModifications(msg, 0, logger)
+	require.NoError(t, err)
+
+	// Both the temp playlist and its silence padded copy must be cleaned up
+	require.Len(t, tempPaths, 2)
+	assert.NotContains(t, tempPaths[0], "_with_silence")
+	assert.Contains(t, tempPaths[1], "_with_silence")
+
+	for _, tempPath := range tempPaths {
+		require.FileExists(t, tempPath)
+	}
+
+	callback := service.createCleanupCallback(tempPaths, logger)
+	require.NotNil(t, callback)
+	require.NoError(t, callback())
+
+	for _, tempPath := range tempPaths {
+		assert.NoFileExists(t, tempPath)
+	}
+
+	// A second run reports the files that are already gone
+	assert.Error(t, callback())
+	assert.Nil(t, service.createCleanupCallback(nil, logger))
+}
+
 func TestGetAudioDurationWithSox(t *testing.T) {
 	t.Setenv(goenv.EnvVarName, goenv.Dev)
 
@@ -256,6 +307,7 @@ func TestProcessPlayOnceTimeoutEdgeCases(t *testing.T) {
 			result, err := service.processPlayOnceTimeout(
 				msg,
 				tt.audioFile,
+				0,
 				tt.originalTimeout,
 				logger,
 			)
@@ -275,8 +327,8 @@ func TestProcessPlayOnceTimeoutEdgeCases(t *testing.T) {
 func TestGenerateSilenceFilePath(t *testing.T) {
 	service := &PIrateRF{}
 
-	path1 := service.generateSilenceFilePath()
-	path2 := service.generateSilenceFilePath()
+	path1 := service.generateSilenceFilePath(executionTempDir)
+	path2 := service.generateSilenceFilePath(executionTempDir)
 
 	// Paths should be different (different UUIDs)
 	assert.NotEqual(t, path1, path2)
@@ -0,0 +1,214 @@
+package piraterf
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/psyb0t/gorpitx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeScheduleCreate  dabluveees.EventType = "schedule.create"
+	eventTypeScheduleList    dabluveees.EventType = "schedule.list"
+	eventTypeScheduleDelete  dabluveees.EventType = "schedule.delete"
+	eventTypeScheduleUpdated dabluveees.EventType = "schedule.updated"
+	eventTypeScheduleError   dabluveees.EventType = "schedule.error"
+)
+
+type scheduleCreateMessage struct {
+	ModuleName gorpitx.ModuleName `json:"moduleName"`
+	PresetName string             `json:"presetName"`
+	Cron       string             `json:"cron"` // cron expression (recurring)
+	At         int64              `json:"at"`   // unix time (one-shot)
+}
+
+type scheduleDeleteMessage struct {
+	ID string `json:"id"`
+}
+
+type scheduleUpdatedMessageData struct {
+	Schedules []schedule `json:"schedules"`
+	Timestamp int64      `json:"timestamp"`
+}
+
+type scheduleErrorMessageData struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (s *PIrateRF) handleScheduleCreate(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Schedule create requested")
+
+	var msg scheduleCreateMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.scheduler.sendErrorEvent("invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	if msg.ModuleName == "" || msg.PresetName == "" {
+		s.scheduler.sendErrorEvent(
+			"invalid request", "module name and preset name are required",
+		)
+
+		return nil
+	}
+
+	if !s.rpitx.IsSupportedModule(msg.ModuleName) {
+		s.scheduler.sendErrorEvent(
+			"unknown module", string(msg.ModuleName)+": unknown module",
+		)
+
+		return nil
+	}
+
+	presetPath := s.getPresetPath(string(msg.ModuleName), msg.PresetName)
+	if _, err := os.Stat(presetPath); err != nil {
+		s.scheduler.sendErrorEvent("preset not found", errorMessage(err))
+
+		return nil
+	}
+
+	sc, err := newSchedule(
+		msg.ModuleName, msg.PresetName, msg.Cron, msg.At, s.scheduler.now(),
+	)
+	if err != nil {
+		s.scheduler.sendErrorEvent("invalid schedule", errorMessage(err))
+
+		return nil
+	}
+
+	if err := s.scheduler.add(sc); err != nil {
+		s.scheduler.sendErrorEvent("save failed", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Schedule created: %s (%s/%s)",
+		sc.ID, sc.ModuleName, sc.PresetName)
+
+	return nil
+}
+
+func (s *PIrateRF) handleScheduleList(
+	_ wshub.Hub,
+	client *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Schedule list requested")
+
+	client.SendEvent(dabluveees.NewEvent(
+		eventTypeScheduleUpdated,
+		scheduleUpdatedMessageData{
+			Schedules: s.scheduler.list(),
+			Timestamp: s.scheduler.now().Unix(),
+		},
+	))
+
+	return nil
+}
+
+func (s *PIrateRF) handleScheduleDelete(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("Schedule delete requested")
+
+	var msg scheduleDeleteMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		s.scheduler.sendErrorEvent("invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	id, err := uuid.Parse(msg.ID)
+	if err != nil {
+		s.scheduler.sendErrorEvent("invalid request", errorMessage(err))
+
+		return nil
+	}
+
+	if err := s.scheduler.remove(id); err != nil {
+		s.scheduler.sendErrorEvent("schedule not found", errorMessage(err))
+
+		return nil
+	}
+
+	logger.Infof("Schedule deleted: %s", id)
+
+	return nil
+}
+
+// runScheduledPreset loads a preset and sends it through the same
+// processing as a start request coming from a client.
+func (s *PIrateRF) runScheduledPreset(sc schedule) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"scheduleID": sc.ID,
+		"moduleName": sc.ModuleName,
+		"presetName": sc.PresetName,
+	})
+
+	data, err := os.ReadFile(
+		s.getPresetPath(string(sc.ModuleName), sc.PresetName),
+	)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to read preset file")
+	}
+
+	msg, err := presetToExecutionMessage(sc.ModuleName, data)
+	if err != nil {
+		return ctxerrors.Wrap(err, "failed to convert preset")
+	}
+
+	if err := s.validateModuleInDev(msg.ModuleName, logger); err != nil {
+		return ctxerrors.Wrap(err, "module validation failed")
+	}
+
+	if violation := s.bandPlan.check(msg.ModuleName, msg.Args); violation != nil {
+		return ctxerrors.Wrap(commonerrors.ErrInvalidValue, violation.message())
+	}
+
+	// Setup errors come back to the scheduler, which reports them
+	prepared, err := s.prepareModuleExecution(msg, logger)
+	if err != nil {
+		return err
+	}
+
+	return s.executionManager.startExecution(
+		s.serviceCtx,
+		prepared.moduleName,
+		prepared.args,
+		prepared.timeout,
+		s.scheduler.client,
+		prepared.callback,
+		prepared.opts...,
+	)
+}
@@ -0,0 +1,195 @@
+package piraterf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newScheduleTestService(t *testing.T) *PIrateRF {
+	t.Helper()
+
+	em := newBusyExecutionManager(t)
+	filesDir := t.TempDir()
+
+	service := &PIrateRF{
+		config:           Config{FilesDir: filesDir},
+		rpitx:            gorpitx.GetInstance(),
+		websocketHub:     em.hub,
+		executionManager: em,
+		serviceCtx:       context.Background(),
+	}
+
+	service.scheduler = newScheduler(
+		filepath.Join(filesDir, schedulesFilename),
+		em.hub,
+		service.runScheduledPreset,
+	)
+
+	presetDir := filepath.Join(filesDir, presetsDir, gorpitx.ModuleNamePICHIRP)
+	require.NoError(t, os.MkdirAll(presetDir, dirPerms))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(presetDir, "chirp.json"),
+		[]byte(`{"frequency": "100000000", "bandwidth": "1000", "time": "1"}`),
+		filePerms,
+	))
+
+	return service
+}
+
+func TestHandleScheduleCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          map[string]any
+		expectCreated bool
+	}{
+		{
+			name: "cron schedule",
+			data: map[string]any{
+				"moduleName": gorpitx.ModuleNamePICHIRP,
+				"presetName": "chirp",
+				"cron":       "@hourly",
+			},
+			expectCreated: true,
+		},
+		{
+			name: "one-shot schedule",
+			data: map[string]any{
+				"moduleName": gorpitx.ModuleNamePICHIRP,
+				"presetName": "chirp",
+				"at":         time.Now().Add(time.Hour).Unix(),
+			},
+			expectCreated: true,
+		},
+		{
+			name: "missing preset",
+			data: map[string]any{
+				"moduleName": gorpitx.ModuleNamePICHIRP,
+				"presetName": "nope",
+				"cron":       "@hourly",
+			},
+		},
+		{
+			name: "unknown module",
+			data: map[string]any{
+				"moduleName": "nope",
+				"presetName": "chirp",
+				"cron":       "@hourly",
+			},
+		},
+		{
+			name: "missing preset name",
+			data: map[string]any{
+				"moduleName": gorpitx.ModuleNamePICHIRP,
+				"cron":       "@hourly",
+			},
+		},
+		{
+			name: "invalid cron",
+			data: map[string]any{
+				"moduleName": gorpitx.ModuleNamePICHIRP,
+				"presetName": "chirp",
+				"cron":       "every day",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newScheduleTestService(t)
+			event := dabluveees.NewEvent(eventTypeScheduleCreate, tt.data)
+
+			err := service.handleScheduleCreate(
+				service.websocketHub, &wshub.Client{}, event,
+			)
+			require.NoError(t, err)
+
+			if !tt.expectCreated {
+				assert.Empty(t, service.scheduler.list())
+
+				return
+			}
+
+			require.Len(t, service.scheduler.list(), 1)
+			assert.FileExists(t, service.scheduler.filePath)
+		})
+	}
+}
+
+func TestHandleScheduleDelete(t *testing.T) {
+	service := newScheduleTestService(t)
+
+	sc, err := newSchedule(
+		gorpitx.ModuleNamePICHIRP, "chirp", "@daily", 0, time.Now(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, service.scheduler.add(sc))
+
+	for _, id := range []string{"not-a-uuid", uuid.NewString()} {
+		event := dabluveees.NewEvent(
+			eventTypeScheduleDelete, map[string]any{"id": id},
+		)
+		require.NoError(t, service.handleScheduleDelete(
+			service.websocketHub, &wshub.Client{}, event,
+		))
+		assert.Len(t, service.scheduler.list(), 1)
+	}
+
+	event := dabluveees.NewEvent(
+		eventTypeScheduleDelete, map[string]any{"id": sc.ID.String()},
+	)
+	require.NoError(t, service.handleScheduleDelete(
+		service.websocketHub, &wshub.Client{}, event,
+	))
+	assert.Empty(t, service.scheduler.list())
+}
+
+func TestHandleScheduleList(t *testing.T) {
+	service := newScheduleTestService(t)
+	client := wshub.NewClient()
+
+	event := dabluveees.NewEvent(eventTypeScheduleList, nil)
+
+	require.NotPanics(t, func() {
+		err := service.handleScheduleList(service.websocketHub, client, event)
+		require.NoError(t, err)
+	})
+}
+
+func TestRunScheduledPreset(t *testing.T) {
+	service := newScheduleTestService(t)
+
+	err := service.runScheduledPreset(schedule{
+		ID:         uuid.New(),
+		ModuleName: gorpitx.ModuleNamePICHIRP,
+		PresetName: "chirp",
+	})
+	require.NoError(t, err)
+
+	// The transmitter is busy so the preset waits in the queue
+	jobs := service.executionManager.getQueueSnapshot()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, gorpitx.ModuleNamePICHIRP, jobs[0].ModuleName)
+	assert.Equal(t, uuid.Nil.String(), jobs[0].ClientID)
+	assert.JSONEq(
+		t,
+		`{"frequency": 100000000, "bandwidth": 1000, "time": 1}`,
+		string(jobs[0].Args),
+	)
+
+	err = service.runScheduledPreset(schedule{
+		ID:         uuid.New(),
+		ModuleName: gorpitx.ModuleNamePICHIRP,
+		PresetName: "missing",
+	})
+	require.Error(t, err)
+}
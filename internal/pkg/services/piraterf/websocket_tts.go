@@ -0,0 +1,346 @@
+package piraterf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/ctxerrors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventTypeTTSGenerate = dabluveees.EventType(
+		"tts.generate",
+	)
+	eventTypeTTSGenerateSuccess = dabluveees.EventType(
+		"tts.generate.success",
+	)
+	eventTypeTTSGenerateError = dabluveees.EventType(
+		"tts.generate.error",
+	)
+
+	ttsEngineEspeakNG  = "espeak-ng"
+	ttsEnginePico2Wave = "pico2wave"
+
+	ttsDefaultVoiceEspeakNG  = "en"
+	ttsDefaultVoicePico2Wave = "en-US"
+
+	// Speeds are in words per minute, espeak-ng's own unit. pico2wave
+	// can't change speed so sox stretches its output instead.
+	ttsDefaultSpeed = 175
+	ttsMinSpeed     = 80
+	ttsMaxSpeed     = 450
+
+	ttsMaxTextLength = 2000 // characters
+	ttsTimeout       = 60 * time.Second
+	ttsFilePrefix    = "tts_"
+)
+
+type ttsGenerateMessage struct {
+	Text     string `json:"text"`
+	Voice    string `json:"voice"`    // Engine voice, empty for the default
+	Speed    int    `json:"speed"`    // Words per minute, 0 for the default
+	Engine   string `json:"engine"`   // espeak-ng (default) or pico2wave
+	FileName string `json:"fileName"` // Output name, empty for tts_<unix>
+}
+
+type ttsGenerateSuccessMessageData struct {
+	FileName  string `json:"fileName"`
+	FilePath  string `json:"filePath"`
+	Engine    string `json:"engine"`
+	Voice     string `json:"voice"`
+	Speed     int    `json:"speed"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type ttsGenerateErrorMessageData struct {
+	FileName  string `json:"fileName"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (s *PIrateRF) handleTTSGenerate(
+	_ wshub.Hub,
+	_ *wshub.Client,
+	event *dabluveees.Event,
+) error {
+	logger := logrus.WithFields(logrus.Fields{
+		constants.FieldEventType: event.Type,
+		constants.FieldEventID:   event.ID,
+	})
+
+	logger.Debug("TTS generation requested")
+
+	var msg ttsGenerateMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		logger.WithError(err).Error("failed to unmarshal tts generate message")
+		s.sendTTSGenerateErrorEvent(msg.FileName, "invalid request", err.Error())
+
+		return nil
+	}
+
+	if err := normalizeTTSRequest(&msg); err != nil {
+		logger.WithError(err).Warn("invalid tts request")
+		s.sendTTSGenerateErrorEvent(
+			msg.FileName,
+			"invalid request",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	outputPath, err := s.generateSpeech(msg)
+	if err != nil {
+		logger.WithError(err).Error("failed to generate speech")
+		s.sendTTSGenerateErrorEvent(
+			msg.FileName,
+			"generation failed",
+			errorMessage(err),
+		)
+
+		return nil
+	}
+
+	logger.WithFields(logrus.Fields{
+		"engine": msg.Engine,
+		"voice":  msg.Voice,
+		"speed":  msg.Speed,
+	}).Infof("Speech generated: %s", outputPath)
+	s.sendTTSGenerateSuccessEvent(msg, outputPath)
+
+	return nil
+}
+
+// normalizeTTSRequest validates a tts request and fills in the defaults.
+func normalizeTTSRequest(msg *ttsGenerateMessage) error {
+	msg.Text = strings.TrimSpace(msg.Text)
+	if msg.Text == "" {
+		return ctxerrors.Wrap(commonerrors.ErrRequiredFieldNotSet, "text")
+	}
+
+	if utf8.RuneCountInString(msg.Text) > ttsMaxTextLength {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"text is longer than %d characters",
+			ttsMaxTextLength,
+		)
+	}
+
+	switch msg.Engine {
+	case "", ttsEngineEspeakNG:
+		msg.Engine = ttsEngineEspeakNG
+
+		if msg.Voice == "" {
+			msg.Voice = ttsDefaultVoiceEspeakNG
+		}
+
+		// Voice names with an optional variant like "en-us", "mb-en1" or
+		// "en+f3"
+		voicePattern := regexp.MustCompile(
+			`^[a-zA-Z0-9_-]{1,32}(\+[a-zA-Z0-9_-]{1,16})?$`,
+		)
+
+		if !voicePattern.MatchString(msg.Voice) {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"invalid voice: %s",
+				msg.Voice,
+			)
+		}
+	case ttsEnginePico2Wave:
+		if msg.Voice == "" {
+			msg.Voice = ttsDefaultVoicePico2Wave
+		}
+
+		// The languages pico2wave ships with
+		voices := []string{
+			"en-US", "en-GB", "de-DE", "es-ES", "fr-FR", "it-IT",
+		}
+
+		if !slices.Contains(voices, msg.Voice) {
+			return ctxerrors.Wrapf(
+				commonerrors.ErrInvalidValue,
+				"invalid voice: %s, pico2wave has %s",
+				msg.Voice,
+				strings.Join(voices, ", "),
+			)
+		}
+	default:
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"unknown engine: %s",
+			msg.Engine,
+		)
+	}
+
+	if msg.Speed == 0 {
+		msg.Speed = ttsDefaultSpeed
+	}
+
+	if msg.Speed < ttsMinSpeed || msg.Speed > ttsMaxSpeed {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"speed must be between %d and %d words per minute",
+			ttsMinSpeed,
+			ttsMaxSpeed,
+		)
+	}
+
+	if msg.FileName == "" {
+		msg.FileName = fmt.Sprintf("%s%d", ttsFilePrefix, time.Now().Unix())
+	}
+
+	if msg.FileName != filepath.Base(msg.FileName) ||
+		strings.HasPrefix(msg.FileName, ".") {
+		return ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"invalid file name: %s",
+			msg.FileName,
+		)
+	}
+
+	return nil
+}
+
+// generateSpeech renders the text with the local engine and converts it to
+// the same 48kHz mono WAV every other upload ends up as.
+func (s *PIrateRF) generateSpeech(msg ttsGenerateMessage) (string, error) {
+	if err := s.ensureFilesDirsExist(); err != nil {
+		return "", err
+	}
+
+	outputPath := filepath.Join(
+		s.config.FilesDir,
+		audioUploadsPath,
+		s.ensureWavExtension(msg.FileName),
+	)
+
+	if _, err := os.Stat(outputPath); err == nil {
+		return "", ctxerrors.Wrapf(
+			commonerrors.ErrInvalidValue,
+			"file already exists: %s",
+			filepath.Base(outputPath),
+		)
+	}
+
+	tempDir, err := os.MkdirTemp("", "piraterf_tts_")
+	if err != nil {
+		return "", ctxerrors.Wrap(err, "failed to create temp dir")
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			logrus.WithError(err).Warn("failed to remove tts temp dir")
+		}
+	}()
+
+	// pico2wave wants the output to end in .wav
+	rawPath := filepath.Join(tempDir, "speech"+constants.FileExtensionWAV)
+
+	ctx, cancel := context.WithTimeout(s.serviceCtx, ttsTimeout)
+	defer cancel()
+
+	// The text goes in on stdin so it can never be taken for a flag
+	_, stderr, err := s.commander.Output(
+		ctx,
+		msg.Engine,
+		ttsEngineArgs(msg, rawPath),
+		commander.WithStdin(strings.NewReader(msg.Text)),
+	)
+	if err != nil {
+		return "", ctxerrors.Wrapf(
+			err,
+			"%s failed, stderr: %s",
+			msg.Engine,
+			string(stderr),
+		)
+	}
+
+	soxArgs := s.buildSoxArgs(
+		[]string{rawPath}, outputPath, audioChannels,
+	)
+	if msg.Engine == ttsEnginePico2Wave && msg.Speed != ttsDefaultSpeed {
+		soxArgs = append(soxArgs, "tempo", strconv.FormatFloat(
+			float64(msg.Speed)/ttsDefaultSpeed, 'f', 3, 64,
+		))
+	}
+
+	_, stderr, err = s.commander.Output(ctx, constants.ToolSox, soxArgs)
+	if err != nil {
+		return "", ctxerrors.Wrapf(
+			err,
+			"sox conversion failed, stderr: %s",
+			string(stderr),
+		)
+	}
+
+	if _, err := os.Stat(outputPath); err != nil {
+		return "", ctxerrors.Wrap(err, "generated file not found")
+	}
+
+	cacheAudioPeaks(outputPath)
+
+	return outputPath, nil
+}
+
+// ttsEngineArgs builds the engine command line, the text comes on stdin.
+func ttsEngineArgs(msg ttsGenerateMessage, outputPath string) []string {
+	if msg.Engine == ttsEnginePico2Wave {
+		return []string{"-l", msg.Voice, "-w", outputPath}
+	}
+
+	return []string{
+		"-v", msg.Voice,
+		"-s", strconv.Itoa(msg.Speed),
+		"-w", outputPath,
+		"--stdin",
+	}
+}
+
+// Event sending functions for tts operations.
+func (s *PIrateRF) sendTTSGenerateSuccessEvent(
+	msg ttsGenerateMessage,
+	filePath string,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeTTSGenerateSuccess,
+		ttsGenerateSuccessMessageData{
+			FileName:  filepath.Base(filePath),
+			FilePath:  filePath,
+			Engine:    msg.Engine,
+			Voice:     msg.Voice,
+			Speed:     msg.Speed,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
+
+func (s *PIrateRF) sendTTSGenerateErrorEvent(
+	fileName, errorType, message string,
+) {
+	s.websocketHub.BroadcastToAll(dabluveees.NewEvent(
+		eventTypeTTSGenerateError,
+		ttsGenerateErrorMessageData{
+			FileName:  fileName,
+			Error:     errorType,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		},
+	))
+}
@@ -0,0 +1,264 @@
+package piraterf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dabluveees "github.com/psyb0t/aichteeteapee/server/dabluvee-es"
+	"github.com/psyb0t/aichteeteapee/server/dabluvee-es/wshub"
+	"github.com/psyb0t/commander"
+	"github.com/psyb0t/common-go/constants"
+	commonerrors "github.com/psyb0t/common-go/errors"
+	"github.com/psyb0t/goenv"
+	"github.com/psyb0t/gorpitx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTTSRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         ttsGenerateMessage
+		expected    ttsGenerateMessage
+		expectError error
+	}{
+		{
+			name: "espeak-ng defaults",
+			msg:  ttsGenerateMessage{Text: "  this is pirate radio  ", FileName: "id"},
+			expected: ttsGenerateMessage{
+				Text:     "this is pirate radio",
+				Voice:    ttsDefaultVoiceEspeakNG,
+				Speed:    ttsDefaultSpeed,
+				Engine:   ttsEngineEspeakNG,
+				FileName: "id",
+			},
+		},
+		{
+			name: "pico2wave defaults",
+			msg: ttsGenerateMessage{
+				Text: "hello", Engine: ttsEnginePico2Wave, FileName: "id",
+			},
+			expected: ttsGenerateMessage{
+				Text:     "hello",
+				Voice:    ttsDefaultVoicePico2Wave,
+				Speed:    ttsDefaultSpeed,
+				Engine:   ttsEnginePico2Wave,
+				FileName: "id",
+			},
+		},
+		{
+			name: "espeak-ng voice variant",
+			msg: ttsGenerateMessage{
+				Text: "hello", Voice: "en-us+f3", Speed: 140, FileName: "id",
+			},
+			expected: ttsGenerateMessage{
+				Text:     "hello",
+				Voice:    "en-us+f3",
+				Speed:    140,
+				Engine:   ttsEngineEspeakNG,
+				FileName: "id",
+			},
+		},
+		{
+			name:        "no text",
+			msg:         ttsGenerateMessage{Text: "   "},
+			expectError: commonerrors.ErrRequiredFieldNotSet,
+		},
+		{
+			name:        "unknown engine",
+			msg:         ttsGenerateMessage{Text: "hi", Engine: "festival"},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "bad espeak-ng voice",
+			msg:         ttsGenerateMessage{Text: "hi", Voice: "en; rm -rf"},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name: "pico2wave voice it doesn't have",
+			msg: ttsGenerateMessage{
+				Text: "hi", Engine: ttsEnginePico2Wave, Voice: "ro-RO",
+			},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "too slow",
+			msg:         ttsGenerateMessage{Text: "hi", Speed: 10},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+		{
+			name:        "file name with a path",
+			msg:         ttsGenerateMessage{Text: "hi", FileName: "../../etc/x"},
+			expectError: commonerrors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			err := normalizeTTSRequest(&msg)
+
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, msg)
+		})
+	}
+
+	// No file name gets a timestamped one
+	msg := ttsGenerateMessage{Text: "hi"}
+	require.NoError(t, normalizeTTSRequest(&msg))
+	assert.Regexp(t, `^tts_\d+$`, msg.FileName)
+}
+
+func TestTTSEngineArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-v", "en", "-s", "175", "-w", "out.wav", "--stdin"},
+		ttsEngineArgs(ttsGenerateMessage{
+			Engine: ttsEngineEspeakNG, Voice: "en", Speed: 175,
+		}, "out.wav"),
+	)
+
+	assert.Equal(t,
+		[]string{"-l", "en-GB", "-w", "out.wav"},
+		ttsEngineArgs(ttsGenerateMessage{
+			Engine: ttsEnginePico2Wave, Voice: "en-GB", Speed: 175,
+		}, "out.wav"),
+	)
+}
+
+func TestGenerateSpeech(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	tests := []struct {
+		name  string
+		msg   ttsGenerateMessage
+		setup func(mock *fileCreatingMockCommander)
+	}{
+		{
+			name: "espeak-ng",
+			msg: ttsGenerateMessage{
+				Text: "hello", Voice: "en", Speed: 175,
+				Engine: ttsEngineEspeakNG, FileName: "station_id",
+			},
+			setup: func(mock *fileCreatingMockCommander) {
+				mock.ExpectWithMatchers(ttsEngineEspeakNG,
+					commander.Exact("-v"), commander.Exact("en"),
+					commander.Exact("-s"), commander.Exact("175"),
+					commander.Exact("-w"), commander.Any(),
+					commander.Exact("--stdin"),
+				)
+				mock.ExpectWithMatchers(constants.ToolSox,
+					commander.Any(),
+					commander.Exact("-r"), commander.Exact(audioSampleRate),
+					commander.Exact("-b"), commander.Exact(audioBitDepth),
+					commander.Exact("-c"), commander.Exact(audioChannels),
+					commander.Regex(`station_id\.wav$`),
+				)
+			},
+		},
+		{
+			name: "pico2wave stretched by sox",
+			msg: ttsGenerateMessage{
+				Text: "hello", Voice: "en-US", Speed: 210,
+				Engine: ttsEnginePico2Wave, FileName: "station_id.wav",
+			},
+			setup: func(mock *fileCreatingMockCommander) {
+				mock.ExpectWithMatchers(ttsEnginePico2Wave,
+					commander.Exact("-l"), commander.Exact("en-US"),
+					commander.Exact("-w"), commander.Any(),
+				)
+				mock.ExpectWithMatchers(constants.ToolSox,
+					commander.Any(),
+					commander.Exact("-r"), commander.Exact(audioSampleRate),
+					commander.Exact("-b"), commander.Exact(audioBitDepth),
+					commander.Exact("-c"), commander.Exact(audioChannels),
+					commander.Regex(`station_id\.wav$`),
+					commander.Exact("tempo"), commander.Exact("1.200"),
+				)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+
+			mock := &fileCreatingMockCommander{
+				MockCommander: *commander.NewMock(),
+			}
+			tt.setup(mock)
+
+			service := &PIrateRF{
+				serviceCtx: context.Background(),
+				config:     Config{FilesDir: tempDir},
+				commander:  mock,
+				rpitx:      gorpitx.GetInstance(),
+			}
+
+			outputPath, err := service.generateSpeech(tt.msg)
+			require.NoError(t, err)
+			require.NoError(t, mock.VerifyExpectations())
+
+			assert.Equal(t,
+				filepath.Join(tempDir, audioUploadsPath, "station_id.wav"),
+				outputPath,
+			)
+			assert.FileExists(t, outputPath)
+
+			// Never overwrites what's there
+			_, err = service.generateSpeech(tt.msg)
+			require.ErrorIs(t, err, commonerrors.ErrInvalidValue)
+		})
+	}
+}
+
+func TestHandleTTSGenerate(t *testing.T) {
+	t.Setenv(goenv.EnvVarName, goenv.Dev)
+
+	hub := wshub.NewHub("test")
+	defer hub.Close()
+
+	tempDir := t.TempDir()
+
+	mock := &fileCreatingMockCommander{MockCommander: *commander.NewMock()}
+	mock.Expect(ttsEngineEspeakNG).ReturnError(os.ErrNotExist)
+
+	service := &PIrateRF{
+		serviceCtx:   context.Background(),
+		config:       Config{FilesDir: tempDir},
+		commander:    mock,
+		rpitx:        gorpitx.GetInstance(),
+		websocketHub: hub,
+	}
+
+	tests := []struct {
+		name string
+		data any
+	}{
+		{name: "invalid json", data: invalidJSONData},
+		{name: "invalid request", data: ttsGenerateMessage{}},
+		{
+			name: "engine fails",
+			data: ttsGenerateMessage{Text: "hello", FileName: "broken"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, service.handleTTSGenerate(
+				hub,
+				wshub.NewClient(),
+				dabluveees.NewEvent(eventTypeTTSGenerate, tt.data),
+			))
+		})
+	}
+
+	assert.NoFileExists(t, filepath.Join(tempDir, audioUploadsPath, "broken.wav"))
+}
@@ -18,6 +18,7 @@ PIRATERF_HTMLDIR="${PIRATERF_HTMLDIR:-./html}"
 PIRATERF_STATICDIR="${PIRATERF_STATICDIR:-./static}"
 PIRATERF_FILESDIR="${PIRATERF_FILESDIR:-./files}"
 PIRATERF_UPLOADDIR="${PIRATERF_UPLOADDIR:-./uploads}"
+PIRATERF_HISTORYDIR="${PIRATERF_HISTORYDIR:-./history}"
 
 # Directory permissions (matching piraterf service)
 DIR_PERMS=750
@@ -27,6 +28,7 @@ mkdir -p "$PIRATERF_HTMLDIR" && chmod $DIR_PERMS "$PIRATERF_HTMLDIR"
 mkdir -p "$PIRATERF_STATICDIR" && chmod $DIR_PERMS "$PIRATERF_STATICDIR"
 mkdir -p "$PIRATERF_FILESDIR" && chmod $DIR_PERMS "$PIRATERF_FILESDIR"
 mkdir -p "$PIRATERF_UPLOADDIR" && chmod $DIR_PERMS "$PIRATERF_UPLOADDIR"
+mkdir -p "$PIRATERF_HISTORYDIR" && chmod $DIR_PERMS "$PIRATERF_HISTORYDIR"
 
 # Audio directories
 mkdir -p "$PIRATERF_FILESDIR/audio" && chmod $DIR_PERMS "$PIRATERF_FILESDIR/audio"
@@ -56,6 +58,7 @@ docker run -i --rm \
     -v "$(pwd)/static:/app/static" \
     -v "$(pwd)/uploads:/app/uploads" \
     -v "$(pwd)/files:/app/files" \
+    -v "$(pwd)/history:/app/history" \
     -v "$(pwd)/.tls:/app/.tls" \
     -e ENV=dev \
     -e LOG_LEVEL=debug \
@@ -72,6 +75,7 @@ docker run -i --rm \
     -e PIRATERF_STATICDIR=./static \
     -e PIRATERF_FILESDIR=./files \
     -e PIRATERF_UPLOADDIR=./uploads \
+    -e PIRATERF_HISTORYDIR=./history \
     "$APP_NAME-dev" sh -c "CGO_ENABLED=1 go build -race -o ./build/$APP_NAME ./cmd/... && ./build/$APP_NAME run"
 
 success "✅ Development container fucking finished"
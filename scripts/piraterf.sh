@@ -50,5 +50,6 @@ export PIRATERF_HTMLDIR=./html
 export PIRATERF_STATICDIR=./static
 export PIRATERF_FILESDIR=./files
 export PIRATERF_UPLOADDIR=./uploads
+export PIRATERF_HISTORYDIR=./history
 
 ./piraterf run
@@ -46,6 +46,7 @@ sudo apt-get install -y \
     sox \
     libsox-fmt-all \
     ffmpeg \
+    espeak-ng \
     imagemagick \
     openssl \
     minimodem \
@@ -13,9 +13,13 @@ class PIrateRFController {
     this.heartbeatIntervalMs = 5000; // Send heartbeat every 5 seconds
     this.heartbeatTimeoutMs = 5000; // 5 second timeout
 
+    // SFX playing over the live broadcast by clip ID
+    this.liveSFX = new Map();
+
     // Initialize centralized state object
     this.state = {
       modulename: "pifmrds",
+      deadMan: false,
 
       pifmrds: {
         freq: "87.9",
@@ -23,12 +27,24 @@ class PIrateRFController {
         pi: "",
         ps: "",
         rt: "",
+        autoRT: false,
+        autoPS: false,
         ppm: "",
         timeout: "0",
         playOnce: false,
         introOutroToggled: false,
+        normalizeToggled: false,
+        stereoToggled: null, // null goes with the server default
+        loudnessTarget: "-16",
+        truePeak: "-1.5",
+        loudnessRange: "11",
+        highpass: "0",
         introSelect: "",
         outroSelect: "",
+        crossfade: "0",
+        gap: "0",
+        fadeIn: "0",
+        fadeOut: "0",
       },
 
       morse: {
@@ -105,6 +121,18 @@ class PIrateRFController {
         bufferSize: "4096",
         modulation: "FM",
         gain: "1.0",
+        source: "mic",
+        audio: "",
+        playOnce: false,
+        intro: "",
+        outro: "",
+        record: false,
+        bed: "",
+        bedVolume: "",
+        duckDepth: "",
+        duckThreshold: "",
+        sfxGain: "",
+        sfxExclusive: false,
       },
 
       sendiq: {
@@ -321,8 +349,12 @@ class PIrateRFController {
     this.titleEl = document.getElementById("title");
     this.statusBar = document.getElementById("statusBar");
     this.statusText = document.getElementById("statusText");
+    this.progressBar = document.getElementById("progressBar");
+    this.progressFill = document.getElementById("progressFill");
+    this.progressText = document.getElementById("progressText");
     this.moduleSelect = document.getElementById("moduleSelect");
     this.startBtn = document.getElementById("startBtn");
+    this.deadManToggle = document.getElementById("deadManToggle");
     this.stopBtn = document.getElementById("stopBtn");
     this.outputContent = document.getElementById("outputContent");
     this.controlPanel = document.getElementById("controlPanel");
@@ -345,6 +377,25 @@ class PIrateRFController {
     this.playModeToggle = document.getElementById("playModeToggle");
     this.introOutroToggle = document.getElementById("introOutroToggle");
     this.introOutroControls = document.getElementById("introOutroControls");
+    this.ttsToggle = document.getElementById("ttsToggle");
+    this.ttsControls = document.getElementById("ttsControls");
+    this.ttsText = document.getElementById("ttsText");
+    this.ttsEngine = document.getElementById("ttsEngine");
+    this.ttsVoice = document.getElementById("ttsVoice");
+    this.ttsSpeed = document.getElementById("ttsSpeed");
+    this.ttsFileName = document.getElementById("ttsFileName");
+    this.ttsGenerateBtn = document.getElementById("ttsGenerateBtn");
+    this.normalizeToggle = document.getElementById("normalizeToggle");
+    this.stereoToggle = document.getElementById("stereoToggle");
+    this.normalizeControls = document.getElementById("normalizeControls");
+    this.loudnessTargetInput = document.getElementById("loudnessTarget");
+    this.crossfadeInput = document.getElementById("crossfade");
+    this.gapInput = document.getElementById("gap");
+    this.fadeInInput = document.getElementById("fadeIn");
+    this.fadeOutInput = document.getElementById("fadeOut");
+    this.truePeakInput = document.getElementById("truePeak");
+    this.loudnessRangeInput = document.getElementById("loudnessRange");
+    this.highpassInput = document.getElementById("highpass");
     this.introSelect = document.getElementById("introSelect");
     this.outroSelect = document.getElementById("outroSelect");
     this.playIntroBtn = document.getElementById("playIntroBtn");
@@ -352,6 +403,11 @@ class PIrateRFController {
     this.piInput = document.getElementById("pi");
     this.psInput = document.getElementById("ps");
     this.rtInput = document.getElementById("rt");
+    this.autoRTInput = document.getElementById("autoRT");
+    this.audioWaveform = document.getElementById("audioWaveform");
+    this.audioWaveformCanvas = document.getElementById("audioWaveformCanvas");
+    this.audioWaveformInfo = document.getElementById("audioWaveformInfo");
+    this.autoPSInput = document.getElementById("autoPS");
     this.ppmInput = document.getElementById("ppm");
     this.timeoutInput = document.getElementById("timeout");
 
@@ -425,6 +481,21 @@ class PIrateRFController {
     this.audioSockBroadcastBufferSizeInput = document.getElementById("audioSockBroadcastBufferSize");
     this.audioSockBroadcastModulationInput = document.getElementById("audioSockBroadcastModulation");
     this.audioSockBroadcastGainInput = document.getElementById("audioSockBroadcastGain");
+    this.audioSockSourceInput = document.getElementById("audioSockSource");
+    this.audioSockAudioInput = document.getElementById("audioSockAudio");
+    this.audioSockPlayOnceInput = document.getElementById("audioSockPlayOnce");
+    this.audioSockIntroInput = document.getElementById("audioSockIntro");
+    this.audioSockOutroInput = document.getElementById("audioSockOutro");
+    this.audioSockRecordInput = document.getElementById("audioSockRecord");
+    this.audioSockBedInput = document.getElementById("audioSockBed");
+    this.audioSockBedVolumeInput = document.getElementById("audioSockBedVolume");
+    this.audioSockDuckDepthInput = document.getElementById("audioSockDuckDepth");
+    this.audioSockDuckThresholdInput = document.getElementById("audioSockDuckThreshold");
+    this.soundboard = document.getElementById("soundboard");
+    this.sfxGainInput = document.getElementById("sfxGain");
+    this.sfxExclusiveInput = document.getElementById("sfxExclusive");
+    this.sfxStopAllBtn = document.getElementById("sfxStopAllBtn");
+    this.sfxPlayingList = document.getElementById("sfxPlaying");
     this.fskDataFile = document.getElementById("fskDataFile");
 
     // SENDIQ form inputs
@@ -438,6 +509,7 @@ class PIrateRFController {
     this.sendiqSharedMemTokenInput = document.getElementById("sendiqSharedMemToken");
     this.sendiqTimeoutInput = document.getElementById("sendiqTimeout");
     this.sendiqLoopModeInput = document.getElementById("sendiqLoopMode");
+    this.sendiqCarrierBtn = document.getElementById("sendiqCarrierBtn");
     this.refreshIqBtn = document.getElementById("refreshIqBtn");
     this.iqFileSelectBtn = document.getElementById("iqFileSelectBtn");
     this.editIqFileBtn = document.getElementById("editIqFileBtn");
@@ -467,6 +539,13 @@ class PIrateRFController {
     this.renameFileBtn = document.getElementById("renameFileBtn");
     this.editFileName = document.getElementById("editFileName");
     this.deleteFileBtn = document.getElementById("deleteFileBtn");
+    this.audioEditSection = document.getElementById("audioEditSection");
+    this.trimStartInput = document.getElementById("trimStart");
+    this.trimEndInput = document.getElementById("trimEnd");
+    this.trimCutInput = document.getElementById("trimCut");
+    this.audioEditReplaceInput = document.getElementById("audioEditReplace");
+    this.trimAudioBtn = document.getElementById("trimAudioBtn");
+    this.stripSilenceBtn = document.getElementById("stripSilenceBtn");
 
     // Playlist modal elements
     this.playlistBtn = document.getElementById("playlistBtn");
@@ -480,6 +559,12 @@ class PIrateRFController {
     this.clearPlaylistBtn = document.getElementById("clearPlaylistBtn");
     this.playlistName = document.getElementById("playlistName");
     this.createPlaylistBtn = document.getElementById("createPlaylistBtn");
+    this.savePlaylistBtn = document.getElementById("savePlaylistBtn");
+    this.playlistCrossfadeInput = document.getElementById("playlistCrossfade");
+    this.playlistGapInput = document.getElementById("playlistGap");
+    this.playlistFadeInInput = document.getElementById("playlistFadeIn");
+    this.playlistFadeOutInput = document.getElementById("playlistFadeOut");
+    this.storedPlaylistList = document.getElementById("storedPlaylistList");
     this.playlistError = document.getElementById("playlistError");
 
     // Preset elements
@@ -522,6 +607,8 @@ class PIrateRFController {
 
     // Playlist functionality
     this.playlist = [];
+    this.storedPlaylists = [];
+    this.editingPlaylistId = null;
 
     // Audio file upload/record elements
     this.audioFileInput = document.getElementById("audioFile");
@@ -560,6 +647,12 @@ class PIrateRFController {
     this.startBtn.addEventListener("click", () => this.startExecution());
     this.stopBtn.addEventListener("click", () => this.stopExecution());
 
+    // Dead-man switch toggle
+    this.deadManToggle.addEventListener("click", () => {
+      this.deadManToggle.classList.toggle("active");
+      this.saveState();
+    });
+
     // Play mode toggle
     this.playModeToggle.addEventListener("click", () => {
       this.togglePlayMode();
@@ -572,6 +665,41 @@ class PIrateRFController {
       this.saveState();
     });
 
+    // Text-to-speech
+    this.ttsToggle.addEventListener("click", () => {
+      const show = this.ttsControls.classList.contains("hidden");
+      this.ttsControls.classList.toggle("hidden", !show);
+      this.ttsToggle.classList.toggle("active", show);
+    });
+    this.ttsEngine.addEventListener("change", () => {
+      this.ttsVoice.placeholder =
+        this.ttsEngine.value === "pico2wave" ? "en-US" : "en";
+    });
+    this.ttsGenerateBtn.addEventListener("click", () => this.generateTTS());
+
+    // Loudness normalisation toggle and targets
+    this.normalizeToggle.addEventListener("click", () => {
+      this.toggleNormalize();
+      this.saveState();
+    });
+    this.stereoToggle.addEventListener("click", () => {
+      this.stereoToggle.classList.toggle("active");
+      this.stereoChosen = true;
+      this.saveState();
+    });
+    [
+      this.loudnessTargetInput,
+      this.truePeakInput,
+      this.loudnessRangeInput,
+      this.highpassInput,
+      this.crossfadeInput,
+      this.gapInput,
+      this.fadeInInput,
+      this.fadeOutInput,
+    ].forEach((input) =>
+      input.addEventListener("change", () => this.saveState())
+    );
+
     // Intro/Outro play buttons and select change handlers
     this.introSelect.addEventListener("change", () => {
       this.onIntroOutroChange();
@@ -613,6 +741,8 @@ class PIrateRFController {
     this.modalCancelBtn.addEventListener("click", () => this.closeEditModal());
     this.renameFileBtn.addEventListener("click", () => this.renameFile());
     this.deleteFileBtn.addEventListener("click", () => this.deleteFile());
+    this.trimAudioBtn.addEventListener("click", () => this.trimAudio());
+    this.stripSilenceBtn.addEventListener("click", () => this.stripSilence());
 
     // Image upload handling
     this.imageFile.addEventListener("change", () => this.uploadImage());
@@ -626,6 +756,7 @@ class PIrateRFController {
     this.createPlaylistBtn.addEventListener("click", () =>
       this.createPlaylist()
     );
+    this.savePlaylistBtn.addEventListener("click", () => this.savePlaylist());
     this.playlistName.addEventListener("input", () =>
       this.validatePlaylistCreation()
     );
@@ -702,6 +833,12 @@ class PIrateRFController {
     this.piInput.addEventListener("input", () => this.saveState());
     this.psInput.addEventListener("input", () => this.saveState());
     this.rtInput.addEventListener("input", () => this.saveState());
+    this.autoRTInput.addEventListener("change", () => this.saveState());
+    this.autoPSInput.addEventListener("change", () => this.saveState());
+
+    // Live RDS updates while an FM broadcast is on air
+    this.psInput.addEventListener("change", () => this.sendRDSText("ps"));
+    this.rtInput.addEventListener("change", () => this.sendRDSText("rt"));
     this.ppmInput.addEventListener("input", () => this.saveState());
     document
       .getElementById("timeout")
@@ -927,6 +1064,51 @@ class PIrateRFController {
       this.saveState();
       this.validateForm();
     });
+    this.audioSockSourceInput.addEventListener("change", () => {
+      this.saveState();
+      this.onAudioSockSourceChange();
+      this.validateForm();
+    });
+    this.audioSockAudioInput.addEventListener("change", () => {
+      this.saveState();
+      this.validateForm();
+    });
+    [
+      this.audioSockPlayOnceInput,
+      this.audioSockIntroInput,
+      this.audioSockOutroInput,
+    ].forEach((input) =>
+      input.addEventListener("change", () => this.saveState())
+    );
+    this.audioSockRecordInput.addEventListener("change", () => this.saveState());
+    this.audioSockBedInput.addEventListener("change", () => this.saveState());
+
+    // Live bed levels while a broadcast with a bed is on air
+    this.audioSockBedVolumeInput.addEventListener("change", () => {
+      this.saveState();
+      this.sendLiveAudioControl("live.bed.volume.set", {
+        volume: parseFloat(this.audioSockBedVolumeInput.value),
+      });
+    });
+    [this.audioSockDuckDepthInput, this.audioSockDuckThresholdInput].forEach(
+      (input) =>
+        input.addEventListener("change", () => {
+          this.saveState();
+          this.sendLiveAudioControl("live.bed.duck.set", {
+            depth: this.optionalNumber(this.audioSockDuckDepthInput.value),
+            threshold: this.optionalNumber(
+              this.audioSockDuckThresholdInput.value
+            ),
+          });
+        })
+    );
+
+    // Soundboard SFX over the live broadcast
+    this.sfxGainInput.addEventListener("change", () => this.saveState());
+    this.sfxExclusiveInput.addEventListener("change", () => this.saveState());
+    this.sfxStopAllBtn.addEventListener("click", () =>
+      this.sendLiveAudioControl("live.sfx.stop", {})
+    );
 
     // SENDIQ module form events
     this.sendiqFreqInput.addEventListener("input", () => {
@@ -951,6 +1133,24 @@ class PIrateRFController {
       this.saveState();
     });
 
+    // Live SENDIQ control while an IQ replay is on air
+    this.sendiqFreqInput.addEventListener("change", () =>
+      this.sendSendiqControl("sendiq.frequency.set", {
+        frequency: parseFloat(this.sendiqFreqInput.value),
+      })
+    );
+    this.sendiqPowerInput.addEventListener("change", () =>
+      this.sendSendiqControl("sendiq.power.set", {
+        power: parseFloat(this.sendiqPowerInput.value),
+      })
+    );
+    this.sendiqCarrierBtn.addEventListener("click", () =>
+      this.sendSendiqControl(
+        this.sendiqCarrier ? "sendiq.resume" : "sendiq.carrier",
+        {}
+      )
+    );
+
     // SENDIQ file control buttons
     this.refreshIqBtn.addEventListener("click", () => this.loadIqFiles());
     this.iqFileSelectBtn.addEventListener("click", () => this.iqFile.click());
@@ -977,11 +1177,22 @@ class PIrateRFController {
     const port =
       window.location.port ||
       (window.location.protocol === "https:" ? "443" : "80");
-    const wsUrl = `${protocol}//${host}:${port}/ws`;
+    // Reconnect under the same client ID so the server knows it's still us
+    const wsUrl = `${protocol}//${host}:${port}/ws?clientID=${this.getClientID()}`;
 
     this.connectToUrl(wsUrl);
   }
 
+  // Client ID kept for the lifetime of the tab
+  getClientID() {
+    let clientID = sessionStorage.getItem("piraterf_client_id");
+    if (!clientID) {
+      clientID = this.generateUUID();
+      sessionStorage.setItem("piraterf_client_id", clientID);
+    }
+    return clientID;
+  }
+
   connectToUrl(wsUrl) {
     try {
       this.statusText.textContent = `🔌 Connecting...`;
@@ -994,6 +1205,7 @@ class PIrateRFController {
         this.clearWebSocketErrors(); // Clear any WebSocket error notifications
         this.setControlsEnabled(true);
         this.startHeartbeat(); // Start heartbeat on connection
+        this.sendPlaylistEvent("playlist.list", {});
       };
 
       this.ws.onclose = (event) => {
@@ -1071,7 +1283,40 @@ class PIrateRFController {
         this.onExecutionError(message.data);
         break;
       case "rpitx.execution.output-line":
-        this.onOutputLine(message.data);
+        this.onOutputLines(message.data);
+        break;
+      case "rpitx.execution.status":
+        this.onExecutionStatus(message.data);
+        break;
+      case "rpitx.execution.progress":
+        this.onExecutionProgress(message.data);
+        break;
+      case "rpitx.execution.rejected":
+        this.onExecutionRejected(message.data);
+        break;
+      case "rds.ps.set.success":
+        this.psInput.value = message.data.text;
+        this.log(`📻 PS now: ${message.data.text}`, "system");
+        break;
+      case "rds.rt.set.success":
+        this.rtInput.value = message.data.text;
+        this.log(`📻 RT now: ${message.data.text}`, "system");
+        break;
+      case "sendiq.status":
+        this.onSendiqStatus(message.data);
+        break;
+      case "sendiq.control.error":
+        this.log(
+          `❌ IQ control failed: ${message.data.error} - ${message.data.message}`,
+          "system"
+        );
+        break;
+      case "rds.ps.set.error":
+      case "rds.rt.set.error":
+        this.log(
+          `❌ RDS update failed: ${message.data.error} - ${message.data.message}`,
+          "system"
+        );
         break;
       case "file.rename.success":
         // Check file type based on the file path
@@ -1163,6 +1408,53 @@ class PIrateRFController {
           this.onFileDeleteError(message.data);
         }
         break;
+      case "tts.generate.success":
+        this.onTTSGenerateSuccess(message.data);
+        break;
+      case "tts.generate.error":
+        this.onTTSGenerateError(message.data);
+        break;
+      case "playlist.updated":
+        this.onPlaylistsUpdated(message.data);
+        break;
+      case "playlist.error":
+        this.onStoredPlaylistError(message.data);
+        break;
+      case "audio.trim.success":
+      case "audio.silence.strip.success":
+        this.onAudioEditSuccess(message.data);
+        break;
+      case "audio.trim.error":
+      case "audio.silence.strip.error":
+        this.onAudioEditError(message.data);
+        break;
+      case "recording.saved":
+        this.onRecordingSaved(message.data);
+        break;
+      case "recording.error":
+        this.log(`❌ Recording failed: ${message.data.message}`, "system");
+        break;
+      case "live.bed.status":
+        this.onLiveBedStatus(message.data);
+        break;
+      case "live.bed.error":
+        this.log(
+          `❌ Bed control failed: ${message.data.error} - ${message.data.message}`,
+          "system"
+        );
+        break;
+      case "live.sfx.started":
+        this.onLiveSFXStarted(message.data);
+        break;
+      case "live.sfx.stopped":
+        this.onLiveSFXStopped(message.data);
+        break;
+      case "live.sfx.error":
+        this.log(
+          `❌ SFX failed: ${message.data.error} - ${message.data.message}`,
+          "system"
+        );
+        break;
       case "audio.playlist.create.success":
         this.onPlaylistCreateSuccess(message.data);
         break;
@@ -1317,6 +1609,7 @@ class PIrateRFController {
     }
     this.psInput.value = result;
     this.saveState();
+    this.sendRDSText("ps");
   }
 
   generateRandomRT() {
@@ -1333,6 +1626,209 @@ class PIrateRFController {
     }
     this.rtInput.value = result;
     this.saveState();
+    this.sendRDSText("rt");
+  }
+
+  // Change PS or RT of the FM broadcast on air through its control pipe
+  sendRDSText(field) {
+    if (!this.isExecuting || this.onAirModule !== "pifmrds") {
+      return;
+    }
+
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      return;
+    }
+
+    const input = field === "ps" ? this.psInput : this.rtInput;
+    const message = {
+      type: `rds.${field}.set`,
+      data: { text: input.value },
+      id: this.generateUUID(),
+    };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+
+    this.ws.send(JSON.stringify(message));
+  }
+
+  // Retune, change power or pause the IQ replay on air through its shared
+  // memory control block
+  sendSendiqControl(type, data) {
+    if (!this.isExecuting || this.onAirModule !== "sendiq") {
+      return;
+    }
+
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      return;
+    }
+
+    const message = { type, data, id: this.generateUUID() };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+
+    this.ws.send(JSON.stringify(message));
+  }
+
+  // Change the bed levels of the live broadcast on air or fire SFX into it
+  sendLiveAudioControl(type, data) {
+    if (!this.isExecuting || this.onAirModule !== "audiosock-broadcast") {
+      return;
+    }
+
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      return;
+    }
+
+    const message = { type, data, id: this.generateUUID() };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+
+    this.ws.send(JSON.stringify(message));
+  }
+
+  onLiveBedStatus(data) {
+    if (!data.onAir) {
+      return;
+    }
+
+    this.audioSockBedVolumeInput.value = data.volume;
+    this.audioSockDuckDepthInput.value = data.duckDepth;
+    this.audioSockDuckThresholdInput.value = data.duckThreshold;
+
+    this.log(
+      `🎵 Bed live: volume ${data.volume} dB, ducking ${data.duckDepth} dB below ${data.duckThreshold} dBFS`,
+      "system"
+    );
+  }
+
+  // One button per SFX, they only do something while on air
+  renderSoundboard(sfxFiles) {
+    this.soundboard.innerHTML = "";
+
+    if (sfxFiles.length === 0) {
+      const empty = document.createElement("span");
+      empty.className = "soundboard-empty";
+      empty.textContent = "No SFX uploaded";
+      this.soundboard.appendChild(empty);
+      return;
+    }
+
+    sfxFiles.forEach((file) => {
+      const serverPath = this.buildFilePath(file.name, "sfx", true);
+      const button = document.createElement("button");
+      button.type = "button";
+      button.className = "sfx-btn";
+      button.dataset.sfxPath = serverPath;
+      button.textContent = file.name.split("/").pop().replace(/\.wav$/, "");
+      button.title = `Play ${file.name} on air`;
+      button.addEventListener("click", () => this.playLiveSFX(serverPath));
+      this.soundboard.appendChild(button);
+    });
+
+    this.renderLiveSFX();
+  }
+
+  playLiveSFX(serverPath) {
+    if (!this.isExecuting || this.onAirModule !== "audiosock-broadcast") {
+      this.log("❌ SFX only play over a live broadcast on air", "system");
+      return;
+    }
+
+    this.sendLiveAudioControl("live.sfx.play", {
+      file: serverPath,
+      gain: this.optionalNumber(this.sfxGainInput.value),
+      exclusive: this.sfxExclusiveInput.checked,
+    });
+  }
+
+  onLiveSFXStarted(data) {
+    this.liveSFX.set(data.id, data);
+    this.renderLiveSFX();
+
+    this.log(
+      `🔊 SFX on air: ${data.fileName} (${data.duration.toFixed(1)}s at ${data.gain} dB)`,
+      "system"
+    );
+  }
+
+  onLiveSFXStopped(data) {
+    this.liveSFX.delete(data.id);
+    this.renderLiveSFX();
+
+    if (data.reason !== "finished") {
+      this.log(`🔇 SFX ${data.reason}: ${data.fileName}`, "system");
+    }
+  }
+
+  // List what's playing with a stop button each and light up its buttons
+  renderLiveSFX() {
+    this.sfxPlayingList.innerHTML = "";
+
+    const playing = new Set();
+
+    this.liveSFX.forEach((clip) => {
+      playing.add(clip.filePath);
+
+      const item = document.createElement("li");
+      const stopBtn = document.createElement("button");
+      stopBtn.type = "button";
+      stopBtn.className = "sfx-stop-btn";
+      stopBtn.textContent = "⏹️";
+      stopBtn.title = `Stop ${clip.fileName}`;
+      stopBtn.addEventListener("click", () =>
+        this.sendLiveAudioControl("live.sfx.stop", { id: clip.id })
+      );
+
+      const name = document.createElement("span");
+      name.textContent = clip.fileName;
+
+      item.appendChild(stopBtn);
+      item.appendChild(name);
+      this.sfxPlayingList.appendChild(item);
+    });
+
+    this.soundboard.querySelectorAll(".sfx-btn").forEach((button) => {
+      button.classList.toggle("playing", playing.has(button.dataset.sfxPath));
+    });
+  }
+
+  // Empty inputs leave the server default
+  optionalNumber(value) {
+    return value === "" ? null : parseFloat(value);
+  }
+
+  onSendiqStatus(data) {
+    if (!data.onAir) {
+      this.sendiqCarrier = false;
+      this.sendiqCarrierBtn.classList.add("hidden");
+      return;
+    }
+
+    this.sendiqCarrier = data.carrier;
+    this.sendiqCarrierBtn.classList.remove("hidden");
+    this.sendiqCarrierBtn.classList.toggle("active", data.carrier);
+    this.sendiqCarrierBtn.textContent = data.carrier
+      ? "▶️ Resume IQ"
+      : "📶 Carrier Only";
+
+    if (data.frequency) {
+      this.sendiqFreqInput.value = data.frequency;
+    }
+
+    if (data.power) {
+      this.sendiqPowerInput.value = data.power;
+    }
+
+    this.log(
+      `📡 IQ live: ${data.frequency} Hz, power ${data.power}${data.carrier ? ", unmodulated carrier (still on air)" : ""}`,
+      "system"
+    );
   }
 
   setExecutionMode(isExecuting) {
@@ -1467,7 +1963,11 @@ class PIrateRFController {
         break;
       case "audiosock-broadcast":
         // For AudioSock, socket path gets populated after connecting, so don't require it
-        isValid = module && this.audioSockBroadcastFreqInput.value;
+        isValid =
+          module &&
+          this.audioSockBroadcastFreqInput.value &&
+          (this.audioSockSourceInput.value !== "file" ||
+            this.audioSockAudioInput.value);
         break;
       case "sendiq":
         isValid = module && this.sendiqFreqInput.value && this.sendiqInputFileInput.value;
@@ -2120,6 +2620,11 @@ class PIrateRFController {
         timeout = 0; // No timeout for fsk by default
         break;
       case "audiosock-broadcast":
+        if (this.audioSockSourceInput.value === "file") {
+          this.startAudioSockPlayout();
+          return;
+        }
+
         // For audiosock-broadcast, we first need to connect to /wsunix to get socket path
         this.startAudioSockBroadcast();
         return; // Return early, don't send normal rpitx message yet
@@ -2143,6 +2648,9 @@ class PIrateRFController {
           module === "pifmrds"
             ? this.playModeToggle.classList.contains("active")
             : false,
+        deadMan: this.deadManToggle.classList.contains("active"),
+        autoRT: module === "pifmrds" && this.autoRTInput.checked,
+        autoPS: module === "pifmrds" && this.autoPSInput.checked,
         intro:
           module === "pifmrds" &&
           this.introOutroToggle.classList.contains("active")
@@ -2157,6 +2665,22 @@ class PIrateRFController {
       id: this.generateUUID(),
     };
 
+    // Transitions live in the intro/outro panel
+    if (
+      module === "pifmrds" &&
+      this.introOutroToggle.classList.contains("active")
+    ) {
+      Object.assign(
+        message.data,
+        this.readTransitions(
+          this.crossfadeInput,
+          this.gapInput,
+          this.fadeInInput,
+          this.fadeOutInput
+        )
+      );
+    }
+
     if (this.isDebugMode) {
       this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
     }
@@ -2191,6 +2715,11 @@ class PIrateRFController {
 
   onExecutionStarted(data) {
     this.setExecutionMode(true);
+    this.onAirModule = data.moduleName;
+
+    if (data.moduleName === "sendiq" && data.args.sharedMemToken) {
+      this.sendSendiqControl("sendiq.status", {});
+    }
 
     // Format as command line dynamically
     let cmdLine = data.moduleName;
@@ -2221,10 +2750,54 @@ class PIrateRFController {
     }
   }
 
+  onExecutionProgress(data) {
+    const items = { intro: "🎬 Intro", main: "🎵 Main", outro: "🏁 Outro" };
+    const item = items[data.currentItem] || data.currentItem;
+
+    this.progressBar.classList.remove("hidden");
+    this.progressFill.style.width = `${Math.min(data.percent, 100)}%`;
+    this.progressText.textContent = `${item} · ${this.formatSeconds(
+      data.elapsed
+    )} / ${this.formatSeconds(data.total)} · ${Math.round(data.percent)}%`;
+  }
+
+  hideExecutionProgress() {
+    this.progressBar.classList.add("hidden");
+    this.progressFill.style.width = "0";
+    this.progressText.textContent = "";
+  }
+
+  formatSeconds(seconds) {
+    const total = Math.floor(seconds);
+    const minutes = Math.floor(total / 60);
+    return `${minutes}:${String(total % 60).padStart(2, "0")}`;
+  }
+
   onExecutionStopped(data) {
     this.setExecutionMode(false);
+    this.hideExecutionProgress();
+    this.onSendiqStatus({ onAir: false });
+    this.liveSFX.clear();
+    this.renderLiveSFX();
+
+    const reasons = {
+      user_stop: "stopped by user",
+      timeout: "timeout reached",
+      play_once_complete: "playback finished",
+      process_exit: "process exited",
+      shutdown: "PIrateRF is shutting down",
+      dead_man: "dead-man switch, initiating device went quiet",
+      error: "execution failed",
+    };
+    let details = reasons[data.reason] || data.reason || "";
+    if (data.exitCode !== null && data.exitCode !== undefined) {
+      details += `, exit code ${data.exitCode}`;
+    }
+    if (typeof data.duration === "number") {
+      details += `, on air for ${data.duration.toFixed(1)}s`;
+    }
 
-    this.log("🛑 EXECUTION STOPPED", "system");
+    this.log(`🛑 EXECUTION STOPPED${details ? ` (${details})` : ""}`, "system");
     if (this.isDebugMode) {
       this.log(`Client: ${data.stoppingClientId}`, "system");
     }
@@ -2253,11 +2826,47 @@ class PIrateRFController {
     }
   }
 
+  onExecutionRejected(data) {
+    // Only we get this - whatever is on air for everyone else keeps going
+    this.log(`🚫 TRANSMISSION REJECTED: ${data.error}`, "system");
+    this.log(`Message: ${data.message}`, "system");
+
+    // Nothing will read from the unix socket (USB AudioSock Broadcast)
+    if (this.unixSocket && this.unixSocket.readyState === WebSocket.OPEN) {
+      this.stopMicrophoneCapture();
+      this.unixSocket.close();
+      this.unixSocket = null;
+    }
+  }
+
+  onOutputLines(data) {
+    // Output comes in batches, one event per flush interval
+    (data.lines || []).forEach((line) => this.onOutputLine(line));
+  }
+
   onOutputLine(data) {
     const prefix = data.type.toUpperCase();
     this.log(`[${prefix}] ${data.line}`, "output");
   }
 
+  onExecutionStatus(data) {
+    // Sent when we (re)connect - catch up with whatever is on air
+    if (data.state === "idle" || !data.moduleName) {
+      this.setExecutionMode(false);
+      this.hideExecutionProgress();
+      return;
+    }
+
+    this.onExecutionStarted({
+      moduleName: data.moduleName,
+      args: data.args || {},
+      initiatingClientId: data.initiatingClientId,
+    });
+    this.log(`⏱️ On air for ${data.elapsed}s`, "system");
+
+    (data.outputLines || []).forEach((line) => this.onOutputLine(line));
+  }
+
   log(message, type = "system") {
     const entry = document.createElement("div");
     entry.className = `log-entry log-${type}`;
@@ -2301,6 +2910,8 @@ class PIrateRFController {
     const formData = new FormData();
     formData.append("file", file);
     formData.append("module", "pifmrds");
+    this.appendNormalizeFields(formData);
+    this.appendStereoField(formData);
 
     this.setUploadStatus("Uploading...", "uploading");
 
@@ -2327,6 +2938,8 @@ class PIrateRFController {
           `Uploaded: ${result.original_filename}`,
           "success"
         );
+        this.logLoudness(result);
+        this.logMetadata(result);
 
         // Clear the file input after successful upload
         this.audioFileInput.value = "";
@@ -2611,6 +3224,8 @@ class PIrateRFController {
       const formData = new FormData();
       formData.append("file", audioBlob, filename);
       formData.append("module", "pifmrds");
+      this.appendNormalizeFields(formData);
+      this.appendStereoField(formData);
 
       this.setRecordStatus("Uploading recording...", "uploading");
 
@@ -2637,6 +3252,8 @@ class PIrateRFController {
 
       if (result.status === "success") {
         this.setRecordStatus(`Recorded and uploaded: ${filename}`, "success");
+        this.logLoudness(result);
+        this.logMetadata(result);
 
         // Refresh dropdown to show new file and auto-select it
         await this.loadAudioFiles();
@@ -2652,7 +3269,7 @@ class PIrateRFController {
   }
 
   async loadAudioFiles(selectFilename = null) {
-    return this.loadFiles({
+    await this.loadFiles({
       endpoint: window.PIrateRFConfig.paths.audioUploadFiles,
       selectElement: this.audioInput,
       fileTypes: ['.wav'],
@@ -2665,6 +3282,100 @@ class PIrateRFController {
       useServerPath: true,
       pathType: "uploads"
     });
+
+    this.renderStoredPlaylistOptions();
+  }
+
+  // Stored playlists go at the bottom of the audio dropdown as
+  // "playlist:<id>" so the server expands them when transmitting
+  renderStoredPlaylistOptions() {
+    const selected = this.audioInput.value;
+    const existing = this.audioInput.querySelector("optgroup.stored-playlists");
+    if (existing) {
+      existing.remove();
+    }
+
+    if (this.storedPlaylists.length === 0) {
+      this.renderAudioSockBedOptions();
+      return;
+    }
+
+    const group = document.createElement("optgroup");
+    group.className = "stored-playlists";
+    group.label = "Stored Playlists";
+
+    this.storedPlaylists.forEach((pl) => {
+      const option = document.createElement("option");
+      option.value = `playlist:${pl.id}`;
+      option.textContent = `📋 ${pl.name} (${pl.items.length})`;
+      group.appendChild(option);
+    });
+
+    this.audioInput.appendChild(group);
+
+    // A saved stored playlist selection wins once, when they first arrive
+    const savedAudio = this.state.pifmrds && this.state.pifmrds.audio;
+    const restoreSaved =
+      !this.storedPlaylistRestored &&
+      savedAudio &&
+      savedAudio.startsWith("playlist:");
+    const candidates = restoreSaved ? [savedAudio, selected] : [selected];
+    this.storedPlaylistRestored = true;
+
+    const wanted = candidates.find(
+      (value) =>
+        value &&
+        Array.from(this.audioInput.options).some((o) => o.value === value)
+    );
+    if (wanted) {
+      this.audioInput.value = wanted;
+    }
+
+    this.renderAudioSockBedOptions();
+    this.onAudioFileChange();
+  }
+
+  // The live broadcast audio and bed pick from the same audio files and
+  // stored playlists the audio dropdown has
+  renderAudioSockBedOptions() {
+    const state = this.state["audiosock-broadcast"];
+
+    this.copyAudioOptions(
+      this.audioSockBedInput,
+      "None",
+      this.audioSockBedInput.value || state.bed || ""
+    );
+    this.copyAudioOptions(
+      this.audioSockAudioInput,
+      "Select audio...",
+      this.audioSockAudioInput.value || state.audio || ""
+    );
+    this.validateForm();
+  }
+
+  copyAudioOptions(select, emptyText, selected) {
+    select.innerHTML = "";
+    select.add(new Option(emptyText, ""));
+
+    Array.from(this.audioInput.querySelectorAll("option"))
+      .filter((o) => o.value && !o.disabled)
+      .forEach((o) => select.add(new Option(o.textContent, o.value)));
+
+    const exists = Array.from(select.options).some(
+      (o) => o.value === selected
+    );
+    select.value = exists ? selected : "";
+  }
+
+  onAudioSockSourceChange() {
+    const fromFile = this.audioSockSourceInput.value === "file";
+
+    this.audioSockBroadcastForm
+      .querySelectorAll(".audiosock-file-group")
+      .forEach((group) => group.classList.toggle("hidden", !fromFile));
+    this.audioSockBroadcastForm
+      .querySelectorAll(".audiosock-mic-group")
+      .forEach((group) => group.classList.toggle("hidden", fromFile));
   }
 
   async loadImageFiles(selectFilename = null) {
@@ -2714,12 +3425,78 @@ class PIrateRFController {
 
   onAudioFileChange() {
     const hasSelection = this.audioInput.value && this.audioInput.value !== "";
-    this.editAudioBtn.disabled = !hasSelection;
-    this.playAudioBtn.disabled = !hasSelection;
+    // Stored playlists only become audio at transmit time
+    const isStoredPlaylist = this.audioInput.value.startsWith("playlist:");
+    this.editAudioBtn.disabled = !hasSelection || isStoredPlaylist;
+    this.playAudioBtn.disabled = !hasSelection || isStoredPlaylist;
+    this.showWaveform(this.audioInput.value);
   }
 
-  async openEditModal() {
-    const selectedValue = this.audioInput.value;
+  // showWaveform draws the selected upload from its peaks sidecar, files
+  // without one just don't get a waveform
+  async showWaveform(selectedValue) {
+    const serverAudioPath = window.PIrateRFConfig.serverPaths.audioUploads;
+    const token = (this.waveformToken = (this.waveformToken || 0) + 1);
+
+    if (!selectedValue || !selectedValue.startsWith(`${serverAudioPath}/`)) {
+      this.audioWaveform.classList.add("hidden");
+      return;
+    }
+
+    const relativePath = selectedValue
+      .slice(serverAudioPath.length + 1)
+      .replace(/\.wav$/i, ".peaks.json");
+    const url =
+      window.PIrateRFConfig.paths.audioUploadFiles +
+      "/" +
+      relativePath.split("/").map(encodeURIComponent).join("/");
+
+    let peaks = null;
+    try {
+      const response = await fetch(url, { cache: "no-cache" });
+      if (response.ok) {
+        peaks = await response.json();
+      }
+    } catch (error) {
+      this.debug("Waveform fetch failed:", error);
+    }
+
+    // Another file got selected in the meantime
+    if (token !== this.waveformToken) {
+      return;
+    }
+
+    if (!peaks || !peaks.min || peaks.min.length === 0) {
+      this.audioWaveform.classList.add("hidden");
+      return;
+    }
+
+    this.drawWaveform(peaks);
+    this.audioWaveformInfo.textContent =
+      `⏱️ ${this.formatSeconds(peaks.duration)} · ` +
+      `RMS ${peaks.rms.toFixed(1)} dBFS · ` +
+      `peak ${peaks.peak.toFixed(1)} dBFS`;
+    this.audioWaveform.classList.remove("hidden");
+  }
+
+  drawWaveform(peaks) {
+    const canvas = this.audioWaveformCanvas;
+    const ctx = canvas.getContext("2d");
+    const middle = canvas.height / 2;
+    const barWidth = canvas.width / peaks.min.length;
+
+    ctx.clearRect(0, 0, canvas.width, canvas.height);
+    ctx.fillStyle = "#00ff00";
+
+    peaks.min.forEach((low, i) => {
+      const top = middle - peaks.max[i] * middle;
+      const height = Math.max((peaks.max[i] - low) * middle, 1);
+      ctx.fillRect(i * barWidth, top, Math.max(barWidth, 1), height);
+    });
+  }
+
+  async openEditModal() {
+    const selectedValue = this.audioInput.value;
     if (!selectedValue || selectedValue === "") {
       return;
     }
@@ -2735,6 +3512,13 @@ class PIrateRFController {
     // Set the filename in the input
     this.editFileName.value = this.currentEditFile;
 
+    // Uploads can be trimmed and stripped too
+    this.trimStartInput.value = "0";
+    this.trimEndInput.value = "0";
+    this.trimCutInput.checked = false;
+    this.audioEditReplaceInput.checked = false;
+    this.audioEditSection.classList.remove("hidden");
+
     // Show the modal
     this.fileEditModal.style.display = "flex";
   }
@@ -2743,6 +3527,7 @@ class PIrateRFController {
   closeEditModal() {
     // Reset all modal state regardless of type
     this.fileEditModal.style.display = "none";
+    this.audioEditSection.classList.add("hidden");
     this.currentEditFile = null;
     this.currentEditDirectory = null;
     this.currentEditModule = null;
@@ -2750,6 +3535,82 @@ class PIrateRFController {
     this.editFileName.value = "";
   }
 
+  // sendAudioEdit asks for an edit of the selected upload, the result
+  // becomes a new file unless replacing the original
+  sendAudioEdit(type, data, loadingText) {
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      this.log("❌ WebSocket not connected", "system");
+      return;
+    }
+
+    this.showLoadingScreen(loadingText);
+
+    const message = {
+      type: type,
+      data: {
+        filePath: this.audioInput.value,
+        replace: this.audioEditReplaceInput.checked,
+        ...data,
+      },
+      id: this.generateUUID(),
+    };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+    this.ws.send(JSON.stringify(message));
+  }
+
+  trimAudio() {
+    this.sendAudioEdit(
+      "audio.trim",
+      {
+        start: parseFloat(this.trimStartInput.value) || 0,
+        end: parseFloat(this.trimEndInput.value) || 0,
+        cut: this.trimCutInput.checked,
+      },
+      "Trimming audio..."
+    );
+  }
+
+  stripSilence() {
+    this.sendAudioEdit("audio.silence.strip", {}, "Stripping silence...");
+  }
+
+  onAudioEditSuccess(data) {
+    this.hideLoadingScreen();
+
+    const fileName = data.filePath.split("/").pop();
+    const duration = data.duration
+      ? ` (${this.formatSeconds(data.duration)})`
+      : "";
+    this.log(
+      data.replaced
+        ? `✅ Edited in place: ${fileName}${duration}`
+        : `✅ Edited into: ${fileName}${duration}`,
+      "system"
+    );
+
+    this.closeEditModal();
+    this.loadAudioFiles(fileName).then(() => {
+      this.validateForm();
+      this.saveState();
+    });
+  }
+
+  onAudioEditError(data) {
+    this.hideLoadingScreen();
+    this.log(`❌ Failed to edit audio: ${data.message}`, "system");
+  }
+
+  onRecordingSaved(data) {
+    const url = `${window.PIrateRFConfig.paths.audioRecordingFiles}/${encodeURIComponent(data.fileName)}`;
+    this.log(
+      `💾 Recording saved: ${data.fileName} (${this.formatSeconds(data.duration)}) - ${url}`,
+      "system"
+    );
+  }
+
   renameFile() {
     const newFileName = this.editFileName.value.trim();
 
@@ -3212,9 +4073,17 @@ class PIrateRFController {
         <span class="playlist-item-name" title="${item.name}">${
         item.name
       }</span>
+        <input type="number" class="playlist-item-gain" min="-30" max="12" step="0.5" value="${
+          item.gain || 0
+        }" title="Gain (dB) - used by saved playlists" />
         <button class="remove-btn" data-index="${index}">Remove</button>
       `;
 
+      const gainInput = playlistItem.querySelector(".playlist-item-gain");
+      gainInput.addEventListener("input", () => {
+        item.gain = parseFloat(gainInput.value) || 0;
+      });
+
       const removeBtn = playlistItem.querySelector(".remove-btn");
       removeBtn.addEventListener("click", () => this.removeFromPlaylist(index));
 
@@ -3232,6 +4101,7 @@ class PIrateRFController {
 
   clearPlaylist() {
     this.playlist = [];
+    this.editingPlaylistId = null;
     this.renderPlaylist();
     if (this.isDebugMode) {
       this.log(`📋 Playlist cleared`, "system");
@@ -3244,6 +4114,10 @@ class PIrateRFController {
     const hasValidName = playlistName.length > 0;
 
     this.createPlaylistBtn.disabled = !(hasPlaylistItems && hasValidName);
+    this.savePlaylistBtn.disabled = !(hasPlaylistItems && hasValidName);
+    this.savePlaylistBtn.textContent = this.editingPlaylistId
+      ? "💾 Update"
+      : "💾 Save";
   }
 
   createPlaylist() {
@@ -3277,6 +4151,12 @@ class PIrateRFController {
       data: {
         playlistFileName: finalPlaylistName,
         files: this.playlist.map((item) => item.path),
+        ...this.readTransitions(
+          this.playlistCrossfadeInput,
+          this.playlistGapInput,
+          this.playlistFadeInInput,
+          this.playlistFadeOutInput
+        ),
       },
       id: this.generateUUID(),
     };
@@ -3295,6 +4175,22 @@ class PIrateRFController {
     );
   }
 
+  // readTransitions returns the crossfade, gap and fades set in the given
+  // inputs, leaving out the ones that are off
+  readTransitions(crossfade, gap, fadeIn, fadeOut) {
+    const transitions = {};
+    Object.entries({ crossfade, gap, fadeIn, fadeOut }).forEach(
+      ([key, input]) => {
+        const value = parseFloat(input.value);
+        if (value > 0) {
+          transitions[key] = value;
+        }
+      }
+    );
+
+    return transitions;
+  }
+
   onPlaylistCreateSuccess(data) {
     this.hideLoadingScreen();
     this.log(
@@ -3316,6 +4212,197 @@ class PIrateRFController {
     });
   }
 
+  sendPlaylistEvent(type, data) {
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      this.log("❌ WebSocket not connected", "system");
+      return false;
+    }
+
+    const message = { type, data, id: this.generateUUID() };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+    this.ws.send(JSON.stringify(message));
+
+    return true;
+  }
+
+  // Saves the playlist being built as a stored playlist, or updates the
+  // one loaded from the stored list
+  savePlaylist() {
+    const name = this.playlistName.value.trim();
+    if (!name || this.playlist.length === 0) {
+      return;
+    }
+
+    const items = this.playlist.map((item) => ({
+      file: item.displayPath,
+      gain: item.gain || 0,
+    }));
+
+    this.playlistError.style.display = "none";
+
+    if (!this.editingPlaylistId) {
+      if (this.sendPlaylistEvent("playlist.create", { name, items })) {
+        this.log(`💾 Saving playlist: ${name}`, "system");
+      }
+      return;
+    }
+
+    const id = this.editingPlaylistId;
+    const stored = this.storedPlaylists.find((pl) => pl.id === id);
+
+    this.sendPlaylistEvent("playlist.update", { id, items });
+    if (stored && stored.name !== name) {
+      this.sendPlaylistEvent("playlist.rename", { id, name });
+    }
+
+    this.log(`💾 Updating playlist: ${name}`, "system");
+  }
+
+  editStoredPlaylist(pl) {
+    this.editingPlaylistId = pl.id;
+    this.playlistName.value = pl.name;
+    this.playlist = pl.items.map((item) => {
+      const slash = item.file.indexOf("/");
+      const type = item.file.slice(0, slash);
+      const name = item.file.slice(slash + 1);
+
+      return {
+        name: name.split("/").pop(),
+        path: this.buildFilePath(name, type, true),
+        displayPath: `${window.PIrateRFConfig.paths.files}/${window.PIrateRFConfig.directories.audioFiles}/${item.file}`,
+        type,
+        gain: item.gain || 0,
+      };
+    });
+    this.renderPlaylist();
+  }
+
+  deleteStoredPlaylist(pl) {
+    if (!confirm(`Delete stored playlist "${pl.name}"?`)) {
+      return;
+    }
+
+    this.sendPlaylistEvent("playlist.delete", { id: pl.id });
+  }
+
+  renderStoredPlaylists() {
+    this.storedPlaylistList.innerHTML = "";
+
+    if (this.storedPlaylists.length === 0) {
+      this.storedPlaylistList.innerHTML =
+        '<div style="color: #666; padding: 10px;">No stored playlists</div>';
+      return;
+    }
+
+    this.storedPlaylists.forEach((pl) => {
+      const entry = document.createElement("div");
+      entry.className = "file-item";
+      entry.innerHTML = `
+        <span class="file-name" title="${pl.name}">📋 ${pl.name} (${pl.items.length})</span>
+        <div class="file-buttons">
+          <button class="add-btn" title="Load into the editor">Edit</button>
+          <button class="remove-btn" title="Delete stored playlist">Delete</button>
+        </div>
+      `;
+
+      entry
+        .querySelector(".add-btn")
+        .addEventListener("click", () => this.editStoredPlaylist(pl));
+      entry
+        .querySelector(".remove-btn")
+        .addEventListener("click", () => this.deleteStoredPlaylist(pl));
+
+      this.storedPlaylistList.appendChild(entry);
+    });
+  }
+
+  onPlaylistsUpdated(data) {
+    this.storedPlaylists = (data.playlists || []).sort((a, b) =>
+      a.name.localeCompare(b.name)
+    );
+
+    // The playlist being edited got deleted somewhere else
+    if (
+      this.editingPlaylistId &&
+      !this.storedPlaylists.some((pl) => pl.id === this.editingPlaylistId)
+    ) {
+      this.editingPlaylistId = null;
+      this.validatePlaylistCreation();
+    }
+
+    this.renderStoredPlaylists();
+    this.renderStoredPlaylistOptions();
+
+    if (this.isDebugMode) {
+      this.log(
+        `📋 ${this.storedPlaylists.length} stored playlists`,
+        "system"
+      );
+    }
+  }
+
+  onStoredPlaylistError(data) {
+    this.playlistError.textContent = `${data.error}: ${data.message}`;
+    this.playlistError.style.display = "block";
+    this.log(`❌ Playlist ${data.error}: ${data.message}`, "system");
+  }
+
+  generateTTS() {
+    const text = this.ttsText.value.trim();
+    if (!text) {
+      this.log("❌ Nothing to say. Type some text first.", "system");
+      return;
+    }
+
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      this.log("❌ WebSocket not connected", "system");
+      return;
+    }
+
+    this.showLoadingScreen("Generating speech...");
+
+    const message = {
+      type: "tts.generate",
+      data: {
+        text: text,
+        engine: this.ttsEngine.value,
+        voice: this.ttsVoice.value.trim(),
+        speed: parseInt(this.ttsSpeed.value, 10) || 0,
+        fileName: this.ttsFileName.value.trim(),
+      },
+      id: this.generateUUID(),
+    };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+    this.ws.send(JSON.stringify(message));
+
+    this.log(`🗣️ Generating speech with ${this.ttsEngine.value}`, "system");
+  }
+
+  onTTSGenerateSuccess(data) {
+    this.hideLoadingScreen();
+    this.log(`✅ Speech generated: ${data.fileName}`, "system");
+
+    this.ttsText.value = "";
+    this.ttsFileName.value = "";
+
+    // Refresh the dropdown and select the new file
+    this.loadAudioFiles(data.fileName).then(() => {
+      this.validateForm();
+      this.saveState();
+    });
+  }
+
+  onTTSGenerateError(data) {
+    this.hideLoadingScreen();
+    this.log(`❌ Failed to generate speech: ${data.message}`, "system");
+  }
+
   onPlaylistCreateError(data) {
     this.hideLoadingScreen();
     this.log(`❌ Failed to create playlist: ${data.message}`, "system");
@@ -3426,6 +4513,61 @@ class PIrateRFController {
     }
   }
 
+  toggleNormalize() {
+    const show = this.normalizeControls.classList.contains("hidden");
+    this.normalizeControls.classList.toggle("hidden", !show);
+    this.normalizeToggle.classList.toggle("active", show);
+  }
+
+  // Asks /upload to normalise the audio when the normalize toggle is on
+  appendNormalizeFields(formData) {
+    if (!this.normalizeToggle.classList.contains("active")) {
+      return;
+    }
+
+    formData.append("normalize", "true");
+    formData.append("loudnessTarget", this.loudnessTargetInput.value);
+    formData.append("truePeak", this.truePeakInput.value);
+    formData.append("loudnessRange", this.loudnessRangeInput.value);
+    formData.append("highpass", this.highpassInput.value || "0");
+  }
+
+  // Stereo uploads stay stereo when the stereo toggle is on, so pifmrds
+  // broadcasts them in FM Stereo
+  appendStereoField(formData) {
+    formData.append(
+      "stereo",
+      String(this.stereoToggle.classList.contains("active"))
+    );
+  }
+
+  logLoudness(result) {
+    if (!result.loudness) {
+      return;
+    }
+
+    const { input, output } = result.loudness;
+    this.log(
+      `📊 Loudness ${input.integrated.toFixed(1)} → ` +
+        `${output.integrated.toFixed(1)} LUFS, ` +
+        `true peak ${input.truePeak.toFixed(1)} → ` +
+        `${output.truePeak.toFixed(1)} dBTP`,
+      "system"
+    );
+  }
+
+  logMetadata(result) {
+    if (!result.metadata) {
+      return;
+    }
+
+    const { title, artist, album } = result.metadata;
+    this.log(
+      "🏷️ Tags: " + [artist, title, album].filter(Boolean).join(" · "),
+      "system"
+    );
+  }
+
   toggleIntroOutro() {
     if (this.introOutroControls.classList.contains("hidden")) {
       // Show intro/outro controls
@@ -3461,6 +4603,9 @@ class PIrateRFController {
     this.introSelect.innerHTML = '<option value="">No intro</option>';
     this.outroSelect.innerHTML = '<option value="">No outro</option>';
 
+    this.renderSoundboard(sfxFiles);
+    this.populateAudioSockIntroOutro(sfxFiles);
+
     // Add SFX files to both dropdowns
     sfxFiles.forEach((file) => {
       const serverPath = this.buildFilePath(file.name, "sfx", true); // Server path for backend
@@ -3477,6 +4622,35 @@ class PIrateRFController {
     this.restoreSfxSelections();
   }
 
+  populateAudioSockIntroOutro(sfxFiles) {
+    const state = this.state["audiosock-broadcast"];
+    const selects = [
+      [this.audioSockIntroInput, "No intro", state.intro],
+      [this.audioSockOutroInput, "No outro", state.outro],
+    ];
+
+    selects.forEach(([select, emptyText, saved]) => {
+      const selected = select.value || saved || "";
+
+      select.innerHTML = "";
+      select.add(new Option(emptyText, ""));
+
+      sfxFiles.forEach((file) => {
+        select.add(
+          new Option(
+            file.name.split("/").pop(),
+            this.buildFilePath(file.name, "sfx", true)
+          )
+        );
+      });
+
+      const exists = Array.from(select.options).some(
+        (o) => o.value === selected
+      );
+      select.value = exists ? selected : "";
+    });
+  }
+
   onIntroOutroChange() {
     // Enable/disable play buttons based on selection
     this.playIntroBtn.disabled = !this.introSelect.value;
@@ -3777,6 +4951,7 @@ class PIrateRFController {
 
     // Update state object from current DOM values
     this.state.modulename = this.moduleSelect.value;
+    this.state.deadMan = this.deadManToggle.classList.contains("active");
 
     // Update PIFMRDS state
     this.state.pifmrds.freq = this.freqInput.value;
@@ -3784,6 +4959,8 @@ class PIrateRFController {
     this.state.pifmrds.pi = this.piInput.value;
     this.state.pifmrds.ps = this.psInput.value;
     this.state.pifmrds.rt = this.rtInput.value;
+    this.state.pifmrds.autoRT = this.autoRTInput.checked;
+    this.state.pifmrds.autoPS = this.autoPSInput.checked;
     this.state.pifmrds.ppm = this.ppmInput.value;
     this.state.pifmrds.timeout = document.getElementById("timeout").value;
     this.state.pifmrds.playOnce =
@@ -3791,7 +4968,20 @@ class PIrateRFController {
     this.state.pifmrds.introOutroToggled =
       this.introOutroToggle.classList.contains("active");
     this.state.pifmrds.introSelect = this.introSelect.value;
+    this.state.pifmrds.normalizeToggled =
+      this.normalizeToggle.classList.contains("active");
+    this.state.pifmrds.stereoToggled = this.stereoChosen
+      ? this.stereoToggle.classList.contains("active")
+      : null;
+    this.state.pifmrds.loudnessTarget = this.loudnessTargetInput.value;
+    this.state.pifmrds.truePeak = this.truePeakInput.value;
+    this.state.pifmrds.loudnessRange = this.loudnessRangeInput.value;
+    this.state.pifmrds.highpass = this.highpassInput.value;
     this.state.pifmrds.outroSelect = this.outroSelect.value;
+    this.state.pifmrds.crossfade = this.crossfadeInput.value;
+    this.state.pifmrds.gap = this.gapInput.value;
+    this.state.pifmrds.fadeIn = this.fadeInInput.value;
+    this.state.pifmrds.fadeOut = this.fadeOutInput.value;
 
     // Update MORSE state
     this.state.morse.freq = document.getElementById("morseFreq")?.value || "";
@@ -3877,6 +5067,18 @@ class PIrateRFController {
     this.state["audiosock-broadcast"].bufferSize = this.audioSockBroadcastBufferSizeInput.value;
     this.state["audiosock-broadcast"].modulation = this.audioSockBroadcastModulationInput.value;
     this.state["audiosock-broadcast"].gain = this.audioSockBroadcastGainInput.value;
+    this.state["audiosock-broadcast"].source = this.audioSockSourceInput.value;
+    this.state["audiosock-broadcast"].audio = this.audioSockAudioInput.value;
+    this.state["audiosock-broadcast"].playOnce = this.audioSockPlayOnceInput.checked;
+    this.state["audiosock-broadcast"].intro = this.audioSockIntroInput.value;
+    this.state["audiosock-broadcast"].outro = this.audioSockOutroInput.value;
+    this.state["audiosock-broadcast"].record = this.audioSockRecordInput.checked;
+    this.state["audiosock-broadcast"].bed = this.audioSockBedInput.value;
+    this.state["audiosock-broadcast"].bedVolume = this.audioSockBedVolumeInput.value;
+    this.state["audiosock-broadcast"].duckDepth = this.audioSockDuckDepthInput.value;
+    this.state["audiosock-broadcast"].duckThreshold = this.audioSockDuckThresholdInput.value;
+    this.state["audiosock-broadcast"].sfxGain = this.sfxGainInput.value;
+    this.state["audiosock-broadcast"].sfxExclusive = this.sfxExclusiveInput.checked;
 
     // Update SENDIQ state
     this.state.sendiq.freq = this.sendiqFreqInput.value;
@@ -3991,12 +5193,17 @@ class PIrateRFController {
     if (this.state.pifmrds.pi) this.piInput.value = this.state.pifmrds.pi;
     if (this.state.pifmrds.ps) this.psInput.value = this.state.pifmrds.ps;
     if (this.state.pifmrds.rt) this.rtInput.value = this.state.pifmrds.rt;
+    this.autoRTInput.checked = !!this.state.pifmrds.autoRT;
+    this.autoPSInput.checked = !!this.state.pifmrds.autoPS;
     if (this.state.pifmrds.ppm !== undefined) this.ppmInput.value = this.state.pifmrds.ppm;
     if (this.state.pifmrds.timeout) {
       const timeoutEl = document.getElementById("timeout");
       if (timeoutEl) timeoutEl.value = this.state.pifmrds.timeout;
     }
 
+    // Sync dead-man switch toggle
+    this.deadManToggle.classList.toggle("active", !!this.state.deadMan);
+
     // Sync play mode toggle (PIFMRDS only)
     if (this.state.pifmrds.playOnce !== undefined) {
       if (this.state.pifmrds.playOnce) {
@@ -4023,6 +5230,48 @@ class PIrateRFController {
       this.loadSfxFiles();
     }
 
+    // Keeping stereo goes with the server default until it's toggled
+    const savedStereo = this.state.pifmrds.stereoToggled;
+    this.stereoChosen = typeof savedStereo === "boolean";
+    this.stereoToggle.classList.toggle(
+      "active",
+      this.stereoChosen
+        ? savedStereo
+        : Boolean(
+            window.PIrateRFConfig.audio &&
+              window.PIrateRFConfig.audio.preserveStereo
+          )
+    );
+
+    // Sync loudness normalisation (PIFMRDS uploads only)
+    const normalizeToggled = Boolean(this.state.pifmrds.normalizeToggled);
+    this.normalizeToggle.classList.toggle("active", normalizeToggled);
+    this.normalizeControls.classList.toggle("hidden", !normalizeToggled);
+    if (this.state.pifmrds.loudnessTarget) {
+      this.loudnessTargetInput.value = this.state.pifmrds.loudnessTarget;
+    }
+    if (this.state.pifmrds.truePeak) {
+      this.truePeakInput.value = this.state.pifmrds.truePeak;
+    }
+    if (this.state.pifmrds.loudnessRange) {
+      this.loudnessRangeInput.value = this.state.pifmrds.loudnessRange;
+    }
+    if (this.state.pifmrds.highpass) {
+      this.highpassInput.value = this.state.pifmrds.highpass;
+    }
+
+    // Sync intro/outro transitions (PIFMRDS only)
+    [
+      [this.crossfadeInput, this.state.pifmrds.crossfade],
+      [this.gapInput, this.state.pifmrds.gap],
+      [this.fadeInInput, this.state.pifmrds.fadeIn],
+      [this.fadeOutInput, this.state.pifmrds.fadeOut],
+    ].forEach(([input, value]) => {
+      if (value) {
+        input.value = value;
+      }
+    });
+
     // Sync MORSE form inputs
     if (this.state.morse.freq) {
       const morseFreqEl = document.getElementById("morseFreq");
@@ -4199,6 +5448,20 @@ class PIrateRFController {
       this.audioSockBroadcastModulationInput.value = this.state["audiosock-broadcast"].modulation;
     if (this.state["audiosock-broadcast"].gain && this.audioSockBroadcastGainInput)
       this.audioSockBroadcastGainInput.value = this.state["audiosock-broadcast"].gain;
+    if (this.state["audiosock-broadcast"].source && this.audioSockSourceInput)
+      this.audioSockSourceInput.value = this.state["audiosock-broadcast"].source;
+    this.audioSockPlayOnceInput.checked = !!this.state["audiosock-broadcast"].playOnce;
+    this.onAudioSockSourceChange();
+    this.audioSockRecordInput.checked = !!this.state["audiosock-broadcast"].record;
+    if (this.state["audiosock-broadcast"].bedVolume && this.audioSockBedVolumeInput)
+      this.audioSockBedVolumeInput.value = this.state["audiosock-broadcast"].bedVolume;
+    if (this.state["audiosock-broadcast"].duckDepth && this.audioSockDuckDepthInput)
+      this.audioSockDuckDepthInput.value = this.state["audiosock-broadcast"].duckDepth;
+    if (this.state["audiosock-broadcast"].duckThreshold && this.audioSockDuckThresholdInput)
+      this.audioSockDuckThresholdInput.value = this.state["audiosock-broadcast"].duckThreshold;
+    if (this.state["audiosock-broadcast"].sfxGain && this.sfxGainInput)
+      this.sfxGainInput.value = this.state["audiosock-broadcast"].sfxGain;
+    this.sfxExclusiveInput.checked = !!this.state["audiosock-broadcast"].sfxExclusive;
 
     // Restore SENDIQ state
     if (this.state.sendiq.freq && this.sendiqFreqInput)
@@ -4302,25 +5565,8 @@ class PIrateRFController {
   }
 
   startGorpitxAudioSockModule(initData, unixWs) {
-    const args = {
-      frequency: parseFloat(this.audioSockBroadcastFreqInput.value),
-      socketPath: initData.writerSocket
-    };
-
-    // Add optional sample rate if provided
-    if (this.audioSockBroadcastSampleRateInput.value && this.audioSockBroadcastSampleRateInput.value.trim() !== "") {
-      args.sampleRate = parseInt(this.audioSockBroadcastSampleRateInput.value);
-    }
-
-    // Add optional modulation if provided
-    if (this.audioSockBroadcastModulationInput.value && this.audioSockBroadcastModulationInput.value.trim() !== "") {
-      args.modulation = this.audioSockBroadcastModulationInput.value.trim();
-    }
-
-    // Add optional gain if provided
-    if (this.audioSockBroadcastGainInput.value && this.audioSockBroadcastGainInput.value.trim() !== "") {
-      args.gain = parseFloat(this.audioSockBroadcastGainInput.value);
-    }
+    const args = this.buildAudioSockArgs();
+    args.socketPath = initData.writerSocket;
 
     // Now start the gorpitx module through normal WebSocket
     const message = {
@@ -4330,8 +5576,10 @@ class PIrateRFController {
         args: args,
         timeout: 0, // No timeout - run until stopped
         playOnce: false,
+        deadMan: this.deadManToggle.classList.contains("active"),
         intro: null,
         outro: null,
+        ...this.audioSockMixFields(),
       },
       id: this.generateUUID(),
     };
@@ -4347,6 +5595,70 @@ class PIrateRFController {
     this.unixSocket = unixWs;
   }
 
+  // Plays an audio file or stored playlist through audiosock-broadcast
+  // instead of the mic, the server paces it into the socket
+  startAudioSockPlayout() {
+    const args = this.buildAudioSockArgs();
+    args.audio = this.audioSockAudioInput.value;
+
+    const message = {
+      type: "rpitx.execution.start",
+      data: {
+        moduleName: "audiosock-broadcast",
+        args: args,
+        timeout: 0,
+        playOnce: this.audioSockPlayOnceInput.checked,
+        deadMan: this.deadManToggle.classList.contains("active"),
+        intro: this.audioSockIntroInput.value || null,
+        outro: this.audioSockOutroInput.value || null,
+        ...this.audioSockMixFields(),
+      },
+      id: this.generateUUID(),
+    };
+
+    if (this.isDebugMode) {
+      this.log("📤 SENDING: " + JSON.stringify(message, null, 2), "send");
+    }
+
+    this.ws.send(JSON.stringify(message));
+  }
+
+  buildAudioSockArgs() {
+    const args = {
+      frequency: parseFloat(this.audioSockBroadcastFreqInput.value),
+    };
+
+    // Add optional sample rate if provided
+    if (this.audioSockBroadcastSampleRateInput.value && this.audioSockBroadcastSampleRateInput.value.trim() !== "") {
+      args.sampleRate = parseInt(this.audioSockBroadcastSampleRateInput.value);
+    }
+
+    // Add optional modulation if provided
+    if (this.audioSockBroadcastModulationInput.value && this.audioSockBroadcastModulationInput.value.trim() !== "") {
+      args.modulation = this.audioSockBroadcastModulationInput.value.trim();
+    }
+
+    // Add optional gain if provided
+    if (this.audioSockBroadcastGainInput.value && this.audioSockBroadcastGainInput.value.trim() !== "") {
+      args.gain = parseFloat(this.audioSockBroadcastGainInput.value);
+    }
+
+    return args;
+  }
+
+  // Recording and bed go with the broadcast whatever its source
+  audioSockMixFields() {
+    return {
+      record: this.audioSockRecordInput.checked,
+      bed: this.audioSockBedInput.value,
+      bedVolume: this.optionalNumber(this.audioSockBedVolumeInput.value),
+      duckDepth: this.optionalNumber(this.audioSockDuckDepthInput.value),
+      duckThreshold: this.optionalNumber(
+        this.audioSockDuckThresholdInput.value
+      ),
+    };
+  }
+
   async startMicrophoneCapture(unixWs) {
     // Initialize microphone status
     this.microphoneReady = false;
@@ -0,0 +1,12 @@
+module github.com/psyb0t/gorpitx
+
+go 1.25
+
+require (
+	github.com/psyb0t/commander v0.4.1
+	github.com/psyb0t/common-go v0.0.0-20250914061813-a517b076b64a
+	github.com/psyb0t/ctxerrors v0.2.0
+	github.com/psyb0t/goenv v1.0.1
+	github.com/psyb0t/gonfiguration v1.2.0
+	github.com/sirupsen/logrus v1.9.3
+)